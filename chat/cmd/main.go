@@ -12,15 +12,18 @@ import (
 	"syscall"
 
 	"github.com/1ocknight/mess/chat/config"
+	"github.com/1ocknight/mess/chat/internal/adapter/archive"
+	"github.com/1ocknight/mess/chat/internal/adapter/profile"
 	"github.com/1ocknight/mess/chat/internal/ctxkey"
 	"github.com/1ocknight/mess/chat/internal/domain"
 	"github.com/1ocknight/mess/chat/internal/loglables"
 	"github.com/1ocknight/mess/chat/internal/storage"
 	"github.com/1ocknight/mess/chat/internal/transport"
 	"github.com/1ocknight/mess/chat/internal/worker"
-	"github.com/1ocknight/mess/shared/auth/keycloak"
+	"github.com/1ocknight/mess/shared/cursor"
 	"github.com/1ocknight/mess/shared/logger"
 	"github.com/1ocknight/mess/shared/postgres"
+	"github.com/1ocknight/mess/shared/verify"
 )
 
 func main() {
@@ -62,11 +65,35 @@ func main() {
 	}
 	lg.Info("up migrations")
 
-	dom := domain.New(storage)
+	dataExportArchive, err := archive.New(ctx, cfg.DataExportArchive)
+	if err != nil {
+		lg.Error(fmt.Errorf("data export archive new: %w", err))
+		return
+	}
 
-	keycloak, err := keycloak.New(cfg.Keycloak, lg)
+	archive, err := archive.New(ctx, cfg.Archive)
 	if err != nil {
-		lg.Error(fmt.Errorf("keycloak new: %w", err))
+		lg.Error(fmt.Errorf("archive new: %w", err))
+		return
+	}
+
+	profile, err := profile.New(cfg.Profile)
+	if err != nil {
+		lg.Error(fmt.Errorf("profile new: %w", err))
+		return
+	}
+
+	dom := domain.New(storage, archive, profile)
+
+	verify, err := verify.New(cfg.Verify, lg)
+	if err != nil {
+		lg.Error(fmt.Errorf("verify new: %w", err))
+		return
+	}
+
+	cursors, err := cursor.New(cfg.Cursor)
+	if err != nil {
+		lg.Error(fmt.Errorf("cursor new: %w", err))
 		return
 	}
 
@@ -86,7 +113,27 @@ func main() {
 	}
 	go lastreadWorker.Run(ctx)
 
-	server := transport.NewServer(cfg.HTTP, lg, dom, keycloak)
+	exportWorkerLg := lg.With(loglables.Service, "export worker")
+	exportWorker := worker.NewExportWorker(storage, archive, profile, exportWorkerLg, &cfg.ExportWorker)
+	go exportWorker.Run(ctx)
+
+	dataExportWorkerLg := lg.With(loglables.Service, "data export worker")
+	dataExportWorker, err := worker.NewDataExportWorker(storage, dataExportArchive, dataExportWorkerLg, &cfg.DataExportWorker)
+	if err != nil {
+		lg.Error(fmt.Errorf("new data export worker: %w", err))
+		return
+	}
+	go dataExportWorker.Run(ctx)
+
+	profileWorkerLg := lg.With(loglables.Service, "profile worker")
+	profileWorker, err := worker.NewProfileWorker(storage, profileWorkerLg, &cfg.ProfileWorker)
+	if err != nil {
+		lg.Error(fmt.Errorf("new profile worker: %w", err))
+		return
+	}
+	go profileWorker.Run(ctx)
+
+	server := transport.NewServer(cfg.HTTP, lg, dom, verify, cursors)
 	go func() {
 		if err := server.Run(); err != nil && !errors.Is(http.ErrServerClosed, err) {
 			lg.Error(fmt.Errorf("server run: %w", err))
@@ -94,6 +141,14 @@ func main() {
 		}
 	}()
 
+	rpcServer := transport.NewRPCServer(cfg.RPC, lg, dom, verify)
+	go func() {
+		if err := rpcServer.Run(); err != nil && !errors.Is(http.ErrServerClosed, err) {
+			lg.Error(fmt.Errorf("rpc server run: %w", err))
+			return
+		}
+	}()
+
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
 	<-stop
@@ -106,6 +161,12 @@ func main() {
 	}
 	lg.Info("server is stop")
 
+	err = rpcServer.Stop(ctx)
+	if err != nil {
+		lg.Error(fmt.Errorf("rpc server stop: %w", err))
+	}
+	lg.Info("rpc server is stop")
+
 	cancel()
 	lg.Info("successful stop")
 }
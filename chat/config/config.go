@@ -5,8 +5,11 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/1ocknight/mess/chat/internal/adapter/archive"
+	"github.com/1ocknight/mess/chat/internal/adapter/profile"
 	"github.com/1ocknight/mess/chat/internal/transport"
 	"github.com/1ocknight/mess/chat/internal/worker"
+	"github.com/1ocknight/mess/shared/cursor"
 	"github.com/1ocknight/mess/shared/postgres"
 	"github.com/1ocknight/mess/shared/verify"
 	"github.com/goccy/go-yaml"
@@ -16,12 +19,23 @@ type Config struct {
 	MigrationsPath string           `yaml:"migrations_path"`
 	Postgres       postgres.Config  `yaml:"postgres"`
 	HTTP           transport.Config `yaml:"http"`
+	RPC            transport.Config `yaml:"rpc"`
 
-	MessageWorker worker.MessageWorkerConfig `yaml:"message_worker"`
+	MessageWorker  worker.MessageWorkerConfig `yaml:"message_worker"`
+	LastReadWorker worker.LastReadConfig      `yaml:"last_read_worker"`
+	ExportWorker   worker.ExportWorkerConfig  `yaml:"export_worker"`
+
+	DataExportWorker worker.DataExportWorkerConfig `yaml:"data_export_worker"`
+	ProfileWorker    worker.ProfileWorkerConfig    `yaml:"profile_worker"`
+
+	Archive           archive.Config `yaml:"archive"`
+	DataExportArchive archive.Config `yaml:"data_export_archive"`
+	Profile           profile.Config `yaml:"profile"`
 
 	LoggerDebug bool `yaml:"logger_debug"`
 
 	Verify verify.Config `yaml:"verify"`
+	Cursor cursor.Config `yaml:"cursor"`
 }
 
 func LoadConfig() (*Config, error) {
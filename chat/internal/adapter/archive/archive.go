@@ -0,0 +1,11 @@
+package archive
+
+import (
+	"context"
+	"io"
+)
+
+type Service interface {
+	Upload(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	GetDownloadURL(ctx context.Context, key string) (string, error)
+}
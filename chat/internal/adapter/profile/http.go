@@ -0,0 +1,120 @@
+package profile
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	httpdto "github.com/1ocknight/mess/shared/dto/http"
+	"github.com/go-resty/resty/v2"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+type Config struct {
+	ProfileURL   string        `yaml:"profile_url"`
+	RPCURL       string        `yaml:"rpc_url"`
+	KeycloakURL  string        `yaml:"keycloak_url"`
+	Realm        string        `yaml:"realm"`
+	ClientID     string        `yaml:"client_id"`
+	ClientSecret string        `yaml:"client_secret"`
+	Timeout      time.Duration `yaml:"timeout"`
+}
+
+type HTTP struct {
+	cfg    Config
+	client *resty.Client
+	tokens oauth2.TokenSource
+}
+
+func New(cfg Config) (Service, error) {
+	tokenURL := fmt.Sprintf("%s/realms/%s/protocol/openid-connect/token",
+		cfg.KeycloakURL, cfg.Realm)
+
+	oauthConfig := &clientcredentials.Config{
+		ClientID:     cfg.ClientID,
+		ClientSecret: cfg.ClientSecret,
+		TokenURL:     tokenURL,
+	}
+
+	client := resty.New()
+	client.SetTimeout(cfg.Timeout)
+
+	// the token source keeps the token until it expires, so calls do not go to keycloak each time
+	return &HTTP{
+		cfg:    cfg,
+		client: client,
+		tokens: oauthConfig.TokenSource(context.Background()),
+	}, nil
+}
+
+// batchSize is the limit of ids in one POST /profiles/batch of profile
+const batchSize = 100
+
+func (h *HTTP) GetAliases(ctx context.Context, subjectIDs []string) (map[string]string, error) {
+	token, err := h.tokens.Token()
+	if err != nil {
+		return nil, fmt.Errorf("oauth token: %w", err)
+	}
+
+	res := make(map[string]string, len(subjectIDs))
+	for ids := range slices.Chunk(subjectIDs, batchSize) {
+		resp, err := h.client.R().
+			SetContext(ctx).
+			SetAuthToken(token.AccessToken).
+			SetBody(httpdto.ProfilesBatchRequest{SubjectIDs: ids}).
+			Post(fmt.Sprintf("%s/profiles/batch", h.cfg.ProfileURL))
+		if err != nil {
+			return nil, fmt.Errorf("get profiles batch: %w", err)
+		}
+
+		if resp.StatusCode() != http.StatusOK {
+			return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode(), resp.Body())
+		}
+
+		var batch httpdto.ProfilesBatchResponse
+		if err := json.Unmarshal(resp.Body(), &batch); err != nil {
+			return nil, fmt.Errorf("unmarshal profiles batch: %w", err)
+		}
+
+		for _, profile := range batch.Profiles {
+			res[profile.SubjectID] = profile.Alias
+		}
+	}
+
+	return res, nil
+}
+
+func (h *HTTP) CanStartChat(ctx context.Context, fromSubjectID string, toSubjectID string) (bool, error) {
+	token, err := h.tokens.Token()
+	if err != nil {
+		return false, fmt.Errorf("oauth token: %w", err)
+	}
+
+	resp, err := h.client.R().
+		SetContext(ctx).
+		SetAuthToken(token.AccessToken).
+		SetQueryParams(map[string]string{"from": fromSubjectID, "to": toSubjectID}).
+		Get(fmt.Sprintf("%s/rpc/chat_permission", h.cfg.RPCURL))
+	if err != nil {
+		return false, fmt.Errorf("get chat permission: %w", err)
+	}
+
+	switch resp.StatusCode() {
+	case http.StatusOK:
+	case http.StatusNoContent, http.StatusNotFound:
+		return false, ErrNotFound
+	default:
+		return false, fmt.Errorf("unexpected status %d: %s", resp.StatusCode(), resp.Body())
+	}
+
+	var permission httpdto.ChatPermissionResponse
+	if err := json.Unmarshal(resp.Body(), &permission); err != nil {
+		return false, fmt.Errorf("unmarshal chat permission: %w", err)
+	}
+
+	return permission.Allowed, nil
+}
@@ -0,0 +1,16 @@
+package profile
+
+import (
+	"context"
+	"fmt"
+)
+
+var ErrNotFound = fmt.Errorf("profile not found")
+
+type Service interface {
+	// GetAliases returns subject_id -> alias, unknown subjects are skipped
+	GetAliases(ctx context.Context, subjectIDs []string) (map[string]string, error)
+	// CanStartChat tells whether the privacy settings of toSubjectID let fromSubjectID start a chat,
+	// ErrNotFound is returned for an unknown toSubjectID
+	CanStartChat(ctx context.Context, fromSubjectID string, toSubjectID string) (bool, error)
+}
@@ -5,11 +5,13 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/1ocknight/mess/chat/internal/adapter/profile"
 	"github.com/1ocknight/mess/chat/internal/ctxkey"
 	loglables "github.com/1ocknight/mess/chat/internal/loglables"
 	"github.com/1ocknight/mess/chat/internal/model"
 	"github.com/1ocknight/mess/chat/internal/storage"
 
+	"github.com/1ocknight/mess/shared/cursor"
 	"github.com/1ocknight/mess/shared/utils"
 )
 
@@ -23,6 +25,17 @@ func (d *Domain) AddChat(ctx context.Context, secondSubjectID string) (*model.Ch
 		return nil, fmt.Errorf("extract logger: %w", err)
 	}
 
+	allowed, err := d.Profile.CanStartChat(ctx, subj.GetSubjectId(), secondSubjectID)
+	if errors.Is(err, profile.ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can start chat: %w", err)
+	}
+	if !allowed {
+		return nil, ErrChatNotAllowed
+	}
+
 	tx, err := d.Storage.WithTransaction(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("storage with transaction: %w", err)
@@ -60,34 +73,32 @@ type LastReadsPair struct {
 	Other *model.LastRead
 }
 
-func (d *Domain) GetChatsMetadata(ctx context.Context, filter *ChatPaginationFilter) ([]*model.ChatMetadata, error) {
+func (d *Domain) GetChatsMetadata(ctx context.Context, filter *ChatPaginationFilter) ([]*model.ChatMetadata, *cursor.Page, error) {
 	subj, err := ctxkey.ExtractSubject(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("extract subject: %w", err)
+		return nil, nil, fmt.Errorf("extract subject: %w", err)
 	}
 
 	storageFilter := DefaultPaginationChat
-	switch filter.Direction {
-	case DirectionBefore:
-		storageFilter.Asc = false
-	default:
-		storageFilter.Asc = true
+	if err := applyCursor(&storageFilter, filter.Limit, filter.Cursor, cursor.DirectionAfter, false); err != nil {
+		return nil, nil, fmt.Errorf("apply cursor: %w", err)
 	}
-
-	storageFilter.LastID = filter.LastChatID
+	limit := storageFilter.Limit
+	storageFilter.Limit++
 
 	chats, err := d.Storage.Chat().GetChatsBySubjectID(ctx, subj.GetSubjectId(), &storageFilter)
 	if err != nil {
-		return nil, fmt.Errorf("get chats bu subject id: %w", err)
+		return nil, nil, fmt.Errorf("get chats bu subject id: %w", err)
 	}
 
+	chats, page := cursor.NewPage(chats, limit, filter.Cursor, cursor.DirectionAfter, chatCursor)
 	if len(chats) == 0 {
-		return []*model.ChatMetadata{}, nil
+		return []*model.ChatMetadata{}, page, nil
 	}
 
 	lastReads, err := d.Storage.LastRead().GetLastReadsByChatIDs(ctx, model.GetChatsID(chats))
 	if err != nil {
-		return nil, fmt.Errorf("get last read by chat ids: %w", err)
+		return nil, nil, fmt.Errorf("get last read by chat ids: %w", err)
 	}
 
 	lastReadsMap := map[int]*LastReadsPair{}
@@ -107,7 +118,7 @@ func (d *Domain) GetChatsMetadata(ctx context.Context, filter *ChatPaginationFil
 
 	lastMessages, err := d.Storage.Message().GetLastMessagesByChatsID(ctx, model.GetChatsID(chats))
 	if err != nil {
-		return nil, fmt.Errorf("get last messages by chats id: %w", err)
+		return nil, nil, fmt.Errorf("get last messages by chats id: %w", err)
 	}
 
 	lastMessagesMap := map[int]*model.Message{}
@@ -123,7 +134,8 @@ func (d *Domain) GetChatsMetadata(ctx context.Context, filter *ChatPaginationFil
 		}
 
 		meta := &model.ChatMetadata{
-			ChatID: chat.ID,
+			ChatID:    chat.ID,
+			UpdatedAt: chat.UpdatedAt,
 			LastMessage: model.LastMessage{
 				MessageID: lastMessage.ID,
 				Content:   lastMessage.Content,
@@ -144,7 +156,7 @@ func (d *Domain) GetChatsMetadata(ctx context.Context, filter *ChatPaginationFil
 		res = append(res, meta)
 	}
 
-	return res, nil
+	return res, page, nil
 }
 
 func (d *Domain) GetChatBySubjectID(ctx context.Context, secondSubjectID string) (*model.Chat, error) {
@@ -189,77 +201,188 @@ func (d *Domain) UpdateLastRead(ctx context.Context, chatID int, messageID int)
 	if err != nil {
 		return nil, fmt.Errorf("get chat by id: %w", err)
 	}
+	if chat.FirstSubjectID != subj.GetSubjectId() && chat.SecondSubjectID != subj.GetSubjectId() {
+		return nil, SubjectNotHaveThisResource
+	}
 
 	mess, err := d.Storage.Message().GetMessageByID(ctx, messageID)
 	if err != nil {
 		return nil, fmt.Errorf("get message by id: %w", err)
 	}
+	if mess.ChatID != chatID {
+		return nil, ErrNotFound
+	}
+
+	lastRead, err := d.markRead(ctx, chat, subj.GetSubjectId(), mess)
+	if err != nil {
+		return nil, fmt.Errorf("mark read: %w", err)
+	}
+
+	return lastRead, nil
+}
+
+// markRead moves the last read mark forward and queues the receipt in one transaction,
+// storage.ErrNoRows is returned when the mark is already at or past the message.
+func (d *Domain) markRead(ctx context.Context, chat *model.Chat, subjectID string, mess *model.Message) (*model.LastRead, error) {
+	tx, err := d.Storage.WithTransaction(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("storage with transaction: %w", err)
+	}
+	defer tx.Rollback()
 
-	lastRead, err := d.Storage.LastRead().UpdateLastRead(ctx, subj.GetSubjectId(), chatID, messageID, mess.Number)
+	lastRead, err := tx.LastRead().UpdateLastRead(ctx, subjectID, chat.ID, mess.ID, mess.Number)
 	if err != nil {
 		return nil, fmt.Errorf("update last read: %w", err)
 	}
 
-	_, err = d.Storage.LastReadOutbox().AddLastReadOutbox(ctx, chat.GetSecondSubject(subj.GetSubjectId()), subj.GetSubjectId(), chatID, messageID)
+	_, err = tx.LastReadOutbox().AddLastReadOutbox(ctx, chat.GetSecondSubject(subjectID), subjectID, chat.ID, mess.ID)
 	if err != nil {
 		return nil, fmt.Errorf("add last read outbox: %w", err)
 	}
 
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+
 	return lastRead, nil
 }
 
-func (d *Domain) GetMessages(ctx context.Context, chatID int, filter *MessagePaginationFilter) ([]*model.Message, error) {
+func (d *Domain) GetMessages(ctx context.Context, chatID int, filter *MessagePaginationFilter) ([]*model.Message, *cursor.Page, error) {
 	subj, err := ctxkey.ExtractSubject(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("extract subject: %w", err)
+		return nil, nil, fmt.Errorf("extract subject: %w", err)
 	}
 	chat, err := d.Storage.Chat().GetChatByID(ctx, chatID)
 	if err != nil {
-		return nil, fmt.Errorf("get chat by in: %w", err)
+		return nil, nil, fmt.Errorf("get chat by in: %w", err)
 	}
 	if chat.FirstSubjectID != subj.GetSubjectId() && chat.SecondSubjectID != subj.GetSubjectId() {
-		return nil, SubjectNotHaveThisResource
+		return nil, nil, SubjectNotHaveThisResource
 	}
 
 	storageFilter := DefaultPaginationMessage
-	switch filter.Direction {
-	case DirectionBefore:
-		storageFilter.Asc = false
-	default:
-		storageFilter.Asc = true
+	if err := applyCursor(&storageFilter, filter.Limit, filter.Cursor, cursor.DirectionBefore, true); err != nil {
+		return nil, nil, fmt.Errorf("apply cursor: %w", err)
 	}
-
-	storageFilter.LastID = filter.LastMessageID
+	limit := storageFilter.Limit
+	storageFilter.Limit++
 
 	messages, err := d.Storage.Message().GetMessagesByChatID(ctx, chatID, &storageFilter)
 	if err != nil {
-		return nil, fmt.Errorf("get messages by chat id: %w", err)
+		return nil, nil, fmt.Errorf("get messages by chat id: %w", err)
 	}
 
+	messages, page := cursor.NewPage(messages, limit, filter.Cursor, cursor.DirectionBefore, messageCursor)
 	if len(messages) == 0 {
-		return messages, nil
+		return messages, page, nil
 	}
 
-	if !storageFilter.Asc {
-		utils.ReverseSlice(messages)
+	if filter.Peek {
+		return messages, page, nil
 	}
 
-	lastMess := messages[len(messages)-1]
-	lastRead, err := d.Storage.LastRead().UpdateLastRead(ctx, subj.GetSubjectId(), chatID, lastMess.ID, lastMess.Number)
+	_, err = d.markRead(ctx, chat, subj.GetSubjectId(), messages[len(messages)-1])
 	if err != nil && !errors.Is(err, storage.ErrNoRows) {
-		return nil, fmt.Errorf("update last read: %w", err)
+		return nil, nil, fmt.Errorf("mark read: %w", err)
+	}
+
+	return messages, page, nil
+}
+
+func (d *Domain) GetMessagesAround(ctx context.Context, chatID int, messageID int, before int, after int, peek bool) ([]*model.Message, *cursor.Page, error) {
+	subj, err := ctxkey.ExtractSubject(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("extract subject: %w", err)
+	}
+	lg, err := ctxkey.ExtractLogger(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("extract logger: %w", err)
+	}
+
+	chat, err := d.Storage.Chat().GetChatByID(ctx, chatID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get chat by id: %w", err)
 	}
-	if lastRead != nil {
-		_, err = d.Storage.LastReadOutbox().AddLastReadOutbox(ctx, chat.GetSecondSubject(subj.GetSubjectId()), subj.GetSubjectId(), chatID, lastMess.ID)
-		if err != nil {
-			return nil, fmt.Errorf("add last read outbox: %w", err)
+	if chat.FirstSubjectID != subj.GetSubjectId() && chat.SecondSubjectID != subj.GetSubjectId() {
+		return nil, nil, SubjectNotHaveThisResource
+	}
+	lg = lg.With(loglables.Chat, *chat)
+
+	target, err := d.Storage.Message().GetMessageByID(ctx, messageID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get message by id: %w", err)
+	}
+	if target.ChatID != chatID {
+		return nil, nil, ErrNotFound
+	}
+	lg = lg.With(loglables.Message, *target)
+
+	before = min(max(before, 0), MaxPaginationLimit)
+	after = min(max(after, 0), MaxPaginationLimit)
+
+	olderFilter := DefaultPaginationAround
+	olderFilter.Limit = before + 1
+	olderFilter.Asc = false
+	olderFilter.LastID = &target.ID
+	olderFilter.LastSortValue = target.Number
+
+	older, err := d.Storage.Message().GetMessagesByChatID(ctx, chatID, &olderFilter)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get older messages by chat id: %w", err)
+	}
+
+	newerFilter := DefaultPaginationAround
+	newerFilter.Limit = after + 1
+	newerFilter.Asc = true
+	newerFilter.LastID = &target.ID
+	newerFilter.LastSortValue = target.Number
+
+	newer, err := d.Storage.Message().GetMessagesByChatID(ctx, chatID, &newerFilter)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get newer messages by chat id: %w", err)
+	}
+
+	hasOlder := len(older) > before
+	if hasOlder {
+		older = older[:before]
+	}
+	hasNewer := len(newer) > after
+	if hasNewer {
+		newer = newer[:after]
+	}
+
+	utils.ReverseSlice(older)
+	messages := make([]*model.Message, 0, len(older)+len(newer)+1)
+	messages = append(messages, older...)
+	messages = append(messages, target)
+	messages = append(messages, newer...)
+
+	page := &cursor.Page{}
+	if hasOlder {
+		page.Prev = messageCursor(messages[0])
+		page.Prev.Direction = cursor.DirectionBefore
+	}
+	if hasNewer {
+		page.Next = messageCursor(messages[len(messages)-1])
+		page.Next.Direction = cursor.DirectionAfter
+	}
+
+	if !peek {
+		lastRead, err := d.markRead(ctx, chat, subj.GetSubjectId(), messages[len(messages)-1])
+		if err != nil && !errors.Is(err, storage.ErrNoRows) {
+			return nil, nil, fmt.Errorf("mark read: %w", err)
+		}
+		if lastRead != nil {
+			lg = lg.With(loglables.Updated, *lastRead)
 		}
 	}
 
-	return messages, nil
+	lg.Debug("get messages around")
+
+	return messages, page, nil
 }
 
-func (d *Domain) GetMessagesToLastRead(ctx context.Context, chatID int, limit int) ([]*model.Message, error) {
+func (d *Domain) GetMessagesToLastRead(ctx context.Context, chatID int, limit int, peek bool) ([]*model.Message, error) {
 	subj, err := ctxkey.ExtractSubject(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("extract subject: %w", err)
@@ -304,17 +427,13 @@ func (d *Domain) GetMessagesToLastRead(ctx context.Context, chatID int, limit in
 		utils.ReverseSlice(messages)
 	}
 
-	lastMess := messages[len(messages)-1]
-	lastRead, err = d.Storage.LastRead().UpdateLastRead(ctx, subj.GetSubjectId(), chatID, lastMess.ID, lastMess.Number)
-	if err != nil && !errors.Is(err, storage.ErrNoRows) {
-		return nil, fmt.Errorf("update last read: %w", err)
-	}
-	if lastRead != nil {
-		lg = lg.With(loglables.Updated, *lastRead)
-
-		_, err = d.Storage.LastReadOutbox().AddLastReadOutbox(ctx, chat.GetSecondSubject(subj.GetSubjectId()), subj.GetSubjectId(), chatID, lastMess.ID)
-		if err != nil {
-			return nil, fmt.Errorf("add last read outbox: %w", err)
+	if !peek {
+		updated, err := d.markRead(ctx, chat, subj.GetSubjectId(), messages[len(messages)-1])
+		if err != nil && !errors.Is(err, storage.ErrNoRows) {
+			return nil, fmt.Errorf("mark read: %w", err)
+		}
+		if updated != nil {
+			lg = lg.With(loglables.Updated, *updated)
 		}
 	}
 
@@ -323,7 +442,7 @@ func (d *Domain) GetMessagesToLastRead(ctx context.Context, chatID int, limit in
 	return messages, nil
 }
 
-func (d *Domain) SendMessage(ctx context.Context, chatID int, content string) (*model.Message, error) {
+func (d *Domain) SendMessage(ctx context.Context, chatID int, content string, clientMessageID string) (*model.Message, error) {
 	subj, err := ctxkey.ExtractSubject(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("extract subject: %w", err)
@@ -345,7 +464,16 @@ func (d *Domain) SendMessage(ctx context.Context, chatID int, content string) (*
 	}
 	lg = lg.With(loglables.Chat, *chat)
 
-	message, err := tx.Message().CreateMessage(ctx, chatID, subj.GetSubjectId(), content, chat.MessagesCount)
+	var clientID *string
+	if clientMessageID != "" {
+		clientID = &clientMessageID
+	}
+
+	message, err := tx.Message().CreateMessage(ctx, chatID, subj.GetSubjectId(), content, chat.MessagesCount, clientID)
+	if errors.Is(err, storage.ErrNoRows) && clientID != nil {
+		tx.Rollback()
+		return d.getRetriedMessage(ctx, chatID, subj.GetSubjectId(), clientMessageID)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("create message: %w", err)
 	}
@@ -371,6 +499,26 @@ func (d *Domain) SendMessage(ctx context.Context, chatID int, content string) (*
 	return message, nil
 }
 
+// getRetriedMessage returns the message already created by the first attempt of a retried send.
+// The id stays taken after the message is deleted, such retry gets ErrMessageDeleted.
+func (d *Domain) getRetriedMessage(ctx context.Context, chatID int, senderSubjectID string, clientMessageID string) (*model.Message, error) {
+	lg, err := ctxkey.ExtractLogger(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("extract logger: %w", err)
+	}
+
+	message, err := d.Storage.Message().GetMessageByClientMessageID(ctx, chatID, senderSubjectID, clientMessageID)
+	if errors.Is(err, storage.ErrNoRows) {
+		return nil, ErrMessageDeleted
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get message by client message id: %w", err)
+	}
+	lg.With(loglables.Message, *message).Debug("send message retry")
+
+	return message, nil
+}
+
 func (d *Domain) UpdateMessage(ctx context.Context, messageID int, content string, version int) (*model.Message, error) {
 	subj, err := ctxkey.ExtractSubject(ctx)
 	if err != nil {
@@ -9,4 +9,6 @@ import (
 var (
 	SubjectNotHaveThisResource = fmt.Errorf("subject not have this resource")
 	ErrNotFound                = storage.ErrNoRows
+	ErrChatNotAllowed          = fmt.Errorf("subject does not accept new chats from this subject")
+	ErrMessageDeleted          = fmt.Errorf("message with this client message id is deleted")
 )
@@ -0,0 +1,63 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/1ocknight/mess/chat/internal/ctxkey"
+	"github.com/1ocknight/mess/chat/internal/loglables"
+	"github.com/1ocknight/mess/chat/internal/model"
+)
+
+func (d *Domain) CreateExport(ctx context.Context, chatID int) (*model.Export, error) {
+	subj, err := ctxkey.ExtractSubject(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("extract subject: %w", err)
+	}
+	lg, err := ctxkey.ExtractLogger(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("extract logger: %w", err)
+	}
+
+	chat, err := d.Storage.Chat().GetChatByID(ctx, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("get chat by id: %w", err)
+	}
+	if chat.FirstSubjectID != subj.GetSubjectId() && chat.SecondSubjectID != subj.GetSubjectId() {
+		return nil, SubjectNotHaveThisResource
+	}
+
+	export, err := d.Storage.Export().CreateExport(ctx, subj.GetSubjectId(), chatID)
+	if err != nil {
+		return nil, fmt.Errorf("create export: %w", err)
+	}
+	lg.With(loglables.Export, *export).Debug("create export")
+
+	return export, nil
+}
+
+func (d *Domain) GetExport(ctx context.Context, exportID int) (*model.Export, string, error) {
+	subj, err := ctxkey.ExtractSubject(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("extract subject: %w", err)
+	}
+
+	export, err := d.Storage.Export().GetExportByID(ctx, exportID)
+	if err != nil {
+		return nil, "", fmt.Errorf("get export by id: %w", err)
+	}
+	if export.SubjectID != subj.GetSubjectId() {
+		return nil, "", SubjectNotHaveThisResource
+	}
+
+	if export.Status != model.ExportDone || export.ObjectKey == nil {
+		return export, "", nil
+	}
+
+	url, err := d.Archive.GetDownloadURL(ctx, *export.ObjectKey)
+	if err != nil {
+		return nil, "", fmt.Errorf("get download url: %w", err)
+	}
+
+	return export, url, nil
+}
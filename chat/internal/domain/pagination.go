@@ -0,0 +1,52 @@
+package domain
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/1ocknight/mess/chat/internal/model"
+	"github.com/1ocknight/mess/chat/internal/storage"
+	"github.com/1ocknight/mess/shared/cursor"
+)
+
+func chatCursor(chat *model.Chat) *cursor.Cursor {
+	return &cursor.Cursor{
+		SortKey: chat.UpdatedAt.UTC().Format(time.RFC3339Nano),
+		ID:      strconv.Itoa(chat.ID),
+	}
+}
+
+func messageCursor(mess *model.Message) *cursor.Cursor {
+	return &cursor.Cursor{
+		SortKey: mess.CreatedAt.UTC().Format(time.RFC3339Nano),
+		ID:      strconv.Itoa(mess.ID),
+	}
+}
+
+// applyCursor sets order and keyset of the storage filter,
+// displayAsc is the order in which pages are shown to the client.
+func applyCursor(filter *storage.PaginationFilterIntLastID, limit int, c *cursor.Cursor, def cursor.Direction, displayAsc bool) error {
+	if limit > 0 {
+		filter.Limit = min(limit, MaxPaginationLimit)
+	}
+
+	filter.Asc = (c.GetDirection(def) == cursor.DirectionAfter) == displayAsc
+	if c == nil {
+		return nil
+	}
+
+	sortValue, err := time.Parse(time.RFC3339Nano, c.SortKey)
+	if err != nil {
+		return fmt.Errorf("parse sort key: %w", cursor.ErrInvalidCursor)
+	}
+	lastID, err := strconv.Atoi(c.ID)
+	if err != nil {
+		return fmt.Errorf("parse id: %w", cursor.ErrInvalidCursor)
+	}
+
+	filter.LastSortValue = sortValue
+	filter.LastID = &lastID
+
+	return nil
+}
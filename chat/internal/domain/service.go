@@ -3,22 +3,22 @@ package domain
 import (
 	"context"
 
+	"github.com/1ocknight/mess/chat/internal/adapter/archive"
+	"github.com/1ocknight/mess/chat/internal/adapter/profile"
 	"github.com/1ocknight/mess/chat/internal/model"
 	"github.com/1ocknight/mess/chat/internal/storage"
+	"github.com/1ocknight/mess/shared/cursor"
 )
 
-type Direction int
-
 const (
-	DirectionUnknown Direction = iota
-	DirectionAfter
-	DirectionBefore
+	MaxPaginationLimit = 100
+	DefaultAroundLimit = 20
 )
 
 type MessagePaginationFilter struct {
-	Limit         int
-	LastMessageID *int
-	Direction     Direction
+	Limit  int
+	Cursor *cursor.Cursor
+	Peek   bool
 }
 
 var DefaultPaginationMessage = storage.PaginationFilterIntLastID{
@@ -27,10 +27,13 @@ var DefaultPaginationMessage = storage.PaginationFilterIntLastID{
 	SortLabel: storage.MessageCreatedAtLabel,
 }
 
+var DefaultPaginationAround = storage.PaginationFilterIntLastID{
+	SortLabel: storage.MessageNumberLabel,
+}
+
 type ChatPaginationFilter struct {
-	Limit      int
-	LastChatID *int
-	Direction  Direction
+	Limit  int
+	Cursor *cursor.Cursor
 }
 
 var DefaultPaginationChat = storage.PaginationFilterIntLastID{
@@ -41,24 +44,32 @@ var DefaultPaginationChat = storage.PaginationFilterIntLastID{
 
 type Service interface {
 	AddChat(ctx context.Context, secondSubjectID string) (*model.Chat, error)
-	GetChatsMetadata(ctx context.Context, filter *ChatPaginationFilter) ([]*model.ChatMetadata, error)
+	GetChatsMetadata(ctx context.Context, filter *ChatPaginationFilter) ([]*model.ChatMetadata, *cursor.Page, error)
 	GetChatBySubjectID(ctx context.Context, secondSubjectID string) (*model.Chat, error)
 
 	GetLastReads(ctx context.Context, chatID int) ([]*model.LastRead, error)
 	UpdateLastRead(ctx context.Context, chatID int, messageID int) (*model.LastRead, error)
 
-	GetMessages(ctx context.Context, chatID int, filter *MessagePaginationFilter) ([]*model.Message, error)
-	GetMessagesToLastRead(ctx context.Context, chatID int, limit int) ([]*model.Message, error)
-	SendMessage(ctx context.Context, chatID int, content string) (*model.Message, error)
+	GetMessages(ctx context.Context, chatID int, filter *MessagePaginationFilter) ([]*model.Message, *cursor.Page, error)
+	GetMessagesAround(ctx context.Context, chatID int, messageID int, before int, after int, peek bool) ([]*model.Message, *cursor.Page, error)
+	GetMessagesToLastRead(ctx context.Context, chatID int, limit int, peek bool) ([]*model.Message, error)
+	SendMessage(ctx context.Context, chatID int, content string, clientMessageID string) (*model.Message, error)
 	UpdateMessage(ctx context.Context, messageID int, content string, version int) (*model.Message, error)
+
+	CreateExport(ctx context.Context, chatID int) (*model.Export, error)
+	GetExport(ctx context.Context, exportID int) (*model.Export, string, error)
 }
 
 type Domain struct {
 	Storage storage.Service
+	Archive archive.Service
+	Profile profile.Service
 }
 
-func New(s storage.Service) Service {
+func New(s storage.Service, archive archive.Service, profile profile.Service) Service {
 	return &Domain{
 		Storage: s,
+		Archive: archive,
+		Profile: profile,
 	}
 }
@@ -11,6 +11,9 @@ const (
 
 	MessageOutbox = "message_outbox"
 
+	Export     = "export"
+	DataExport = "data_export"
+
 	Updated = "updated"
 
 	RequestMetadata = "request_metadata"
@@ -0,0 +1,39 @@
+package model
+
+import "time"
+
+type ExportStatus int
+
+const (
+	ExportPending ExportStatus = iota
+	ExportDone
+	ExportFailed
+	ExportProcessing
+)
+
+func (s ExportStatus) String() string {
+	switch s {
+	case ExportPending:
+		return "pending"
+	case ExportDone:
+		return "done"
+	case ExportFailed:
+		return "failed"
+	case ExportProcessing:
+		return "processing"
+	default:
+		return "unknown"
+	}
+}
+
+type Export struct {
+	ID        int
+	ChatID    int
+	SubjectID string
+	Status    ExportStatus
+	ObjectKey *string
+	Error     *string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt *time.Time
+}
@@ -9,6 +9,7 @@ type Message struct {
 	Content         string
 	Number          int
 	Version         int
+	ClientMessageID *string
 	CreatedAt       time.Time
 	UpdatedAt       time.Time
 	DeletedAt       *time.Time
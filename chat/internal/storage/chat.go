@@ -96,11 +96,12 @@ func (s *Storage) GetChatsBySubjectID(ctx context.Context, subjectID string, fil
 		Where(sq.Expr(deletedATIsNullChatFilter))
 
 	storageFilter := &postgres.PaginationFilter[int]{
-		Limit:     filter.Limit,
-		Asc:       filter.Asc,
-		SortLabel: filter.SortLabel,
-		IDLabel:   ChatIDLabel,
-		LastID:    filter.LastID,
+		Limit:         filter.Limit,
+		Asc:           filter.Asc,
+		SortLabel:     filter.SortLabel,
+		IDLabel:       ChatIDLabel,
+		LastID:        filter.LastID,
+		LastSortValue: filter.LastSortValue,
 	}
 	query, args, err := postgres.MakeQueryWithPagination(ctx, b, storageFilter)
 	if err != nil {
@@ -110,6 +111,33 @@ func (s *Storage) GetChatsBySubjectID(ctx context.Context, subjectID string, fil
 	return s.doAndReturnChats(ctx, query, args)
 }
 
+// GetPartnerSubjectIDs returns the other subject of every alive chat of the subject.
+func (s *Storage) GetPartnerSubjectIDs(ctx context.Context, subjectID string) ([]string, error) {
+	partner := fmt.Sprintf("CASE WHEN %v = ? THEN %v ELSE %v END", ChatFirstSubjectIDLabel, ChatSecondSubjectIDLabel, ChatFirstSubjectIDLabel)
+	query, args, err := sq.
+		Select().
+		Distinct().
+		Column(sq.Expr(partner, subjectID)).
+		From(ChatTable).
+		Where(sq.Or{
+			sq.Eq{ChatFirstSubjectIDLabel: subjectID},
+			sq.Eq{ChatSecondSubjectIDLabel: subjectID},
+		}).
+		Where(sq.Expr(deletedATIsNullChatFilter)).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build sql: %w", err)
+	}
+
+	var ids []string
+	if err := sqlx.SelectContext(ctx, s.exec, &ids, query, args...); err != nil {
+		return nil, fmt.Errorf("db select: %w", err)
+	}
+
+	return ids, nil
+}
+
 func (s *Storage) IncrementChatMessageNumber(ctx context.Context, chatID int) (*model.Chat, error) {
 	query, args, err := sq.
 		Update(ChatTable).
@@ -2,6 +2,7 @@ package storage_test
 
 import (
 	"github.com/1ocknight/mess/chat/internal/storage"
+	"slices"
 	"testing"
 
 	"github.com/1ocknight/mess/shared/utils"
@@ -75,6 +76,33 @@ func TestStorage_GetChatsBySubjectID(t *testing.T) {
 	}
 }
 
+func TestStorage_GetPartnerSubjectIDs(t *testing.T) {
+	s, err := storage.New(CFG)
+	if err != nil {
+		t.Fatalf("could not construct receiver type: %v", err)
+	}
+
+	initData(t)
+	defer cleanupDB(t)
+
+	ids, err := s.Chat().GetPartnerSubjectIDs(t.Context(), "subj-1")
+	if err != nil {
+		t.Fatalf("get partner subject ids: %v", err)
+	}
+	slices.Sort(ids)
+	if !slices.Equal(ids, []string{"subj-2", "subj-3"}) {
+		t.Fatalf("wait %v, have %v", []string{"subj-2", "subj-3"}, ids)
+	}
+
+	ids, err = s.Chat().GetPartnerSubjectIDs(t.Context(), "subj-2")
+	if err != nil {
+		t.Fatalf("get partner subject ids: %v", err)
+	}
+	if !slices.Equal(ids, []string{"subj-1"}) {
+		t.Fatalf("wait %v, have %v", []string{"subj-1"}, ids)
+	}
+}
+
 func TestStorage_IncrementChatMessageNumber(t *testing.T) {
 	s, err := storage.New(CFG)
 	if err != nil {
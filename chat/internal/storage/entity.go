@@ -71,6 +71,7 @@ type MessageEntity struct {
 	Content         string     `db:"content"`
 	Number          int        `db:"number"`
 	Version         int        `db:"version"`
+	ClientMessageID *string    `db:"client_message_id"`
 	CreatedAt       time.Time  `db:"created_at"`
 	UpdatedAt       time.Time  `db:"updated_at"`
 	DeletedAt       *time.Time `db:"deleted_at"`
@@ -84,6 +85,7 @@ func (e *MessageEntity) ToModel() *model.Message {
 		Content:         e.Content,
 		Number:          e.Number,
 		Version:         e.Version,
+		ClientMessageID: e.ClientMessageID,
 		CreatedAt:       e.CreatedAt,
 		UpdatedAt:       e.UpdatedAt,
 		DeletedAt:       e.DeletedAt,
@@ -151,3 +153,29 @@ func LastReadOutboxEntityToModels(entities []*LastReadOutboxEntity) []*model.Las
 	}
 	return models
 }
+
+type ExportEntity struct {
+	ID        int        `db:"id"`
+	ChatID    int        `db:"chat_id"`
+	SubjectID string     `db:"subject_id"`
+	Status    int        `db:"status"`
+	ObjectKey *string    `db:"object_key"`
+	Error     *string    `db:"error"`
+	CreatedAt time.Time  `db:"created_at"`
+	UpdatedAt time.Time  `db:"updated_at"`
+	DeletedAt *time.Time `db:"deleted_at"`
+}
+
+func (e *ExportEntity) ToModel() *model.Export {
+	return &model.Export{
+		ID:        e.ID,
+		ChatID:    e.ChatID,
+		SubjectID: e.SubjectID,
+		Status:    model.ExportStatus(e.Status),
+		ObjectKey: e.ObjectKey,
+		Error:     e.Error,
+		CreatedAt: e.CreatedAt,
+		UpdatedAt: e.UpdatedAt,
+		DeletedAt: e.DeletedAt,
+	}
+}
@@ -0,0 +1,138 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/1ocknight/mess/chat/internal/model"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	deletedATIsNullExportFilter = fmt.Sprintf("%v %v", ExportDeletedAtLabel, IsNullLabel)
+)
+
+func (s *Storage) doAndReturnExport(ctx context.Context, query string, args []interface{}) (*model.Export, error) {
+	var entity ExportEntity
+	err := sqlx.GetContext(ctx, s.exec, &entity, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("db get: %w", err)
+	}
+
+	return entity.ToModel(), nil
+}
+
+func (s *Storage) CreateExport(ctx context.Context, subjectID string, chatID int) (*model.Export, error) {
+	query, args, err := sq.
+		Insert(ExportTable).
+		Columns(
+			ExportSubjectIDLabel,
+			ExportChatIDLabel,
+		).
+		Values(subjectID, chatID).
+		Suffix(ReturningSuffix).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build sql: %w", err)
+	}
+
+	return s.doAndReturnExport(ctx, query, args)
+}
+
+func (s *Storage) GetExportByID(ctx context.Context, exportID int) (*model.Export, error) {
+	query, args, err := sq.
+		Select(AllLabelsSelect).
+		From(ExportTable).
+		Where(sq.Eq{ExportIDLabel: exportID}).
+		Where(sq.Expr(deletedATIsNullExportFilter)).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build sql: %w", err)
+	}
+
+	return s.doAndReturnExport(ctx, query, args)
+}
+
+// GetPendingExport returns a pending export or a processing one whose worker
+// has not finished it since processingBefore.
+func (s *Storage) GetPendingExport(ctx context.Context, processingBefore time.Time) (*model.Export, error) {
+	query, args, err := sq.
+		Select(AllLabelsSelect).
+		From(ExportTable).
+		Where(sq.Or{
+			sq.Eq{ExportStatusLabel: model.ExportPending},
+			sq.And{
+				sq.Eq{ExportStatusLabel: model.ExportProcessing},
+				sq.Lt{ExportUpdatedAtLabel: processingBefore},
+			},
+		}).
+		Where(sq.Expr(deletedATIsNullExportFilter)).
+		OrderBy(ExportIDLabel).
+		Limit(1).
+		Suffix(SkipLocked).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build sql: %w", err)
+	}
+
+	return s.doAndReturnExport(ctx, query, args)
+}
+
+func (s *Storage) StartExport(ctx context.Context, exportID int) (*model.Export, error) {
+	query, args, err := sq.
+		Update(ExportTable).
+		Set(ExportStatusLabel, model.ExportProcessing).
+		Set(ExportUpdatedAtLabel, time.Now().UTC()).
+		Where(sq.Eq{ExportIDLabel: exportID}).
+		Where(sq.Expr(deletedATIsNullExportFilter)).
+		Suffix(ReturningSuffix).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build sql: %w", err)
+	}
+
+	return s.doAndReturnExport(ctx, query, args)
+}
+
+func (s *Storage) CompleteExport(ctx context.Context, exportID int, objectKey string) (*model.Export, error) {
+	query, args, err := sq.
+		Update(ExportTable).
+		Set(ExportStatusLabel, model.ExportDone).
+		Set(ExportObjectKeyLabel, objectKey).
+		Set(ExportUpdatedAtLabel, time.Now().UTC()).
+		Where(sq.Eq{ExportIDLabel: exportID}).
+		Where(sq.Expr(deletedATIsNullExportFilter)).
+		Suffix(ReturningSuffix).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build sql: %w", err)
+	}
+
+	return s.doAndReturnExport(ctx, query, args)
+}
+
+func (s *Storage) FailExport(ctx context.Context, exportID int, reason string) (*model.Export, error) {
+	query, args, err := sq.
+		Update(ExportTable).
+		Set(ExportStatusLabel, model.ExportFailed).
+		Set(ExportErrorLabel, reason).
+		Set(ExportUpdatedAtLabel, time.Now().UTC()).
+		Where(sq.Eq{ExportIDLabel: exportID}).
+		Where(sq.Expr(deletedATIsNullExportFilter)).
+		Suffix(ReturningSuffix).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build sql: %w", err)
+	}
+
+	return s.doAndReturnExport(ctx, query, args)
+}
@@ -0,0 +1,105 @@
+package storage_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/1ocknight/mess/chat/internal/model"
+	"github.com/1ocknight/mess/chat/internal/storage"
+)
+
+func TestStorage_GetPendingExport(t *testing.T) {
+	s, err := storage.New(CFG)
+	if err != nil {
+		t.Fatalf("could not construct receiver type: %v", err)
+	}
+
+	initData(t)
+	defer cleanupDB(t)
+
+	created, err := s.Export().CreateExport(t.Context(), InitChats[0].FirstSubjectID, InitChats[0].ID)
+	if err != nil {
+		t.Fatalf("create export: %v", err)
+	}
+
+	pending, err := s.Export().GetPendingExport(t.Context(), time.Now().UTC())
+	if err != nil {
+		t.Fatalf("get pending export: %v", err)
+	}
+	if pending.ID != created.ID || pending.Status != model.ExportPending {
+		t.Fatalf("not equal, want: %v, have: %v", *created, *pending)
+	}
+
+	done, err := s.Export().CompleteExport(t.Context(), created.ID, "exports/1/1.zip")
+	if err != nil {
+		t.Fatalf("complete export: %v", err)
+	}
+	if done.Status != model.ExportDone || done.ObjectKey == nil || *done.ObjectKey != "exports/1/1.zip" {
+		t.Fatalf("export not completed: %v", *done)
+	}
+
+	_, err = s.Export().GetPendingExport(t.Context(), time.Now().UTC())
+	if !errors.Is(err, storage.ErrNoRows) {
+		t.Fatalf("want err no rows, have: %v", err)
+	}
+}
+
+func TestStorage_FailExport(t *testing.T) {
+	s, err := storage.New(CFG)
+	if err != nil {
+		t.Fatalf("could not construct receiver type: %v", err)
+	}
+
+	initData(t)
+	defer cleanupDB(t)
+
+	created, err := s.Export().CreateExport(t.Context(), InitChats[0].FirstSubjectID, InitChats[0].ID)
+	if err != nil {
+		t.Fatalf("create export: %v", err)
+	}
+
+	failed, err := s.Export().FailExport(t.Context(), created.ID, "upload failed")
+	if err != nil {
+		t.Fatalf("fail export: %v", err)
+	}
+	if failed.Status != model.ExportFailed || failed.Error == nil {
+		t.Fatalf("export not failed: %v", *failed)
+	}
+}
+
+func TestStorage_StartExport(t *testing.T) {
+	s, err := storage.New(CFG)
+	if err != nil {
+		t.Fatalf("could not construct receiver type: %v", err)
+	}
+
+	initData(t)
+	defer cleanupDB(t)
+
+	created, err := s.Export().CreateExport(t.Context(), InitChats[0].FirstSubjectID, InitChats[0].ID)
+	if err != nil {
+		t.Fatalf("create export: %v", err)
+	}
+
+	started, err := s.Export().StartExport(t.Context(), created.ID)
+	if err != nil {
+		t.Fatalf("start export: %v", err)
+	}
+	if started.Status != model.ExportProcessing {
+		t.Fatalf("export not processing: %v", *started)
+	}
+
+	_, err = s.Export().GetPendingExport(t.Context(), started.UpdatedAt.Add(-time.Minute))
+	if !errors.Is(err, storage.ErrNoRows) {
+		t.Fatalf("want err no rows, have: %v", err)
+	}
+
+	stale, err := s.Export().GetPendingExport(t.Context(), started.UpdatedAt.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("get stale export: %v", err)
+	}
+	if stale.ID != created.ID {
+		t.Fatalf("not equal, want: %v, have: %v", created.ID, stale.ID)
+	}
+}
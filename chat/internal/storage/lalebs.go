@@ -17,6 +17,7 @@ const (
 	MessageTable        Table = "message"
 	MessageOutboxTable  Table = "message_outbox"
 	LastReadOutboxTable Table = "last_read_outbox"
+	ExportTable         Table = "chat_export"
 )
 
 type Label = string
@@ -50,6 +51,7 @@ const (
 	MessageContentLabel         Label = "content"
 	MessageNumberLabel          Label = "number"
 	MessageVersionLabel         Label = "version"
+	MessageClientMessageIDLabel Label = "client_message_id"
 	MessageCreatedAtLabel       Label = "created_at"
 	MessageUpdatedAtLabel       Label = "updated_at"
 	MessageDeletedAtLabel       Label = "deleted_at"
@@ -73,3 +75,16 @@ const (
 	LastReadOutboxMessageIDLabel   Label = "message_id"
 	LastReadOutboxDeletedAtLabel   Label = "deleted_at"
 )
+
+// ExportTable
+const (
+	ExportIDLabel        Label = "id"
+	ExportChatIDLabel    Label = "chat_id"
+	ExportSubjectIDLabel Label = "subject_id"
+	ExportStatusLabel    Label = "status"
+	ExportObjectKeyLabel Label = "object_key"
+	ExportErrorLabel     Label = "error"
+	ExportCreatedAtLabel Label = "created_at"
+	ExportUpdatedAtLabel Label = "updated_at"
+	ExportDeletedAtLabel Label = "deleted_at"
+)
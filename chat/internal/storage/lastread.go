@@ -105,7 +105,7 @@ func (s *Storage) UpdateLastRead(ctx context.Context, subjectID string, chatID i
 		Set(LastReadMessageIDLabel, messageID).
 		Set(LastReadMessageNumberLabel, messageNumber).
 		Set(LastReadUpdatedAtLabel, time.Now().UTC()).
-		Where(sq.Lt{LastReadMessageIDLabel: messageID}).
+		Where(sq.Lt{LastReadMessageNumberLabel: messageNumber}).
 		Where(sq.Eq{LastReadSubjectIDLabel: subjectID}).
 		Where(sq.Eq{LastReadChatIDLabel: chatID}).
 		Where(sq.Expr(deletedATIsNullLastReadFilter)).
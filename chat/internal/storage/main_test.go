@@ -196,6 +196,11 @@ func cleanupDB(t *testing.T) {
 	if err != nil {
 		t.Fatalf("cleanup db: %v", err)
 	}
+
+	_, err = db.Exec(fmt.Sprintf("TRUNCATE TABLE %s RESTART IDENTITY CASCADE", storage.ExportTable))
+	if err != nil {
+		t.Fatalf("cleanup db: %v", err)
+	}
 }
 
 func initData(t *testing.T) {
@@ -221,7 +226,7 @@ func initData(t *testing.T) {
 	}
 
 	for _, ms := range InitMessages {
-		_, err = s.Message().CreateMessage(t.Context(), ms.ChatID, ms.SenderSubjectID, ms.Content, ms.Number)
+		_, err = s.Message().CreateMessage(t.Context(), ms.ChatID, ms.SenderSubjectID, ms.Content, ms.Number, ms.ClientMessageID)
 		if err != nil {
 			t.Fatalf("create message: %v", err)
 		}
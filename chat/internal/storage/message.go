@@ -14,6 +14,13 @@ import (
 
 var (
 	deletedATIsNullMessageFilter = fmt.Sprintf("%v %v", MessageDeletedAtLabel, IsNullLabel)
+
+	// a retry with the same client message id inserts nothing and returns no rows
+	onConflictClientMessageIDSuffix = fmt.Sprintf(
+		"ON CONFLICT (%v, %v, %v) WHERE %v IS NOT NULL DO NOTHING %v",
+		MessageChatIDLabel, MessageSenderSubjectIDLabel, MessageClientMessageIDLabel,
+		MessageClientMessageIDLabel, ReturningSuffix,
+	)
 )
 
 func (s *Storage) doAndReturnMessage(ctx context.Context, query string, args []interface{}) (*model.Message, error) {
@@ -36,7 +43,7 @@ func (s *Storage) doAndReturnMessages(ctx context.Context, query string, args []
 	return MessageEntitiesToModels(entities), nil
 }
 
-func (s *Storage) CreateMessage(ctx context.Context, chatID int, senderSubjectID string, content string, number int) (*model.Message, error) {
+func (s *Storage) CreateMessage(ctx context.Context, chatID int, senderSubjectID string, content string, number int, clientMessageID *string) (*model.Message, error) {
 	query, args, err := sq.
 		Insert(MessageTable).
 		Columns(
@@ -44,9 +51,10 @@ func (s *Storage) CreateMessage(ctx context.Context, chatID int, senderSubjectID
 			MessageSenderSubjectIDLabel,
 			MessageContentLabel,
 			MessageNumberLabel,
+			MessageClientMessageIDLabel,
 		).
-		Values(chatID, senderSubjectID, content, number).
-		Suffix(ReturningSuffix).
+		Values(chatID, senderSubjectID, content, number, clientMessageID).
+		Suffix(onConflictClientMessageIDSuffix).
 		PlaceholderFormat(sq.Dollar).
 		ToSql()
 	if err != nil {
@@ -88,6 +96,24 @@ func (s *Storage) GetMessageByID(ctx context.Context, messageID int) (*model.Mes
 	return s.doAndReturnMessage(ctx, query, args)
 }
 
+func (s *Storage) GetMessageByClientMessageID(ctx context.Context, chatID int, senderSubjectID string, clientMessageID string) (*model.Message, error) {
+	query, args, err := sq.
+		Select(AllLabelsSelect).
+		From(MessageTable).
+		Where(sq.Eq{MessageChatIDLabel: chatID}).
+		Where(sq.Eq{MessageSenderSubjectIDLabel: senderSubjectID}).
+		Where(sq.Eq{MessageClientMessageIDLabel: clientMessageID}).
+		Where(sq.Expr(deletedATIsNullMessageFilter)).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+
+	if err != nil {
+		return nil, fmt.Errorf("build sql: %w", err)
+	}
+
+	return s.doAndReturnMessage(ctx, query, args)
+}
+
 func (s *Storage) GetLastMessagesByChatsID(ctx context.Context, chatsID []int) ([]*model.Message, error) {
 	aliasRowNumber := "rn"
 	subQuery := sq.
@@ -108,6 +134,7 @@ func (s *Storage) GetLastMessagesByChatsID(ctx context.Context, chatsID []int) (
 			MessageContentLabel,
 			MessageNumberLabel,
 			MessageVersionLabel,
+			MessageClientMessageIDLabel,
 			MessageCreatedAtLabel,
 			MessageUpdatedAtLabel,
 			MessageDeletedAtLabel,
@@ -131,11 +158,12 @@ func (s *Storage) GetMessagesByChatID(ctx context.Context, chatID int, filter *P
 		Where(sq.Expr(deletedATIsNullMessageFilter))
 
 	storageFilter := &postgres.PaginationFilter[int]{
-		Limit:     filter.Limit,
-		Asc:       filter.Asc,
-		SortLabel: filter.SortLabel,
-		IDLabel:   MessageIDLabel,
-		LastID:    filter.LastID,
+		Limit:         filter.Limit,
+		Asc:           filter.Asc,
+		SortLabel:     filter.SortLabel,
+		IDLabel:       MessageIDLabel,
+		LastID:        filter.LastID,
+		LastSortValue: filter.LastSortValue,
 	}
 	query, args, err := postgres.MakeQueryWithPagination(ctx, b, storageFilter)
 	if err != nil {
@@ -1,6 +1,7 @@
 package storage_test
 
 import (
+	"errors"
 	"github.com/1ocknight/mess/chat/internal/storage"
 	"testing"
 )
@@ -69,6 +70,45 @@ func TestStorage_GetMessagesByChatID(t *testing.T) {
 	}
 }
 
+func TestStorage_GetMessagesByChatID_Keyset(t *testing.T) {
+	s, err := storage.New(CFG)
+	if err != nil {
+		t.Fatalf("could not construct receiver type: %v", err)
+	}
+
+	initData(t)
+	defer cleanupDB(t)
+
+	filter := &storage.PaginationFilterIntLastID{
+		Limit:     1,
+		Asc:       true,
+		SortLabel: storage.MessageCreatedAtLabel,
+	}
+
+	first, err := s.Message().GetMessagesByChatID(t.Context(), InitChats[0].ID, filter)
+	if err != nil {
+		t.Fatalf("get messages by chat id: %v", err)
+	}
+	if len(first) != 1 {
+		t.Fatalf("wait len 1, have: %v", len(first))
+	}
+
+	filter.LastID = &first[0].ID
+	filter.LastSortValue = first[0].CreatedAt
+
+	second, err := s.Message().GetMessagesByChatID(t.Context(), InitChats[0].ID, filter)
+	if err != nil {
+		t.Fatalf("get messages by chat id: %v", err)
+	}
+	if len(second) != 1 {
+		t.Fatalf("wait len 1, have: %v", len(second))
+	}
+
+	if second[0].ID == first[0].ID || second[0].Content != InitMessages[1].Content {
+		t.Fatalf("not equal, wait: %v, have: %v", *InitMessages[1], *second[0])
+	}
+}
+
 func TestStorage_UpdateMessageContent(t *testing.T) {
 	s, err := storage.New(CFG)
 	if err != nil {
@@ -131,3 +171,38 @@ func TestStorage_GetMessageByID(t *testing.T) {
 		t.Fatalf("not equal, want: %v. have: %v", *InitMessages[0], *mess)
 	}
 }
+
+func TestStorage_CreateMessage_ClientMessageID(t *testing.T) {
+	s, err := storage.New(CFG)
+	if err != nil {
+		t.Fatalf("could not construct receiver type: %v", err)
+	}
+
+	initData(t)
+	defer cleanupDB(t)
+
+	clientMessageID := "client-message-1"
+	created, err := s.Message().CreateMessage(t.Context(), InitChats[0].ID, "subj-1", "test-content", 3, &clientMessageID)
+	if err != nil {
+		t.Fatalf("create message: %v", err)
+	}
+
+	_, err = s.Message().CreateMessage(t.Context(), InitChats[0].ID, "subj-1", "test-content", 4, &clientMessageID)
+	if !errors.Is(err, storage.ErrNoRows) {
+		t.Fatalf("wait err no rows on retry, have: %v", err)
+	}
+
+	_, err = s.Message().CreateMessage(t.Context(), InitChats[0].ID, "subj-2", "test-content", 4, &clientMessageID)
+	if err != nil {
+		t.Fatalf("create message of other sender: %v", err)
+	}
+
+	got, err := s.Message().GetMessageByClientMessageID(t.Context(), InitChats[0].ID, "subj-1", clientMessageID)
+	if err != nil {
+		t.Fatalf("get message by client message id: %v", err)
+	}
+
+	if got.ID != created.ID {
+		t.Fatalf("wait message %v, have: %v", created.ID, got.ID)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"time"
 
 	"github.com/1ocknight/mess/chat/internal/model"
 
@@ -17,6 +18,7 @@ type Chat interface {
 	GetChatByID(ctx context.Context, chatID int) (*model.Chat, error)
 	GetChatIDBySubjects(ctx context.Context, firstSubjectID, secondSubjectID string) (*model.Chat, error)
 	GetChatsBySubjectID(ctx context.Context, subjectID string, filter *PaginationFilterIntLastID) ([]*model.Chat, error)
+	GetPartnerSubjectIDs(ctx context.Context, subjectID string) ([]string, error)
 
 	IncrementChatMessageNumber(ctx context.Context, chatID int) (*model.Chat, error)
 
@@ -36,10 +38,11 @@ type LastRead interface {
 }
 
 type Message interface {
-	CreateMessage(ctx context.Context, chatID int, senderSubjectID string, content string, number int) (*model.Message, error)
+	CreateMessage(ctx context.Context, chatID int, senderSubjectID string, content string, number int, clientMessageID *string) (*model.Message, error)
 
 	GetMessagesByIDs(ctx context.Context, messageIDs []int) ([]*model.Message, error)
 	GetMessageByID(ctx context.Context, messageID int) (*model.Message, error)
+	GetMessageByClientMessageID(ctx context.Context, chatID int, senderSubjectID string, clientMessageID string) (*model.Message, error)
 	GetLastMessagesByChatsID(ctx context.Context, chatsID []int) ([]*model.Message, error)
 	GetMessagesByChatID(ctx context.Context, chatID int, filter *PaginationFilterIntLastID) ([]*model.Message, error)
 
@@ -60,6 +63,15 @@ type LastReadOutbox interface {
 	DeleteLastReadOutbox(ctx context.Context, ids []int) ([]*model.LastReadOutbox, error)
 }
 
+type Export interface {
+	CreateExport(ctx context.Context, subjectID string, chatID int) (*model.Export, error)
+	GetExportByID(ctx context.Context, exportID int) (*model.Export, error)
+	GetPendingExport(ctx context.Context, processingBefore time.Time) (*model.Export, error)
+	StartExport(ctx context.Context, exportID int) (*model.Export, error)
+	CompleteExport(ctx context.Context, exportID int, objectKey string) (*model.Export, error)
+	FailExport(ctx context.Context, exportID int, reason string) (*model.Export, error)
+}
+
 type Service interface {
 	WithTransaction(ctx context.Context) (ServiceTransaction, error)
 	Chat() Chat
@@ -67,6 +79,7 @@ type Service interface {
 	Message() Message
 	MessageOutbox() MessageOutbox
 	LastReadOutbox() LastReadOutbox
+	Export() Export
 }
 
 type ServiceTransaction interface {
@@ -75,15 +88,17 @@ type ServiceTransaction interface {
 	Message() Message
 	MessageOutbox() MessageOutbox
 	LastReadOutbox() LastReadOutbox
+	Export() Export
 	Commit() error
 	Rollback() error
 }
 
 type PaginationFilterIntLastID struct {
-	LastID    *int
-	Limit     int
-	Asc       bool
-	SortLabel string
+	LastID        *int
+	LastSortValue any
+	Limit         int
+	Asc           bool
+	SortLabel     string
 }
 
 var (
@@ -158,6 +173,13 @@ func (s *Storage) LastReadOutbox() LastReadOutbox {
 	}
 }
 
+func (s *Storage) Export() Export {
+	return &Storage{
+		db:   s.db,
+		exec: s.exec,
+	}
+}
+
 func (s *Storage) Commit() error {
 	tx, ok := s.exec.(*sqlx.Tx)
 	if !ok {
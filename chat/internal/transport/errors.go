@@ -2,6 +2,11 @@ package transport
 
 import "fmt"
 
+const (
+	// InternalErrorMessage is answered instead of the text of an unexpected error.
+	InternalErrorMessage = "internal error"
+)
+
 var (
 	InvalidRequestError = fmt.Errorf("invalid request")
 )
@@ -8,17 +8,20 @@ import (
 
 	"github.com/1ocknight/mess/chat/internal/ctxkey"
 	"github.com/1ocknight/mess/chat/internal/domain"
+	"github.com/1ocknight/mess/shared/cursor"
 	httpdto "github.com/1ocknight/mess/shared/dto/http"
 	"github.com/gin-gonic/gin"
 )
 
 type Handler struct {
-	domain domain.Service
+	domain  domain.Service
+	cursors cursor.Service
 }
 
-func NewHandler(domain domain.Service) *Handler {
+func NewHandler(domain domain.Service, cursors cursor.Service) *Handler {
 	return &Handler{
-		domain: domain,
+		domain:  domain,
+		cursors: cursors,
 	}
 }
 
@@ -144,30 +147,38 @@ func (h *Handler) returnChatResponse(c *gin.Context, chatID int, limit int) {
 
 func (h *Handler) GetChats(c *gin.Context) {
 	sLimit := c.Query("limit")
-	sBefore := c.Query("before")
-	sAfter := c.Query("after")
+	sCursor := c.Query("cursor")
 
-	filter, err := MakeChatPaginationFilter(sLimit, sBefore, sAfter)
+	filter, err := MakeChatPaginationFilter(h.cursors, sLimit, sCursor)
 	if err != nil {
 		h.sendError(c, err)
 		return
 	}
 
-	chatsMetadata, err := h.domain.GetChatsMetadata(c.Request.Context(), filter)
+	chatsMetadata, page, err := h.domain.GetChatsMetadata(c.Request.Context(), filter)
 	if err != nil {
 		h.sendError(c, err)
 		return
 	}
-	resChats := ChatsMetadataModelToDTO(chatsMetadata)
 
-	c.JSON(http.StatusOK, resChats)
+	next, prev, err := cursor.EncodePage(h.cursors, page)
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, httpdto.ChatsResponse{
+		Chats:      ChatsMetadataModelToDTO(chatsMetadata),
+		NextCursor: next,
+		PrevCursor: prev,
+	})
 }
 
 func (h *Handler) GetMessages(c *gin.Context) {
 	sChat := c.Query("chat_id")
 	sLimit := c.Query("limit")
-	sBefore := c.Query("before")
-	sAfter := c.Query("after")
+	sCursor := c.Query("cursor")
+	sPeek := c.Query("peek")
 
 	chatID, err := strconv.Atoi(sChat)
 	if err != nil {
@@ -175,20 +186,79 @@ func (h *Handler) GetMessages(c *gin.Context) {
 		return
 	}
 
-	filter, err := MakeMessagePaginationFilter(sLimit, sBefore, sAfter)
+	filter, err := MakeMessagePaginationFilter(h.cursors, sLimit, sCursor, sPeek)
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	messages, page, err := h.domain.GetMessages(c.Request.Context(), chatID, filter)
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	next, prev, err := cursor.EncodePage(h.cursors, page)
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, httpdto.MessagesResponse{
+		Messages:   MessagesModelToMessageDTO(messages),
+		NextCursor: next,
+		PrevCursor: prev,
+	})
+}
+
+func (h *Handler) GetMessagesAround(c *gin.Context) {
+	chatID, err := strconv.Atoi(c.Param("chat_id"))
+	if err != nil {
+		h.sendError(c, fmt.Errorf("%w: chat id: %w", InvalidRequestError, err))
+		return
+	}
+
+	messageID, err := strconv.Atoi(c.Param("message_id"))
+	if err != nil {
+		h.sendError(c, fmt.Errorf("%w: message id: %w", InvalidRequestError, err))
+		return
+	}
+
+	before, err := parseLimit(c.DefaultQuery("before", strconv.Itoa(domain.DefaultAroundLimit)))
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	after, err := parseLimit(c.DefaultQuery("after", strconv.Itoa(domain.DefaultAroundLimit)))
 	if err != nil {
 		h.sendError(c, err)
 		return
 	}
 
-	messages, err := h.domain.GetMessages(c.Request.Context(), chatID, filter)
+	peek, err := parsePeek(c.Query("peek"))
 	if err != nil {
 		h.sendError(c, err)
 		return
 	}
-	resMessages := MessagesModelToMessageDTO(messages)
 
-	c.JSON(http.StatusOK, resMessages)
+	messages, page, err := h.domain.GetMessagesAround(c.Request.Context(), chatID, messageID, before, after, peek)
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	next, prev, err := cursor.EncodePage(h.cursors, page)
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, httpdto.MessagesResponse{
+		Messages:   MessagesModelToMessageDTO(messages),
+		NextCursor: next,
+		PrevCursor: prev,
+	})
 }
 
 func (h *Handler) AddMessage(c *gin.Context) {
@@ -198,7 +268,7 @@ func (h *Handler) AddMessage(c *gin.Context) {
 		return
 	}
 
-	mess, err := h.domain.SendMessage(c.Request.Context(), req.ChatID, req.Content)
+	mess, err := h.domain.SendMessage(c.Request.Context(), req.ChatID, req.Content, req.ClientMessageID)
 	if err != nil {
 		h.sendError(c, err)
 		return
@@ -241,10 +311,42 @@ func (h *Handler) UpdateLastRead(c *gin.Context) {
 	})
 }
 
+func (h *Handler) CreateExport(c *gin.Context) {
+	chatID, err := strconv.Atoi(c.Param("chat_id"))
+	if err != nil {
+		h.sendError(c, fmt.Errorf("%w: chat id: %w", InvalidRequestError, err))
+		return
+	}
+
+	export, err := h.domain.CreateExport(c.Request.Context(), chatID)
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, ExportModelToDTO(export, ""))
+}
+
+func (h *Handler) GetExport(c *gin.Context) {
+	exportID, err := strconv.Atoi(c.Param("export_id"))
+	if err != nil {
+		h.sendError(c, fmt.Errorf("%w: export id: %w", InvalidRequestError, err))
+		return
+	}
+
+	export, url, err := h.domain.GetExport(c.Request.Context(), exportID)
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ExportModelToDTO(export, url))
+}
+
 func (h *Handler) sendError(c *gin.Context, err error) {
 	var code int
 
-	if errors.Is(err, InvalidRequestError) {
+	if errors.Is(err, InvalidRequestError) || errors.Is(err, cursor.ErrInvalidCursor) {
 		code = http.StatusBadRequest
 	}
 
@@ -252,6 +354,14 @@ func (h *Handler) sendError(c *gin.Context, err error) {
 		code = http.StatusNoContent
 	}
 
+	if errors.Is(err, domain.ErrChatNotAllowed) {
+		code = http.StatusForbidden
+	}
+
+	if errors.Is(err, domain.ErrMessageDeleted) {
+		code = http.StatusConflict
+	}
+
 	if code == 0 {
 		code = http.StatusInternalServerError
 	}
@@ -0,0 +1,144 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/1ocknight/mess/chat/internal/domain"
+	"github.com/1ocknight/mess/shared/cursor"
+	httpdto "github.com/1ocknight/mess/shared/dto/http"
+	"github.com/1ocknight/mess/shared/logger"
+	"github.com/1ocknight/mess/shared/verify"
+	"github.com/gin-gonic/gin"
+)
+
+// RPCServer is the internal API used by other services to write on behalf of a subject.
+// It is not exposed outside of the cluster, the subject is taken from the forwarded token.
+type RPCServer struct {
+	cfg    *Config
+	srv    *gin.Engine
+	httpSv *http.Server
+}
+
+func NewRPCServer(cfg Config, lg logger.Logger, domain domain.Service, verify verify.Service) *RPCServer {
+	h := NewHandler(domain, nil)
+
+	if !cfg.DebugMode {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	r := gin.New()
+
+	r.Use(InitLoggerMiddleware(lg))
+	r.Use(SetRequestMetadataMiddleware())
+	r.Use(LogResponseMiddleware())
+	r.Use(InitSubjectMiddleware(verify))
+
+	r.POST("/rpc/send_message", h.RPCSendMessage)
+	r.POST("/rpc/update_message", h.RPCUpdateMessage)
+	r.POST("/rpc/mark_read", h.RPCMarkRead)
+
+	return &RPCServer{
+		cfg: &cfg,
+		srv: r,
+	}
+}
+
+func (s *RPCServer) Run() error {
+	addr := fmt.Sprintf("%s:%s", s.cfg.Host, s.cfg.Port)
+
+	s.httpSv = &http.Server{
+		Addr:    addr,
+		Handler: s.srv,
+	}
+
+	return s.httpSv.ListenAndServe()
+}
+
+func (s *RPCServer) Stop(ctx context.Context) error {
+	return s.httpSv.Shutdown(ctx)
+}
+
+func (h *Handler) RPCSendMessage(c *gin.Context) {
+	var req *httpdto.AddMessageRequest
+	if err := c.BindJSON(&req); err != nil {
+		h.sendRPCError(c, fmt.Errorf("%w: %w", InvalidRequestError, err))
+		return
+	}
+
+	mess, err := h.domain.SendMessage(c.Request.Context(), req.ChatID, req.Content, req.ClientMessageID)
+	if err != nil {
+		h.sendRPCError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, MessageModelToMessageDTO(mess))
+}
+
+func (h *Handler) RPCUpdateMessage(c *gin.Context) {
+	var req *httpdto.UpdateMessageRequest
+	if err := c.BindJSON(&req); err != nil {
+		h.sendRPCError(c, fmt.Errorf("%w: %w", InvalidRequestError, err))
+		return
+	}
+
+	mess, err := h.domain.UpdateMessage(c.Request.Context(), req.MessageID, req.Content, req.Version)
+	if err != nil {
+		h.sendRPCError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, MessageModelToMessageDTO(mess))
+}
+
+func (h *Handler) RPCMarkRead(c *gin.Context) {
+	var req *httpdto.UpdateLastReadRequest
+	if err := c.BindJSON(&req); err != nil {
+		h.sendRPCError(c, fmt.Errorf("%w: %w", InvalidRequestError, err))
+		return
+	}
+
+	lastRead, err := h.domain.UpdateLastRead(c.Request.Context(), req.ChatID, req.MessageID)
+	if err != nil {
+		h.sendRPCError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, httpdto.UpdateLastReadResponse{
+		MessageID: lastRead.MessageID,
+	})
+}
+
+// sendRPCError unlike sendError always answers with a body, callers forward the code to their clients.
+// An internal error is answered with a generic message, its details are only logged.
+func (h *Handler) sendRPCError(c *gin.Context, err error) {
+	res := httpdto.ErrorResponse{
+		Code:    httpdto.InternalCode,
+		Message: InternalErrorMessage,
+	}
+	status := http.StatusInternalServerError
+
+	switch {
+	case errors.Is(err, InvalidRequestError) || errors.Is(err, cursor.ErrInvalidCursor):
+		res.Code = httpdto.InvalidRequestCode
+		res.Message = err.Error()
+		status = http.StatusBadRequest
+	case errors.Is(err, domain.SubjectNotHaveThisResource) || errors.Is(err, domain.ErrChatNotAllowed):
+		res.Code = httpdto.ForbiddenCode
+		res.Message = err.Error()
+		status = http.StatusForbidden
+	case errors.Is(err, domain.ErrNotFound):
+		res.Code = httpdto.NotFoundCode
+		res.Message = err.Error()
+		status = http.StatusNotFound
+	case errors.Is(err, domain.ErrMessageDeleted):
+		res.Code = httpdto.ConflictCode
+		res.Message = err.Error()
+		status = http.StatusConflict
+	}
+
+	c.Error(err)
+	c.AbortWithStatusJSON(status, res)
+}
@@ -0,0 +1,79 @@
+package transport
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/1ocknight/mess/chat/internal/domain"
+	httpdto "github.com/1ocknight/mess/shared/dto/http"
+	"github.com/gin-gonic/gin"
+)
+
+func TestHandler_SendRPCError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name    string
+		err     error
+		status  int
+		code    httpdto.ErrorCode
+		message string
+	}{
+		{
+			name:    "internal error is not forwarded",
+			err:     fmt.Errorf("add message: db exec: %w", errors.New("pq: connection refused")),
+			status:  http.StatusInternalServerError,
+			code:    httpdto.InternalCode,
+			message: InternalErrorMessage,
+		},
+		{
+			name:    "invalid request",
+			err:     fmt.Errorf("%w: %w", InvalidRequestError, errors.New("unexpected EOF")),
+			status:  http.StatusBadRequest,
+			code:    httpdto.InvalidRequestCode,
+			message: "invalid request: unexpected EOF",
+		},
+		{
+			name:    "forbidden",
+			err:     domain.ErrChatNotAllowed,
+			status:  http.StatusForbidden,
+			code:    httpdto.ForbiddenCode,
+			message: domain.ErrChatNotAllowed.Error(),
+		},
+		{
+			name:    "deleted message",
+			err:     domain.ErrMessageDeleted,
+			status:  http.StatusConflict,
+			code:    httpdto.ConflictCode,
+			message: domain.ErrMessageDeleted.Error(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			(&Handler{}).sendRPCError(c, tt.err)
+
+			if w.Code != tt.status {
+				t.Fatalf("wait %v, have %v", tt.status, w.Code)
+			}
+			var res httpdto.ErrorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			if res.Code != tt.code || res.Message != tt.message {
+				t.Fatalf("wait %v %q, have %v %q", tt.code, tt.message, res.Code, res.Message)
+			}
+			// the real error stays for the log middleware
+			if len(c.Errors) != 1 || !errors.Is(c.Errors[0].Err, tt.err) {
+				t.Fatalf("error is not kept for the log: %v", c.Errors)
+			}
+		})
+	}
+}
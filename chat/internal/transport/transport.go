@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/1ocknight/mess/chat/internal/domain"
+	"github.com/1ocknight/mess/shared/cursor"
 	"github.com/1ocknight/mess/shared/logger"
 	"github.com/1ocknight/mess/shared/verify"
 	"github.com/gin-contrib/cors"
@@ -26,8 +27,8 @@ type HTTPServer struct {
 	httpSv *http.Server
 }
 
-func NewServer(cfg Config, lg logger.Logger, domain domain.Service, verify verify.Service) *HTTPServer {
-	h := NewHandler(domain)
+func NewServer(cfg Config, lg logger.Logger, domain domain.Service, verify verify.Service, cursors cursor.Service) *HTTPServer {
+	h := NewHandler(domain, cursors)
 
 	if !cfg.DebugMode {
 		gin.SetMode(gin.ReleaseMode)
@@ -53,6 +54,7 @@ func NewServer(cfg Config, lg logger.Logger, domain domain.Service, verify verif
 	r.GET("/chat/subject/:subject_id", h.GetChatBySubjectID)
 	r.POST("/chat/subject/:subject_id", h.AddChat)
 	r.GET("/chat/:chat_id", h.GetChatByID)
+	r.GET("/chat/:chat_id/messages/around/:message_id", h.GetMessagesAround)
 	r.GET("/chats", h.GetChats)
 
 	r.GET("/messages", h.GetMessages)
@@ -61,6 +63,9 @@ func NewServer(cfg Config, lg logger.Logger, domain domain.Service, verify verif
 
 	r.PATCH("/lastread", h.UpdateLastRead)
 
+	r.POST("/chat/:chat_id/export", h.CreateExport)
+	r.GET("/export/:export_id", h.GetExport)
+
 	return &HTTPServer{
 		cfg: &cfg,
 		srv: r,
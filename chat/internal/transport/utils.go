@@ -1,21 +1,29 @@
 package transport
 
 import (
+	"fmt"
 	"strconv"
 
 	"github.com/1ocknight/mess/chat/internal/domain"
 	"github.com/1ocknight/mess/chat/internal/model"
+	"github.com/1ocknight/mess/shared/cursor"
 	httpdto "github.com/1ocknight/mess/shared/dto/http"
 )
 
 func MessageModelToMessageDTO(mess *model.Message) *httpdto.MessageResponse {
-	return &httpdto.MessageResponse{
+	res := &httpdto.MessageResponse{
 		ID:        mess.ID,
+		ChatID:    mess.ChatID,
 		Version:   mess.Version,
 		Content:   mess.Content,
 		SenderID:  mess.SenderSubjectID,
 		CreatedAt: mess.CreatedAt,
 	}
+	if mess.ClientMessageID != nil {
+		res.ClientMessageID = *mess.ClientMessageID
+	}
+
+	return res
 }
 
 func MessagesModelToMessageDTO(messages []*model.Message) []*httpdto.MessageResponse {
@@ -47,74 +55,95 @@ func ChatsMetadataModelToDTO(chatsMetadata []*model.ChatMetadata) []*httpdto.Cha
 	return resChats
 }
 
-func MakeMessagePaginationFilter(sLimit string, sBefore string, sAfter string) (*domain.MessagePaginationFilter, error) {
-	if sBefore == "" && sAfter == "" || sAfter != "" && sBefore != "" {
-		return nil, InvalidRequestError
+func ExportModelToDTO(export *model.Export, url string) *httpdto.ExportResponse {
+	res := &httpdto.ExportResponse{
+		ID:          export.ID,
+		ChatID:      export.ChatID,
+		Status:      export.Status.String(),
+		DownloadURL: url,
+		CreatedAt:   export.CreatedAt,
+	}
+	if export.Error != nil {
+		res.Error = *export.Error
 	}
 
+	return res
+}
+
+func MakeMessagePaginationFilter(cursors cursor.Service, sLimit string, sCursor string, sPeek string) (*domain.MessagePaginationFilter, error) {
 	filter := domain.MessagePaginationFilter{}
 
 	var err error
-	if sLimit != "" {
-		filter.Limit, err = strconv.Atoi(sLimit)
-		if err != nil {
-			return nil, err
-		}
+	filter.Limit, err = parseLimit(sLimit)
+	if err != nil {
+		return nil, err
 	}
 
-	var last int
-	if sBefore != "" {
-		last, err = strconv.Atoi(sBefore)
-		if err != nil {
-			return nil, err
-		}
-		filter.Direction = domain.DirectionBefore
+	filter.Peek, err = parsePeek(sPeek)
+	if err != nil {
+		return nil, err
 	}
-	if sAfter != "" {
-		last, err = strconv.Atoi(sAfter)
-		if err != nil {
-			return nil, err
-		}
-		filter.Direction = domain.DirectionAfter
+
+	filter.Cursor, err = decodeCursor(cursors, sCursor)
+	if err != nil {
+		return nil, err
 	}
 
-	filter.LastMessageID = &last
+	return &filter, nil
+}
+
+func MakeChatPaginationFilter(cursors cursor.Service, sLimit string, sCursor string) (*domain.ChatPaginationFilter, error) {
+	filter := domain.ChatPaginationFilter{}
+
+	var err error
+	filter.Limit, err = parseLimit(sLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	filter.Cursor, err = decodeCursor(cursors, sCursor)
+	if err != nil {
+		return nil, err
+	}
 
 	return &filter, nil
 }
 
-func MakeChatPaginationFilter(sLimit string, sBefore string, sAfter string) (*domain.ChatPaginationFilter, error) {
-	if sAfter != "" && sBefore != "" {
-		return nil, InvalidRequestError
+func parseLimit(sLimit string) (int, error) {
+	if sLimit == "" {
+		return 0, nil
 	}
 
-	filter := domain.ChatPaginationFilter{}
+	limit, err := strconv.Atoi(sLimit)
+	if err != nil || limit < 0 {
+		return 0, InvalidRequestError
+	}
 
-	var err error
-	if sLimit != "" {
-		filter.Limit, err = strconv.Atoi(sLimit)
-		if err != nil {
-			return nil, err
-		}
+	return limit, nil
+}
+
+func parsePeek(sPeek string) (bool, error) {
+	if sPeek == "" {
+		return false, nil
 	}
 
-	var last int
-	if sBefore != "" {
-		last, err = strconv.Atoi(sBefore)
-		if err != nil {
-			return nil, err
-		}
-		filter.Direction = domain.DirectionBefore
+	peek, err := strconv.ParseBool(sPeek)
+	if err != nil {
+		return false, InvalidRequestError
 	}
-	if sAfter != "" {
-		last, err = strconv.Atoi(sAfter)
-		if err != nil {
-			return nil, err
-		}
-		filter.Direction = domain.DirectionAfter
+
+	return peek, nil
+}
+
+func decodeCursor(cursors cursor.Service, sCursor string) (*cursor.Cursor, error) {
+	if sCursor == "" {
+		return nil, nil
 	}
 
-	filter.LastChatID = &last
+	c, err := cursors.Decode(sCursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", InvalidRequestError, err)
+	}
 
-	return &filter, nil
+	return c, nil
 }
@@ -0,0 +1,277 @@
+package worker
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/1ocknight/mess/chat/internal/adapter/archive"
+	"github.com/1ocknight/mess/chat/internal/loglables"
+	"github.com/1ocknight/mess/chat/internal/model"
+	"github.com/1ocknight/mess/chat/internal/storage"
+	mqdto "github.com/1ocknight/mess/shared/dto/mq"
+	"github.com/1ocknight/mess/shared/kafkav2"
+	"github.com/1ocknight/mess/shared/logger"
+)
+
+const (
+	DataExportServiceName = "chat"
+
+	dataExportChatsName     = "chats.jsonl"
+	dataExportMessagesName  = "messages.jsonl"
+	dataExportLastReadsName = "last_reads.jsonl"
+)
+
+type DataExportWorkerConfig struct {
+	Consumer kafkav2.GroupConsumerConfig `yaml:"kafka_consumer"`
+	Producer kafkav2.ProducerConfig      `yaml:"kafka_producer"`
+	Delay    time.Duration               `yaml:"delay"`
+	PageSize int                         `yaml:"page_size"`
+}
+
+// DataExportWorker answers data export requests of profile with everything chat stores about the subject.
+type DataExportWorker struct {
+	Consumer *kafkav2.GroupConsumer
+	Producer *kafkav2.Producer
+	Storage  storage.Service
+	Archive  archive.Service
+	lg       logger.Logger
+	cfg      *DataExportWorkerConfig
+}
+
+func NewDataExportWorker(storage storage.Service, archive archive.Service, lg logger.Logger, cfg *DataExportWorkerConfig) (*DataExportWorker, error) {
+	consumer, err := kafkav2.NewGroupConsumer(cfg.Consumer)
+	if err != nil {
+		return nil, fmt.Errorf("new group consumer: %w", err)
+	}
+
+	producer, err := kafkav2.NewProducer(cfg.Producer)
+	if err != nil {
+		return nil, fmt.Errorf("new producer: %w", err)
+	}
+
+	return &DataExportWorker{
+		Consumer: consumer,
+		Producer: producer,
+		Storage:  storage,
+		Archive:  archive,
+		lg:       lg,
+		cfg:      cfg,
+	}, nil
+}
+
+func DataExportPartKey(req *mqdto.DataExportRequest) string {
+	return fmt.Sprintf("%s/%s/%d.zip", DataExportServiceName, req.SubjectID, req.ExportID)
+}
+
+type exportChat struct {
+	ID              int       `json:"id"`
+	FirstSubjectID  string    `json:"first_subject_id"`
+	SecondSubjectID string    `json:"second_subject_id"`
+	MessagesCount   int       `json:"messages_count"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+type exportLastRead struct {
+	ChatID        int       `json:"chat_id"`
+	MessageID     int       `json:"message_id"`
+	MessageNumber int       `json:"message_number"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+func (dew *DataExportWorker) Export(ctx context.Context, req *mqdto.DataExportRequest) *mqdto.DataExportPart {
+	part := &mqdto.DataExportPart{
+		ExportID:  req.ExportID,
+		SubjectID: req.SubjectID,
+		Service:   DataExportServiceName,
+		Key:       DataExportPartKey(req),
+	}
+
+	if err := dew.build(ctx, req.SubjectID, part.Key); err != nil {
+		// the error text reaches the subject through profile, so only the generic reason is sent
+		dew.lg.With(loglables.DataExport, *req).Error(fmt.Errorf("build data export part: %w", err))
+		part.Key = ""
+		part.Error = ExportFailedReason
+	}
+
+	return part
+}
+
+func (dew *DataExportWorker) build(ctx context.Context, subjectID string, key string) error {
+	chats, err := dew.getChats(ctx, subjectID)
+	if err != nil {
+		return fmt.Errorf("get chats: %w", err)
+	}
+
+	file, err := os.CreateTemp("", "chat-data-export-*.zip")
+	if err != nil {
+		return fmt.Errorf("create temp: %w", err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	zw := zip.NewWriter(file)
+
+	w, err := zw.Create(dataExportChatsName)
+	if err != nil {
+		return fmt.Errorf("create %v: %w", dataExportChatsName, err)
+	}
+	for _, chat := range chats {
+		err := writeJSONLine(w, &exportChat{
+			ID:              chat.ID,
+			FirstSubjectID:  chat.FirstSubjectID,
+			SecondSubjectID: chat.SecondSubjectID,
+			MessagesCount:   chat.MessagesCount,
+			CreatedAt:       chat.CreatedAt,
+			UpdatedAt:       chat.UpdatedAt,
+		})
+		if err != nil {
+			return fmt.Errorf("write %v: %w", dataExportChatsName, err)
+		}
+	}
+
+	w, err = zw.Create(dataExportMessagesName)
+	if err != nil {
+		return fmt.Errorf("create %v: %w", dataExportMessagesName, err)
+	}
+	for _, chat := range chats {
+		err := forEachMessage(ctx, dew.Storage.Message(), chat.ID, dew.cfg.PageSize, func(mess *model.Message) error {
+			return writeJSONLine(w, newExportMessage(mess, nil))
+		})
+		if err != nil {
+			return fmt.Errorf("write %v: %w", dataExportMessagesName, err)
+		}
+	}
+
+	w, err = zw.Create(dataExportLastReadsName)
+	if err != nil {
+		return fmt.Errorf("create %v: %w", dataExportLastReadsName, err)
+	}
+	if len(chats) != 0 {
+		lastReads, err := dew.Storage.LastRead().GetLastReadsByChatIDs(ctx, model.GetChatsID(chats))
+		if err != nil {
+			return fmt.Errorf("get last reads by chat ids: %w", err)
+		}
+		for _, lr := range lastReads {
+			if lr.SubjectID != subjectID {
+				continue
+			}
+			err := writeJSONLine(w, &exportLastRead{
+				ChatID:        lr.ChatID,
+				MessageID:     lr.MessageID,
+				MessageNumber: lr.MessageNumber,
+				UpdatedAt:     lr.UpdatedAt,
+			})
+			if err != nil {
+				return fmt.Errorf("write %v: %w", dataExportLastReadsName, err)
+			}
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("close zip: %w", err)
+	}
+
+	size, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return fmt.Errorf("seek: %w", err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("seek: %w", err)
+	}
+
+	if err := dew.Archive.Upload(ctx, key, file, size, exportContentType); err != nil {
+		return fmt.Errorf("upload: %w", err)
+	}
+
+	return nil
+}
+
+func (dew *DataExportWorker) getChats(ctx context.Context, subjectID string) ([]*model.Chat, error) {
+	filter := storage.PaginationFilterIntLastID{
+		Limit:     dew.cfg.PageSize,
+		Asc:       true,
+		SortLabel: storage.ChatIDLabel,
+	}
+
+	res := make([]*model.Chat, 0)
+	for {
+		chats, err := dew.Storage.Chat().GetChatsBySubjectID(ctx, subjectID, &filter)
+		if err != nil {
+			return nil, fmt.Errorf("get chats by subject id: %w", err)
+		}
+		res = append(res, chats...)
+
+		if len(chats) == 0 || len(chats) < filter.Limit {
+			return res, nil
+		}
+
+		filter.LastID = &chats[len(chats)-1].ID
+	}
+}
+
+func (dew *DataExportWorker) publish(ctx context.Context, part *mqdto.DataExportPart) error {
+	val, err := json.Marshal(part)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	pairs := []*kafkav2.KeyValPair{{Key: []byte(part.SubjectID), Val: val}}
+	for {
+		err := dew.Producer.Publish(pairs)
+		if err == nil {
+			return nil
+		}
+		dew.lg.Error(fmt.Errorf("publish: %w", err))
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(dew.cfg.Delay):
+		}
+	}
+}
+
+func (dew *DataExportWorker) Run(ctx context.Context) {
+	dew.lg.Info("run data export worker")
+
+	defer dew.Producer.Close()
+
+	go func() {
+		if err := dew.Consumer.Run(ctx); err != nil {
+			dew.lg.Error(fmt.Errorf("consumer run: %w", err))
+		}
+	}()
+
+	msgs := dew.Consumer.GetMessagesChan()
+	for {
+		select {
+		case <-ctx.Done():
+			dew.Consumer.Close()
+			dew.lg.Info("context done - stop")
+			return
+		case msg := <-msgs:
+			var req mqdto.DataExportRequest
+			if err := json.Unmarshal(msg.Value, &req); err != nil {
+				dew.lg.Error(fmt.Errorf("unmarshal: %w", err))
+				dew.Consumer.Commit(msg)
+				continue
+			}
+
+			part := dew.Export(ctx, &req)
+			if err := dew.publish(ctx, part); err != nil {
+				dew.lg.Error(fmt.Errorf("publish part: %w", err))
+				continue
+			}
+			dew.Consumer.Commit(msg)
+
+			lg := dew.lg.With(loglables.DataExport, *part)
+			lg.Info("export subject data")
+		}
+	}
+}
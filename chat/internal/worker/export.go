@@ -0,0 +1,274 @@
+package worker
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/1ocknight/mess/chat/internal/adapter/archive"
+	"github.com/1ocknight/mess/chat/internal/adapter/profile"
+	"github.com/1ocknight/mess/chat/internal/loglables"
+	"github.com/1ocknight/mess/chat/internal/model"
+	"github.com/1ocknight/mess/chat/internal/storage"
+	"github.com/1ocknight/mess/shared/logger"
+)
+
+type ExportWorkerConfig struct {
+	Delay             time.Duration `yaml:"delay"`
+	PageSize          int           `yaml:"page_size"`
+	ProcessingTimeout time.Duration `yaml:"processing_timeout"`
+}
+
+const (
+	DefaultExportProcessingTimeout = 10 * time.Minute
+	// ExportFailedReason is stored for the client instead of the internal build error
+	ExportFailedReason = "export failed"
+)
+
+type ExportWorker struct {
+	Storage storage.Service
+	Archive archive.Service
+	Profile profile.Service
+	lg      logger.Logger
+	cfg     *ExportWorkerConfig
+}
+
+func NewExportWorker(storage storage.Service, archive archive.Service, profile profile.Service, lg logger.Logger, cfg *ExportWorkerConfig) *ExportWorker {
+	if cfg.ProcessingTimeout <= 0 {
+		cfg.ProcessingTimeout = DefaultExportProcessingTimeout
+	}
+
+	return &ExportWorker{
+		Storage: storage,
+		Archive: archive,
+		Profile: profile,
+		lg:      lg,
+		cfg:     cfg,
+	}
+}
+
+var (
+	NoExportsError = fmt.Errorf("no pending exports")
+)
+
+func ExportObjectKey(export *model.Export) string {
+	return fmt.Sprintf("exports/%d/%d.zip", export.ChatID, export.ID)
+}
+
+func (ew *ExportWorker) Export(ctx context.Context) (*model.Export, error) {
+	export, err := ew.claim(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	key := ExportObjectKey(export)
+	buildErr := ew.build(ctx, export, key)
+
+	tx, err := ew.Storage.WithTransaction(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("with transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if buildErr != nil {
+		export, err = tx.Export().FailExport(ctx, export.ID, ExportFailedReason)
+		if err != nil {
+			return nil, fmt.Errorf("fail export: %w", err)
+		}
+	} else {
+		export, err = tx.Export().CompleteExport(ctx, export.ID, key)
+		if err != nil {
+			return nil, fmt.Errorf("complete export: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+
+	if buildErr != nil {
+		return export, fmt.Errorf("build export %v: %w", export.ID, buildErr)
+	}
+
+	return export, nil
+}
+
+// claim marks a pending export as processing and commits, so the build and the upload
+// run without holding the row lock. A processing export is claimed again after ProcessingTimeout.
+func (ew *ExportWorker) claim(ctx context.Context) (*model.Export, error) {
+	tx, err := ew.Storage.WithTransaction(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("with transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	export, err := tx.Export().GetPendingExport(ctx, time.Now().UTC().Add(-ew.cfg.ProcessingTimeout))
+	if errors.Is(err, storage.ErrNoRows) {
+		return nil, NoExportsError
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get pending export: %w", err)
+	}
+
+	export, err = tx.Export().StartExport(ctx, export.ID)
+	if err != nil {
+		return nil, fmt.Errorf("start export: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+
+	return export, nil
+}
+
+func (ew *ExportWorker) build(ctx context.Context, export *model.Export, key string) error {
+	chat, err := ew.Storage.Chat().GetChatByID(ctx, export.ChatID)
+	if err != nil {
+		return fmt.Errorf("get chat by id: %w", err)
+	}
+
+	aliases, err := ew.Profile.GetAliases(ctx, []string{chat.FirstSubjectID, chat.SecondSubjectID})
+	if err != nil {
+		ew.lg.Error(fmt.Errorf("get aliases, fallback to subject ids: %w", err))
+		aliases = map[string]string{}
+	}
+
+	file, err := os.CreateTemp("", "chat-export-*.zip")
+	if err != nil {
+		return fmt.Errorf("create temp: %w", err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	zw := zip.NewWriter(file)
+
+	jsonl, err := zw.Create(exportJSONLinesName)
+	if err != nil {
+		return fmt.Errorf("create %v: %w", exportJSONLinesName, err)
+	}
+	err = forEachMessage(ctx, ew.Storage.Message(), chat.ID, ew.cfg.PageSize, func(mess *model.Message) error {
+		return writeJSONLine(jsonl, newExportMessage(mess, aliases))
+	})
+	if err != nil {
+		return fmt.Errorf("write %v: %w", exportJSONLinesName, err)
+	}
+
+	html, err := zw.Create(exportHTMLName)
+	if err != nil {
+		return fmt.Errorf("create %v: %w", exportHTMLName, err)
+	}
+	err = htmlHeader.Execute(html, htmlHeaderData{
+		ChatID:      chat.ID,
+		FirstAlias:  aliasOrID(aliases, chat.FirstSubjectID),
+		SecondAlias: aliasOrID(aliases, chat.SecondSubjectID),
+		ExportedAt:  time.Now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("write html header: %w", err)
+	}
+	err = forEachMessage(ctx, ew.Storage.Message(), chat.ID, ew.cfg.PageSize, func(mess *model.Message) error {
+		return htmlMessage.Execute(html, newExportMessage(mess, aliases))
+	})
+	if err != nil {
+		return fmt.Errorf("write %v: %w", exportHTMLName, err)
+	}
+	if _, err := io.WriteString(html, htmlFooter); err != nil {
+		return fmt.Errorf("write html footer: %w", err)
+	}
+
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("close zip: %w", err)
+	}
+
+	size, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return fmt.Errorf("seek: %w", err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("seek: %w", err)
+	}
+
+	if err := ew.Archive.Upload(ctx, key, file, size, exportContentType); err != nil {
+		return fmt.Errorf("upload: %w", err)
+	}
+
+	return nil
+}
+
+func forEachMessage(ctx context.Context, messages storage.Message, chatID int, pageSize int, fn func(mess *model.Message) error) error {
+	filter := storage.PaginationFilterIntLastID{
+		Limit:     pageSize,
+		Asc:       true,
+		SortLabel: storage.MessageNumberLabel,
+	}
+
+	for {
+		page, err := messages.GetMessagesByChatID(ctx, chatID, &filter)
+		if err != nil {
+			return fmt.Errorf("get messages by chat id: %w", err)
+		}
+
+		for _, mess := range page {
+			if err := fn(mess); err != nil {
+				return err
+			}
+		}
+
+		if len(page) == 0 || len(page) < filter.Limit {
+			return nil
+		}
+
+		last := page[len(page)-1]
+		filter.LastID = &last.ID
+		filter.LastSortValue = last.Number
+	}
+}
+
+func aliasOrID(aliases map[string]string, subjectID string) string {
+	if alias, ok := aliases[subjectID]; ok {
+		return alias
+	}
+	return subjectID
+}
+
+func (ew *ExportWorker) Run(ctx context.Context) {
+	ew.lg.Info("run export worker")
+
+	ticker := time.NewTicker(ew.cfg.Delay)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			ew.lg.Info("context done - stop")
+			return
+		default:
+			export, err := ew.Export(ctx)
+			if err == nil {
+				lg := ew.lg.With(loglables.Export, *export)
+				lg.Info("export chat")
+				continue
+			}
+
+			if errors.Is(err, NoExportsError) {
+				ew.lg.Info("no exports")
+			} else {
+				ew.lg.Error(fmt.Errorf("export: %w", err))
+			}
+
+			select {
+			case <-ctx.Done():
+				ew.lg.Info("context done - stop")
+				return
+			case <-ticker.C:
+				ew.lg.Info("wait delay")
+				continue
+			}
+		}
+	}
+}
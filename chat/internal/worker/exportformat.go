@@ -0,0 +1,93 @@
+package worker
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"time"
+
+	"github.com/1ocknight/mess/chat/internal/model"
+)
+
+const (
+	exportJSONLinesName = "messages.jsonl"
+	exportHTMLName      = "messages.html"
+	exportContentType   = "application/zip"
+)
+
+type exportMessage struct {
+	ID          int       `json:"id"`
+	Number      int       `json:"number"`
+	SenderID    string    `json:"sender_id"`
+	SenderAlias string    `json:"sender_alias"`
+	Content     string    `json:"content"`
+	Version     int       `json:"version"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func newExportMessage(mess *model.Message, aliases map[string]string) *exportMessage {
+	alias, ok := aliases[mess.SenderSubjectID]
+	if !ok {
+		alias = mess.SenderSubjectID
+	}
+
+	return &exportMessage{
+		ID:          mess.ID,
+		Number:      mess.Number,
+		SenderID:    mess.SenderSubjectID,
+		SenderAlias: alias,
+		Content:     mess.Content,
+		Version:     mess.Version,
+		CreatedAt:   mess.CreatedAt,
+		UpdatedAt:   mess.UpdatedAt,
+	}
+}
+
+var (
+	htmlHeader = template.Must(template.New("header").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Chat {{.ChatID}}</title>
+<style>
+body { font-family: sans-serif; max-width: 800px; margin: 0 auto; }
+.message { margin: 8px 0; }
+.meta { color: #888; font-size: 12px; }
+.content { white-space: pre-wrap; }
+</style>
+</head>
+<body>
+<h1>Chat {{.ChatID}}</h1>
+<p class="meta">{{.FirstAlias}} &amp; {{.SecondAlias}}, exported {{.ExportedAt.Format "2006-01-02 15:04:05 MST"}}</p>
+`))
+
+	htmlMessage = template.Must(template.New("message").Parse(`<div class="message" id="message-{{.ID}}">
+<div class="meta">{{.SenderAlias}} · {{.CreatedAt.Format "2006-01-02 15:04:05"}}{{if gt .Version 1}} · edited{{end}}</div>
+<div class="content">{{.Content}}</div>
+</div>
+`))
+
+	htmlFooter = "</body>\n</html>\n"
+)
+
+type htmlHeaderData struct {
+	ChatID      int
+	FirstAlias  string
+	SecondAlias string
+	ExportedAt  time.Time
+}
+
+func writeJSONLine(w io.Writer, v any) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	if _, err := w.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+
+	return nil
+}
@@ -92,6 +92,9 @@ func (mw *MessageWorker) Send(ctx context.Context) ([]int, error) {
 				CreatedAt: mess.CreatedAt,
 			},
 		}
+		if mess.ClientMessageID != nil {
+			sendMessage.Message.ClientMessageID = *mess.ClientMessageID
+		}
 
 		if out.Operation == model.AddOperation {
 			sendMessage.Operation = mqdto.AddOperation
@@ -0,0 +1,132 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/1ocknight/mess/chat/internal/loglables"
+	"github.com/1ocknight/mess/chat/internal/storage"
+	mqdto "github.com/1ocknight/mess/shared/dto/mq"
+	"github.com/1ocknight/mess/shared/kafkav2"
+	"github.com/1ocknight/mess/shared/logger"
+)
+
+type ProfileWorkerConfig struct {
+	Consumer kafkav2.GroupConsumerConfig `yaml:"kafka_consumer"`
+	Producer kafkav2.ProducerConfig      `yaml:"kafka_producer"`
+	Delay    time.Duration               `yaml:"delay"`
+}
+
+// ProfileWorker sends the profile changes of a subject to everyone the subject has a chat with,
+// a new profile has no chats yet, so created events are skipped.
+type ProfileWorker struct {
+	Consumer *kafkav2.GroupConsumer
+	Producer *kafkav2.Producer
+	Storage  storage.Service
+	lg       logger.Logger
+	cfg      *ProfileWorkerConfig
+}
+
+func NewProfileWorker(storage storage.Service, lg logger.Logger, cfg *ProfileWorkerConfig) (*ProfileWorker, error) {
+	consumer, err := kafkav2.NewGroupConsumer(cfg.Consumer)
+	if err != nil {
+		return nil, fmt.Errorf("new group consumer: %w", err)
+	}
+
+	producer, err := kafkav2.NewProducer(cfg.Producer)
+	if err != nil {
+		return nil, fmt.Errorf("new producer: %w", err)
+	}
+
+	return &ProfileWorker{
+		Consumer: consumer,
+		Producer: producer,
+		Storage:  storage,
+		lg:       lg,
+		cfg:      cfg,
+	}, nil
+}
+
+func (pw *ProfileWorker) fanOut(ctx context.Context, event *mqdto.ProfileEvent) (int, error) {
+	if event.Type == mqdto.ProfileCreatedEvent {
+		return 0, nil
+	}
+
+	partners, err := pw.Storage.Chat().GetPartnerSubjectIDs(ctx, event.SubjectID)
+	if err != nil {
+		return 0, fmt.Errorf("get partner subject ids: %w", err)
+	}
+
+	pairs := make([]*kafkav2.KeyValPair, 0, len(partners))
+	for _, partner := range partners {
+		if partner == event.SubjectID {
+			continue
+		}
+
+		val, err := json.Marshal(mqdto.ProfileUpdated{
+			RecipientID: partner,
+			SubjectID:   event.SubjectID,
+			Version:     event.Version,
+			UpdatedAt:   event.UpdatedAt,
+		})
+		if err != nil {
+			return 0, fmt.Errorf("marshal: %w", err)
+		}
+		pairs = append(pairs, &kafkav2.KeyValPair{Key: []byte(partner), Val: val})
+	}
+	if len(pairs) == 0 {
+		return 0, nil
+	}
+
+	if err := pw.Producer.Publish(pairs); err != nil {
+		return 0, fmt.Errorf("publish: %w", err)
+	}
+
+	return len(pairs), nil
+}
+
+func (pw *ProfileWorker) Run(ctx context.Context) {
+	pw.lg.Info("run profile worker")
+
+	defer pw.Producer.Close()
+
+	go func() {
+		if err := pw.Consumer.Run(ctx); err != nil {
+			pw.lg.Error(fmt.Errorf("consumer run: %w", err))
+		}
+	}()
+
+	msgs := pw.Consumer.GetMessagesChan()
+	for {
+		select {
+		case <-ctx.Done():
+			pw.Consumer.Close()
+			pw.lg.Info("context done - stop")
+			return
+		case msg := <-msgs:
+			var event mqdto.ProfileEvent
+			if err := json.Unmarshal(msg.Value, &event); err != nil {
+				pw.lg.Error(fmt.Errorf("unmarshal: %w", err))
+				pw.Consumer.Commit(msg)
+				continue
+			}
+
+			sent, err := pw.fanOut(ctx, &event)
+			for err != nil {
+				pw.lg.Error(fmt.Errorf("fan out: %w", err))
+				select {
+				case <-ctx.Done():
+					pw.Consumer.Close()
+					return
+				case <-time.After(pw.cfg.Delay):
+				}
+				sent, err = pw.fanOut(ctx, &event)
+			}
+			pw.Consumer.Commit(msg)
+
+			pw.lg.With(loglables.SubjectID, event.SubjectID).With(loglables.Updated, sent).Info("send profile update")
+		}
+	}
+}
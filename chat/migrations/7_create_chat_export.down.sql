@@ -0,0 +1 @@
+DROP TABLE IF EXISTS chat_export;
\ No newline at end of file
@@ -0,0 +1,15 @@
+CREATE TABLE chat_export (
+    id SERIAL PRIMARY KEY,
+    chat_id INT NOT NULL,
+    subject_id TEXT NOT NULL,
+    status INT NOT NULL DEFAULT 0,
+    object_key TEXT,
+    error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    deleted_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_chat_export_pending
+ON chat_export (id)
+WHERE status = 0 AND deleted_at IS NULL;
\ No newline at end of file
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_message_unique_client_id;
+
+ALTER TABLE message DROP COLUMN IF EXISTS client_message_id;
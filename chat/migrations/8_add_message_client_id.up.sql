@@ -0,0 +1,5 @@
+ALTER TABLE message ADD COLUMN client_message_id TEXT;
+
+CREATE UNIQUE INDEX idx_message_unique_client_id
+ON message (chat_id, sender_subject_id, client_message_id)
+WHERE client_message_id IS NOT NULL;
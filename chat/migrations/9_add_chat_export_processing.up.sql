@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_chat_export_pending;
+
+CREATE INDEX idx_chat_export_pending
+ON chat_export (id)
+WHERE status IN (0, 3) AND deleted_at IS NULL;
@@ -4,6 +4,16 @@ resource "minio_s3_bucket" "profile-bucket" {
   acl    = "private"
 }
 
+resource "minio_s3_bucket" "chat-export-bucket" {
+  bucket = "chat-export"
+  acl    = "private"
+}
+
+resource "minio_s3_bucket" "data-export-bucket" {
+  bucket = "data-export"
+  acl    = "private"
+}
+
 //users
 resource "minio_iam_user" "profile-user" {
   name = "profile"
@@ -15,6 +25,16 @@ resource "minio_iam_user_policy_attachment" "profile-attach" {
   depends_on = [minio_iam_user.profile-user]
 }
 
+resource "minio_iam_user" "chat-user" {
+  name = "chat"
+  secret = "chat-secret"
+}
+resource "minio_iam_user_policy_attachment" "chat-attach" {
+  user_name   = minio_iam_user.chat-user.name
+  policy_name = "readwrite"
+  depends_on = [minio_iam_user.chat-user]
+}
+
 //kafka
 resource "minio_s3_bucket_notification" "profile-event" {
   bucket = minio_s3_bucket.profile-bucket.bucket
@@ -23,6 +43,8 @@ resource "minio_s3_bucket_notification" "profile-event" {
     id = var.kafka_topic_profile
     queue_arn = "arn:minio:sqs::${var.kafka_topic_profile}:kafka"
     events = ["s3:ObjectCreated:*"]
+    // processed images are put by the service itself, only raw uploads need processing
+    filter_prefix = "uploads/"
   }
 }
 
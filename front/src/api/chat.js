@@ -17,6 +17,8 @@ export async function addChat(subjectId, token) {
     method: 'POST',
     headers: { Authorization: `Bearer ${token}` },
   });
+  // собеседник принимает новые чаты только от контактов
+  if (res.status === 403) throw new Error('Chat is not allowed');
   if (!res.ok) throw new Error('Failed to add chat');
   return res.json();
 }
@@ -33,9 +35,13 @@ export async function openChatByID(chatId, token, limit) {
   return res.json();
 }
 
-// Получить все чаты
-export async function getChats(token) {
-  const res = await fetch(`${API_BASE}/chats`, {
+// Получить страницу чатов, ответ { chats, next_cursor, prev_cursor }
+export async function getChats(token, { limit, cursor } = {}) {
+  const params = new URLSearchParams();
+  if (limit) params.append('limit', limit);
+  if (cursor) params.append('cursor', cursor);
+
+  const res = await fetch(`${API_BASE}/chats?${params.toString()}`, {
     headers: { Authorization: `Bearer ${token}` },
   });
   if (!res.ok) throw new Error('Failed to fetch chats');
@@ -44,15 +50,16 @@ export async function getChats(token) {
 
 // --- MESSAGES ENDPOINTS ---
 
+// Страница сообщений, ответ { messages, next_cursor, prev_cursor }.
+// Без cursor отдаются последние сообщения, prev_cursor ведет к более старым
 export async function getMessages(
   token,
-  { chat_id, before, after, limit } = {}
+  { chat_id, cursor, limit } = {}
 ) {
   const params = new URLSearchParams();
 
   if (chat_id) params.append('chat_id', chat_id);
-  if (before !== undefined) params.append('before', before);
-  if (after !== undefined) params.append('after', after);
+  if (cursor) params.append('cursor', cursor);
   if (limit) params.append('limit', limit);
 
   const url = `${API_BASE}/messages?${params.toString()}`;
@@ -70,7 +77,7 @@ export async function getMessages(
 
   // 🔥 защита от пустого ответа
   const text = await res.text();
-  if (!text) return [];
+  if (!text) return { messages: [] };
 
   return JSON.parse(text);
 }
@@ -16,6 +16,28 @@ export async function getProfileById(token, id) {
   return res.json();
 }
 
+// до 100 id за запрос, ответ { profiles, not_found, deleted }
+export async function getProfilesBatch(token, subjectIds) {
+  const res = await fetch(`${API_BASE}/profiles/batch`, {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json',
+      Authorization: `Bearer ${token}`,
+    },
+    body: JSON.stringify({ subject_ids: subjectIds }),
+  });
+  if (!res.ok) throw new Error('Failed to fetch profiles batch');
+  return res.json();
+}
+
+export async function getProfileByUsername(token, username) {
+  const res = await fetch(`${API_BASE}/profile/by-username/${encodeURIComponent(username)}`, {
+    headers: { Authorization: `Bearer ${token}` },
+  });
+  if (!res.ok) throw new Error('Failed to fetch profile by username');
+  return res.json();
+}
+
 export async function getProfiles(token, params = {}) {
   const query = new URLSearchParams(params).toString();
   const res = await fetch(`${API_BASE}/profiles?${query}`, {
@@ -38,28 +60,118 @@ export async function addProfile(token, alias) {
   return res.json();
 }
 
-export async function updateProfile(token, alias, version) {
+// PUT меняет только переданные поля: alias, display_name, bio, status { text, emoji, expires_at } (null очищает) и links [{ title, url }]
+export async function updateProfile(token, fields, version) {
   const res = await fetch(`${API_BASE}/profile`, {
     method: 'PUT',
     headers: {
       'Content-Type': 'application/json',
       Authorization: `Bearer ${token}`,
     },
-    body: JSON.stringify({ alias, version }),
+    body: JSON.stringify({ ...fields, version }),
   });
   if (!res.ok) throw new Error('Failed to update profile');
   return res.json();
 }
 
-export async function getUploadAvatarUrl(token) {
-  const res = await fetch(`${API_BASE}/avatar`, {
+// username меняется не чаще раза в неделю, ответы 409 (занят) и 429 (рано) показываем как есть
+export async function updateUsername(token, username, version) {
+  const res = await fetch(`${API_BASE}/profile/username`, {
     method: 'PUT',
+    headers: {
+      'Content-Type': 'application/json',
+      Authorization: `Bearer ${token}`,
+    },
+    body: JSON.stringify({ username, version }),
+  });
+  if (res.status === 409) throw new Error('Username is taken');
+  if (res.status === 429) throw new Error('Username was changed recently');
+  if (!res.ok) throw new Error('Failed to update username');
+  return res.json();
+}
+
+// privacy: { search, avatar, last_seen, new_chat }, значения everyone, contacts или nobody
+export async function updatePrivacy(token, privacy, version) {
+  const res = await fetch(`${API_BASE}/profile/privacy`, {
+    method: 'PUT',
+    headers: {
+      'Content-Type': 'application/json',
+      Authorization: `Bearer ${token}`,
+    },
+    body: JSON.stringify({ ...privacy, version }),
+  });
+  if (!res.ok) throw new Error('Failed to update privacy');
+  return res.json();
+}
+
+// контакт добавляется по subject_id или username, повторное добавление меняет nickname
+export async function addContact(token, { subjectId, username, nickname }) {
+  const res = await fetch(`${API_BASE}/contacts`, {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json',
+      Authorization: `Bearer ${token}`,
+    },
+    body: JSON.stringify({ subject_id: subjectId, username, nickname: nickname || '' }),
+  });
+  if (!res.ok) throw new Error('Failed to add contact');
+  return res.json();
+}
+
+export async function getContacts(token, params = {}) {
+  const query = new URLSearchParams(params).toString();
+  const res = await fetch(`${API_BASE}/contacts?${query}`, {
     headers: { Authorization: `Bearer ${token}` },
   });
-  if (!res.ok) throw new Error('Failed to get upload URL');
+  if (res.status === 204) return { contacts: [] };
+  if (!res.ok) throw new Error('Failed to fetch contacts');
+  return res.json();
+}
+
+export async function deleteContact(token, subjectId) {
+  const res = await fetch(`${API_BASE}/contacts/${subjectId}`, {
+    method: 'DELETE',
+    headers: { Authorization: `Bearer ${token}` },
+  });
+  if (!res.ok) throw new Error('Failed to delete contact');
   return res.json();
 }
 
+async function sha256Hex(blob) {
+  const digest = await crypto.subtle.digest('SHA-256', await blob.arrayBuffer());
+  return Array.from(new Uint8Array(digest))
+    .map(b => b.toString(16).padStart(2, '0'))
+    .join('');
+}
+
+// ключ аватарки содержит хеш файла, поэтому сначала считаем его, потом грузим формой по подписанной политике:
+// S3 сам проверяет размер, тип и хеш файла. Аватарка станет активной после обработки,
+// результат придет событием avatar_processed
+export async function uploadAvatar(token, file) {
+  const contentType = file.type || 'application/octet-stream';
+  const res = await fetch(`${API_BASE}/avatar`, {
+    method: 'PUT',
+    headers: {
+      'Content-Type': 'application/json',
+      Authorization: `Bearer ${token}`,
+    },
+    body: JSON.stringify({ sha256: await sha256Hex(file), content_type: contentType }),
+  });
+  if (!res.ok) throw new Error('Failed to get upload URL');
+  const { upload_url, fields } = await res.json();
+
+  const form = new FormData();
+  Object.entries(fields || {}).forEach(([name, value]) => form.append(name, value));
+  // файл должен идти последним полем формы
+  form.append('file', file);
+
+  const upload = await fetch(upload_url, {
+    method: 'POST',
+    body: form,
+  });
+  if (!upload.ok) throw new Error('Failed to upload avatar');
+}
+
 export async function deleteAvatar(token) {
   const res = await fetch(`${API_BASE}/avatar`, {
     method: 'DELETE',
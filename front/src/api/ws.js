@@ -0,0 +1,13 @@
+const API_BASE = 'http://localhost:8082';
+
+// --- WS ENDPOINTS ---
+
+// Обменять токен на одноразовый тикет для подключения к /ws
+export async function getWSTicket(token) {
+  const res = await fetch(`${API_BASE}/ws/ticket`, {
+    method: 'POST',
+    headers: { Authorization: `Bearer ${token}` },
+  });
+  if (!res.ok) throw new Error('Failed to get ws ticket');
+  return res.json();
+}
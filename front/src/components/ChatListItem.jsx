@@ -1,18 +1,7 @@
-import React, { useEffect, useState } from 'react';
-import { getProfileById } from '../api/profile';
-
-export default function ChatListItem({ chat, token, onClick }) {
-  const [profile, setProfile] = useState(null);
-
-  useEffect(() => {
-    const subjectId = chat.second_subject_id;
-    if (!subjectId) return;
-
-    getProfileById(token, subjectId)
-      .then(setProfile)
-      .catch(console.error);
-  }, [chat.second_subject_id, token]);
+import React from 'react';
 
+// профиль собеседника грузит ChatsList одним batch запросом на страницу чатов
+export default function ChatListItem({ chat, profile, onClick }) {
   const unreadCount = chat.unread_count || 0;
 
   const lastMessage = chat.last_message;
@@ -55,7 +44,10 @@ export default function ChatListItem({ chat, token, onClick }) {
 
       <div style={{ flex: 1 }}>
         <div style={{ fontWeight: 'bold' }}>
-          {profile?.alias || 'Loading...'}
+          {profile === null
+            ? 'Deleted account'
+            : profile ? (profile.display_name || profile.alias) : 'Loading...'}
+          {profile?.status?.emoji && <span style={{ marginLeft: 6 }}>{profile.status.emoji}</span>}
         </div>
 
         <div
@@ -12,10 +12,9 @@ export default function ChatWindow({ chatId, token, myProfile }) {
   const [otherUserLastRead, setOtherUserLastRead] = useState(0);
   const [messages, setMessages] = useState([]);
   const [loadingUp, setLoadingUp] = useState(false);
-  const [hasMoreUp, setHasMoreUp] = useState(true);
-  const [hasMoreDown, setHasMoreDown] = useState(false);
+  // prev_cursor самой старой загруженной страницы, null - история загружена целиком
+  const [prevCursor, setPrevCursor] = useState(null);
   const [text, setText] = useState('');
-  const [isDown, setIsDown] = useState(true);
 
   const containerRef = useRef(null);
   const bottomRef = useRef(null);
@@ -69,89 +68,24 @@ export default function ChatWindow({ chatId, token, myProfile }) {
         // Вычисляем last_read для текущего пользователя
         const lastReadId = fullChat.last_reads?.[myProfile.subject_id] || 0;
 
+        // Без cursor приходит последняя страница в порядке показа, к старым ведет prev_cursor
         let finalMessages = [];
-        let hasMoreUpFlag = false;
-        let hasMoreDownFlag = false;
-        let isDownFlag = false;
-
+        let olderCursor = null;
         try {
-          if (lastReadId) {
-            // Есть last_read: сначала загружаем сообщения ПОСЛЕ него (after)
-            const newerMessages = await getMessages(token, {
-              chat_id: fullChat.chat_id,
-              after: lastReadId,
-              limit: PAGE_SIZE,
-            });
-
-            if (newerMessages && newerMessages.length > 0) {
-              // Есть новые сообщения после last_read
-              finalMessages = newerMessages.sort(
-                (a, b) => new Date(a.created_at) - new Date(b.created_at)
-              );
-              hasMoreDownFlag = newerMessages.length >= PAGE_SIZE;
-              isDownFlag = false; // Пока не дойдем до конца
-
-              // Загружаем и предыдущие сообщения (until last_read)
-              if (finalMessages.length > 0) {
-                const oldestId = finalMessages[0].id;
-                try {
-                  const olderMessages = await getMessages(token, {
-                    chat_id: fullChat.chat_id,
-                    before: oldestId,
-                    limit: PAGE_SIZE,
-                  });
-                  if (olderMessages && olderMessages.length > 0) {
-                    finalMessages = [...olderMessages, ...finalMessages].sort(
-                      (a, b) => new Date(a.created_at) - new Date(b.created_at)
-                    );
-                    hasMoreUpFlag = olderMessages.length >= PAGE_SIZE;
-                  }
-                } catch (err) {
-                  console.error('Failed to load messages before last_read', err);
-                }
-              }
-            } else {
-              // Нет сообщений после last_read: грузим before последние сообщения
-              const latestMessages = await getMessages(token, {
-                chat_id: fullChat.chat_id,
-                before: 999999999,
-                limit: PAGE_SIZE,
-              });
-              if (latestMessages && latestMessages.length > 0) {
-                finalMessages = latestMessages.sort(
-                  (a, b) => new Date(a.created_at) - new Date(b.created_at)
-                );
-                hasMoreUpFlag = latestMessages.length >= PAGE_SIZE;
-              }
-              isDownFlag = true; // Нет новых — ставим isDown = true
-              hasMoreDownFlag = false;
-            }
-          } else {
-            // Нет last_read: грузим последние сообщения и ставим isDown = true
-            const latestMessages = await getMessages(token, {
-              chat_id: fullChat.chat_id,
-              before: 999999999,
-              limit: PAGE_SIZE,
-            });
-            if (latestMessages && latestMessages.length > 0) {
-              finalMessages = latestMessages.sort(
-                (a, b) => new Date(a.created_at) - new Date(b.created_at)
-              );
-              hasMoreUpFlag = latestMessages.length >= PAGE_SIZE;
-            }
-            isDownFlag = true;
-            hasMoreDownFlag = false;
-          }
+          const page = await getMessages(token, {
+            chat_id: fullChat.chat_id,
+            limit: PAGE_SIZE,
+          });
+          finalMessages = page.messages || [];
+          olderCursor = page.prev_cursor || null;
         } catch (err) {
           console.error('Failed to load messages', err);
         }
 
         if (isMounted) {
           setMessages(finalMessages);
-          setHasMoreUp(hasMoreUpFlag);
-          setHasMoreDown(hasMoreDownFlag);
-          setIsDown(isDownFlag);
-          isUserAtBottomRef.current = isDownFlag;
+          setPrevCursor(olderCursor);
+          isUserAtBottomRef.current = true;
 
           // Если есть last_read и он в списке, скроллим к нему
           if (lastReadId && finalMessages.some(m => m.id === lastReadId)) {
@@ -193,7 +127,6 @@ export default function ChatWindow({ chatId, token, myProfile }) {
         // Скроллим вниз только если сообщение отправлено текущим пользователем
         if (data.sender_id === myProfile.subject_id) {
           isUserAtBottomRef.current = true;
-          setIsDown(true);
         }
       } else if (type === 'update_last_read') {
         // Обновляем last_read другого юзера
@@ -203,65 +136,32 @@ export default function ChatWindow({ chatId, token, myProfile }) {
       }
     });
 
+    // загружена всегда последняя страница, поэтому новые сообщения просто дописываются в конец,
+    // автоскролл срабатывает только если пользователь внизу
     if (toUpsert.length > 0) {
-      // Если isDown (пользователь у низа) — добавляем сообщения в конец
-      if (isDown) {
-        upsertMessages(toUpsert);
-      } else {
-        // Если NOT isDown — запрашиваем сообщения до первого текущего
-        // Это имитирует пагинацию вверх автоматически
-        if (messages.length > 0) {
-          const firstId = messages[0].id;
-          (async () => {
-            try {
-              const older = await getMessages(token, {
-                chat_id: chat.chat_id,
-                before: firstId,
-                limit: PAGE_SIZE,
-              });
-              if (older && older.length > 0) {
-                setMessages(prev => {
-                  const combined = [...older, ...prev].sort(
-                    (a, b) => new Date(a.created_at) - new Date(b.created_at)
-                  );
-                  return combined;
-                });
-              }
-            } catch (err) {
-              console.error('Failed to load messages before in WS handler', err);
-            }
-          })();
-        }
-      }
+      upsertMessages(toUpsert);
     }
-  }, [wsMessages, chat, upsertMessages, myProfile.subject_id, isDown, messages, token, otherUserLastRead]);
+  }, [wsMessages, chat, upsertMessages, myProfile.subject_id]);
 
   // ---------- ПАГИНАЦИЯ ВВЕРХ ----------
   const loadHistoryUp = useCallback(async () => {
-    if (loadingUp || !hasMoreUp || !messages.length || !chat) return;
+    if (loadingUp || !prevCursor || !chat) return;
     setLoadingUp(true);
 
-    const oldestId = messages[0].id;
-
     try {
-      const older = await getMessages(token, {
+      const page = await getMessages(token, {
         chat_id: chat.chat_id,
-        before: oldestId,
+        cursor: prevCursor,
         limit: PAGE_SIZE,
       });
+      const older = page.messages || [];
 
-      if (older.length < PAGE_SIZE) setHasMoreUp(false);
+      setPrevCursor(page.prev_cursor || null);
 
       if (older.length > 0) {
         const prevHeight = containerRef.current.scrollHeight;
-        
-        // Обновляем messages напрямую
-        setMessages(prev => {
-          const combined = [...older, ...prev].sort(
-            (a, b) => new Date(a.created_at) - new Date(b.created_at)
-          );
-          return combined;
-        });
+
+        upsertMessages(older);
 
         requestAnimationFrame(() => {
           if (containerRef.current) {
@@ -269,33 +169,12 @@ export default function ChatWindow({ chatId, token, myProfile }) {
           }
         });
       }
+    } catch (err) {
+      console.error('Failed to load history', err);
     } finally {
       setLoadingUp(false);
     }
-  }, [loadingUp, hasMoreUp, messages, chat, token]);
-
-  const loadMoreDown = useCallback(async () => {
-    if (hasMoreDown === false) return;
-    if (!messages.length || !chat) return;
-    const lastId = messages[messages.length - 1].id;
-    try {
-      const newer = await getMessages(token, {
-        chat_id: chat.chat_id,
-        after: lastId,
-        limit: PAGE_SIZE,
-      });
-      // Если получили пустой массив — значит достигли конца, выставляем isDown = true
-      if (newer.length === 0) {
-        setHasMoreDown(false);
-        setIsDown(true);
-      } else {
-        if (newer.length < PAGE_SIZE) setHasMoreDown(false);
-        upsertMessages(newer);
-      }
-    } catch (err) {
-      console.error('Failed to load more down', err);
-    }
-  }, [hasMoreDown, messages, chat, token, upsertMessages]);
+  }, [loadingUp, prevCursor, chat, token, upsertMessages]);
 
   const handleScroll = useCallback(() => {
     const el = containerRef.current;
@@ -303,17 +182,11 @@ export default function ChatWindow({ chatId, token, myProfile }) {
 
     const distanceFromBottom = el.scrollHeight - el.scrollTop - el.clientHeight;
     isUserAtBottomRef.current = distanceFromBottom < 100;
-    setIsDown(distanceFromBottom < 100);
 
     if (el.scrollTop < 50) {
       loadHistoryUp();
     }
-
-    // Подгрузка вниз, если скроллим к низу
-    if (distanceFromBottom < 100) {
-      loadMoreDown();
-    }
-  }, [loadHistoryUp, loadMoreDown]);
+  }, [loadHistoryUp]);
 
   // ---------- ОТПРАВКА СООБЩЕНИЯ (БЕЗ ОБНОВЛЕНИЯ СТЕЙТА) ----------
   const handleSend = async () => {
@@ -1,6 +1,7 @@
 import React, { useEffect, useState, useRef } from 'react';
 import { useNavigate } from 'react-router-dom';
 import { getChats } from '../api/chat';
+import { getProfilesBatch } from '../api/profile';
 import { useWS } from '../context/WebSocketContext';
 import ChatListItem from './ChatListItem';
 
@@ -8,36 +9,54 @@ export default function ChatsList({ token, pageSize = 20 }) {
   const [chats, setChats] = useState([]);
   const [loading, setLoading] = useState(false);
   const [hasMore, setHasMore] = useState(true);
+  // subject_id -> профиль, null для удаленных и не найденных
+  const [profiles, setProfiles] = useState({});
 
-  const afterRef = useRef(null);
+  // next_cursor последней загруженной страницы
+  const cursorRef = useRef(null);
   const containerRef = useRef(null);
 
   const { messages } = useWS();
   const navigate = useNavigate(); // <-- для перехода
 
+  // ---------- Загрузка профилей ----------
+  const loadProfiles = async (subjectIds) => {
+    const ids = [...new Set(subjectIds.filter(Boolean))];
+    if (!token || ids.length === 0) return;
+
+    try {
+      const res = await getProfilesBatch(token, ids);
+      setProfiles(prev => {
+        const next = { ...prev };
+        (res.profiles || []).forEach(p => { next[p.subject_id] = p; });
+        [...(res.not_found || []), ...(res.deleted || [])].forEach(id => { next[id] = null; });
+        return next;
+      });
+    } catch (err) {
+      console.error(err);
+    }
+  };
+
   // ---------- Загрузка чатов ----------
-  const fetchChats = async (after = null) => {
+  const fetchChats = async (cursor = null) => {
     if (!token || loading || !hasMore) return;
     setLoading(true);
 
     try {
-      const params = { limit: pageSize };
-      if (after) params.after = after;
-
-      const newChats = await getChats(token, params);
-      if (!Array.isArray(newChats)) return;
+      const res = await getChats(token, { limit: pageSize, cursor });
+      const newChats = res.chats || [];
 
       setChats(prev => {
         const ids = new Set(prev.map(c => c.chat_id));
         return [...prev, ...newChats.filter(c => !ids.has(c.chat_id))];
       });
+      loadProfiles(newChats.map(c => c.second_subject_id));
 
-      if (newChats.length > 0) {
-        afterRef.current = newChats[newChats.length - 1].chat_id;
-      }
-
-      if (newChats.length < pageSize) setHasMore(false);
+      cursorRef.current = res.next_cursor || null;
+      if (!res.next_cursor) setHasMore(false);
 
+    } catch (err) {
+      console.error(err);
     } finally {
       setLoading(false);
     }
@@ -52,7 +71,7 @@ export default function ChatsList({ token, pageSize = 20 }) {
     if (!containerRef.current || loading || !hasMore) return;
     const { scrollTop, scrollHeight, clientHeight } = containerRef.current;
     if (scrollTop + clientHeight >= scrollHeight - 50) {
-      fetchChats(afterRef.current);
+      fetchChats(cursorRef.current);
     }
   };
 
@@ -61,6 +80,11 @@ export default function ChatsList({ token, pageSize = 20 }) {
     messages.forEach(msg => {
       const chatMsg = msg.data;
 
+      if (msg.type === 'profile_updated') {
+        loadProfiles([chatMsg.subject_id]);
+        return;
+      }
+
       setChats(prev => {
         const map = new Map(prev.map(c => [c.chat_id, c]));
         const existing = map.get(chatMsg.chat_id);
@@ -97,6 +121,7 @@ export default function ChatsList({ token, pageSize = 20 }) {
             });
           }
         } else {
+          loadProfiles([chatMsg.sender_id]);
           map.set(chatMsg.chat_id, {
             chat_id: chatMsg.chat_id,
             second_subject_id: chatMsg.sender_id,
@@ -130,7 +155,7 @@ export default function ChatsList({ token, pageSize = 20 }) {
         <ChatListItem
           key={chat.chat_id}
           chat={chat}
-          token={token}
+          profile={profiles[chat.second_subject_id]}
           onClick={() => navigate(`/chat/${chat.chat_id}`)} // <-- переход на страницу чата
         />
       ))}
@@ -1,8 +1,23 @@
 import React, { useState } from 'react';
-import { updateProfile, getUploadAvatarUrl } from '../api/profile';
+import { updatePrivacy, updateProfile, updateUsername, uploadAvatar } from '../api/profile';
+
+const PRIVACY_FIELDS = [
+  { name: 'search', label: 'Поиск', options: ['everyone', 'nobody'] },
+  { name: 'avatar', label: 'Аватар', options: ['everyone', 'contacts', 'nobody'] },
+  { name: 'last_seen', label: 'Был в сети', options: ['everyone', 'contacts', 'nobody'] },
+  { name: 'new_chat', label: 'Новые чаты', options: ['everyone', 'contacts'] },
+];
+
+const DEFAULT_PRIVACY = { search: 'everyone', avatar: 'everyone', last_seen: 'everyone', new_chat: 'everyone' };
 
 export default function ProfileModal({ profile, token, onClose, onUpdate, keycloak }) {
   const [alias, setAlias] = useState(profile.alias);
+  const [username, setUsername] = useState(profile.username || '');
+  const [displayName, setDisplayName] = useState(profile.display_name || '');
+  const [bio, setBio] = useState(profile.bio || '');
+  const [statusText, setStatusText] = useState(profile.status?.text || '');
+  const [statusEmoji, setStatusEmoji] = useState(profile.status?.emoji || '');
+  const [privacy, setPrivacy] = useState(profile.privacy || DEFAULT_PRIVACY);
   const [avatarFile, setAvatarFile] = useState(null);
   const [avatarPreview, setAvatarPreview] = useState(profile.avatar_url);
   const [loading, setLoading] = useState(false);
@@ -18,21 +33,36 @@ export default function ProfileModal({ profile, token, onClose, onUpdate, keyclo
   const handleSave = async () => {
     setLoading(true);
     try {
-      const updated = await updateProfile(token, alias, profile.version);
+      const hasStatus = statusText || statusEmoji;
+      let updated = await updateProfile(token, {
+        alias,
+        display_name: displayName,
+        bio,
+        // срок статуса пока не редактируем, сохраняем прежний
+        status: hasStatus ? { text: statusText, emoji: statusEmoji, expires_at: profile.status?.expires_at } : null,
+        links: profile.links || [],
+      }, profile.version);
+
+      if (username && username !== (profile.username || '')) {
+        updated = await updateUsername(token, username, updated.version);
+      }
+
+      const prevPrivacy = profile.privacy || DEFAULT_PRIVACY;
+      if (PRIVACY_FIELDS.some(({ name }) => privacy[name] !== prevPrivacy[name])) {
+        updated = await updatePrivacy(token, privacy, updated.version);
+      }
 
       if (avatarFile) {
-        const { upload_url } = await getUploadAvatarUrl(token);
-        await fetch(upload_url, {
-          method: 'PUT',
-          body: avatarFile,
-        });
+        await uploadAvatar(token, avatarFile);
+        // до обработки показываем локальное превью
+        updated.avatar_url = avatarPreview;
       }
 
       onUpdate(updated);
       onClose();
     } catch (err) {
       console.error(err);
-      alert('Failed to update profile or upload avatar');
+      alert(err.message || 'Failed to update profile or upload avatar');
     } finally {
       setLoading(false);
     }
@@ -114,6 +144,131 @@ export default function ProfileModal({ profile, token, onClose, onUpdate, keyclo
         </label>
       </div>
 
+      {/* Username */}
+      <div style={{ marginBottom: 20, textAlign: 'center' }}>
+        <label>
+          Username:
+          <input
+            value={username}
+            onChange={(e) => setUsername(e.target.value)}
+            placeholder="john_doe"
+            style={{
+              marginLeft: 10,
+              padding: '5px 10px',
+              borderRadius: 8,
+              border: 'none',
+              outline: 'none',
+              fontSize: 14,
+            }}
+          />
+        </label>
+      </div>
+
+      {/* Display name */}
+      <div style={{ marginBottom: 20, textAlign: 'center' }}>
+        <label>
+          Display name:
+          <input
+            value={displayName}
+            onChange={(e) => setDisplayName(e.target.value)}
+            maxLength={64}
+            style={{
+              marginLeft: 10,
+              padding: '5px 10px',
+              borderRadius: 8,
+              border: 'none',
+              outline: 'none',
+              fontSize: 14,
+            }}
+          />
+        </label>
+      </div>
+
+      {/* Bio */}
+      <div style={{ marginBottom: 20, textAlign: 'center' }}>
+        <label>
+          Bio:
+          <textarea
+            value={bio}
+            onChange={(e) => setBio(e.target.value)}
+            maxLength={500}
+            style={{
+              marginLeft: 10,
+              padding: '5px 10px',
+              borderRadius: 8,
+              border: 'none',
+              outline: 'none',
+              fontSize: 14,
+            }}
+          />
+        </label>
+      </div>
+
+      {/* Status */}
+      <div style={{ marginBottom: 20, textAlign: 'center' }}>
+        <label>
+          Status:
+          <input
+            value={statusText}
+            onChange={(e) => setStatusText(e.target.value)}
+            maxLength={100}
+            style={{
+              marginLeft: 10,
+              padding: '5px 10px',
+              borderRadius: 8,
+              border: 'none',
+              outline: 'none',
+              fontSize: 14,
+            }}
+          />
+        </label>
+      </div>
+
+      {/* Status emoji */}
+      <div style={{ marginBottom: 20, textAlign: 'center' }}>
+        <label>
+          Emoji:
+          <input
+            value={statusEmoji}
+            onChange={(e) => setStatusEmoji(e.target.value)}
+            maxLength={16}
+            style={{
+              marginLeft: 10,
+              padding: '5px 10px',
+              borderRadius: 8,
+              border: 'none',
+              outline: 'none',
+              fontSize: 14,
+            }}
+          />
+        </label>
+      </div>
+
+      {/* Приватность */}
+      {PRIVACY_FIELDS.map(({ name, label, options }) => (
+        <div key={name} style={{ marginBottom: 15, textAlign: 'center' }}>
+          <label>
+            {label}:
+            <select
+              value={privacy[name]}
+              onChange={(e) => setPrivacy({ ...privacy, [name]: e.target.value })}
+              style={{
+                marginLeft: 10,
+                padding: '5px 10px',
+                borderRadius: 8,
+                border: 'none',
+                outline: 'none',
+                fontSize: 14,
+              }}
+            >
+              {options.map(option => (
+                <option key={option} value={option}>{option}</option>
+              ))}
+            </select>
+          </label>
+        </div>
+      ))}
+
       {/* Кнопки */}
       <div style={{ display: 'flex', justifyContent: 'center', flexWrap: 'wrap', gap: 10 }}>
         <button
@@ -1,5 +1,5 @@
 import React, { useState } from 'react';
-import { getProfiles, getProfileById } from '../api/profile';
+import { getProfiles, getProfileById, addContact } from '../api/profile';
 import { getChatBySubject, addChat } from '../api/chat';
 import ProfileCard from './ProfileCard';
 import { useNavigate } from 'react-router-dom';
@@ -9,9 +9,8 @@ export default function ProfileSearchModal({ token, onClose }) {
   const [profiles, setProfiles] = useState([]);
   const [loading, setLoading] = useState(false);
   const [limit] = useState(8);
-  const [after, setAfter] = useState(null);
-  const [before, setBefore] = useState(null);
-  const [hasMore, setHasMore] = useState(false);
+  const [nextCursor, setNextCursor] = useState(null);
+  const [prevCursor, setPrevCursor] = useState(null);
 
   const navigate = useNavigate();
 
@@ -21,31 +20,36 @@ export default function ProfileSearchModal({ token, onClose }) {
     try {
       // если начинается с @ — ищем по alias (поддерживаем пагинацию)
       if (query.startsWith('@')) {
-        const alias = query.slice(1);
+        const alias = query.slice(1).trim();
+        // сервис ищет от 3 символов
+        if (alias.length < 3) {
+          setProfiles([]);
+          setNextCursor(null);
+          setPrevCursor(null);
+          return;
+        }
         const params = { limit };
-        if (dir === 'after' && after) params.after = after;
-        if (dir === 'before' && before) params.before = before;
+        if (dir === 'after' && nextCursor) params.cursor = nextCursor;
+        if (dir === 'before' && prevCursor) params.cursor = prevCursor;
 
         try {
           const res = await getProfiles(token, { alias, ...params });
-          const arr = (res && res.profiles) || [];
-          setProfiles(arr);
-          setHasMore(arr.length === limit);
-          if (arr.length > 0) {
-            setAfter(arr[arr.length - 1].subject_id);
-            setBefore(arr[0].subject_id);
-          }
+          setProfiles((res && res.profiles) || []);
+          setNextCursor((res && res.next_cursor) || null);
+          setPrevCursor((res && res.prev_cursor) || null);
         } catch (err) {
           // 204 No Content или другие ошибки — считаем пустым
           setProfiles([]);
-          setHasMore(false);
+          setNextCursor(null);
+          setPrevCursor(null);
         }
       } else {
         // обычный ввод — трактуем как ID
         try {
           const p = await getProfileById(token, query);
           setProfiles([p]);
-          setHasMore(false);
+          setNextCursor(null);
+          setPrevCursor(null);
         } catch (err) {
           setProfiles([]);
         }
@@ -75,7 +79,18 @@ export default function ProfileSearchModal({ token, onClose }) {
       }
     } catch (err) {
       console.error('Failed to open or create chat', err);
-      alert('Не удалось открыть или создать чат');
+      alert(err.message === 'Chat is not allowed'
+        ? 'Пользователь не принимает новые чаты'
+        : 'Не удалось открыть или создать чат');
+    }
+  };
+
+  const addToContacts = async (profile) => {
+    try {
+      await addContact(token, { subjectId: profile.subject_id });
+    } catch (err) {
+      console.error('Failed to add contact', err);
+      alert('Не удалось добавить контакт');
     }
   };
 
@@ -124,8 +139,17 @@ export default function ProfileSearchModal({ token, onClose }) {
 
         <div style={{ display: 'flex', flexDirection: 'column', gap: 8, maxHeight: 320, overflowY: 'auto' }}>
           {profiles.map((p) => (
-            <div key={p.subject_id} onClick={() => openChatFor(p)}>
-              <ProfileCard profile={{ alias: p.alias, avatar_url: p.avatar_url }} />
+            <div key={p.subject_id} style={{ display: 'flex', alignItems: 'center', gap: 8 }}>
+              <div onClick={() => openChatFor(p)}>
+                <ProfileCard profile={{ alias: p.alias, avatar_url: p.avatar_url }} />
+              </div>
+              <button
+                onClick={() => addToContacts(p)}
+                title="Добавить в контакты"
+                style={{ padding: '6px 10px', borderRadius: 8, border: 'none', cursor: 'pointer' }}
+              >
+                +
+              </button>
             </div>
           ))}
           {profiles.length === 0 && <div style={{ textAlign: 'center', opacity: 0.9 }}>Ничего не найдено</div>}
@@ -135,7 +159,7 @@ export default function ProfileSearchModal({ token, onClose }) {
           <div>
             <button
               onClick={() => search('before')}
-              disabled={!before}
+              disabled={!prevCursor}
               style={{ padding: '6px 10px', borderRadius: 8, border: 'none', background: '#7b1fa2', color: '#fff' }}
             >
               Назад
@@ -150,7 +174,7 @@ export default function ProfileSearchModal({ token, onClose }) {
             </button>
             <button
               onClick={() => search('after')}
-              disabled={!hasMore}
+              disabled={!nextCursor}
               style={{ padding: '6px 10px', borderRadius: 8, border: 'none', background: '#9c27b0', color: '#fff' }}
             >
               Далее
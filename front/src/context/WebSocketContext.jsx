@@ -1,52 +1,142 @@
-import React, { createContext, useContext, useState, useCallback } from 'react';
+import React, { createContext, useContext, useState, useCallback, useEffect, useRef } from 'react';
 import useWebSocket, { ReadyState } from 'react-use-websocket';
+import { getWSTicket } from '../api/ws';
 
 const WSContext = createContext();
 
+const WS_BASE = 'ws://localhost:8082';
+const SSE_BASE = 'http://localhost:8082';
+
 export const WSProvider = ({ token, children }) => {
   const [messages, setMessages] = useState([]);
+  // если websocket не поднимается (прокси режет upgrade), переходим на SSE
+  const [fallback, setFallback] = useState(false);
+  const [sseOpen, setSseOpen] = useState(false);
+
+  const tokenRef = useRef(token);
+  tokenRef.current = token;
+  const lastEventIdRef = useRef(null);
+  // задержка из кадра reconnect, сервер разносит переподключения при рестарте
+  const reconnectDelayRef = useRef(null);
+
+  const handleRaw = useCallback((raw) => {
+    const msgs = raw
+      .split('\n')
+      .map(str => str.trim())
+      .filter(Boolean);
+
+    msgs.forEach(msgStr => {
+      try {
+        const msg = JSON.parse(msgStr);
+        if (msg.type === 'reconnect') {
+          reconnectDelayRef.current = msg.data?.delay_ms ?? null;
+          return;
+        }
+        if (msg.type === 'avatar_processed' && msg.data?.status === 'rejected') {
+          alert(`Avatar rejected: ${msg.data.reason}`);
+        }
+        // у ack и error id - это id команды, а не события
+        if (msg.id && msg.type !== 'ack' && msg.type !== 'error') {
+          lastEventIdRef.current = msg.id;
+        }
+        setMessages(prev => [...prev, msg]);
+      } catch (e) {
+        console.warn('WS ignored non-JSON message:', msgStr);
+      }
+    });
+  }, []);
+
+  const resumeQuery = () => (lastEventIdRef.current
+    ? `&last_event_id=${encodeURIComponent(lastEventIdRef.current)}`
+    : '');
 
-  const wsUrl = token
-    ? `ws://localhost:8082/ws?token=${encodeURIComponent(token)}`
-    : null;
+  // тикет одноразовый, поэтому берем новый на каждое подключение
+  const getSocketUrl = useCallback(async () => {
+    const { ticket } = await getWSTicket(tokenRef.current);
+    return `${WS_BASE}/ws?ticket=${encodeURIComponent(ticket)}${resumeQuery()}`;
+  }, []); // eslint-disable-line react-hooks/exhaustive-deps
 
   const {
     sendMessage,
     lastMessage,
     readyState,
-  } = useWebSocket(wsUrl, {
+  } = useWebSocket(token && !fallback ? getSocketUrl : null, {
     onOpen: () => console.log('WS connected'),
-    onMessage: (event) => {
-      const raw = event.data;
-      const msgs = raw
-        .split('\n')
-        .map(str => str.trim())
-        .filter(Boolean);
-
-      msgs.forEach(msgStr => {
-        try {
-          const msg = JSON.parse(msgStr);
-          setMessages(prev => [...prev, msg]);
-        } catch (e) {
-          console.warn('WS ignored non-JSON message:', msgStr);
-        }
-      });
-    },
+    onMessage: (event) => handleRaw(event.data),
     onError: (err) => {
       console.error('WS error:', err);
     },
     shouldReconnect: (closeEvent) => true, // всегда переподключаемся
     reconnectAttempts: 10, // макс попыток
-    reconnectInterval: 3000, // пауза между попытками (мс)
+    reconnectInterval: () => { // пауза между попытками (мс)
+      const delay = reconnectDelayRef.current ?? 3000;
+      reconnectDelayRef.current = null;
+      return delay;
+    },
+    onReconnectStop: () => setFallback(true),
   });
 
+  // при обновлении токена поток не пересоздаем, новый токен возьмется при следующем переподключении
+  const hasToken = Boolean(token);
+
+  // EventSource сам переподключается со старым url: после обрыва сервер держит тикет потока sse.resume_ttl.
+  // Если тикет уже не пускает (закрытие сессии, истек токен), EventSource закрывается и берем новый
+  useEffect(() => {
+    if (!fallback || !hasToken) return undefined;
+
+    let es = null;
+    let closed = false;
+    const open = async (reuseTicket) => {
+      try {
+        const ticket = reuseTicket ?? (await getWSTicket(tokenRef.current)).ticket;
+        if (closed) return;
+        let opened = false;
+        es = new EventSource(`${SSE_BASE}/events?ticket=${encodeURIComponent(ticket)}${resumeQuery()}`);
+        es.onopen = () => {
+          opened = true;
+          setSseOpen(true);
+        };
+        es.onmessage = (event) => handleRaw(event.data);
+        es.onerror = () => {
+          setSseOpen(false);
+          const reuse = opened;
+          opened = false;
+          const delay = reconnectDelayRef.current;
+          reconnectDelayRef.current = null;
+          // обрыв: EventSource переподключится сам с тем же тикетом и Last-Event-ID
+          if (es.readyState === EventSource.CONNECTING && delay == null) return;
+          es.close();
+          if (closed) return;
+          // при рестарте ждем delay_ms из кадра reconnect, тикет к тому времени еще жив
+          setTimeout(() => open(reuse ? ticket : null), delay ?? 3000);
+        };
+      } catch (e) {
+        if (!closed) setTimeout(() => open(null), 3000);
+      }
+    };
+    open(null);
+
+    return () => {
+      closed = true;
+      if (es) es.close();
+      setSseOpen(false);
+    };
+  }, [fallback, hasToken]); // eslint-disable-line react-hooks/exhaustive-deps
+
   const addListener = useCallback((cb) => {
     if (!lastMessage) return () => {};
     cb(JSON.parse(lastMessage.data));
     return () => {};
   }, [lastMessage]);
 
-  const connected = readyState === ReadyState.OPEN;
+  const connected = fallback ? sseOpen : readyState === ReadyState.OPEN;
+
+  // обновляем токен в открытом соединении, иначе сервер закроет его по истечении
+  useEffect(() => {
+    if (!fallback && connected && token) {
+      sendMessage(JSON.stringify({ type: 'auth', data: { token } }));
+    }
+  }, [token]); // eslint-disable-line react-hooks/exhaustive-deps
 
   return (
     <WSContext.Provider value={{ connected, messages, addListener, sendMessage }}>
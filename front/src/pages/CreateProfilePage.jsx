@@ -1,6 +1,6 @@
 import React, { useState, useEffect } from 'react';
 import { useKeycloak } from '@react-keycloak/web';
-import { addProfile, getUploadAvatarUrl, getProfile } from '../api/profile';
+import { addProfile, uploadAvatar, getProfile } from '../api/profile';
 import { useNavigate } from 'react-router-dom';
 import defaultAvatar from '../../public/vite.svg'; // дефолтный аватар
 
@@ -58,14 +58,12 @@ export default function CreateProfilePage() {
 
       await addProfile(keycloak.token, alias);
 
-      const { upload_url } = await getUploadAvatarUrl(keycloak.token);
-
       if (avatarFile) {
-        await fetch(upload_url, { method: 'PUT', body: avatarFile });
+        await uploadAvatar(keycloak.token, avatarFile);
       } else {
         const response = await fetch(defaultAvatar);
         const blob = await response.blob();
-        await fetch(upload_url, { method: 'PUT', body: blob });
+        await uploadAvatar(keycloak.token, blob);
       }
 
       alert('Profile created!');
@@ -11,6 +11,7 @@ import (
 	"syscall"
 
 	"github.com/1ocknight/mess/profile/config"
+	"github.com/1ocknight/mess/profile/internal/adapter/archive"
 	"github.com/1ocknight/mess/profile/internal/adapter/avatar"
 	"github.com/1ocknight/mess/profile/internal/ctxkey"
 	"github.com/1ocknight/mess/profile/internal/domain"
@@ -19,6 +20,7 @@ import (
 	"github.com/1ocknight/mess/profile/internal/transport"
 	workers "github.com/1ocknight/mess/profile/internal/wokers"
 	"github.com/1ocknight/mess/shared/auth/keycloak"
+	"github.com/1ocknight/mess/shared/cursor"
 	"github.com/1ocknight/mess/shared/logger"
 	"github.com/1ocknight/mess/shared/postgres"
 )
@@ -62,9 +64,15 @@ func main() {
 		return
 	}
 
-	dom := domain.New(storage, avatar)
+	archive, err := archive.New(ctx, cfg.Archive)
+	if err != nil {
+		lg.Error(fmt.Errorf("archive new: %w", err))
+		return
+	}
+
+	dom := domain.New(storage, avatar, archive)
 
-	ad := workers.NewAvatarDeleter(cfg.AvatarDeleter, avatar, storage)
+	ad := workers.NewAvatarDeleter(cfg.AvatarDeleter, cfg.AvatarProcessor.ThumbnailSizes, avatar, storage)
 	avdelLog := lg.With(loglables.Layer, "worker_avatar_deleter")
 	err = ad.Start(ctxkey.WithLogger(ctx, avdelLog))
 	if err != nil {
@@ -73,6 +81,24 @@ func main() {
 	}
 	lg.Info("avatar deleter started")
 
+	ap := workers.NewAvatarProcessor(cfg.AvatarProcessor, cfg.S3.Upload, storage, avatar)
+	avprocLog := lg.With(loglables.Layer, "worker_avatar_processor")
+	err = ap.Start(ctxkey.WithLogger(ctx, avprocLog))
+	if err != nil {
+		lg.Error(fmt.Errorf("avatar processor start: %w", err))
+		return
+	}
+	lg.Info("avatar processor started")
+
+	lab := workers.NewLegacyAvatarBackfill(cfg.LegacyAvatars, avatar, storage)
+	labLog := lg.With(loglables.Layer, "worker_legacy_avatar_backfill")
+	err = lab.Start(ctxkey.WithLogger(ctx, labLog))
+	if err != nil {
+		lg.Error(fmt.Errorf("legacy avatar backfill start: %w", err))
+		return
+	}
+	lg.Info("legacy avatar backfill started")
+
 	pd := workers.NewProfileDeleter(cfg.ProfileDeleter, storage)
 	pdelLog := lg.With(loglables.Layer, "worker_profile_deleter")
 	err = pd.Start(ctxkey.WithLogger(ctx, pdelLog))
@@ -82,13 +108,37 @@ func main() {
 	}
 	lg.Info("profile deleter started")
 
+	de := workers.NewDataExporter(cfg.DataExporter, storage, avatar, archive)
+	dexpLog := lg.With(loglables.Layer, "worker_data_exporter")
+	err = de.Start(ctxkey.WithLogger(ctx, dexpLog))
+	if err != nil {
+		lg.Error(fmt.Errorf("data exporter start: %w", err))
+		return
+	}
+	lg.Info("data exporter started")
+
+	pes := workers.NewProfileEventSender(cfg.ProfileEvents, storage)
+	pevLog := lg.With(loglables.Layer, "worker_profile_event_sender")
+	err = pes.Start(ctxkey.WithLogger(ctx, pevLog))
+	if err != nil {
+		lg.Error(fmt.Errorf("profile event sender start: %w", err))
+		return
+	}
+	lg.Info("profile event sender started")
+
 	keycloak, err := keycloak.New(cfg.Keycloak, lg)
 	if err != nil {
 		lg.Error(fmt.Errorf("keycloak new: %w", err))
 		return
 	}
 
-	server := transport.NewServer(cfg.HTTP, lg, dom, keycloak)
+	cursors, err := cursor.New(cfg.Cursor)
+	if err != nil {
+		lg.Error(fmt.Errorf("cursor new: %w", err))
+		return
+	}
+
+	server := transport.NewServer(cfg.HTTP, lg, dom, keycloak, cursors)
 	go func() {
 		if err := server.Run(); err != nil && !errors.Is(http.ErrServerClosed, err) {
 			lg.Error(fmt.Errorf("server run: %w", err))
@@ -96,6 +146,14 @@ func main() {
 		}
 	}()
 
+	rpcServer := transport.NewRPCServer(cfg.RPC, lg, dom, keycloak)
+	go func() {
+		if err := rpcServer.Run(); err != nil && !errors.Is(http.ErrServerClosed, err) {
+			lg.Error(fmt.Errorf("rpc server run: %w", err))
+			return
+		}
+	}()
+
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
 	<-stop
@@ -108,6 +166,12 @@ func main() {
 	}
 	lg.Info("server is stop")
 
+	err = rpcServer.Stop(ctx)
+	if err != nil {
+		lg.Error(fmt.Errorf("rpc server stop: %w", err))
+	}
+	lg.Info("rpc server is stop")
+
 	cancel()
 	lg.Info("successful stop")
 }
@@ -5,22 +5,31 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/1ocknight/mess/profile/internal/adapter/archive"
 	"github.com/1ocknight/mess/profile/internal/adapter/avatar"
 	"github.com/1ocknight/mess/profile/internal/transport"
 	workers "github.com/1ocknight/mess/profile/internal/wokers"
 	"github.com/1ocknight/mess/shared/auth/keycloak"
+	"github.com/1ocknight/mess/shared/cursor"
 	"github.com/1ocknight/mess/shared/postgres"
 	"github.com/goccy/go-yaml"
 )
 
 type Config struct {
-	MigrationsPath string                       `yaml:"migrations_path"`
-	Postgres       postgres.Config              `yaml:"postgres"`
-	S3             avatar.Config                `yaml:"s3"`
-	HTTP           transport.Config             `yaml:"http"`
-	Keycloak       keycloak.Config              `yaml:"keycloak"`
-	AvatarDeleter  workers.AvatarDeleterConfig  `yaml:"avatar_deleter"`
-	ProfileDeleter workers.ProfileDeleterConfig `yaml:"profile_deleter"`
+	MigrationsPath  string                             `yaml:"migrations_path"`
+	Postgres        postgres.Config                    `yaml:"postgres"`
+	S3              avatar.Config                      `yaml:"s3"`
+	Archive         archive.Config                     `yaml:"archive"`
+	HTTP            transport.Config                   `yaml:"http"`
+	RPC             transport.RPCConfig                `yaml:"rpc"`
+	Keycloak        keycloak.Config                    `yaml:"keycloak"`
+	AvatarDeleter   workers.AvatarDeleterConfig        `yaml:"avatar_deleter"`
+	AvatarProcessor workers.AvatarProcessorConfig      `yaml:"avatar_processor"`
+	LegacyAvatars   workers.LegacyAvatarBackfillConfig `yaml:"legacy_avatars"`
+	ProfileDeleter  workers.ProfileDeleterConfig       `yaml:"profile_deleter"`
+	DataExporter    workers.DataExporterConfig         `yaml:"data_exporter"`
+	ProfileEvents   workers.ProfileEventSenderConfig   `yaml:"profile_events"`
+	Cursor          cursor.Config                      `yaml:"cursor"`
 }
 
 func LoadConfig() (*Config, error) {
@@ -0,0 +1,91 @@
+package archive
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/1ocknight/mess/shared/s3client"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+type Config struct {
+	Client          s3client.Config `yaml:"client"`
+	Bucket          string          `yaml:"bucket"`
+	PresignDuration time.Duration   `yaml:"presign_duration"`
+}
+
+type S3 struct {
+	cfg Config
+	c   *s3.Client
+	p   *s3.PresignClient
+}
+
+func New(ctx context.Context, cfg Config) (Service, error) {
+	client, err := s3client.New(ctx, cfg.Client)
+	if err != nil {
+		return nil, fmt.Errorf("create s3 client: %w", err)
+	}
+
+	return &S3{
+		cfg: cfg,
+		c:   client,
+		p:   s3.NewPresignClient(client),
+	}, nil
+}
+
+func (s *S3) Upload(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	_, err := s.c.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:        &s.cfg.Bucket,
+		Key:           &key,
+		Body:          body,
+		ContentLength: aws.Int64(size),
+		ContentType:   &contentType,
+	})
+	if err != nil {
+		return fmt.Errorf("put object: %w", err)
+	}
+
+	return nil
+}
+
+func (s *S3) Download(ctx context.Context, key string) (io.ReadCloser, error) {
+	out, err := s.c.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: &s.cfg.Bucket,
+		Key:    &key,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get object: %w", err)
+	}
+
+	return out.Body, nil
+}
+
+func (s *S3) GetDownloadURL(ctx context.Context, key string) (string, error) {
+	req, err := s.p.PresignGetObject(ctx,
+		&s3.GetObjectInput{
+			Bucket: &s.cfg.Bucket,
+			Key:    &key,
+		},
+		s3.WithPresignExpires(s.cfg.PresignDuration),
+	)
+	if err != nil {
+		return "", fmt.Errorf("presign get object: %w", err)
+	}
+
+	return req.URL, nil
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	_, err := s.c.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: &s.cfg.Bucket,
+		Key:    &key,
+	})
+	if err != nil {
+		return fmt.Errorf("delete object: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,13 @@
+package archive
+
+import (
+	"context"
+	"io"
+)
+
+type Service interface {
+	Upload(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	Download(ctx context.Context, key string) (io.ReadCloser, error)
+	GetDownloadURL(ctx context.Context, key string) (string, error)
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,39 @@
+package avatar
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// UploadPrefix holds raw uploads until they are processed, the bucket notifies only about this prefix.
+	UploadPrefix = "uploads/"
+
+	thumbnailSeparator = "_"
+)
+
+func UploadKey(key string) string {
+	return UploadPrefix + key
+}
+
+// KeyFromUpload returns the avatar key of a raw upload, false means the object is not an upload.
+func KeyFromUpload(uploadKey string) (string, bool) {
+	return strings.CutPrefix(uploadKey, UploadPrefix)
+}
+
+func ThumbnailKey(key string, size int) string {
+	return fmt.Sprintf("%s%s%d", key, thumbnailSeparator, size)
+}
+
+// WithThumbnails adds the thumbnails of every key, sizes removed from the config keep their thumbnails
+// of old avatars in the bucket.
+func WithThumbnails(keys []string, sizes []int) []string {
+	res := make([]string, 0, len(keys)*(len(sizes)+1))
+	for _, key := range keys {
+		res = append(res, key)
+		for _, size := range sizes {
+			res = append(res, ThumbnailKey(key, size))
+		}
+	}
+	return res
+}
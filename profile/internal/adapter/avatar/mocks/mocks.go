@@ -6,8 +6,10 @@ package avatarmocks
 
 import (
 	context "context"
+	io "io"
 	reflect "reflect"
 
+	avatar "github.com/1ocknight/mess/profile/internal/adapter/avatar"
 	gomock "github.com/golang/mock/gomock"
 )
 
@@ -48,6 +50,37 @@ func (mr *MockServiceMockRecorder) DeleteObjects(ctx, keys interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteObjects", reflect.TypeOf((*MockService)(nil).DeleteObjects), ctx, keys)
 }
 
+// Exists mocks base method.
+func (m *MockService) Exists(ctx context.Context, key string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Exists", ctx, key)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Exists indicates an expected call of Exists.
+func (mr *MockServiceMockRecorder) Exists(ctx, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Exists", reflect.TypeOf((*MockService)(nil).Exists), ctx, key)
+}
+
+// GetAvatar mocks base method.
+func (m *MockService) GetAvatar(ctx context.Context, key string) (io.ReadCloser, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAvatar", ctx, key)
+	ret0, _ := ret[0].(io.ReadCloser)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetAvatar indicates an expected call of GetAvatar.
+func (mr *MockServiceMockRecorder) GetAvatar(ctx, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAvatar", reflect.TypeOf((*MockService)(nil).GetAvatar), ctx, key)
+}
+
 // GetAvatarURL mocks base method.
 func (m *MockService) GetAvatarURL(ctx context.Context, key string) (string, error) {
 	m.ctrl.T.Helper()
@@ -64,16 +97,30 @@ func (mr *MockServiceMockRecorder) GetAvatarURL(ctx, key interface{}) *gomock.Ca
 }
 
 // GetUploadURL mocks base method.
-func (m *MockService) GetUploadURL(ctx context.Context, key string) (string, error) {
+func (m *MockService) GetUploadURL(ctx context.Context, key, contentType, checksum string) (*avatar.Upload, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetUploadURL", ctx, key)
-	ret0, _ := ret[0].(string)
+	ret := m.ctrl.Call(m, "GetUploadURL", ctx, key, contentType, checksum)
+	ret0, _ := ret[0].(*avatar.Upload)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetUploadURL indicates an expected call of GetUploadURL.
-func (mr *MockServiceMockRecorder) GetUploadURL(ctx, key interface{}) *gomock.Call {
+func (mr *MockServiceMockRecorder) GetUploadURL(ctx, key, contentType, checksum interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUploadURL", reflect.TypeOf((*MockService)(nil).GetUploadURL), ctx, key, contentType, checksum)
+}
+
+// Put mocks base method.
+func (m *MockService) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Put", ctx, key, body, size, contentType)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Put indicates an expected call of Put.
+func (mr *MockServiceMockRecorder) Put(ctx, key, body, size, contentType interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUploadURL", reflect.TypeOf((*MockService)(nil).GetUploadURL), ctx, key)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Put", reflect.TypeOf((*MockService)(nil).Put), ctx, key, body, size, contentType)
 }
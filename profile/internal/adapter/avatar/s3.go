@@ -2,7 +2,14 @@ package avatar
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"maps"
+	"net/url"
+	"slices"
 	"time"
 
 	"github.com/1ocknight/mess/shared/s3client"
@@ -16,8 +23,33 @@ type Config struct {
 	Client          s3client.Config `yaml:"client"`
 	Bucket          string          `yaml:"bucket"`
 	PresignDuration time.Duration   `yaml:"presign_duration"`
+
+	// PublicURL is a CDN that reads the private bucket with its own credentials, avatar keys never change
+	// their content, so their urls are stable and cached for CacheMaxAge. Empty means presigned urls.
+	PublicURL   string        `yaml:"public_url"`
+	CacheMaxAge time.Duration `yaml:"cache_max_age"`
+
+	Upload UploadConfig `yaml:"upload"`
+}
+
+// UploadConfig limits what a client may upload as an avatar, the limits are part of the presigned policy
+// and are checked again when the upload is processed.
+type UploadConfig struct {
+	MaxSizeBytes int64    `yaml:"max_size_bytes"`
+	ContentTypes []string `yaml:"content_types"`
 }
 
+const (
+	contentTypeField       = "Content-Type"
+	checksumAlgorithmField = "x-amz-checksum-algorithm"
+	checksumSHA256Field    = "x-amz-checksum-sha256"
+
+	contentLengthRangeCondition = "content-length-range"
+
+	// deleteObjectsLimit is the most keys one DeleteObjects request takes
+	deleteObjectsLimit = 1000
+)
+
 type S3 struct {
 	cfg Config
 	c   *s3.Client
@@ -39,26 +71,67 @@ func New(ctx context.Context, cfg Config) (Service, error) {
 	}, nil
 }
 
-func (s *S3) GetUploadURL(ctx context.Context, subjID string) (string, error) {
-	req, err := s.p.PresignPutObject(ctx,
-		&s3.PutObjectInput{
-			Bucket: &s.cfg.Bucket,
-			Key:    &subjID,
-		},
-		s3.WithPresignExpires(s.cfg.PresignDuration),
-	)
+// GetUploadURL presigns a POST policy that accepts only this key, content type, size range and checksum,
+// S3 rejects any other file before it is stored.
+func (s *S3) GetUploadURL(ctx context.Context, key string, contentType string, checksum string) (*Upload, error) {
+	if len(s.cfg.Upload.ContentTypes) != 0 && !slices.Contains(s.cfg.Upload.ContentTypes, contentType) {
+		return nil, fmt.Errorf("%w: %v", ErrContentTypeNotAllowed, contentType)
+	}
+
+	sum, err := hex.DecodeString(checksum)
 	if err != nil {
-		return "", fmt.Errorf("presign upload part: %w", err)
+		return nil, fmt.Errorf("decode checksum: %w", err)
 	}
 
-	return req.URL, nil
+	fields := map[string]string{
+		contentTypeField:       contentType,
+		checksumAlgorithmField: string(types.ChecksumAlgorithmSha256),
+		checksumSHA256Field:    base64.StdEncoding.EncodeToString(sum),
+	}
+
+	conditions := make([]any, 0, len(fields)+1)
+	for name, val := range fields {
+		conditions = append(conditions, map[string]string{name: val})
+	}
+	if s.cfg.Upload.MaxSizeBytes > 0 {
+		conditions = append(conditions, []any{contentLengthRangeCondition, 1, s.cfg.Upload.MaxSizeBytes})
+	}
+
+	input := &s3.PutObjectInput{
+		Bucket: &s.cfg.Bucket,
+		Key:    &key,
+	}
+
+	req, err := s.p.PresignPostObject(ctx, input, func(o *s3.PresignPostOptions) {
+		o.Expires = s.cfg.PresignDuration
+		o.Conditions = conditions
+	})
+	if err != nil {
+		return nil, fmt.Errorf("presign post object: %w", err)
+	}
+
+	// the policy checks these fields, so the client has to send them with the form
+	maps.Copy(req.Values, fields)
+
+	return &Upload{
+		URL:    req.URL,
+		Fields: req.Values,
+	}, nil
 }
 
-func (s *S3) GetAvatarURL(ctx context.Context, subjID string) (string, error) {
+func (s *S3) GetAvatarURL(ctx context.Context, key string) (string, error) {
+	if s.cfg.PublicURL != "" {
+		res, err := url.JoinPath(s.cfg.PublicURL, key)
+		if err != nil {
+			return "", fmt.Errorf("join path: %w", err)
+		}
+		return res, nil
+	}
+
 	req, err := s.p.PresignGetObject(ctx,
 		&s3.GetObjectInput{
 			Bucket: &s.cfg.Bucket,
-			Key:    &subjID,
+			Key:    &key,
 		},
 		s3.WithPresignExpires(s.cfg.PresignDuration),
 	)
@@ -70,24 +143,89 @@ func (s *S3) GetAvatarURL(ctx context.Context, subjID string) (string, error) {
 	return req.URL, nil
 }
 
-func (s *S3) DeleteObjects(ctx context.Context, subjIDs []string) error {
-	objects := make([]types.ObjectIdentifier, len(subjIDs))
-	for i, key := range subjIDs {
-		objects[i] = types.ObjectIdentifier{Key: aws.String(key)}
+func (s *S3) GetAvatar(ctx context.Context, key string) (io.ReadCloser, string, error) {
+	out, err := s.c.GetObject(ctx,
+		&s3.GetObjectInput{
+			Bucket: &s.cfg.Bucket,
+			Key:    &key,
+		},
+	)
+
+	var noSuchKey *types.NoSuchKey
+	if errors.As(err, &noSuchKey) {
+		return nil, "", ErrNotFound
 	}
+	if err != nil {
+		return nil, "", fmt.Errorf("get object: %w", err)
+	}
+
+	return out.Body, aws.ToString(out.ContentType), nil
+}
 
-	_, err := s.c.DeleteObjects(ctx,
-		&s3.DeleteObjectsInput{
+// Exists asks only the metadata of the object, the content is not read.
+func (s *S3) Exists(ctx context.Context, key string) (bool, error) {
+	_, err := s.c.HeadObject(ctx,
+		&s3.HeadObjectInput{
 			Bucket: &s.cfg.Bucket,
-			Delete: &types.Delete{
-				Objects: objects,
-				Quiet:   aws.Bool(false),
-			},
+			Key:    &key,
 		},
 	)
 
+	var notFound *types.NotFound
+	if errors.As(err, &notFound) {
+		return false, nil
+	}
 	if err != nil {
-		return fmt.Errorf("delete objects")
+		return false, fmt.Errorf("head object: %w", err)
+	}
+
+	return true, nil
+}
+
+// Put stores a processed image, it is never changed later, so it is cached for CacheMaxAge.
+func (s *S3) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	input := &s3.PutObjectInput{
+		Bucket:        &s.cfg.Bucket,
+		Key:           &key,
+		Body:          body,
+		ContentLength: aws.Int64(size),
+		ContentType:   aws.String(contentType),
+	}
+	if s.cfg.CacheMaxAge > 0 {
+		input.CacheControl = aws.String(fmt.Sprintf("public, max-age=%d, immutable", int(s.cfg.CacheMaxAge.Seconds())))
+	}
+
+	if _, err := s.c.PutObject(ctx, input); err != nil {
+		return fmt.Errorf("put object: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteObjects deletes exactly the given keys, missing keys are not an error.
+func (s *S3) DeleteObjects(ctx context.Context, keys []string) error {
+	for chunk := range slices.Chunk(keys, deleteObjectsLimit) {
+		objects := make([]types.ObjectIdentifier, 0, len(chunk))
+		for _, key := range chunk {
+			objects = append(objects, types.ObjectIdentifier{Key: aws.String(key)})
+		}
+
+		res, err := s.c.DeleteObjects(ctx,
+			&s3.DeleteObjectsInput{
+				Bucket: &s.cfg.Bucket,
+				Delete: &types.Delete{
+					Objects: objects,
+					Quiet:   aws.Bool(true),
+				},
+			},
+		)
+		if err != nil {
+			return fmt.Errorf("delete objects: %w", err)
+		}
+		if len(res.Errors) > 0 {
+			e := res.Errors[0]
+			return fmt.Errorf("delete %v: %v", aws.ToString(e.Key), aws.ToString(e.Message))
+		}
 	}
 
 	return nil
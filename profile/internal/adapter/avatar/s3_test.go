@@ -3,7 +3,11 @@ package avatar_test
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
 	"os"
 	"testing"
@@ -18,6 +22,10 @@ var TestIDs = []string{"test-file-1", "test-file-1"}
 
 var Content = []byte("test file content")
 
+var ContentSHA256 = fmt.Sprintf("%x", sha256.Sum256(Content))
+
+const ContentType = "image/png"
+
 func TestMain(m *testing.M) {
 	cfgClient := s3client.Config{
 		Region:          "us-east-1",
@@ -30,6 +38,10 @@ func TestMain(m *testing.M) {
 	CFG = avatar.Config{
 		Client: cfgClient,
 		Bucket: "avatar",
+		Upload: avatar.UploadConfig{
+			MaxSizeBytes: 1024,
+			ContentTypes: []string{ContentType},
+		},
 	}
 
 	os.Exit(m.Run())
@@ -43,34 +55,78 @@ func cleanUP(t *testing.T, s avatar.Service) {
 	}
 }
 
+func postForm(t *testing.T, upload *avatar.Upload, content []byte) *http.Response {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for name, val := range upload.Fields {
+		if err := w.WriteField(name, val); err != nil {
+			t.Fatalf("write field: %v", err)
+		}
+	}
+	part, err := w.CreateFormFile("file", "avatar")
+	if err != nil {
+		t.Fatalf("create form file: %v", err)
+	}
+	part.Write(content)
+	w.Close()
+
+	resp, err := http.Post(upload.URL, w.FormDataContentType(), &body)
+	if err != nil {
+		t.Fatalf("upload failed: %v", err)
+	}
+
+	return resp
+}
+
 func uploadDefaultFiles(ctx context.Context, t *testing.T, st avatar.Service) {
 	for _, key := range TestIDs {
-		uploadURL, err := st.GetUploadURL(ctx, key)
+		upload, err := st.GetUploadURL(ctx, key, ContentType, ContentSHA256)
 		if err != nil {
 			t.Fatalf("GetUploadURL failed: %v", err)
 		}
-		if uploadURL == "" {
+		if upload.URL == "" {
 			t.Fatal("expected upload URL to be not empty")
 		}
 
-		file := bytes.NewReader(Content)
-
-		req, err := http.NewRequest(http.MethodPut, uploadURL, file)
-		if err != nil {
-			t.Fatalf("failed to create upload request: %v", err)
-		}
-		resp, err := http.DefaultClient.Do(req)
-		if err != nil {
-			t.Fatalf("upload failed: %v", err)
-		}
+		resp := postForm(t, upload, Content)
 		defer resp.Body.Close()
 
-		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
 			t.Fatalf("unexpected upload status: %d", resp.StatusCode)
 		}
 	}
 }
 
+func TestGetUploadURLConstraints(t *testing.T) {
+	st, err := avatar.New(t.Context(), CFG)
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer cleanUP(t, st)
+
+	if _, err := st.GetUploadURL(t.Context(), TestIDs[0], "text/html", ContentSHA256); !errors.Is(err, avatar.ErrContentTypeNotAllowed) {
+		t.Fatalf("expected content type error, got: %v", err)
+	}
+
+	upload, err := st.GetUploadURL(t.Context(), TestIDs[0], ContentType, ContentSHA256)
+	if err != nil {
+		t.Fatalf("GetUploadURL failed: %v", err)
+	}
+
+	tooLarge := bytes.Repeat([]byte{1}, int(CFG.Upload.MaxSizeBytes)+1)
+	resp := postForm(t, upload, tooLarge)
+	defer resp.Body.Close()
+	if resp.StatusCode < 400 {
+		t.Fatalf("too large upload is accepted, status: %d", resp.StatusCode)
+	}
+
+	resp = postForm(t, upload, []byte("other content"))
+	defer resp.Body.Close()
+	if resp.StatusCode < 400 {
+		t.Fatalf("upload with wrong checksum is accepted, status: %d", resp.StatusCode)
+	}
+}
+
 func TestGetUploadURLAndGetAvatarURL(t *testing.T) {
 	st, err := avatar.New(t.Context(), CFG)
 	if err != nil {
@@ -118,3 +174,21 @@ func TestDeleteObjects(t *testing.T) {
 		t.Fatalf("DeleteObjects failed: %v", err)
 	}
 }
+
+func TestGetAvatarURLPublic(t *testing.T) {
+	cfg := CFG
+	cfg.PublicURL = "http://cdn.local/avatar/"
+
+	st, err := avatar.New(t.Context(), cfg)
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+
+	url, err := st.GetAvatarURL(t.Context(), "subj/1-abc")
+	if err != nil {
+		t.Fatalf("failed to get avatar URL: %v", err)
+	}
+	if url != "http://cdn.local/avatar/subj/1-abc" {
+		t.Fatalf("unexpected public url: %v", url)
+	}
+}
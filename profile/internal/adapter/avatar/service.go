@@ -1,9 +1,27 @@
 package avatar
 
-import "context"
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+var (
+	ErrNotFound              = fmt.Errorf("avatar not found")
+	ErrContentTypeNotAllowed = fmt.Errorf("avatar content type is not allowed")
+)
+
+// Upload is a presigned POST, Fields go to the multipart form as is and the file goes last.
+type Upload struct {
+	URL    string
+	Fields map[string]string
+}
 
 type Service interface {
-	GetUploadURL(ctx context.Context, subjID string) (string, error)
-	GetAvatarURL(ctx context.Context, subjID string) (string, error)
-	DeleteObjects(ctx context.Context, subjIDs []string) error
+	GetUploadURL(ctx context.Context, key string, contentType string, checksum string) (*Upload, error)
+	GetAvatarURL(ctx context.Context, key string) (string, error)
+	GetAvatar(ctx context.Context, key string) (io.ReadCloser, string, error)
+	Exists(ctx context.Context, key string) (bool, error)
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	DeleteObjects(ctx context.Context, keys []string) error
 }
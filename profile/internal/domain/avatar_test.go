@@ -0,0 +1,62 @@
+package domain_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/1ocknight/mess/profile/internal/adapter/avatar"
+	"github.com/1ocknight/mess/profile/internal/domain"
+	"github.com/1ocknight/mess/profile/internal/model"
+	"github.com/golang/mock/gomock"
+)
+
+func TestDomain_UploadAvatar(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Finish()
+
+	oldKey := domain.AvatarKey("subj", 1, "old")
+	newKey := domain.AvatarKey("subj", 2, "new")
+	prof := &model.Profile{SubjectID: "subj", AvatarKey: &oldKey, AvatarVersion: 1}
+	upload := &avatar.Upload{URL: "upload"}
+
+	env.subj.EXPECT().GetSubjectId().Return("subj").AnyTimes()
+
+	env.profile.EXPECT().GetProfileFromSubjectID(env.ctx, "subj").Return(prof, nil)
+	env.avatar.EXPECT().GetUploadURL(env.ctx, avatar.UploadKey(newKey), "image/png", "new").Return(upload, nil)
+	env.profile.EXPECT().UpdatePendingAvatarKey(env.ctx, "subj", &newKey).Return(prof, nil)
+
+	res, err := env.domain.UploadAvatar(env.ctx, "image/png", "new")
+	if err != nil {
+		t.Fatalf("upload avatar: %v", err)
+	}
+	if res != upload {
+		t.Fatalf("wait %v, have %v", upload, res)
+	}
+}
+
+func TestDomain_UploadAvatar_ContentType(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Finish()
+
+	env.subj.EXPECT().GetSubjectId().Return("subj").AnyTimes()
+
+	env.profile.EXPECT().GetProfileFromSubjectID(env.ctx, "subj").Return(&model.Profile{SubjectID: "subj"}, nil)
+	env.avatar.EXPECT().GetUploadURL(env.ctx, gomock.Any(), "text/html", "new").Return(nil, avatar.ErrContentTypeNotAllowed)
+
+	_, err := env.domain.UploadAvatar(env.ctx, "text/html", "new")
+	if !errors.Is(err, domain.ErrAvatarContentType) {
+		t.Fatalf("wait %v, have %v", domain.ErrAvatarContentType, err)
+	}
+}
+
+func TestDomain_DeleteAvatar_NoAvatar(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Finish()
+
+	env.subj.EXPECT().GetSubjectId().Return("subj").AnyTimes()
+	env.profile.EXPECT().GetProfileFromSubjectID(env.ctx, "subj").Return(&model.Profile{SubjectID: "subj"}, nil)
+
+	if err := env.domain.DeleteAvatar(env.ctx); err != nil {
+		t.Fatalf("delete avatar: %v", err)
+	}
+}
@@ -8,8 +8,18 @@ import (
 	"github.com/1ocknight/mess/profile/internal/model"
 )
 
-func (d *Domain) GetAvatarURL(ctx context.Context, key string) (string, error) {
-	avatarURL, err := d.Avatar.GetAvatarURL(ctx, key)
+// AvatarKey is content-hashed, so an url never changes its content and can be cached forever.
+// The version keeps a re-uploaded picture away from the same key that may still wait for deletion.
+func AvatarKey(subjectID string, version int, checksum string) string {
+	return fmt.Sprintf("%s/%d-%s", subjectID, version, checksum)
+}
+
+func (d *Domain) GetAvatarURL(ctx context.Context, profile *model.Profile) (string, error) {
+	if profile.AvatarKey == nil {
+		return "", nil
+	}
+
+	avatarURL, err := d.Avatar.GetAvatarURL(ctx, *profile.AvatarKey)
 	if err != nil {
 		return "", fmt.Errorf("get avatar url: %w", err)
 	}
@@ -17,6 +27,9 @@ func (d *Domain) GetAvatarURL(ctx context.Context, key string) (string, error) {
 	return avatarURL, nil
 }
 
+// AvatarURLWorkers bounds the urls presigned at once, a batch of profiles must not start a goroutine per profile.
+const AvatarURLWorkers = 16
+
 func (d *Domain) GetAvatarsURL(ctx context.Context, profiles []*model.Profile) (map[string]string, []error) {
 	res := make(map[string]string)
 	errors := make([]error, 0, len(profiles))
@@ -27,21 +40,30 @@ func (d *Domain) GetAvatarsURL(ctx context.Context, profiles []*model.Profile) (
 	mu := sync.Mutex{}
 
 	ch := make(chan error)
-	for _, profile := range profiles {
-		go func() {
-			defer wg.Done()
-
-			url, err := d.GetAvatarURL(ctx, profile.SubjectID)
-			if err != nil {
-				ch <- err
-				return
-			}
-
-			mu.Lock()
-			res[profile.SubjectID] = url
-			mu.Unlock()
-		}()
-	}
+	sem := make(chan struct{}, AvatarURLWorkers)
+	go func() {
+		for _, profile := range profiles {
+			sem <- struct{}{}
+			go func() {
+				defer func() { <-sem }()
+				defer wg.Done()
+
+				if profile.AvatarKey == nil {
+					return
+				}
+
+				url, err := d.GetAvatarURL(ctx, profile)
+				if err != nil {
+					ch <- err
+					return
+				}
+
+				mu.Lock()
+				res[profile.SubjectID] = url
+				mu.Unlock()
+			}()
+		}
+	}()
 
 	go func() {
 		wg.Wait()
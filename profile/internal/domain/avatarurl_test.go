@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"strconv"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	avatarmocks "github.com/1ocknight/mess/profile/internal/adapter/avatar/mocks"
 	"github.com/1ocknight/mess/profile/internal/ctxkey"
@@ -41,8 +43,10 @@ func TestDomain_GetAvatarsURL(t *testing.T) {
 
 	profiles := []*model.Profile{}
 	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("%v", i)
 		profiles = append(profiles, &model.Profile{
-			SubjectID: fmt.Sprintf("%v", i),
+			SubjectID: key,
+			AvatarKey: &key,
 		})
 	}
 
@@ -52,3 +56,45 @@ func TestDomain_GetAvatarsURL(t *testing.T) {
 		t.Errorf("total results (%d urls + %d errs) != 100", len(res), len(errs))
 	}
 }
+
+func TestDomain_GetAvatarsURL_Bounded(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	avatar := avatarmocks.NewMockService(ctrl)
+
+	d := domain.Domain{
+		Avatar: avatar,
+	}
+
+	var inFlight, maxInFlight atomic.Int32
+	avatar.EXPECT().GetAvatarURL(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, key string) (string, error) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			m := maxInFlight.Load()
+			if n <= m || maxInFlight.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		return "url_for_" + key, nil
+	}).Times(100)
+
+	profiles := []*model.Profile{}
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("%v", i)
+		profiles = append(profiles, &model.Profile{
+			SubjectID: key,
+			AvatarKey: &key,
+		})
+	}
+
+	res, errs := d.GetAvatarsURL(t.Context(), profiles)
+	if len(res) != 100 || len(errs) != 0 {
+		t.Fatalf("wait 100 urls, have %d urls and %d errs", len(res), len(errs))
+	}
+	if maxInFlight.Load() > domain.AvatarURLWorkers {
+		t.Fatalf("%d urls presigned at once, limit %d", maxInFlight.Load(), domain.AvatarURLWorkers)
+	}
+}
@@ -0,0 +1,105 @@
+package domain_test
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/1ocknight/mess/profile/internal/domain"
+	"github.com/1ocknight/mess/profile/internal/model"
+)
+
+func TestDomain_GetProfilesBatch(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Finish()
+
+	key := "id2/1-hash"
+	profiles := []*model.Profile{
+		{SubjectID: "id2", AvatarKey: &key, Privacy: model.DefaultPrivacy},
+		{SubjectID: "id1", Privacy: model.DefaultPrivacy},
+	}
+
+	env.subj.EXPECT().GetSubjectId().Return("viewer").AnyTimes()
+	env.profile.EXPECT().GetProfilesFromSubjectIDs(env.ctx, []string{"id1", "gone", "id2", "never"}).Return(profiles, nil)
+	env.profile.EXPECT().GetDeletedSubjectIDs(env.ctx, []string{"gone", "never"}).Return([]string{"gone"}, nil)
+	env.avatar.EXPECT().GetAvatarURL(env.ctx, key).Return("url", nil)
+
+	batch, err := env.domain.GetProfilesBatch(env.ctx, []string{"id1", "gone", "id2", "id1", "never"})
+	if err != nil {
+		t.Fatalf("get profiles batch: %v", err)
+	}
+
+	if len(batch.Profiles) != 2 || batch.Profiles[0].SubjectID != "id1" || batch.Profiles[1].SubjectID != "id2" {
+		t.Fatalf("profiles must keep the order of the request: %+v", batch.Profiles)
+	}
+	if !slices.Equal(batch.NotFound, []string{"gone", "never"}) || !slices.Equal(batch.Deleted, []string{"gone"}) {
+		t.Fatalf("unexpected not found %v and deleted %v", batch.NotFound, batch.Deleted)
+	}
+	if batch.AvatarURLs["id2"] != "url" {
+		t.Fatalf("wait avatar url of id2, have %v", batch.AvatarURLs)
+	}
+}
+
+func TestDomain_GetProfilesBatch_Contacts(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Finish()
+
+	seen := time.Now()
+	contacts := model.Privacy{Avatar: model.AudienceEveryone, LastSeen: model.AudienceContacts}
+	profiles := []*model.Profile{
+		{SubjectID: "friend", LastSeenAt: &seen, Privacy: contacts},
+		{SubjectID: "stranger", LastSeenAt: &seen, Privacy: contacts},
+		{SubjectID: "open", LastSeenAt: &seen, Privacy: model.DefaultPrivacy},
+		{SubjectID: "viewer", LastSeenAt: &seen, Privacy: contacts},
+	}
+	ids := []string{"friend", "stranger", "open", "viewer"}
+
+	env.subj.EXPECT().GetSubjectId().Return("viewer").AnyTimes()
+	env.profile.EXPECT().GetProfilesFromSubjectIDs(env.ctx, ids).Return(profiles, nil)
+	// one query for all the profiles that depend on contacts, the viewer's own profile is not asked
+	env.contact.EXPECT().GetOwnersWithContact(env.ctx, []string{"friend", "stranger"}, "viewer").Return([]string{"friend"}, nil)
+
+	batch, err := env.domain.GetProfilesBatch(env.ctx, ids)
+	if err != nil {
+		t.Fatalf("get profiles batch: %v", err)
+	}
+
+	for _, profile := range batch.Profiles {
+		hidden := profile.LastSeenAt == nil
+		if hidden != (profile.SubjectID == "stranger") {
+			t.Fatalf("%v: last seen hidden %v", profile.SubjectID, hidden)
+		}
+	}
+}
+
+func TestDomain_GetProfilesBatch_Invalid(t *testing.T) {
+	tooMany := make([]string, 0, domain.MaxBatchProfiles+1)
+	for i := 0; i <= domain.MaxBatchProfiles; i++ {
+		tooMany = append(tooMany, fmt.Sprintf("id%v", i))
+	}
+
+	tests := []struct {
+		name string
+		ids  []string
+	}{
+		{"empty", nil},
+		{"empty id", []string{"id1", ""}},
+		{"too many", tooMany},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			defer env.Finish()
+
+			env.subj.EXPECT().GetSubjectId().Return("viewer").AnyTimes()
+
+			_, err := env.domain.GetProfilesBatch(env.ctx, tt.ids)
+			if !errors.Is(err, domain.ErrInvalidBatch) {
+				t.Fatalf("wait %v, have %v", domain.ErrInvalidBatch, err)
+			}
+		})
+	}
+}
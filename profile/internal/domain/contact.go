@@ -0,0 +1,187 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/1ocknight/mess/profile/internal/ctxkey"
+	"github.com/1ocknight/mess/profile/internal/model"
+	"github.com/1ocknight/mess/profile/internal/storage"
+	"github.com/1ocknight/mess/shared/cursor"
+)
+
+const ContactNicknameMaxLength = 64
+
+type ContactPaginationFilter struct {
+	Limit  int
+	Cursor *cursor.Cursor
+}
+
+// DefaultPaginationContact shows the latest added contacts first.
+var DefaultPaginationContact = storage.ContactPaginationFilter{
+	Limit:     50,
+	Asc:       false,
+	SortLabel: storage.ContactCreatedAtLabel,
+}
+
+// ContactTarget is the subject to add, by SubjectID or by Username.
+type ContactTarget struct {
+	SubjectID string
+	Username  string
+}
+
+// AddContact adds the target to the contacts of the subject, adding the same target again changes the nickname.
+func (d *Domain) AddContact(ctx context.Context, target *ContactTarget, nickname string) (*model.Contact, string, error) {
+	subj, err := ctxkey.ExtractSubject(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("extract subject: %w", err)
+	}
+
+	nickname = strings.TrimSpace(nickname)
+	if utf8.RuneCountInString(nickname) > ContactNicknameMaxLength {
+		return nil, "", fmt.Errorf("%w: nickname is longer than %v", ErrInvalidContact, ContactNicknameMaxLength)
+	}
+
+	var profile *model.Profile
+	switch {
+	case target.SubjectID != "":
+		profile, err = d.Storage.Profile().GetProfileFromSubjectID(ctx, target.SubjectID)
+	case target.Username != "":
+		profile, err = d.Storage.Profile().GetProfileFromUsername(ctx, target.Username)
+	default:
+		return nil, "", fmt.Errorf("%w: empty subject id and username", ErrInvalidContact)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("get contact profile: %w", err)
+	}
+
+	if profile.SubjectID == subj.GetSubjectId() {
+		return nil, "", fmt.Errorf("%w: subject can not add itself", ErrInvalidContact)
+	}
+
+	contact, err := d.Storage.Contact().AddContact(ctx, subj.GetSubjectId(), profile.SubjectID, nickname)
+	if err != nil {
+		return nil, "", fmt.Errorf("add contact: %w", err)
+	}
+
+	contact.Profile, err = d.ForViewer(ctx, subj.GetSubjectId(), profile)
+	if err != nil {
+		return nil, "", fmt.Errorf("for viewer: %w", err)
+	}
+
+	avatarURL, err := d.GetAvatarURL(ctx, contact.Profile)
+	if err != nil {
+		return nil, "", fmt.Errorf("get avatar url: %w", err)
+	}
+
+	return contact, avatarURL, nil
+}
+
+// GetContacts returns a page of the contacts of the subject with their profiles, the map is subject_id -> avatar url.
+func (d *Domain) GetContacts(ctx context.Context, filter *ContactPaginationFilter) ([]*model.Contact, map[string]string, *cursor.Page, error) {
+	subj, err := ctxkey.ExtractSubject(ctx)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("extract subject: %w", err)
+	}
+	lg, err := ctxkey.ExtractLogger(ctx)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("extract logger: %w", err)
+	}
+
+	storeFilter := DefaultPaginationContact
+	if filter.Limit != 0 {
+		storeFilter.Limit = min(filter.Limit, MaxPaginationLimit)
+	}
+	limit := storeFilter.Limit
+	storeFilter.Limit++
+
+	storeFilter.Asc = filter.Cursor.GetDirection(cursor.DirectionAfter) == cursor.DirectionBefore
+	if filter.Cursor != nil {
+		sortValue, err := time.Parse(time.RFC3339Nano, filter.Cursor.SortKey)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("parse sort key: %w", cursor.ErrInvalidCursor)
+		}
+		storeFilter.LastSortValue = sortValue
+		storeFilter.LastID = &filter.Cursor.ID
+	}
+
+	contacts, err := d.Storage.Contact().GetContacts(ctx, subj.GetSubjectId(), &storeFilter)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("get contacts: %w", err)
+	}
+
+	contacts, page := cursor.NewPage(contacts, limit, filter.Cursor, cursor.DirectionAfter, contactCursor)
+	if len(contacts) == 0 {
+		return contacts, nil, page, nil
+	}
+
+	ids := make([]string, 0, len(contacts))
+	for _, contact := range contacts {
+		ids = append(ids, contact.ContactSubjectID)
+	}
+
+	profiles, err := d.Storage.Profile().GetProfilesFromSubjectIDs(ctx, ids)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("get profiles from subject ids: %w", err)
+	}
+
+	profiles, err = d.ForViewerBatch(ctx, subj.GetSubjectId(), profiles)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("for viewer batch: %w", err)
+	}
+
+	bySubject := make(map[string]*model.Profile, len(profiles))
+	for _, profile := range profiles {
+		bySubject[profile.SubjectID] = profile
+	}
+
+	// a profile deleted between the two reads leaves its contact without a profile
+	for _, contact := range contacts {
+		contact.Profile = bySubject[contact.ContactSubjectID]
+	}
+
+	avatarsURLS, errors := d.GetAvatarsURL(ctx, profiles)
+	if len(errors) != 0 {
+		lg.Errors("get avatars url", errors)
+	}
+
+	return contacts, avatarsURLS, page, nil
+}
+
+func contactCursor(contact *model.Contact) *cursor.Cursor {
+	return &cursor.Cursor{
+		SortKey: contact.CreatedAt.UTC().Format(time.RFC3339Nano),
+		ID:      contact.ContactSubjectID,
+	}
+}
+
+func (d *Domain) DeleteContact(ctx context.Context, contactSubjectID string) (*model.Contact, error) {
+	subj, err := ctxkey.ExtractSubject(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("extract subject: %w", err)
+	}
+
+	contact, err := d.Storage.Contact().DeleteContact(ctx, subj.GetSubjectId(), contactSubjectID)
+	if err != nil {
+		return nil, fmt.Errorf("delete contact: %w", err)
+	}
+
+	return contact, nil
+}
+
+// isContact tells whether the owner added the viewer to contacts.
+func (d *Domain) isContact(ctx context.Context, ownerID string, viewerID string) (bool, error) {
+	_, err := d.Storage.Contact().GetContact(ctx, ownerID, viewerID)
+	if errors.Is(err, storage.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("get contact: %w", err)
+	}
+
+	return true, nil
+}
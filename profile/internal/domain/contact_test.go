@@ -0,0 +1,124 @@
+package domain_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/1ocknight/mess/profile/internal/domain"
+	"github.com/1ocknight/mess/profile/internal/model"
+	"github.com/1ocknight/mess/profile/internal/storage"
+	"github.com/1ocknight/mess/shared/cursor"
+)
+
+func TestDomain_AddContact_ByUsername(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Finish()
+
+	target := &model.Profile{SubjectID: "friend", Privacy: model.DefaultPrivacy}
+	contact := &model.Contact{SubjectID: "owner", ContactSubjectID: "friend", Nickname: "bestie"}
+
+	env.subj.EXPECT().GetSubjectId().Return("owner").AnyTimes()
+	env.profile.EXPECT().GetProfileFromUsername(env.ctx, "Friend_1").Return(target, nil)
+	env.contact.EXPECT().AddContact(env.ctx, "owner", "friend", "bestie").Return(contact, nil)
+
+	res, url, err := env.domain.AddContact(env.ctx, &domain.ContactTarget{Username: "Friend_1"}, "  bestie ")
+	if err != nil {
+		t.Fatalf("add contact: %v", err)
+	}
+	if res.Profile == nil || res.Profile.SubjectID != "friend" || url != "" {
+		t.Fatalf("contact without profile: %+v, url %v", res, url)
+	}
+}
+
+func TestDomain_AddContact_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		target   *domain.ContactTarget
+		nickname string
+	}{
+		{"empty target", &domain.ContactTarget{}, ""},
+		{"long nickname", &domain.ContactTarget{SubjectID: "friend"}, string(make([]rune, domain.ContactNicknameMaxLength+1))},
+		{"self", &domain.ContactTarget{SubjectID: "owner"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			defer env.Finish()
+
+			env.subj.EXPECT().GetSubjectId().Return("owner").AnyTimes()
+			env.profile.EXPECT().GetProfileFromSubjectID(env.ctx, "owner").
+				Return(&model.Profile{SubjectID: "owner"}, nil).AnyTimes()
+
+			_, _, err := env.domain.AddContact(env.ctx, tt.target, tt.nickname)
+			if !errors.Is(err, domain.ErrInvalidContact) {
+				t.Fatalf("wait %v, have %v", domain.ErrInvalidContact, err)
+			}
+		})
+	}
+}
+
+func TestDomain_AddContact_NotFound(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Finish()
+
+	env.subj.EXPECT().GetSubjectId().Return("owner").AnyTimes()
+	env.profile.EXPECT().GetProfileFromSubjectID(env.ctx, "ghost").Return(nil, storage.ErrNoRows)
+
+	_, _, err := env.domain.AddContact(env.ctx, &domain.ContactTarget{SubjectID: "ghost"}, "")
+	if !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("wait %v, have %v", domain.ErrNotFound, err)
+	}
+}
+
+func TestDomain_GetContacts(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Finish()
+
+	createdAt := time.Now().UTC()
+	contacts := []*model.Contact{
+		{SubjectID: "owner", ContactSubjectID: "friend1", CreatedAt: createdAt},
+		{SubjectID: "owner", ContactSubjectID: "friend2", CreatedAt: createdAt.Add(-time.Minute)},
+	}
+	profiles := []*model.Profile{
+		{SubjectID: "friend1", Privacy: model.DefaultPrivacy},
+		{SubjectID: "friend2", Privacy: model.DefaultPrivacy},
+	}
+
+	env.subj.EXPECT().GetSubjectId().Return("owner").AnyTimes()
+	env.contact.EXPECT().GetContacts(env.ctx, "owner", gomock.Any()).
+		DoAndReturn(func(_ any, _ string, filter *storage.ContactPaginationFilter) ([]*model.Contact, error) {
+			if filter.Limit != 2 || filter.Asc {
+				t.Fatalf("unexpected filter: %+v", filter)
+			}
+			return contacts, nil
+		})
+	env.profile.EXPECT().GetProfilesFromSubjectIDs(env.ctx, []string{"friend1"}).Return(profiles[:1], nil)
+
+	res, _, page, err := env.domain.GetContacts(env.ctx, &domain.ContactPaginationFilter{Limit: 1})
+	if err != nil {
+		t.Fatalf("get contacts: %v", err)
+	}
+	if len(res) != 1 || res[0].Profile == nil || res[0].Profile.SubjectID != "friend1" {
+		t.Fatalf("unexpected contacts: %+v", res)
+	}
+	if page.Next == nil || page.Next.ID != "friend1" {
+		t.Fatalf("wait next cursor after friend1, have %+v", page.Next)
+	}
+}
+
+func TestDomain_GetContacts_InvalidCursor(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Finish()
+
+	env.subj.EXPECT().GetSubjectId().Return("owner").AnyTimes()
+
+	filter := &domain.ContactPaginationFilter{Cursor: &cursor.Cursor{Direction: cursor.DirectionAfter, SortKey: "alias", ID: "friend"}}
+	_, _, _, err := env.domain.GetContacts(env.ctx, filter)
+	if !errors.Is(err, cursor.ErrInvalidCursor) {
+		t.Fatalf("wait %v, have %v", cursor.ErrInvalidCursor, err)
+	}
+}
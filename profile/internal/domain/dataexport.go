@@ -0,0 +1,71 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/1ocknight/mess/profile/internal/ctxkey"
+	"github.com/1ocknight/mess/profile/internal/loglables"
+	"github.com/1ocknight/mess/profile/internal/model"
+)
+
+func (d *Domain) RequestDataExport(ctx context.Context) (*model.DataExport, error) {
+	subj, err := ctxkey.ExtractSubject(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("extract subject: %w", err)
+	}
+	lg, err := ctxkey.ExtractLogger(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("extract logger: %w", err)
+	}
+
+	tx, err := d.Storage.WithTransaction(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("with transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	export, err := tx.DataExport().AddDataExport(ctx, subj.GetSubjectId())
+	if err != nil {
+		return nil, fmt.Errorf("add data export: %w", err)
+	}
+
+	_, err = tx.DataExportOutbox().AddDataExportOutbox(ctx, export.ID, model.DataExportRequestOperation)
+	if err != nil {
+		return nil, fmt.Errorf("add data export outbox: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+
+	lg.With(loglables.DataExport, *export).Debug("data export requested")
+
+	return export, nil
+}
+
+func (d *Domain) GetDataExport(ctx context.Context, exportID int) (*model.DataExport, string, error) {
+	subj, err := ctxkey.ExtractSubject(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("extract subject: %w", err)
+	}
+
+	export, err := d.Storage.DataExport().GetDataExportByID(ctx, exportID)
+	if err != nil {
+		return nil, "", fmt.Errorf("get data export by id: %w", err)
+	}
+	if export.SubjectID != subj.GetSubjectId() {
+		return nil, "", fmt.Errorf("data export of another subject: %w", ErrNotFound)
+	}
+
+	if export.Status != model.DataExportDone || export.ObjectKey == nil {
+		return export, "", nil
+	}
+
+	url, err := d.Archive.GetDownloadURL(ctx, *export.ObjectKey)
+	if err != nil {
+		return nil, "", fmt.Errorf("get download url: %w", err)
+	}
+
+	return export, url, nil
+}
@@ -0,0 +1,79 @@
+package domain_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/1ocknight/mess/profile/internal/domain"
+	"github.com/1ocknight/mess/profile/internal/model"
+	"github.com/golang/mock/gomock"
+)
+
+func TestDomain_RequestDataExport(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Finish()
+
+	export := &model.DataExport{ID: 1, SubjectID: "subj"}
+
+	env.subj.EXPECT().GetSubjectId().Return("subj").AnyTimes()
+	env.lg.EXPECT().With(gomock.Any(), gomock.Any()).Return(env.lg).AnyTimes()
+	env.lg.EXPECT().Debug(gomock.Any()).AnyTimes()
+
+	env.export.EXPECT().AddDataExport(env.ctx, "subj").Return(export, nil)
+	env.exOut.EXPECT().AddDataExportOutbox(env.ctx, export.ID, model.DataExportRequestOperation).Return(&model.DataExportOutbox{}, nil)
+
+	res, err := env.domain.RequestDataExport(env.ctx)
+	if err != nil {
+		t.Fatalf("request data export: %v", err)
+	}
+	if res != export {
+		t.Fatalf("wait %v, have %v", export, res)
+	}
+}
+
+func TestDomain_GetDataExport(t *testing.T) {
+	key := "data-exports/subj/1.zip"
+
+	tests := []struct {
+		name    string
+		export  *model.DataExport
+		wantURL string
+		wantErr error
+	}{
+		{
+			name:    "done",
+			export:  &model.DataExport{ID: 1, SubjectID: "subj", Status: model.DataExportDone, ObjectKey: &key},
+			wantURL: "url",
+		},
+		{
+			name:   "in progress",
+			export: &model.DataExport{ID: 1, SubjectID: "subj", Status: model.DataExportCollected},
+		},
+		{
+			name:    "another subject",
+			export:  &model.DataExport{ID: 1, SubjectID: "other", Status: model.DataExportDone, ObjectKey: &key},
+			wantErr: domain.ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			defer env.Finish()
+
+			env.subj.EXPECT().GetSubjectId().Return("subj").AnyTimes()
+			env.export.EXPECT().GetDataExportByID(env.ctx, 1).Return(tt.export, nil)
+			if tt.wantURL != "" {
+				env.archive.EXPECT().GetDownloadURL(env.ctx, key).Return(tt.wantURL, nil)
+			}
+
+			_, url, err := env.domain.GetDataExport(env.ctx, 1)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("wait err %v, have %v", tt.wantErr, err)
+			}
+			if url != tt.wantURL {
+				t.Fatalf("wait url %v, have %v", tt.wantURL, url)
+			}
+		})
+	}
+}
@@ -3,10 +3,18 @@ package domain
 import (
 	"context"
 	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
+	"github.com/1ocknight/mess/profile/internal/adapter/avatar"
 	"github.com/1ocknight/mess/profile/internal/ctxkey"
 	"github.com/1ocknight/mess/profile/internal/loglables"
 	"github.com/1ocknight/mess/profile/internal/model"
+	"github.com/1ocknight/mess/profile/internal/storage"
+	"github.com/1ocknight/mess/shared/cursor"
 )
 
 func (d *Domain) GetCurrentProfile(ctx context.Context) (*model.Profile, string, error) {
@@ -20,7 +28,7 @@ func (d *Domain) GetCurrentProfile(ctx context.Context) (*model.Profile, string,
 		return nil, "", fmt.Errorf("profile get profile from subject id: %w", err)
 	}
 
-	avatarURL, err := d.GetAvatarURL(ctx, profile.SubjectID)
+	avatarURL, err := d.GetAvatarURL(ctx, profile)
 	if err != nil {
 		return nil, "", fmt.Errorf("avatar get avatar url: %w", err)
 	}
@@ -29,12 +37,22 @@ func (d *Domain) GetCurrentProfile(ctx context.Context) (*model.Profile, string,
 }
 
 func (d *Domain) GetProfileFromSubjectID(ctx context.Context, subjID string) (*model.Profile, string, error) {
+	subj, err := ctxkey.ExtractSubject(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("extract subject: %w", err)
+	}
+
 	profile, err := d.Storage.Profile().GetProfileFromSubjectID(ctx, subjID)
 	if err != nil {
 		return nil, "", fmt.Errorf("profile get profile from subject id: %w", err)
 	}
 
-	avatarURL, err := d.GetAvatarURL(ctx, profile.SubjectID)
+	profile, err = d.ForViewer(ctx, subj.GetSubjectId(), profile)
+	if err != nil {
+		return nil, "", fmt.Errorf("for viewer: %w", err)
+	}
+
+	avatarURL, err := d.GetAvatarURL(ctx, profile)
 	if err != nil {
 		return nil, "", fmt.Errorf("get avatar url: %w", err)
 	}
@@ -42,33 +60,124 @@ func (d *Domain) GetProfileFromSubjectID(ctx context.Context, subjID string) (*m
 	return profile, avatarURL, nil
 }
 
-func (d *Domain) GetProfilesFromAlias(ctx context.Context, alias string, filter *ProfilePaginationFilter) ([]*model.Profile, map[string]string, error) {
+// GetProfilesBatch looks up to MaxBatchProfiles profiles at once, repeated ids are looked up once.
+func (d *Domain) GetProfilesBatch(ctx context.Context, subjIDs []string) (*ProfilesBatch, error) {
+	subj, err := ctxkey.ExtractSubject(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("extract subject: %w", err)
+	}
 	lg, err := ctxkey.ExtractLogger(ctx)
 	if err != nil {
-		return nil, nil, fmt.Errorf("extract logger: %w", err)
+		return nil, fmt.Errorf("extract logger: %w", err)
 	}
 
-	storeFiler := DefaultPaginationProfile
-	if filter.Limit != 0 {
-		storeFiler.Limit = filter.Limit
+	ids := make([]string, 0, len(subjIDs))
+	seen := make(map[string]struct{}, len(subjIDs))
+	for _, id := range subjIDs {
+		if id == "" {
+			return nil, fmt.Errorf("%w: empty subject id", ErrInvalidBatch)
+		}
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 || len(ids) > MaxBatchProfiles {
+		return nil, fmt.Errorf("%w: from 1 to %v subject ids", ErrInvalidBatch, MaxBatchProfiles)
+	}
+
+	profiles, err := d.Storage.Profile().GetProfilesFromSubjectIDs(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("get profiles from subject ids: %w", err)
+	}
+
+	profiles, err = d.ForViewerBatch(ctx, subj.GetSubjectId(), profiles)
+	if err != nil {
+		return nil, fmt.Errorf("for viewer batch: %w", err)
+	}
+
+	bySubject := make(map[string]*model.Profile, len(profiles))
+	for _, profile := range profiles {
+		bySubject[profile.SubjectID] = profile
+	}
+
+	res := &ProfilesBatch{
+		Profiles: make([]*model.Profile, 0, len(profiles)),
+	}
+	for _, id := range ids {
+		profile, ok := bySubject[id]
+		if !ok {
+			res.NotFound = append(res.NotFound, id)
+			continue
+		}
+		res.Profiles = append(res.Profiles, profile)
+	}
+
+	if len(res.NotFound) != 0 {
+		res.Deleted, err = d.Storage.Profile().GetDeletedSubjectIDs(ctx, res.NotFound)
+		if err != nil {
+			return nil, fmt.Errorf("get deleted subject ids: %w", err)
+		}
 	}
 
-	switch filter.Direction {
-	case DirectionBefore:
-		storeFiler.Asc = false
-	default:
-		storeFiler.Asc = true
+	avatarsURLS, errors := d.GetAvatarsURL(ctx, res.Profiles)
+	if len(errors) != 0 {
+		lg.Errors("get avatars url", errors)
 	}
+	res.AvatarURLs = avatarsURLS
 
-	storeFiler.LastID = filter.LastSubjectID
+	return res, nil
+}
 
-	profiles, err := d.Storage.Profile().GetProfilesFromAlias(ctx, alias, &storeFiler)
+// GetProfilesFromAlias finds profiles by a fuzzy match of the alias or the nickname of a contact,
+// the most relevant first. The query is trimmed and must have from SearchQueryMinLength to SearchQueryMaxLength runes.
+func (d *Domain) GetProfilesFromAlias(ctx context.Context, alias string, filter *ProfilePaginationFilter) ([]*model.Profile, map[string]string, *cursor.Page, error) {
+	subj, err := ctxkey.ExtractSubject(ctx)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("extract subject: %w", err)
+	}
+	lg, err := ctxkey.ExtractLogger(ctx)
 	if err != nil {
-		return nil, nil, fmt.Errorf("get profiles from alias: %w", err)
+		return nil, nil, nil, fmt.Errorf("extract logger: %w", err)
 	}
 
-	if !storeFiler.Asc {
-		reverseProfiles(profiles)
+	alias = strings.TrimSpace(alias)
+	if n := utf8.RuneCountInString(alias); n < SearchQueryMinLength || n > SearchQueryMaxLength {
+		return nil, nil, nil, fmt.Errorf("%w: length must be from %v to %v",
+			ErrInvalidSearch, SearchQueryMinLength, SearchQueryMaxLength)
+	}
+
+	storeFiler := DefaultPaginationProfile
+	if filter.Limit != 0 {
+		storeFiler.Limit = min(filter.Limit, MaxPaginationLimit)
+	}
+	limit := storeFiler.Limit
+	storeFiler.Limit++
+
+	storeFiler.Asc = filter.Cursor.GetDirection(cursor.DirectionAfter) == cursor.DirectionBefore
+	if filter.Cursor != nil {
+		if _, err := strconv.ParseFloat(filter.Cursor.SortKey, 64); err != nil {
+			return nil, nil, nil, fmt.Errorf("parse sort key: %w", cursor.ErrInvalidCursor)
+		}
+		storeFiler.LastSortValue = filter.Cursor.SortKey
+		storeFiler.LastID = &filter.Cursor.ID
+	}
+
+	matches, err := d.Storage.Profile().GetProfilesFromAlias(ctx, subj.GetSubjectId(), alias, &storeFiler)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("get profiles from alias: %w", err)
+	}
+
+	matches, page := cursor.NewPage(matches, limit, filter.Cursor, cursor.DirectionAfter, profileMatchCursor)
+
+	profiles := make([]*model.Profile, 0, len(matches))
+	for _, match := range matches {
+		profiles = append(profiles, match.Profile)
+	}
+	profiles, err = d.ForViewerBatch(ctx, subj.GetSubjectId(), profiles)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("for viewer batch: %w", err)
 	}
 
 	avatarsURLS, errors := d.GetAvatarsURL(ctx, profiles)
@@ -76,12 +185,13 @@ func (d *Domain) GetProfilesFromAlias(ctx context.Context, alias string, filter
 		lg.Errors("get avatars url", errors)
 	}
 
-	return profiles, avatarsURLS, nil
+	return profiles, avatarsURLS, page, nil
 }
 
-func reverseProfiles(profiles []*model.Profile) {
-	for i, j := 0, len(profiles)-1; i < j; i, j = i+1, j-1 {
-		profiles[i], profiles[j] = profiles[j], profiles[i]
+func profileMatchCursor(match *model.ProfileMatch) *cursor.Cursor {
+	return &cursor.Cursor{
+		SortKey: match.Rank,
+		ID:      match.Profile.SubjectID,
 	}
 }
 
@@ -91,26 +201,73 @@ func (d *Domain) AddProfile(ctx context.Context, alias string) (*model.Profile,
 		return nil, "", fmt.Errorf("extract subject: %w", err)
 	}
 
-	profile, err := d.Storage.Profile().AddProfile(ctx, subj.GetSubjectId(), alias)
+	tx, err := d.Storage.WithTransaction(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("with transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	profile, err := tx.Profile().AddProfile(ctx, subj.GetSubjectId(), alias)
 	if err != nil {
 		return nil, "", fmt.Errorf("profile add profile: %w", err)
 	}
 
+	if _, err := tx.ProfileOutbox().AddProfileOutbox(ctx, profile.SubjectID, model.ProfileCreatedOperation); err != nil {
+		return nil, "", fmt.Errorf("add profile outbox: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, "", fmt.Errorf("commit: %w", err)
+	}
+
 	return profile, "", nil
 }
 
-func (d *Domain) UpdateProfileMetadata(ctx context.Context, prevVersion int, alias string) (*model.Profile, string, error) {
+// UpdateProfileMetadata changes the sent fields of alias, display name, bio, status and links, the rest is kept,
+// so an older client does not erase fields it does not know. Other services are told about the change.
+func (d *Domain) UpdateProfileMetadata(ctx context.Context, prevVersion int, patch *model.ProfileMetadataPatch) (*model.Profile, string, error) {
 	subj, err := ctxkey.ExtractSubject(ctx)
 	if err != nil {
 		return nil, "", fmt.Errorf("extract subject: %w", err)
 	}
 
-	profile, err := d.Storage.Profile().UpdateProfileMetadata(ctx, subj.GetSubjectId(), prevVersion, alias)
+	tx, err := d.Storage.WithTransaction(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("with transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	prof, err := tx.Profile().GetProfileFromSubjectID(ctx, subj.GetSubjectId())
+	if err != nil {
+		return nil, "", fmt.Errorf("profile get profile from subject id: %w", err)
+	}
+
+	// the version check of the update rejects a patch over a profile the client has not seen
+	now := time.Now()
+	current := prof.Metadata()
+	if !current.Status.IsActive(now) {
+		// an expired status is not shown, keeping it would fail the validation of an unrelated field
+		current.Status = nil
+	}
+	meta := patch.Apply(current)
+	if err := ValidateProfileMetadata(&meta, now); err != nil {
+		return nil, "", err
+	}
+
+	profile, err := tx.Profile().UpdateProfileMetadata(ctx, subj.GetSubjectId(), prevVersion, &meta)
 	if err != nil {
 		return nil, "", fmt.Errorf("profile update profile metadata: %w", err)
 	}
 
-	avatarURL, err := d.GetAvatarURL(ctx, profile.SubjectID)
+	if _, err := tx.ProfileOutbox().AddProfileOutbox(ctx, profile.SubjectID, model.ProfileUpdatedOperation); err != nil {
+		return nil, "", fmt.Errorf("add profile outbox: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, "", fmt.Errorf("commit: %w", err)
+	}
+
+	avatarURL, err := d.GetAvatarURL(ctx, profile)
 	if err != nil {
 		return nil, "", fmt.Errorf("get avatar url: %w", err)
 	}
@@ -118,18 +275,30 @@ func (d *Domain) UpdateProfileMetadata(ctx context.Context, prevVersion int, ali
 	return profile, avatarURL, nil
 }
 
-func (d *Domain) UploadAvatar(ctx context.Context) (string, error) {
+// UploadAvatar records the next avatar key as pending, the avatar becomes active after the upload is processed.
+// The upload form accepts only a file of contentType with the given checksum.
+func (d *Domain) UploadAvatar(ctx context.Context, contentType string, checksum string) (*avatar.Upload, error) {
 	subj, err := ctxkey.ExtractSubject(ctx)
 	if err != nil {
-		return "", fmt.Errorf("extract subject: %w", err)
+		return nil, fmt.Errorf("extract subject: %w", err)
+	}
+
+	prof, err := d.Storage.Profile().GetProfileFromSubjectID(ctx, subj.GetSubjectId())
+	if err != nil {
+		return nil, fmt.Errorf("get profile from subject id: %w", err)
 	}
 
-	url, err := d.Avatar.GetUploadURL(ctx, subj.GetSubjectId())
+	key := AvatarKey(prof.SubjectID, prof.AvatarVersion+1, checksum)
+	upload, err := d.Avatar.GetUploadURL(ctx, avatar.UploadKey(key), contentType, checksum)
 	if err != nil {
-		return "", fmt.Errorf("get upload url: %w", err)
+		return nil, fmt.Errorf("get upload url: %w", err)
 	}
 
-	return url, nil
+	if _, err := d.Storage.Profile().UpdatePendingAvatarKey(ctx, prof.SubjectID, &key); err != nil {
+		return nil, fmt.Errorf("update pending avatar key: %w", err)
+	}
+
+	return upload, nil
 }
 
 func (d *Domain) DeleteAvatar(ctx context.Context) error {
@@ -142,11 +311,37 @@ func (d *Domain) DeleteAvatar(ctx context.Context) error {
 		return fmt.Errorf("extract logger: %w", err)
 	}
 
-	outbox, err := d.Storage.AvatarOutbox().AddKey(ctx, subj.GetSubjectId())
+	s, err := d.Storage.WithTransaction(ctx)
+	if err != nil {
+		return fmt.Errorf("with transaction: %w", err)
+	}
+	defer s.Rollback()
+
+	prof, err := s.Profile().GetProfileFromSubjectID(ctx, subj.GetSubjectId())
+	if err != nil {
+		return fmt.Errorf("get profile from subject id: %w", err)
+	}
+	if prof.AvatarKey == nil {
+		return nil
+	}
+
+	if _, err := s.Profile().UpdateAvatarKey(ctx, prof.SubjectID, prof.AvatarVersion, nil); err != nil {
+		return fmt.Errorf("update avatar key: %w", err)
+	}
+
+	outbox, err := s.AvatarOutbox().AddKey(ctx, *prof.AvatarKey)
 	if err != nil {
 		return fmt.Errorf("avatar key outbox add key: %w", err)
 	}
-	lg.With(loglables.AvatarOutbox, *outbox)
+
+	if _, err := s.ProfileOutbox().AddProfileOutbox(ctx, prof.SubjectID, model.ProfileUpdatedOperation); err != nil {
+		return fmt.Errorf("add profile outbox: %w", err)
+	}
+
+	if err := s.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	lg.With(loglables.AvatarOutbox, *outbox).Debug("add avatar outbox")
 
 	return nil
 }
@@ -172,11 +367,25 @@ func (d *Domain) DeleteProfile(ctx context.Context) (*model.Profile, string, err
 		return nil, "", fmt.Errorf("delete profile: %w", err)
 	}
 
-	outbox, err := s.AvatarOutbox().AddKey(ctx, prof.SubjectID)
+	outboxes, err := DeleteAvatars(ctx, s, prof)
+	if err != nil {
+		return nil, "", fmt.Errorf("delete avatars: %w", err)
+	}
+	lg = lg.With(loglables.AvatarOutbox, outboxes)
+
+	if err := HoldUsername(ctx, s, prof); err != nil {
+		return nil, "", fmt.Errorf("hold username: %w", err)
+	}
+
+	contacts, err := s.Contact().DeleteSubjectContacts(ctx, prof.SubjectID)
 	if err != nil {
-		return nil, "", fmt.Errorf("add key: %w", err)
+		return nil, "", fmt.Errorf("delete subject contacts: %w", err)
+	}
+	lg = lg.With(loglables.DeletedContacts, len(contacts))
+
+	if _, err := s.ProfileOutbox().AddProfileOutbox(ctx, prof.SubjectID, model.ProfileDeletedOperation); err != nil {
+		return nil, "", fmt.Errorf("add profile outbox: %w", err)
 	}
-	lg.With(loglables.AvatarOutbox, *outbox)
 
 	if err := s.Commit(); err != nil {
 		return nil, "", fmt.Errorf("commit: %w", err)
@@ -186,3 +395,26 @@ func (d *Domain) DeleteProfile(ctx context.Context) (*model.Profile, string, err
 
 	return prof, "", nil
 }
+
+// DeleteAvatars queues the avatar of a deleted profile for deletion. Until the legacy avatar backfill
+// has checked the profile, the object under the bare subject id is queued too, deleting a missing object is fine.
+func DeleteAvatars(ctx context.Context, tx storage.ServiceTransaction, prof *model.Profile) ([]*model.AvatarOutbox, error) {
+	var keys []string
+	if prof.AvatarKey != nil {
+		keys = append(keys, *prof.AvatarKey)
+	}
+	if legacy := prof.LegacyAvatarKey(); !prof.LegacyAvatarChecked && !slices.Contains(keys, legacy) {
+		keys = append(keys, legacy)
+	}
+
+	res := make([]*model.AvatarOutbox, 0, len(keys))
+	for _, key := range keys {
+		outbox, err := tx.AvatarOutbox().AddKey(ctx, key)
+		if err != nil {
+			return nil, fmt.Errorf("add key: %w", err)
+		}
+		res = append(res, outbox)
+	}
+
+	return res, nil
+}
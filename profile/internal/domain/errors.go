@@ -1,7 +1,22 @@
 package domain
 
-import "github.com/1ocknight/mess/profile/internal/storage"
+import (
+	"fmt"
+
+	"github.com/1ocknight/mess/profile/internal/adapter/avatar"
+	"github.com/1ocknight/mess/profile/internal/storage"
+)
 
 var (
-	ErrNotFound = storage.ErrNoRows
+	ErrNotFound          = storage.ErrNoRows
+	ErrAvatarContentType = avatar.ErrContentTypeNotAllowed
+
+	ErrInvalidProfile = fmt.Errorf("invalid profile")
+	ErrInvalidContact = fmt.Errorf("invalid contact")
+	ErrInvalidSearch  = fmt.Errorf("invalid search query")
+	ErrInvalidBatch   = fmt.Errorf("invalid batch")
+
+	ErrInvalidUsername  = fmt.Errorf("invalid username")
+	ErrUsernameTaken    = fmt.Errorf("username is taken")
+	ErrUsernameCooldown = fmt.Errorf("username was changed recently")
 )
@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/1ocknight/mess/profile/internal/ctxkey"
+)
+
+// LastSeenInterval is how often the last seen time of an active subject is written,
+// a more precise time is not worth a write on every request.
+const LastSeenInterval = time.Minute
+
+// lastSeenThrottle remembers when the replica last wrote the last seen time of a subject, the zero value is ready.
+type lastSeenThrottle struct {
+	mu      sync.Mutex
+	seen    map[string]time.Time
+	sweptAt time.Time
+}
+
+// allow tells whether the last seen time of the subject is due, forgotten subjects are swept once an interval.
+func (t *lastSeenThrottle) allow(subjectID string, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.seen == nil {
+		t.seen = make(map[string]time.Time)
+	}
+	if now.Sub(t.sweptAt) >= LastSeenInterval {
+		for id, at := range t.seen {
+			if now.Sub(at) >= LastSeenInterval {
+				delete(t.seen, id)
+			}
+		}
+		t.sweptAt = now
+	}
+
+	if at, ok := t.seen[subjectID]; ok && now.Sub(at) < LastSeenInterval {
+		return false
+	}
+	t.seen[subjectID] = now
+
+	return true
+}
+
+// TouchLastSeen records that the subject is active. A replica writes at most once an interval per subject,
+// and storage skips the write if another replica has just done it.
+func (d *Domain) TouchLastSeen(ctx context.Context) error {
+	subj, err := ctxkey.ExtractSubject(ctx)
+	if err != nil {
+		return fmt.Errorf("extract subject: %w", err)
+	}
+
+	now := time.Now().UTC()
+	if !d.lastSeen.allow(subj.GetSubjectId(), now) {
+		return nil
+	}
+
+	if err := d.Storage.Profile().UpdateLastSeen(ctx, subj.GetSubjectId(), now, LastSeenInterval); err != nil {
+		return fmt.Errorf("profile update last seen: %w", err)
+	}
+
+	return nil
+}
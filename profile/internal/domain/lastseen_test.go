@@ -0,0 +1,57 @@
+package domain_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/1ocknight/mess/profile/internal/domain"
+	"github.com/1ocknight/mess/profile/internal/model"
+	"github.com/golang/mock/gomock"
+)
+
+func TestDomain_TouchLastSeen(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Finish()
+
+	env.subj.EXPECT().GetSubjectId().Return("subj").AnyTimes()
+	// requests within the interval write only once
+	env.profile.EXPECT().UpdateLastSeen(env.ctx, "subj", gomock.Any(), domain.LastSeenInterval).Return(nil).Times(1)
+
+	for range 3 {
+		if err := env.domain.TouchLastSeen(env.ctx); err != nil {
+			t.Fatalf("touch last seen: %v", err)
+		}
+	}
+}
+
+func TestDomain_TouchLastSeen_Failed(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Finish()
+
+	env.subj.EXPECT().GetSubjectId().Return("subj").AnyTimes()
+	env.profile.EXPECT().UpdateLastSeen(env.ctx, "subj", gomock.Any(), domain.LastSeenInterval).Return(fmt.Errorf("db is down"))
+
+	if err := env.domain.TouchLastSeen(env.ctx); err == nil {
+		t.Fatalf("wait error")
+	}
+}
+
+func TestDomain_GetCurrentProfile_DoesNotTouchLastSeen(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Finish()
+
+	seenAt := time.Now().Add(-time.Hour)
+	prof := &model.Profile{SubjectID: "subj", LastSeenAt: &seenAt}
+
+	env.subj.EXPECT().GetSubjectId().Return("subj").AnyTimes()
+	env.profile.EXPECT().GetProfileFromSubjectID(env.ctx, "subj").Return(prof, nil)
+
+	res, _, err := env.domain.GetCurrentProfile(env.ctx)
+	if err != nil {
+		t.Fatalf("get current profile: %v", err)
+	}
+	if res != prof {
+		t.Fatalf("wait %v, have %v", prof, res)
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/golang/mock/gomock"
 
+	archivemocks "github.com/1ocknight/mess/profile/internal/adapter/archive/mocks"
 	avatarmocks "github.com/1ocknight/mess/profile/internal/adapter/avatar/mocks"
 	"github.com/1ocknight/mess/profile/internal/ctxkey"
 	"github.com/1ocknight/mess/profile/internal/domain"
@@ -22,7 +23,13 @@ type TestEnv struct {
 	storage *storagemocks.MockService
 	profile *storagemocks.MockProfile
 	outbox  *storagemocks.MockAvatarOutbox
+	export  *storagemocks.MockDataExport
+	exOut   *storagemocks.MockDataExportOutbox
+	pOut    *storagemocks.MockProfileOutbox
+	hold    *storagemocks.MockUsernameHold
+	contact *storagemocks.MockContact
 	avatar  *avatarmocks.MockService
+	archive *archivemocks.MockService
 	tx      *storagemocks.MockServiceTransaction
 	subj    *subjmocks.MockSubject
 	lg      *logmocks.MockLogger
@@ -34,17 +41,30 @@ func newTestEnv(t *testing.T) *TestEnv {
 	storage := storagemocks.NewMockService(ctrl)
 	profile := storagemocks.NewMockProfile(ctrl)
 	outbox := storagemocks.NewMockAvatarOutbox(ctrl)
+	export := storagemocks.NewMockDataExport(ctrl)
+	exOut := storagemocks.NewMockDataExportOutbox(ctrl)
+	pOut := storagemocks.NewMockProfileOutbox(ctrl)
+	hold := storagemocks.NewMockUsernameHold(ctrl)
+	contact := storagemocks.NewMockContact(ctrl)
 	tx := storagemocks.NewMockServiceTransaction(ctrl)
 
 	storage.EXPECT().Profile().Return(profile).AnyTimes()
 	storage.EXPECT().AvatarOutbox().Return(outbox).AnyTimes()
+	storage.EXPECT().DataExport().Return(export).AnyTimes()
+	storage.EXPECT().Contact().Return(contact).AnyTimes()
 	storage.EXPECT().WithTransaction(gomock.Any()).Return(tx, nil).AnyTimes()
 	tx.EXPECT().Profile().Return(profile).AnyTimes()
 	tx.EXPECT().AvatarOutbox().Return(outbox).AnyTimes()
+	tx.EXPECT().DataExport().Return(export).AnyTimes()
+	tx.EXPECT().DataExportOutbox().Return(exOut).AnyTimes()
+	tx.EXPECT().ProfileOutbox().Return(pOut).AnyTimes()
+	tx.EXPECT().UsernameHold().Return(hold).AnyTimes()
+	tx.EXPECT().Contact().Return(contact).AnyTimes()
 	tx.EXPECT().Commit().Return(nil).AnyTimes()
 	tx.EXPECT().Rollback().Return(fmt.Errorf("test")).AnyTimes()
 
 	avatar := avatarmocks.NewMockService(ctrl)
+	archive := archivemocks.NewMockService(ctrl)
 
 	subj := subjmocks.NewMockSubject(ctrl)
 	ctx := ctxkey.WithSubject(t.Context(), subj)
@@ -52,7 +72,7 @@ func newTestEnv(t *testing.T) *TestEnv {
 	lg := logmocks.NewMockLogger(ctrl)
 	ctx = ctxkey.WithLogger(ctx, lg)
 
-	d := domain.New(storage, avatar)
+	d := domain.New(storage, avatar, archive)
 
 	return &TestEnv{
 		ctrl:    ctrl,
@@ -61,7 +81,13 @@ func newTestEnv(t *testing.T) *TestEnv {
 		storage: storage,
 		profile: profile,
 		outbox:  outbox,
+		export:  export,
+		exOut:   exOut,
+		pOut:    pOut,
+		hold:    hold,
+		contact: contact,
 		avatar:  avatar,
+		archive: archive,
 		tx:      tx,
 		subj:    subj,
 		lg:      lg,
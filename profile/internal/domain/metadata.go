@@ -0,0 +1,89 @@
+package domain
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/1ocknight/mess/profile/internal/model"
+)
+
+const (
+	DisplayNameMaxLength = 64
+	BioMaxLength         = 500
+	StatusTextMaxLength  = 100
+	// StatusEmojiMaxLength leaves room for emoji built from several code points, like flags and skin tones
+	StatusEmojiMaxLength = 16
+
+	MaxLinks           = 5
+	LinkTitleMaxLength = 32
+	LinkURLMaxLength   = 2048
+)
+
+// ValidateProfileMetadata checks the lengths of the fields and the links, lengths are counted in runes.
+// An already expired status is rejected, so the subject notices the mistake.
+func ValidateProfileMetadata(meta *model.ProfileMetadata, now time.Time) error {
+	if strings.TrimSpace(meta.Alias) == "" {
+		return fmt.Errorf("%w: empty alias", ErrInvalidProfile)
+	}
+	if utf8.RuneCountInString(meta.DisplayName) > DisplayNameMaxLength {
+		return fmt.Errorf("%w: display name is longer than %v", ErrInvalidProfile, DisplayNameMaxLength)
+	}
+	if utf8.RuneCountInString(meta.Bio) > BioMaxLength {
+		return fmt.Errorf("%w: bio is longer than %v", ErrInvalidProfile, BioMaxLength)
+	}
+
+	if status := meta.Status; status != nil {
+		if status.Text == "" && status.Emoji == "" {
+			return fmt.Errorf("%w: status without text and emoji", ErrInvalidProfile)
+		}
+		if utf8.RuneCountInString(status.Text) > StatusTextMaxLength {
+			return fmt.Errorf("%w: status text is longer than %v", ErrInvalidProfile, StatusTextMaxLength)
+		}
+		if utf8.RuneCountInString(status.Emoji) > StatusEmojiMaxLength {
+			return fmt.Errorf("%w: status emoji is longer than %v", ErrInvalidProfile, StatusEmojiMaxLength)
+		}
+		if status.ExpiresAt != nil && !status.ExpiresAt.After(now) {
+			return fmt.Errorf("%w: status expires in the past", ErrInvalidProfile)
+		}
+	}
+
+	if len(meta.Links) > MaxLinks {
+		return fmt.Errorf("%w: more than %v links", ErrInvalidProfile, MaxLinks)
+	}
+	for i, link := range meta.Links {
+		if err := validateLink(link); err != nil {
+			return fmt.Errorf("%w: link %v: %w", ErrInvalidProfile, i, err)
+		}
+	}
+
+	return nil
+}
+
+// validateLink accepts only absolute http and https urls, so a link can not run a script in the client.
+func validateLink(link model.ProfileLink) error {
+	if strings.TrimSpace(link.Title) == "" {
+		return fmt.Errorf("empty title")
+	}
+	if utf8.RuneCountInString(link.Title) > LinkTitleMaxLength {
+		return fmt.Errorf("title is longer than %v", LinkTitleMaxLength)
+	}
+	if len(link.URL) > LinkURLMaxLength {
+		return fmt.Errorf("url is longer than %v", LinkURLMaxLength)
+	}
+
+	u, err := url.Parse(link.URL)
+	if err != nil {
+		return fmt.Errorf("parse url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("url scheme must be http or https")
+	}
+	if u.Host == "" || u.User != nil {
+		return fmt.Errorf("url must have a host and no credentials")
+	}
+
+	return nil
+}
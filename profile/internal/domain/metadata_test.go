@@ -0,0 +1,147 @@
+package domain_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/1ocknight/mess/profile/internal/domain"
+	"github.com/1ocknight/mess/profile/internal/model"
+)
+
+func TestValidateProfileMetadata(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Minute)
+	future := now.Add(time.Hour)
+
+	tests := []struct {
+		name  string
+		meta  model.ProfileMetadata
+		valid bool
+	}{
+		{"alias only", model.ProfileMetadata{Alias: "alias"}, true},
+		{"full", model.ProfileMetadata{
+			Alias:       "alias",
+			DisplayName: "Имя Фамилия",
+			Bio:         strings.Repeat("б", domain.BioMaxLength),
+			Status:      &model.ProfileStatus{Text: "on vacation", Emoji: "🏖️", ExpiresAt: &future},
+			Links:       []model.ProfileLink{{Title: "site", URL: "https://example.com/me"}},
+		}, true},
+		{"empty alias", model.ProfileMetadata{Alias: " "}, false},
+		{"long display name", model.ProfileMetadata{Alias: "alias", DisplayName: strings.Repeat("a", domain.DisplayNameMaxLength+1)}, false},
+		{"long bio", model.ProfileMetadata{Alias: "alias", Bio: strings.Repeat("a", domain.BioMaxLength+1)}, false},
+		{"empty status", model.ProfileMetadata{Alias: "alias", Status: &model.ProfileStatus{}}, false},
+		{"expired status", model.ProfileMetadata{Alias: "alias", Status: &model.ProfileStatus{Text: "busy", ExpiresAt: &past}}, false},
+		{"javascript link", model.ProfileMetadata{Alias: "alias", Links: []model.ProfileLink{{Title: "x", URL: "javascript:alert(1)"}}}, false},
+		{"relative link", model.ProfileMetadata{Alias: "alias", Links: []model.ProfileLink{{Title: "x", URL: "/me"}}}, false},
+		{"link with credentials", model.ProfileMetadata{Alias: "alias", Links: []model.ProfileLink{{Title: "x", URL: "https://u:p@example.com"}}}, false},
+		{"link without title", model.ProfileMetadata{Alias: "alias", Links: []model.ProfileLink{{URL: "https://example.com"}}}, false},
+		{"too many links", model.ProfileMetadata{Alias: "alias", Links: make([]model.ProfileLink, domain.MaxLinks+1)}, false},
+	}
+
+	for _, tt := range tests {
+		err := domain.ValidateProfileMetadata(&tt.meta, now)
+		if tt.valid && err != nil {
+			t.Errorf("%v: unexpected error: %v", tt.name, err)
+		}
+		if !tt.valid && !errors.Is(err, domain.ErrInvalidProfile) {
+			t.Errorf("%v: wait %v, have %v", tt.name, domain.ErrInvalidProfile, err)
+		}
+	}
+}
+
+func TestDomain_UpdateProfileMetadata(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Finish()
+
+	status := &model.ProfileStatus{Text: "busy"}
+	links := []model.ProfileLink{{Title: "site", URL: "https://example.com"}}
+	current := &model.Profile{SubjectID: "subj", Alias: "alias", DisplayName: "name", Bio: "old", Status: status, Links: links, Version: 1}
+	bio := "bio"
+	// an older client sends only the bio, the rest of the profile is kept
+	meta := &model.ProfileMetadata{Alias: "alias", DisplayName: "name", Bio: "bio", Status: status, Links: links}
+	updated := &model.Profile{SubjectID: "subj", Alias: "alias", Bio: "bio", Version: 2}
+
+	env.subj.EXPECT().GetSubjectId().Return("subj").AnyTimes()
+	env.profile.EXPECT().GetProfileFromSubjectID(env.ctx, "subj").Return(current, nil)
+	env.profile.EXPECT().UpdateProfileMetadata(env.ctx, "subj", 1, meta).Return(updated, nil)
+	env.pOut.EXPECT().AddProfileOutbox(env.ctx, "subj", model.ProfileUpdatedOperation).Return(&model.ProfileOutbox{}, nil)
+
+	res, _, err := env.domain.UpdateProfileMetadata(env.ctx, 1, &model.ProfileMetadataPatch{Bio: &bio})
+	if err != nil {
+		t.Fatalf("update profile metadata: %v", err)
+	}
+	if res != updated {
+		t.Fatalf("wait %v, have %v", updated, res)
+	}
+}
+
+func TestDomain_UpdateProfileMetadata_ClearFields(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Finish()
+
+	past := time.Now().Add(-time.Minute)
+	current := &model.Profile{
+		SubjectID: "subj",
+		Alias:     "alias",
+		Bio:       "bio",
+		Status:    &model.ProfileStatus{Text: "busy"},
+		Links:     []model.ProfileLink{{Title: "site", URL: "https://example.com"}},
+	}
+	empty := ""
+	patch := &model.ProfileMetadataPatch{Bio: &empty, SetStatus: true, Links: &[]model.ProfileLink{}}
+	meta := &model.ProfileMetadata{Alias: "alias", Links: []model.ProfileLink{}}
+
+	env.subj.EXPECT().GetSubjectId().Return("subj").AnyTimes()
+	env.profile.EXPECT().GetProfileFromSubjectID(env.ctx, "subj").Return(current, nil)
+	env.profile.EXPECT().UpdateProfileMetadata(env.ctx, "subj", 1, meta).Return(&model.Profile{SubjectID: "subj"}, nil)
+	env.pOut.EXPECT().AddProfileOutbox(env.ctx, "subj", model.ProfileUpdatedOperation).Return(&model.ProfileOutbox{}, nil)
+
+	if _, _, err := env.domain.UpdateProfileMetadata(env.ctx, 1, patch); err != nil {
+		t.Fatalf("update profile metadata: %v", err)
+	}
+
+	// an expired status the patch does not touch is dropped instead of failing the validation
+	env.profile.EXPECT().GetProfileFromSubjectID(env.ctx, "subj").
+		Return(&model.Profile{SubjectID: "subj", Alias: "alias", Status: &model.ProfileStatus{Text: "busy", ExpiresAt: &past}}, nil)
+	env.profile.EXPECT().UpdateProfileMetadata(env.ctx, "subj", 2, &model.ProfileMetadata{Alias: "new"}).Return(&model.Profile{SubjectID: "subj"}, nil)
+	env.pOut.EXPECT().AddProfileOutbox(env.ctx, "subj", model.ProfileUpdatedOperation).Return(&model.ProfileOutbox{}, nil)
+
+	alias := "new"
+	if _, _, err := env.domain.UpdateProfileMetadata(env.ctx, 2, &model.ProfileMetadataPatch{Alias: &alias}); err != nil {
+		t.Fatalf("update profile metadata: %v", err)
+	}
+}
+
+func TestDomain_UpdateProfileMetadata_Invalid(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Finish()
+
+	empty := " "
+
+	env.subj.EXPECT().GetSubjectId().Return("subj").AnyTimes()
+	env.profile.EXPECT().GetProfileFromSubjectID(env.ctx, "subj").Return(&model.Profile{SubjectID: "subj", Alias: "alias"}, nil)
+
+	_, _, err := env.domain.UpdateProfileMetadata(env.ctx, 1, &model.ProfileMetadataPatch{Alias: &empty})
+	if !errors.Is(err, domain.ErrInvalidProfile) {
+		t.Fatalf("wait %v, have %v", domain.ErrInvalidProfile, err)
+	}
+}
+
+func TestDomain_UpdateProfileMetadata_OutboxFailed(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Finish()
+
+	meta := &model.ProfileMetadata{Alias: "alias"}
+
+	env.subj.EXPECT().GetSubjectId().Return("subj").AnyTimes()
+	env.profile.EXPECT().GetProfileFromSubjectID(env.ctx, "subj").Return(&model.Profile{SubjectID: "subj", Alias: "alias"}, nil)
+	env.profile.EXPECT().UpdateProfileMetadata(env.ctx, "subj", 1, meta).Return(&model.Profile{SubjectID: "subj"}, nil)
+	env.pOut.EXPECT().AddProfileOutbox(env.ctx, "subj", model.ProfileUpdatedOperation).Return(nil, fmt.Errorf("db is down"))
+
+	if _, _, err := env.domain.UpdateProfileMetadata(env.ctx, 1, &model.ProfileMetadataPatch{}); err == nil {
+		t.Fatalf("the change must fail without its event")
+	}
+}
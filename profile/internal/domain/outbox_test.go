@@ -0,0 +1,91 @@
+package domain_test
+
+import (
+	"testing"
+
+	"github.com/1ocknight/mess/profile/internal/model"
+	"github.com/golang/mock/gomock"
+)
+
+func TestDomain_AddProfile_Outbox(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Finish()
+
+	created := &model.Profile{SubjectID: "subj", Alias: "alias", Version: 1}
+
+	env.subj.EXPECT().GetSubjectId().Return("subj").AnyTimes()
+	env.profile.EXPECT().AddProfile(env.ctx, "subj", "alias").Return(created, nil)
+	env.pOut.EXPECT().AddProfileOutbox(env.ctx, "subj", model.ProfileCreatedOperation).Return(&model.ProfileOutbox{}, nil)
+
+	res, _, err := env.domain.AddProfile(env.ctx, "alias")
+	if err != nil {
+		t.Fatalf("add profile: %v", err)
+	}
+	if res != created {
+		t.Fatalf("wait %v, have %v", created, res)
+	}
+}
+
+func TestDomain_DeleteAvatar_Outbox(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Finish()
+
+	key := "subj/1-sum"
+	prof := &model.Profile{SubjectID: "subj", AvatarKey: &key, AvatarVersion: 1}
+
+	env.subj.EXPECT().GetSubjectId().Return("subj").AnyTimes()
+	env.profile.EXPECT().GetProfileFromSubjectID(env.ctx, "subj").Return(prof, nil)
+	env.profile.EXPECT().UpdateAvatarKey(env.ctx, "subj", 1, nil).Return(&model.Profile{SubjectID: "subj"}, nil)
+	env.outbox.EXPECT().AddKey(env.ctx, key).Return(&model.AvatarOutbox{Key: key}, nil)
+	env.pOut.EXPECT().AddProfileOutbox(env.ctx, "subj", model.ProfileUpdatedOperation).Return(&model.ProfileOutbox{}, nil)
+	env.lg.EXPECT().With(gomock.Any(), gomock.Any()).Return(env.lg)
+	env.lg.EXPECT().Debug(gomock.Any())
+
+	if err := env.domain.DeleteAvatar(env.ctx); err != nil {
+		t.Fatalf("delete avatar: %v", err)
+	}
+}
+
+func TestDomain_DeleteProfile_Outbox(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Finish()
+
+	deleted := &model.Profile{SubjectID: "subj", LegacyAvatarChecked: true}
+
+	env.subj.EXPECT().GetSubjectId().Return("subj").AnyTimes()
+	env.profile.EXPECT().DeleteProfile(env.ctx, "subj").Return(deleted, nil)
+	// contacts go in both directions, in the same transaction as the profile
+	env.contact.EXPECT().DeleteSubjectContacts(env.ctx, "subj").Return([]*model.Contact{
+		{SubjectID: "subj", ContactSubjectID: "friend"},
+		{SubjectID: "other", ContactSubjectID: "subj"},
+	}, nil)
+	env.pOut.EXPECT().AddProfileOutbox(env.ctx, "subj", model.ProfileDeletedOperation).Return(&model.ProfileOutbox{}, nil)
+	env.lg.EXPECT().With(gomock.Any(), gomock.Any()).Return(env.lg).Times(2)
+	env.lg.EXPECT().Debug(gomock.Any())
+
+	if _, _, err := env.domain.DeleteProfile(env.ctx); err != nil {
+		t.Fatalf("delete profile: %v", err)
+	}
+}
+
+func TestDomain_DeleteProfile_LegacyAvatar(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Finish()
+
+	key := "subj/1-hash"
+	deleted := &model.Profile{SubjectID: "subj", AvatarKey: &key}
+
+	env.subj.EXPECT().GetSubjectId().Return("subj").AnyTimes()
+	env.profile.EXPECT().DeleteProfile(env.ctx, "subj").Return(deleted, nil)
+	// the backfill has not checked the profile yet, so the object under the subject id goes too
+	env.outbox.EXPECT().AddKey(env.ctx, key).Return(&model.AvatarOutbox{Key: key}, nil)
+	env.outbox.EXPECT().AddKey(env.ctx, "subj").Return(&model.AvatarOutbox{Key: "subj"}, nil)
+	env.contact.EXPECT().DeleteSubjectContacts(env.ctx, "subj").Return(nil, nil)
+	env.pOut.EXPECT().AddProfileOutbox(env.ctx, "subj", model.ProfileDeletedOperation).Return(&model.ProfileOutbox{}, nil)
+	env.lg.EXPECT().With(gomock.Any(), gomock.Any()).Return(env.lg).Times(2)
+	env.lg.EXPECT().Debug(gomock.Any())
+
+	if _, _, err := env.domain.DeleteProfile(env.ctx); err != nil {
+		t.Fatalf("delete profile: %v", err)
+	}
+}
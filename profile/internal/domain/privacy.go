@@ -0,0 +1,129 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/1ocknight/mess/profile/internal/ctxkey"
+	"github.com/1ocknight/mess/profile/internal/model"
+)
+
+// ForViewer returns a copy of the profile without the fields the viewer is not allowed to see,
+// the owner gets the profile as is. The privacy settings are seen only by the owner.
+func (d *Domain) ForViewer(ctx context.Context, viewerID string, profile *model.Profile) (*model.Profile, error) {
+	res, err := d.ForViewerBatch(ctx, viewerID, []*model.Profile{profile})
+	if err != nil {
+		return nil, err
+	}
+
+	return res[0], nil
+}
+
+// ForViewerBatch is ForViewer for many profiles, the contact relations the privacy depends on
+// are loaded with one query instead of one per profile.
+func (d *Domain) ForViewerBatch(ctx context.Context, viewerID string, profiles []*model.Profile) ([]*model.Profile, error) {
+	var owners []string
+	for _, profile := range profiles {
+		if profile.SubjectID != viewerID && profile.Privacy.NeedsContacts() {
+			owners = append(owners, profile.SubjectID)
+		}
+	}
+
+	contactOf := make(map[string]bool, len(owners))
+	if len(owners) != 0 {
+		ids, err := d.Storage.Contact().GetOwnersWithContact(ctx, owners, viewerID)
+		if err != nil {
+			return nil, fmt.Errorf("get owners with contact: %w", err)
+		}
+		for _, id := range ids {
+			contactOf[id] = true
+		}
+	}
+
+	res := make([]*model.Profile, 0, len(profiles))
+	for _, profile := range profiles {
+		res = append(res, forViewer(viewerID, profile, contactOf[profile.SubjectID]))
+	}
+
+	return res, nil
+}
+
+// forViewer hides the fields, isContact tells whether the owner added the viewer to the contacts.
+func forViewer(viewerID string, profile *model.Profile, isContact bool) *model.Profile {
+	if viewerID == profile.SubjectID {
+		return profile
+	}
+
+	res := *profile
+	res.Privacy = model.Privacy{}
+	res.PendingAvatarKey = nil
+
+	if !profile.Privacy.Avatar.Allows(isContact) {
+		res.AvatarKey = nil
+	}
+	if !profile.Privacy.LastSeen.Allows(isContact) {
+		res.LastSeenAt = nil
+	}
+
+	return &res
+}
+
+func (d *Domain) canSee(ctx context.Context, ownerID string, viewerID string, audience model.Audience) (bool, error) {
+	if audience != model.AudienceContacts {
+		return audience.Allows(false), nil
+	}
+
+	return d.isContact(ctx, ownerID, viewerID)
+}
+
+// CanStartChat is asked by chat before a chat is created, fromID is the subject who starts it.
+func (d *Domain) CanStartChat(ctx context.Context, fromID string, toID string) (bool, error) {
+	profile, err := d.Storage.Profile().GetProfileFromSubjectID(ctx, toID)
+	if err != nil {
+		return false, fmt.Errorf("get profile from subject id: %w", err)
+	}
+
+	if fromID == toID {
+		return true, nil
+	}
+
+	return d.canSee(ctx, toID, fromID, profile.Privacy.NewChat)
+}
+
+func (d *Domain) UpdatePrivacy(ctx context.Context, prevVersion int, privacy *model.Privacy) (*model.Profile, string, error) {
+	subj, err := ctxkey.ExtractSubject(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("extract subject: %w", err)
+	}
+
+	if !privacy.IsValid() {
+		return nil, "", fmt.Errorf("%w: unknown privacy audience", ErrInvalidProfile)
+	}
+
+	tx, err := d.Storage.WithTransaction(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("with transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	profile, err := tx.Profile().UpdatePrivacy(ctx, subj.GetSubjectId(), prevVersion, privacy)
+	if err != nil {
+		return nil, "", fmt.Errorf("update privacy: %w", err)
+	}
+
+	// the avatar may have become hidden or visible for the chat partners
+	if _, err := tx.ProfileOutbox().AddProfileOutbox(ctx, profile.SubjectID, model.ProfileUpdatedOperation); err != nil {
+		return nil, "", fmt.Errorf("add profile outbox: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, "", fmt.Errorf("commit: %w", err)
+	}
+
+	avatarURL, err := d.GetAvatarURL(ctx, profile)
+	if err != nil {
+		return nil, "", fmt.Errorf("get avatar url: %w", err)
+	}
+
+	return profile, avatarURL, nil
+}
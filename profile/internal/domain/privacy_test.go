@@ -0,0 +1,114 @@
+package domain_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/1ocknight/mess/profile/internal/domain"
+	"github.com/1ocknight/mess/profile/internal/model"
+	"github.com/1ocknight/mess/profile/internal/storage"
+)
+
+func TestDomain_GetProfileFromSubjectID_Privacy(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Finish()
+
+	key := "owner/1-hash"
+	seen := time.Now()
+	privacy := model.Privacy{
+		Search:   model.AudienceEveryone,
+		Avatar:   model.AudienceNobody,
+		LastSeen: model.AudienceContacts,
+		NewChat:  model.AudienceEveryone,
+	}
+	prof := &model.Profile{SubjectID: "owner", AvatarKey: &key, LastSeenAt: &seen, Privacy: privacy}
+
+	env.subj.EXPECT().GetSubjectId().Return("viewer").AnyTimes()
+	env.profile.EXPECT().GetProfileFromSubjectID(env.ctx, "owner").Return(prof, nil)
+	env.contact.EXPECT().GetOwnersWithContact(env.ctx, []string{"owner"}, "viewer").Return(nil, nil)
+
+	res, url, err := env.domain.GetProfileFromSubjectID(env.ctx, "owner")
+	if err != nil {
+		t.Fatalf("get profile: %v", err)
+	}
+	if url != "" || res.AvatarKey != nil || res.LastSeenAt != nil || res.Privacy != (model.Privacy{}) {
+		t.Fatalf("hidden fields are returned: %+v, url %v", res, url)
+	}
+	if prof.AvatarKey == nil || prof.Privacy != privacy {
+		t.Fatalf("stored profile is changed: %+v", prof)
+	}
+}
+
+func TestDomain_GetProfileFromSubjectID_Owner(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Finish()
+
+	prof := &model.Profile{SubjectID: "owner", Privacy: model.Privacy{Avatar: model.AudienceNobody}}
+
+	env.subj.EXPECT().GetSubjectId().Return("owner").AnyTimes()
+	env.profile.EXPECT().GetProfileFromSubjectID(env.ctx, "owner").Return(prof, nil)
+
+	res, _, err := env.domain.GetProfileFromSubjectID(env.ctx, "owner")
+	if err != nil {
+		t.Fatalf("get profile: %v", err)
+	}
+	if res != prof {
+		t.Fatalf("owner must get the profile as is: %+v", res)
+	}
+}
+
+func TestDomain_CanStartChat(t *testing.T) {
+	tests := []struct {
+		name      string
+		newChat   model.Audience
+		from      string
+		isContact bool
+		allowed   bool
+	}{
+		{"everyone", model.AudienceEveryone, "stranger", false, true},
+		{"contacts only", model.AudienceContacts, "stranger", false, false},
+		{"contacts only from contact", model.AudienceContacts, "friend", true, true},
+		{"self", model.AudienceContacts, "owner", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			defer env.Finish()
+
+			env.profile.EXPECT().GetProfileFromSubjectID(env.ctx, "owner").
+				Return(&model.Profile{SubjectID: "owner", Privacy: model.Privacy{NewChat: tt.newChat}}, nil)
+			if tt.newChat == model.AudienceContacts && tt.from != "owner" {
+				if tt.isContact {
+					env.contact.EXPECT().GetContact(env.ctx, "owner", tt.from).Return(&model.Contact{}, nil)
+				} else {
+					env.contact.EXPECT().GetContact(env.ctx, "owner", tt.from).Return(nil, storage.ErrNoRows)
+				}
+			}
+
+			allowed, err := env.domain.CanStartChat(env.ctx, tt.from, "owner")
+			if err != nil {
+				t.Fatalf("can start chat: %v", err)
+			}
+			if allowed != tt.allowed {
+				t.Fatalf("wait %v, have %v", tt.allowed, allowed)
+			}
+		})
+	}
+}
+
+func TestDomain_UpdatePrivacy_Invalid(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Finish()
+
+	env.subj.EXPECT().GetSubjectId().Return("owner").AnyTimes()
+
+	privacy := model.DefaultPrivacy
+	privacy.Search = model.AudienceContacts
+
+	_, _, err := env.domain.UpdatePrivacy(env.ctx, 1, &privacy)
+	if !errors.Is(err, domain.ErrInvalidProfile) {
+		t.Fatalf("wait %v, have %v", domain.ErrInvalidProfile, err)
+	}
+}
@@ -0,0 +1,161 @@
+package domain_test
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/1ocknight/mess/profile/internal/domain"
+	"github.com/1ocknight/mess/profile/internal/model"
+	"github.com/1ocknight/mess/profile/internal/storage"
+	"github.com/1ocknight/mess/shared/cursor"
+)
+
+func TestDomain_GetProfilesFromAlias(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Finish()
+
+	matches := []*model.ProfileMatch{
+		{Profile: &model.Profile{SubjectID: "id1", Privacy: model.DefaultPrivacy}, Rank: "2.000000"},
+		{Profile: &model.Profile{SubjectID: "id2", Privacy: model.DefaultPrivacy}, Rank: "0.500000"},
+	}
+
+	env.subj.EXPECT().GetSubjectId().Return("viewer").AnyTimes()
+	env.profile.EXPECT().GetProfilesFromAlias(env.ctx, "viewer", "alice", gomock.Any()).
+		DoAndReturn(func(_ any, _ string, _ string, filter *storage.ProfilePaginationFilter) ([]*model.ProfileMatch, error) {
+			if filter.Limit != 2 || filter.Asc || filter.LastID != nil {
+				t.Fatalf("unexpected filter: %+v", filter)
+			}
+			return matches, nil
+		})
+
+	profiles, _, page, err := env.domain.GetProfilesFromAlias(env.ctx, " alice ", &domain.ProfilePaginationFilter{Limit: 1})
+	if err != nil {
+		t.Fatalf("get profiles from alias: %v", err)
+	}
+	if len(profiles) != 1 || profiles[0].SubjectID != "id1" {
+		t.Fatalf("unexpected profiles: %+v", profiles)
+	}
+	if page.Next == nil || page.Next.SortKey != "2.000000" || page.Next.ID != "id1" {
+		t.Fatalf("wait next cursor by rank, have %+v", page.Next)
+	}
+}
+
+func TestDomain_GetProfilesFromAlias_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		alias  string
+		cursor *cursor.Cursor
+		err    error
+	}{
+		{"short", " al ", nil, domain.ErrInvalidSearch},
+		{"long", string(make([]rune, domain.SearchQueryMaxLength+1)), nil, domain.ErrInvalidSearch},
+		{"cursor without rank", "alice", &cursor.Cursor{Direction: cursor.DirectionAfter, SortKey: "alice", ID: "id1"}, cursor.ErrInvalidCursor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			defer env.Finish()
+
+			env.subj.EXPECT().GetSubjectId().Return("viewer").AnyTimes()
+
+			_, _, _, err := env.domain.GetProfilesFromAlias(env.ctx, tt.alias, &domain.ProfilePaginationFilter{Cursor: tt.cursor})
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("wait %v, have %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestDomain_GetProfilesFromAlias_Cursor(t *testing.T) {
+	matches := func() []*model.ProfileMatch {
+		return []*model.ProfileMatch{
+			{Profile: &model.Profile{SubjectID: "id2", Privacy: model.DefaultPrivacy}, Rank: "1.000000"},
+			{Profile: &model.Profile{SubjectID: "id3", Privacy: model.DefaultPrivacy}, Rank: "0.500000"},
+			{Profile: &model.Profile{SubjectID: "id4", Privacy: model.DefaultPrivacy}, Rank: "0.250000"},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		cursor   *cursor.Cursor
+		asc      bool
+		matches  []*model.ProfileMatch
+		wantIDs  []string
+		wantNext *cursor.Cursor
+		wantPrev *cursor.Cursor
+	}{
+		{
+			name:     "after",
+			cursor:   &cursor.Cursor{Direction: cursor.DirectionAfter, SortKey: "2.000000", ID: "id1"},
+			matches:  matches(),
+			wantIDs:  []string{"id2", "id3"},
+			wantNext: &cursor.Cursor{Direction: cursor.DirectionAfter, SortKey: "0.500000", ID: "id3"},
+			wantPrev: &cursor.Cursor{Direction: cursor.DirectionBefore, SortKey: "1.000000", ID: "id2"},
+		},
+		{
+			name:     "after last page",
+			cursor:   &cursor.Cursor{Direction: cursor.DirectionAfter, SortKey: "2.000000", ID: "id1"},
+			matches:  matches()[:2],
+			wantIDs:  []string{"id2", "id3"},
+			wantPrev: &cursor.Cursor{Direction: cursor.DirectionBefore, SortKey: "1.000000", ID: "id2"},
+		},
+		{
+			name:     "before",
+			cursor:   &cursor.Cursor{Direction: cursor.DirectionBefore, SortKey: "0.100000", ID: "id5"},
+			asc:      true,
+			matches:  []*model.ProfileMatch{matches()[2], matches()[1], matches()[0]},
+			wantIDs:  []string{"id3", "id4"},
+			wantNext: &cursor.Cursor{Direction: cursor.DirectionAfter, SortKey: "0.250000", ID: "id4"},
+			wantPrev: &cursor.Cursor{Direction: cursor.DirectionBefore, SortKey: "0.500000", ID: "id3"},
+		},
+		{
+			name:     "before first page",
+			cursor:   &cursor.Cursor{Direction: cursor.DirectionBefore, SortKey: "0.100000", ID: "id5"},
+			asc:      true,
+			matches:  []*model.ProfileMatch{matches()[2], matches()[1]},
+			wantIDs:  []string{"id3", "id4"},
+			wantNext: &cursor.Cursor{Direction: cursor.DirectionAfter, SortKey: "0.250000", ID: "id4"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			defer env.Finish()
+
+			env.subj.EXPECT().GetSubjectId().Return("viewer").AnyTimes()
+			env.profile.EXPECT().GetProfilesFromAlias(env.ctx, "viewer", "alice", gomock.Any()).
+				DoAndReturn(func(_ any, _ string, _ string, filter *storage.ProfilePaginationFilter) ([]*model.ProfileMatch, error) {
+					if filter.Limit != 3 || filter.Asc != tt.asc || filter.LastID == nil || *filter.LastID != tt.cursor.ID ||
+						filter.LastSortValue != tt.cursor.SortKey {
+						t.Fatalf("unexpected filter: %+v", filter)
+					}
+					return tt.matches, nil
+				})
+
+			profiles, _, page, err := env.domain.GetProfilesFromAlias(env.ctx, "alice", &domain.ProfilePaginationFilter{Limit: 2, Cursor: tt.cursor})
+			if err != nil {
+				t.Fatalf("get profiles from alias: %v", err)
+			}
+
+			ids := make([]string, 0, len(profiles))
+			for _, p := range profiles {
+				ids = append(ids, p.SubjectID)
+			}
+			if !slices.Equal(ids, tt.wantIDs) {
+				t.Fatalf("wait %v, have %v", tt.wantIDs, ids)
+			}
+			if !reflect.DeepEqual(page.Next, tt.wantNext) {
+				t.Fatalf("wait next %+v, have %+v", tt.wantNext, page.Next)
+			}
+			if !reflect.DeepEqual(page.Prev, tt.wantPrev) {
+				t.Fatalf("wait prev %+v, have %+v", tt.wantPrev, page.Prev)
+			}
+		})
+	}
+}
@@ -3,54 +3,82 @@ package domain
 import (
 	"context"
 
+	"github.com/1ocknight/mess/profile/internal/adapter/archive"
 	"github.com/1ocknight/mess/profile/internal/adapter/avatar"
 	"github.com/1ocknight/mess/profile/internal/model"
 	"github.com/1ocknight/mess/profile/internal/storage"
+	"github.com/1ocknight/mess/shared/cursor"
 )
 
-type Direction int
-
 const (
-	DirectionUnknown Direction = iota
-	DirectionAfter
-	DirectionBefore
+	MaxPaginationLimit = 100
+
+	// SearchQueryMinLength is the length of one trigram, a shorter query would match almost every profile
+	SearchQueryMinLength = 3
+	SearchQueryMaxLength = 64
+
+	MaxBatchProfiles = 100
 )
 
+// ProfilesBatch is the answer to a batch lookup, Profiles keep the order of the request. A requested id
+// without an alive profile is in NotFound, and also in Deleted if the subject had a profile before.
+type ProfilesBatch struct {
+	Profiles   []*model.Profile
+	AvatarURLs map[string]string
+	NotFound   []string
+	Deleted    []string
+}
+
 type ProfilePaginationFilter struct {
-	Limit         int
-	LastSubjectID *string
-	Direction     Direction
+	Limit  int
+	Cursor *cursor.Cursor
 }
 
 var DefaultPaginationProfile = storage.ProfilePaginationFilter{
-	Limit:     100,
-	Asc:       true,
-	SortLabel: storage.ProfileAliasLabel,
+	Limit: 100,
 }
 
 type Service interface {
 	GetCurrentProfile(ctx context.Context) (*model.Profile, string, error)
+	TouchLastSeen(ctx context.Context) error
 	GetProfileFromSubjectID(ctx context.Context, subjID string) (*model.Profile, string, error)
-	GetProfilesFromAlias(ctx context.Context, alias string, filter *ProfilePaginationFilter) ([]*model.Profile, map[string]string, error)
+	GetProfileFromUsername(ctx context.Context, username string) (*model.Profile, string, error)
+	GetProfilesFromAlias(ctx context.Context, alias string, filter *ProfilePaginationFilter) ([]*model.Profile, map[string]string, *cursor.Page, error)
+	GetProfilesBatch(ctx context.Context, subjIDs []string) (*ProfilesBatch, error)
 
 	AddProfile(ctx context.Context, alias string) (*model.Profile, string, error)
 
-	UpdateProfileMetadata(ctx context.Context, prevVersion int, alias string) (*model.Profile, string, error)
+	UpdateProfileMetadata(ctx context.Context, prevVersion int, patch *model.ProfileMetadataPatch) (*model.Profile, string, error)
+	UpdateUsername(ctx context.Context, prevVersion int, username string) (*model.Profile, string, error)
+	UpdatePrivacy(ctx context.Context, prevVersion int, privacy *model.Privacy) (*model.Profile, string, error)
 
-	UploadAvatar(ctx context.Context) (string, error)
+	CanStartChat(ctx context.Context, fromID string, toID string) (bool, error)
+
+	AddContact(ctx context.Context, target *ContactTarget, nickname string) (*model.Contact, string, error)
+	GetContacts(ctx context.Context, filter *ContactPaginationFilter) ([]*model.Contact, map[string]string, *cursor.Page, error)
+	DeleteContact(ctx context.Context, contactSubjectID string) (*model.Contact, error)
+
+	UploadAvatar(ctx context.Context, contentType string, checksum string) (*avatar.Upload, error)
 
 	DeleteAvatar(ctx context.Context) error
 	DeleteProfile(ctx context.Context) (*model.Profile, string, error)
+
+	RequestDataExport(ctx context.Context) (*model.DataExport, error)
+	GetDataExport(ctx context.Context, exportID int) (*model.DataExport, string, error)
 }
 
 type Domain struct {
 	Storage storage.Service
 	Avatar  avatar.Service
+	Archive archive.Service
+
+	lastSeen lastSeenThrottle
 }
 
-func New(storage storage.Service, avatar avatar.Service) Service {
+func New(storage storage.Service, avatar avatar.Service, archive archive.Service) Service {
 	return &Domain{
 		Storage: storage,
 		Avatar:  avatar,
+		Archive: archive,
 	}
 }
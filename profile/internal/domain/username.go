@@ -0,0 +1,158 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/1ocknight/mess/profile/internal/ctxkey"
+	"github.com/1ocknight/mess/profile/internal/model"
+	"github.com/1ocknight/mess/profile/internal/storage"
+)
+
+const (
+	UsernameMinLength = 5
+	UsernameMaxLength = 32
+
+	// UsernameChangeCooldown is the time between two username changes of the subject
+	UsernameChangeCooldown = 7 * 24 * time.Hour
+	// UsernameHoldPeriod keeps a released username away from others, so it is not hijacked right after the change
+	UsernameHoldPeriod = 30 * 24 * time.Hour
+)
+
+var (
+	// a username starts with a letter, has no double or trailing underscore and fits the url as is
+	usernamePattern = regexp.MustCompile(`^[a-zA-Z](_?[a-zA-Z0-9])*$`)
+
+	// ReservedUsernames can not be taken by anyone, they are compared lowercased
+	ReservedUsernames = []string{
+		"admin", "administrator", "moderator", "support", "help", "system", "service",
+		"official", "security", "root", "mess", "messenger", "profile", "profiles",
+		"settings", "account", "username", "api", "null", "undefined", "anonymous",
+	}
+)
+
+// ValidateUsername checks the format of the username and that it is not reserved.
+func ValidateUsername(username string) error {
+	if len(username) < UsernameMinLength || len(username) > UsernameMaxLength {
+		return fmt.Errorf("%w: length must be from %v to %v", ErrInvalidUsername, UsernameMinLength, UsernameMaxLength)
+	}
+	if !usernamePattern.MatchString(username) {
+		return fmt.Errorf("%w: only latin letters, digits and single underscores, starting with a letter", ErrInvalidUsername)
+	}
+	if slices.Contains(ReservedUsernames, strings.ToLower(username)) {
+		return fmt.Errorf("%w: username is reserved", ErrInvalidUsername)
+	}
+
+	return nil
+}
+
+func (d *Domain) GetProfileFromUsername(ctx context.Context, username string) (*model.Profile, string, error) {
+	subj, err := ctxkey.ExtractSubject(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("extract subject: %w", err)
+	}
+
+	profile, err := d.Storage.Profile().GetProfileFromUsername(ctx, username)
+	if err != nil {
+		return nil, "", fmt.Errorf("profile get profile from username: %w", err)
+	}
+
+	profile, err = d.ForViewer(ctx, subj.GetSubjectId(), profile)
+	if err != nil {
+		return nil, "", fmt.Errorf("for viewer: %w", err)
+	}
+
+	avatarURL, err := d.GetAvatarURL(ctx, profile)
+	if err != nil {
+		return nil, "", fmt.Errorf("get avatar url: %w", err)
+	}
+
+	return profile, avatarURL, nil
+}
+
+// UpdateUsername changes the username at most once per UsernameChangeCooldown, the previous username is held
+// for the subject for UsernameHoldPeriod. A change of the letter case only is not limited.
+func (d *Domain) UpdateUsername(ctx context.Context, prevVersion int, username string) (*model.Profile, string, error) {
+	subj, err := ctxkey.ExtractSubject(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("extract subject: %w", err)
+	}
+
+	if err := ValidateUsername(username); err != nil {
+		return nil, "", err
+	}
+
+	tx, err := d.Storage.WithTransaction(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("with transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	prof, err := tx.Profile().GetProfileFromSubjectID(ctx, subj.GetSubjectId())
+	if err != nil {
+		return nil, "", fmt.Errorf("get profile from subject id: %w", err)
+	}
+
+	caseOnly := prof.Username != nil && strings.EqualFold(*prof.Username, username)
+	if !caseOnly && prof.UsernameChangedAt != nil {
+		if next := prof.UsernameChangedAt.Add(UsernameChangeCooldown); time.Now().Before(next) {
+			return nil, "", fmt.Errorf("%w: next change after %v", ErrUsernameCooldown, next.Format(time.RFC3339))
+		}
+	}
+
+	hold, err := tx.UsernameHold().GetActiveHold(ctx, username)
+	if err != nil && !errors.Is(err, storage.ErrNoRows) {
+		return nil, "", fmt.Errorf("get active hold: %w", err)
+	}
+	if err == nil && hold.SubjectID != prof.SubjectID {
+		return nil, "", ErrUsernameTaken
+	}
+
+	updated, err := tx.Profile().UpdateUsername(ctx, prof.SubjectID, prevVersion, username)
+	if errors.Is(err, storage.ErrUniqueViolation) {
+		return nil, "", ErrUsernameTaken
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("update username: %w", err)
+	}
+
+	if !caseOnly {
+		if err := HoldUsername(ctx, tx, prof); err != nil {
+			return nil, "", fmt.Errorf("hold username: %w", err)
+		}
+	}
+
+	if _, err := tx.ProfileOutbox().AddProfileOutbox(ctx, updated.SubjectID, model.ProfileUpdatedOperation); err != nil {
+		return nil, "", fmt.Errorf("add profile outbox: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, "", fmt.Errorf("commit: %w", err)
+	}
+
+	avatarURL, err := d.GetAvatarURL(ctx, updated)
+	if err != nil {
+		return nil, "", fmt.Errorf("get avatar url: %w", err)
+	}
+
+	return updated, avatarURL, nil
+}
+
+// HoldUsername keeps the current username of the profile for its owner, it is called when the username
+// is released by a change or by the profile deletion.
+func HoldUsername(ctx context.Context, tx storage.ServiceTransaction, prof *model.Profile) error {
+	if prof.Username == nil {
+		return nil
+	}
+
+	if _, err := tx.UsernameHold().AddHold(ctx, *prof.Username, prof.SubjectID, time.Now().UTC().Add(UsernameHoldPeriod)); err != nil {
+		return fmt.Errorf("add hold: %w", err)
+	}
+
+	return nil
+}
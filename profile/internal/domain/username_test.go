@@ -0,0 +1,130 @@
+package domain_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/1ocknight/mess/profile/internal/domain"
+	"github.com/1ocknight/mess/profile/internal/model"
+	"github.com/1ocknight/mess/profile/internal/storage"
+	"github.com/golang/mock/gomock"
+)
+
+func TestValidateUsername(t *testing.T) {
+	tests := []struct {
+		username string
+		valid    bool
+	}{
+		{"john_doe", true},
+		{"John2000", true},
+		{"jo", false},
+		{"1john", false},
+		{"john__doe", false},
+		{"john_", false},
+		{"john.doe", false},
+		{"Admin", false},
+		{"support", false},
+	}
+
+	for _, tt := range tests {
+		err := domain.ValidateUsername(tt.username)
+		if tt.valid && err != nil {
+			t.Errorf("%v: unexpected error: %v", tt.username, err)
+		}
+		if !tt.valid && !errors.Is(err, domain.ErrInvalidUsername) {
+			t.Errorf("%v: wait %v, have %v", tt.username, domain.ErrInvalidUsername, err)
+		}
+	}
+}
+
+func TestDomain_UpdateUsername(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Finish()
+
+	old := "old_name"
+	changedAt := time.Now().Add(-domain.UsernameChangeCooldown - time.Hour)
+	prof := &model.Profile{SubjectID: "subj", Username: &old, UsernameChangedAt: &changedAt, Version: 1}
+	updated := &model.Profile{SubjectID: "subj", Version: 2}
+
+	env.subj.EXPECT().GetSubjectId().Return("subj").AnyTimes()
+	env.profile.EXPECT().GetProfileFromSubjectID(env.ctx, "subj").Return(prof, nil)
+	env.hold.EXPECT().GetActiveHold(env.ctx, "new_name").Return(nil, storage.ErrNoRows)
+	env.profile.EXPECT().UpdateUsername(env.ctx, "subj", 1, "new_name").Return(updated, nil)
+	env.hold.EXPECT().AddHold(env.ctx, old, "subj", gomock.Any()).Return(&model.UsernameHold{}, nil)
+	env.pOut.EXPECT().AddProfileOutbox(env.ctx, "subj", model.ProfileUpdatedOperation).Return(&model.ProfileOutbox{}, nil)
+
+	res, _, err := env.domain.UpdateUsername(env.ctx, 1, "new_name")
+	if err != nil {
+		t.Fatalf("update username: %v", err)
+	}
+	if res != updated {
+		t.Fatalf("wait %v, have %v", updated, res)
+	}
+}
+
+func TestDomain_UpdateUsername_Cooldown(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Finish()
+
+	old := "old_name"
+	changedAt := time.Now().Add(-time.Hour)
+
+	env.subj.EXPECT().GetSubjectId().Return("subj").AnyTimes()
+	env.profile.EXPECT().GetProfileFromSubjectID(env.ctx, "subj").
+		Return(&model.Profile{SubjectID: "subj", Username: &old, UsernameChangedAt: &changedAt}, nil)
+
+	_, _, err := env.domain.UpdateUsername(env.ctx, 1, "new_name")
+	if !errors.Is(err, domain.ErrUsernameCooldown) {
+		t.Fatalf("wait %v, have %v", domain.ErrUsernameCooldown, err)
+	}
+}
+
+func TestDomain_UpdateUsername_CaseOnly(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Finish()
+
+	old := "old_name"
+	changedAt := time.Now().Add(-time.Hour)
+	updated := &model.Profile{SubjectID: "subj", Version: 2}
+
+	env.subj.EXPECT().GetSubjectId().Return("subj").AnyTimes()
+	env.profile.EXPECT().GetProfileFromSubjectID(env.ctx, "subj").
+		Return(&model.Profile{SubjectID: "subj", Username: &old, UsernameChangedAt: &changedAt}, nil)
+	env.hold.EXPECT().GetActiveHold(env.ctx, "Old_Name").Return(nil, storage.ErrNoRows)
+	env.profile.EXPECT().UpdateUsername(env.ctx, "subj", 1, "Old_Name").Return(updated, nil)
+	env.pOut.EXPECT().AddProfileOutbox(env.ctx, "subj", model.ProfileUpdatedOperation).Return(&model.ProfileOutbox{}, nil)
+
+	if _, _, err := env.domain.UpdateUsername(env.ctx, 1, "Old_Name"); err != nil {
+		t.Fatalf("update username: %v", err)
+	}
+}
+
+func TestDomain_UpdateUsername_Held(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Finish()
+
+	env.subj.EXPECT().GetSubjectId().Return("subj").AnyTimes()
+	env.profile.EXPECT().GetProfileFromSubjectID(env.ctx, "subj").Return(&model.Profile{SubjectID: "subj"}, nil)
+	env.hold.EXPECT().GetActiveHold(env.ctx, "new_name").Return(&model.UsernameHold{Username: "new_name", SubjectID: "other"}, nil)
+
+	_, _, err := env.domain.UpdateUsername(env.ctx, 1, "new_name")
+	if !errors.Is(err, domain.ErrUsernameTaken) {
+		t.Fatalf("wait %v, have %v", domain.ErrUsernameTaken, err)
+	}
+}
+
+func TestDomain_UpdateUsername_Taken(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.Finish()
+
+	env.subj.EXPECT().GetSubjectId().Return("subj").AnyTimes()
+	env.profile.EXPECT().GetProfileFromSubjectID(env.ctx, "subj").Return(&model.Profile{SubjectID: "subj"}, nil)
+	env.hold.EXPECT().GetActiveHold(env.ctx, "new_name").Return(nil, storage.ErrNoRows)
+	env.profile.EXPECT().UpdateUsername(env.ctx, "subj", 1, "new_name").Return(nil, storage.ErrUniqueViolation)
+
+	_, _, err := env.domain.UpdateUsername(env.ctx, 1, "new_name")
+	if !errors.Is(err, domain.ErrUsernameTaken) {
+		t.Fatalf("wait %v, have %v", domain.ErrUsernameTaken, err)
+	}
+}
@@ -3,10 +3,19 @@ package loglables
 const (
 	AvatarOutbox      = "avatar_outbox"
 	DeletedAvatarKeys = "deleted_avatar_keys"
+	RestoredAvatars   = "restored_avatars"
 
 	Profile   = "profile"
+	Profiles  = "profiles"
 	AvatarKey = "avatar_key"
 
+	DataExport       = "data_export"
+	DataExportOutbox = "data_export_outbox"
+
+	ProfileOutbox = "profile_outbox"
+
+	DeletedContacts = "deleted_contacts"
+
 	RequestMetadata = "request_metadata"
 	Response        = "response"
 	RequestID       = "request_id"
@@ -3,7 +3,7 @@ package model
 import "time"
 
 type AvatarOutbox struct {
-	SubjectID string
+	Key       string
 	CreatedAt time.Time
 	DeletedAt *time.Time
 }
@@ -11,7 +11,7 @@ type AvatarOutbox struct {
 func GetOutboxIDs(arr []*AvatarOutbox) []string {
 	res := make([]string, len(arr))
 	for i, k := range arr {
-		res[i] = k.SubjectID
+		res[i] = k.Key
 	}
 
 	return res
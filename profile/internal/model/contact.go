@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// Contact is ContactSubjectID in the address book of SubjectID, Nickname is empty when not set.
+type Contact struct {
+	SubjectID        string
+	ContactSubjectID string
+	Nickname         string
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	// Profile of ContactSubjectID as SubjectID sees it, filled by the domain
+	Profile *Profile
+}
@@ -0,0 +1,78 @@
+package model
+
+import "time"
+
+type DataExportStatus int
+
+const (
+	DataExportRequested DataExportStatus = iota
+	DataExportCollected
+	DataExportDone
+	DataExportFailed
+	DataExportAssembling
+)
+
+func (s DataExportStatus) String() string {
+	switch s {
+	case DataExportRequested:
+		return "requested"
+	case DataExportCollected:
+		return "collected"
+	case DataExportDone:
+		return "done"
+	case DataExportFailed:
+		return "failed"
+	case DataExportAssembling:
+		return "assembling"
+	default:
+		return "unknown"
+	}
+}
+
+type DataExport struct {
+	ID          int
+	SubjectID   string
+	Status      DataExportStatus
+	ChatPartKey *string
+	ObjectKey   *string
+	Error       *string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	DeletedAt   *time.Time
+}
+
+type DataExportOperation int
+
+const (
+	UnknownDataExportOperation DataExportOperation = iota
+	// DataExportRequestOperation asks other services for their parts of the export.
+	DataExportRequestOperation
+	// DataExportNotifyOperation tells the subject that the export is finished.
+	DataExportNotifyOperation
+)
+
+type DataExportOutbox struct {
+	ID        int
+	ExportID  int
+	Operation DataExportOperation
+	CreatedAt time.Time
+	DeletedAt *time.Time
+}
+
+func GetDataExportOutboxIDs(arr []*DataExportOutbox) []int {
+	res := make([]int, len(arr))
+	for i, o := range arr {
+		res[i] = o.ID
+	}
+
+	return res
+}
+
+func GetDataExportIDsFromOutboxes(arr []*DataExportOutbox) []int {
+	res := make([]int, len(arr))
+	for i, o := range arr {
+		res[i] = o.ExportID
+	}
+
+	return res
+}
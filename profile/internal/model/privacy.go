@@ -0,0 +1,53 @@
+package model
+
+import "slices"
+
+type Audience string
+
+const (
+	AudienceEveryone Audience = "everyone"
+	AudienceContacts Audience = "contacts"
+	AudienceNobody   Audience = "nobody"
+)
+
+// Allows tells whether a viewer sees the field, isContact is whether the owner added the viewer to the contacts.
+func (a Audience) Allows(isContact bool) bool {
+	switch a {
+	case AudienceEveryone:
+		return true
+	case AudienceContacts:
+		return isContact
+	default:
+		return false
+	}
+}
+
+// Privacy decides what other subjects can do with the profile, the owner is never limited.
+type Privacy struct {
+	// Search is everyone or nobody, nobody hides the profile from the alias search
+	Search   Audience
+	Avatar   Audience
+	LastSeen Audience
+	// NewChat is everyone or contacts, it is asked by chat before a chat is created
+	NewChat Audience
+}
+
+var DefaultPrivacy = Privacy{
+	Search:   AudienceEveryone,
+	Avatar:   AudienceEveryone,
+	LastSeen: AudienceEveryone,
+	NewChat:  AudienceEveryone,
+}
+
+func (p *Privacy) IsValid() bool {
+	all := []Audience{AudienceEveryone, AudienceContacts, AudienceNobody}
+	return slices.Contains([]Audience{AudienceEveryone, AudienceNobody}, p.Search) &&
+		slices.Contains(all, p.Avatar) &&
+		slices.Contains(all, p.LastSeen) &&
+		slices.Contains([]Audience{AudienceEveryone, AudienceContacts}, p.NewChat)
+}
+
+// NeedsContacts tells whether the fields hidden from a viewer depend on the contacts of the owner.
+func (p *Privacy) NeedsContacts() bool {
+	return p.Avatar == AudienceContacts || p.LastSeen == AudienceContacts
+}
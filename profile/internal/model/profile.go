@@ -5,8 +5,105 @@ import "time"
 type Profile struct {
 	SubjectID string
 	Alias     string
-	Version   int
-	UpdatedAt time.Time
-	CreatedAt time.Time
-	DeletedAt *time.Time
+	// Username is unique case-insensitive, nil until the subject picks one
+	Username          *string
+	UsernameChangedAt *time.Time
+	DisplayName       string
+	Bio               string
+	// Status is nil when the subject has not set one, an expired status is still returned, see ProfileStatus.IsActive
+	Status *ProfileStatus
+	Links  []ProfileLink
+	// LastSeenAt is the last request of the subject to the api with a minute precision, nil if never or hidden by Privacy.LastSeen
+	LastSeenAt *time.Time
+	Privacy    Privacy
+	Version    int
+	// AvatarKey is nil when the subject has no avatar
+	AvatarKey     *string
+	AvatarVersion int
+	// PendingAvatarKey is the uploaded avatar that waits for processing
+	PendingAvatarKey *string
+	// LegacyAvatarChecked is false until the backfill looked for an avatar stored under the bare subject id
+	LegacyAvatarChecked bool
+	UpdatedAt           time.Time
+	CreatedAt           time.Time
+	DeletedAt           *time.Time
+}
+
+// ProfileMatch is a profile found by the search. Rank is the relevance to the query as a decimal string,
+// it is kept as is, so a cursor compares with exactly the same value.
+type ProfileMatch struct {
+	Profile *Profile
+	Rank    string
+}
+
+type ProfileStatus struct {
+	Text  string
+	Emoji string
+	// ExpiresAt is nil for a status without expiry
+	ExpiresAt *time.Time
+}
+
+func (ps *ProfileStatus) IsActive(now time.Time) bool {
+	return ps != nil && (ps.ExpiresAt == nil || ps.ExpiresAt.After(now))
+}
+
+type ProfileLink struct {
+	Title string
+	URL   string
+}
+
+// ProfileMetadata is everything the subject edits at once, empty fields are cleared.
+type ProfileMetadata struct {
+	Alias       string
+	DisplayName string
+	Bio         string
+	Status      *ProfileStatus
+	Links       []ProfileLink
+}
+
+func (p *Profile) Metadata() ProfileMetadata {
+	return ProfileMetadata{
+		Alias:       p.Alias,
+		DisplayName: p.DisplayName,
+		Bio:         p.Bio,
+		Status:      p.Status,
+		Links:       p.Links,
+	}
+}
+
+// ProfileMetadataPatch holds the fields the subject sent, nil fields are left unchanged.
+type ProfileMetadataPatch struct {
+	Alias       *string
+	DisplayName *string
+	Bio         *string
+	// SetStatus replaces the status with Status, a nil Status clears it
+	SetStatus bool
+	Status    *ProfileStatus
+	Links     *[]ProfileLink
+}
+
+// Apply returns meta with the sent fields replaced.
+func (p *ProfileMetadataPatch) Apply(meta ProfileMetadata) ProfileMetadata {
+	if p.Alias != nil {
+		meta.Alias = *p.Alias
+	}
+	if p.DisplayName != nil {
+		meta.DisplayName = *p.DisplayName
+	}
+	if p.Bio != nil {
+		meta.Bio = *p.Bio
+	}
+	if p.SetStatus {
+		meta.Status = p.Status
+	}
+	if p.Links != nil {
+		meta.Links = *p.Links
+	}
+
+	return meta
+}
+
+// LegacyAvatarKey is where avatars were stored before the versioned keys.
+func (p *Profile) LegacyAvatarKey() string {
+	return p.SubjectID
 }
@@ -0,0 +1,40 @@
+package model
+
+import "time"
+
+type ProfileOperation int
+
+const (
+	UnknownProfileOperation ProfileOperation = iota
+	ProfileCreatedOperation
+	ProfileUpdatedOperation
+	ProfileDeletedOperation
+)
+
+// ProfileOutbox is written in the transaction of the change, the event itself is built from the profile
+// when the outbox is published.
+type ProfileOutbox struct {
+	ID        int
+	SubjectID string
+	Operation ProfileOperation
+	CreatedAt time.Time
+	DeletedAt *time.Time
+}
+
+func GetProfileOutboxIDs(arr []*ProfileOutbox) []int {
+	res := make([]int, len(arr))
+	for i, o := range arr {
+		res[i] = o.ID
+	}
+
+	return res
+}
+
+func GetSubjectIDsFromProfileOutboxes(arr []*ProfileOutbox) []string {
+	res := make([]string, len(arr))
+	for i, o := range arr {
+		res[i] = o.SubjectID
+	}
+
+	return res
+}
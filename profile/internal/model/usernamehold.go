@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+// UsernameHold keeps a released username for its last owner, Username is lowercased.
+type UsernameHold struct {
+	Username  string
+	SubjectID string
+	HeldUntil time.Time
+	CreatedAt time.Time
+}
@@ -42,16 +42,23 @@ func (s *Storage) GetKeys(ctx context.Context, limit int) ([]*model.AvatarOutbox
 	return AvatarOutboxEntitiesToModels(entities), nil
 }
 
-func (s *Storage) AddKey(ctx context.Context, subjectID string) (*model.AvatarOutbox, error) {
+// AddKey queues the object for deletion, a key that was deleted before is queued again.
+func (s *Storage) AddKey(ctx context.Context, key string) (*model.AvatarOutbox, error) {
 	query, args, err := sq.
 		Insert(AvatarKeyOutboxTable).
 		Columns(
-			AvatarKeyOutboxSubjectIDLabel,
+			AvatarKeyOutboxKeyLabel,
 			AvatarKeyOutboxCreatedAtLabel,
 			AvatarKeyOutboxDeletedAtLabel,
 		).
-		Values(subjectID, time.Now().UTC(), nil).
-		Suffix(ReturningSuffix).
+		Values(key, time.Now().UTC(), nil).
+		Suffix(fmt.Sprintf(
+			"ON CONFLICT (%v) DO UPDATE SET %v = EXCLUDED.%v, %v = NULL %v",
+			AvatarKeyOutboxKeyLabel,
+			AvatarKeyOutboxCreatedAtLabel, AvatarKeyOutboxCreatedAtLabel,
+			AvatarKeyOutboxDeletedAtLabel,
+			ReturningSuffix,
+		)).
 		PlaceholderFormat(sq.Dollar).
 		ToSql()
 
@@ -68,15 +75,15 @@ func (s *Storage) AddKey(ctx context.Context, subjectID string) (*model.AvatarOu
 	return entity.ToModel(), nil
 }
 
-func (s *Storage) DeleteKeys(ctx context.Context, ids []string) ([]*model.AvatarOutbox, error) {
-	if len(ids) == 0 {
+func (s *Storage) DeleteKeys(ctx context.Context, keys []string) ([]*model.AvatarOutbox, error) {
+	if len(keys) == 0 {
 		return []*model.AvatarOutbox{}, nil
 	}
 
 	query, args, err := sq.
 		Update(AvatarKeyOutboxTable).
 		Set(AvatarKeyOutboxDeletedAtLabel, time.Now().UTC()).
-		Where(sq.Eq{AvatarKeyOutboxSubjectIDLabel: ids}).
+		Where(sq.Eq{AvatarKeyOutboxKeyLabel: keys}).
 		Where(sq.Expr(deletedATIsNullAvatarKeyFilter)).
 		Suffix(ReturningSuffix).
 		PlaceholderFormat(sq.Dollar).
@@ -22,15 +22,15 @@ func TestStorage_GetKeys(t *testing.T) {
 	}
 
 	sort.Slice(keys, func(i, j int) bool {
-		return keys[i].SubjectID > keys[j].SubjectID
+		return keys[i].Key > keys[j].Key
 	})
 
 	sort.Slice(InitAvatarKeys, func(i, j int) bool {
-		return InitAvatarKeys[i].SubjectID > InitAvatarKeys[j].SubjectID
+		return InitAvatarKeys[i].Key > InitAvatarKeys[j].Key
 	})
 
 	for i, k := range keys {
-		if k.SubjectID != InitAvatarKeys[i].SubjectID ||
+		if k.Key != InitAvatarKeys[i].Key ||
 			k.DeletedAt != nil {
 			t.Fatalf("not currently add, wait: %v, have: %v", InitAvatarKeys[i], k)
 		}
@@ -44,16 +44,38 @@ func TestStorage_AddKey(t *testing.T) {
 	}
 	defer cleanupDB(t)
 
-	key, err := s.AvatarOutbox().AddKey(t.Context(), InitAvatarKeys[0].SubjectID)
+	key, err := s.AvatarOutbox().AddKey(t.Context(), InitAvatarKeys[0].Key)
 	if err != nil {
 		t.Fatalf("add keyL %v", err)
 	}
-	if key.SubjectID != InitAvatarKeys[0].SubjectID ||
+	if key.Key != InitAvatarKeys[0].Key ||
 		key.DeletedAt != nil {
 		t.Fatalf("not currently add, wait: %v, have: %v", InitAvatarKeys[0], key)
 	}
 }
 
+func TestStorage_AddKey_Requeue(t *testing.T) {
+	s, err := p.New(CFG)
+	if err != nil {
+		t.Fatalf("could not construct receiver type: %v", err)
+	}
+	initData(t)
+	defer cleanupDB(t)
+
+	key := InitAvatarKeys[0].Key
+	if _, err := s.AvatarOutbox().DeleteKeys(t.Context(), []string{key}); err != nil {
+		t.Fatalf("delete keys: %v", err)
+	}
+
+	out, err := s.AvatarOutbox().AddKey(t.Context(), key)
+	if err != nil {
+		t.Fatalf("add deleted key again: %v", err)
+	}
+	if out.Key != key || out.DeletedAt != nil {
+		t.Fatalf("key is not queued again: %v", out)
+	}
+}
+
 func TestStorage_DeleteKeys(t *testing.T) {
 	s, err := p.New(CFG)
 	if err != nil {
@@ -80,7 +102,7 @@ func TestStorage_DeleteKeys(t *testing.T) {
 	}
 
 	for _, k := range modelKeys {
-		if _, ok := want[k.SubjectID]; !ok {
+		if _, ok := want[k.Key]; !ok {
 			t.Fatalf("unexpected deleted key: %v", k)
 		}
 	}
@@ -0,0 +1,187 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/1ocknight/mess/profile/internal/model"
+	"github.com/1ocknight/mess/shared/postgres"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	// contacts of a profile are deleted with it, the filter only covers the moment between the two
+	aliveContactFilter = fmt.Sprintf(
+		"EXISTS (SELECT 1 FROM %v WHERE %v.%v = %v.%v AND %v.%v %v)",
+		ProfileTable,
+		ProfileTable, ProfileSubjectIDLabel, ContactTable, ContactContactSubjectIDLabel,
+		ProfileTable, ProfileDeletedAtLabel, IsNullLabel,
+	)
+)
+
+func (s *Storage) doAndReturnContact(ctx context.Context, query string, args []interface{}) (*model.Contact, error) {
+	var entity ContactEntity
+	if err := sqlx.GetContext(ctx, s.exec, &entity, query, args...); err != nil {
+		return nil, fmt.Errorf("db get: %w", err)
+	}
+
+	return entity.ToModel(), nil
+}
+
+// AddContact adds the contact to the subject, an existing contact gets the new nickname.
+func (s *Storage) AddContact(ctx context.Context, subjectID string, contactSubjectID string, nickname string) (*model.Contact, error) {
+	query, args, err := sq.
+		Insert(ContactTable).
+		Columns(
+			ContactSubjectIDLabel,
+			ContactContactSubjectIDLabel,
+			ContactNicknameLabel,
+			ContactCreatedAtLabel,
+			ContactUpdatedAtLabel,
+		).
+		Values(subjectID, contactSubjectID, nullString(nickname), time.Now().UTC(), time.Now().UTC()).
+		Suffix(fmt.Sprintf(
+			"ON CONFLICT (%v, %v) DO UPDATE SET %v = EXCLUDED.%v, %v = EXCLUDED.%v %v",
+			ContactSubjectIDLabel, ContactContactSubjectIDLabel,
+			ContactNicknameLabel, ContactNicknameLabel,
+			ContactUpdatedAtLabel, ContactUpdatedAtLabel,
+			ReturningSuffix,
+		)).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build sql: %w", err)
+	}
+
+	return s.doAndReturnContact(ctx, query, args)
+}
+
+func (s *Storage) GetContact(ctx context.Context, subjectID string, contactSubjectID string) (*model.Contact, error) {
+	query, args, err := sq.
+		Select(AllLabelsSelect).
+		From(ContactTable).
+		Where(sq.Eq{ContactSubjectIDLabel: subjectID}).
+		Where(sq.Eq{ContactContactSubjectIDLabel: contactSubjectID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build sql: %w", err)
+	}
+
+	return s.doAndReturnContact(ctx, query, args)
+}
+
+// GetOwnersWithContact returns those of ownerIDs who added contactSubjectID to their contacts.
+func (s *Storage) GetOwnersWithContact(ctx context.Context, ownerIDs []string, contactSubjectID string) ([]string, error) {
+	query, args, err := sq.
+		Select(ContactSubjectIDLabel).
+		From(ContactTable).
+		Where(sq.Eq{ContactSubjectIDLabel: ownerIDs}).
+		Where(sq.Eq{ContactContactSubjectIDLabel: contactSubjectID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build sql: %w", err)
+	}
+
+	var res []string
+	if err := sqlx.SelectContext(ctx, s.exec, &res, query, args...); err != nil {
+		return nil, fmt.Errorf("db select: %w", err)
+	}
+
+	return res, nil
+}
+
+// GetContacts returns the contacts of the subject with alive profiles.
+func (s *Storage) GetContacts(ctx context.Context, subjectID string, filter *ContactPaginationFilter) ([]*model.Contact, error) {
+	b := sq.
+		Select(AllLabelsSelect).
+		From(ContactTable).
+		Where(sq.Eq{ContactSubjectIDLabel: subjectID}).
+		Where(sq.Expr(aliveContactFilter))
+
+	storageFilter := &postgres.PaginationFilter[string]{
+		Limit:         filter.Limit,
+		Asc:           filter.Asc,
+		SortLabel:     filter.SortLabel,
+		IDLabel:       ContactContactSubjectIDLabel,
+		LastID:        filter.LastID,
+		LastSortValue: filter.LastSortValue,
+	}
+
+	query, args, err := postgres.MakeQueryWithPagination(ctx, b, storageFilter)
+	if err != nil {
+		return nil, fmt.Errorf("build sql: %w", err)
+	}
+
+	var entities []*ContactEntity
+	if err := sqlx.SelectContext(ctx, s.exec, &entities, query, args...); err != nil {
+		return nil, fmt.Errorf("db select: %w", err)
+	}
+
+	return ContactEntitiesToModels(entities), nil
+}
+
+func (s *Storage) DeleteContact(ctx context.Context, subjectID string, contactSubjectID string) (*model.Contact, error) {
+	query, args, err := sq.
+		Delete(ContactTable).
+		Where(sq.Eq{ContactSubjectIDLabel: subjectID}).
+		Where(sq.Eq{ContactContactSubjectIDLabel: contactSubjectID}).
+		Suffix(ReturningSuffix).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build sql: %w", err)
+	}
+
+	return s.doAndReturnContact(ctx, query, args)
+}
+
+// GetSubjectContacts returns every contact of the subject for the data export, oldest first.
+func (s *Storage) GetSubjectContacts(ctx context.Context, subjectID string) ([]*model.Contact, error) {
+	query, args, err := sq.
+		Select(AllLabelsSelect).
+		From(ContactTable).
+		Where(sq.Eq{ContactSubjectIDLabel: subjectID}).
+		OrderBy(ContactCreatedAtLabel, ContactContactSubjectIDLabel).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build sql: %w", err)
+	}
+
+	var entities []*ContactEntity
+	if err := sqlx.SelectContext(ctx, s.exec, &entities, query, args...); err != nil {
+		return nil, fmt.Errorf("db select: %w", err)
+	}
+
+	return ContactEntitiesToModels(entities), nil
+}
+
+// DeleteSubjectContacts deletes the contacts of a deleted profile in both directions:
+// its own address book and the entries about it in the address books of others.
+func (s *Storage) DeleteSubjectContacts(ctx context.Context, subjectID string) ([]*model.Contact, error) {
+	query, args, err := sq.
+		Delete(ContactTable).
+		Where(sq.Or{
+			sq.Eq{ContactSubjectIDLabel: subjectID},
+			sq.Eq{ContactContactSubjectIDLabel: subjectID},
+		}).
+		Suffix(ReturningSuffix).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build sql: %w", err)
+	}
+
+	var entities []*ContactEntity
+	if err := sqlx.SelectContext(ctx, s.exec, &entities, query, args...); err != nil {
+		return nil, fmt.Errorf("db select: %w", err)
+	}
+
+	return ContactEntitiesToModels(entities), nil
+}
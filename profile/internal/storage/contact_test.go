@@ -0,0 +1,266 @@
+package storage_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/1ocknight/mess/profile/internal/storage"
+	p "github.com/1ocknight/mess/profile/internal/storage"
+)
+
+func TestStorage_AddContact(t *testing.T) {
+	s, err := p.New(CFG)
+	if err != nil {
+		t.Fatalf("could not construct receiver type: %v", err)
+	}
+
+	initData(t)
+	defer cleanupDB(t)
+
+	owner, target := InitProfiles[0].SubjectID, InitProfiles[1].SubjectID
+
+	contact, err := s.Contact().AddContact(t.Context(), owner, target, "")
+	if err != nil {
+		t.Fatalf("add contact: %v", err)
+	}
+	if contact.SubjectID != owner || contact.ContactSubjectID != target || contact.Nickname != "" {
+		t.Fatalf("unexpected contact: %+v", contact)
+	}
+
+	renamed, err := s.Contact().AddContact(t.Context(), owner, target, "bestie")
+	if err != nil {
+		t.Fatalf("add contact again: %v", err)
+	}
+	if renamed.Nickname != "bestie" || !renamed.CreatedAt.Equal(contact.CreatedAt) {
+		t.Fatalf("contact not renamed: %+v", renamed)
+	}
+
+	got, err := s.Contact().GetContact(t.Context(), owner, target)
+	if err != nil {
+		t.Fatalf("get contact: %v", err)
+	}
+	if got.Nickname != "bestie" {
+		t.Fatalf("wait nickname bestie, have %v", got.Nickname)
+	}
+
+	// contacts are one-sided
+	if _, err := s.Contact().GetContact(t.Context(), target, owner); !errors.Is(err, storage.ErrNoRows) {
+		t.Fatalf("expected no rows for reverse contact, got: %v", err)
+	}
+}
+
+func TestStorage_GetOwnersWithContact(t *testing.T) {
+	s, err := p.New(CFG)
+	if err != nil {
+		t.Fatalf("could not construct receiver type: %v", err)
+	}
+
+	initData(t)
+	defer cleanupDB(t)
+
+	viewer := InitProfiles[0].SubjectID
+	friend, stranger := InitProfiles[1].SubjectID, InitProfiles[2].SubjectID
+
+	if _, err := s.Contact().AddContact(t.Context(), friend, viewer, ""); err != nil {
+		t.Fatalf("add contact: %v", err)
+	}
+	// the viewer's own contacts do not count, only who added the viewer
+	if _, err := s.Contact().AddContact(t.Context(), viewer, stranger, ""); err != nil {
+		t.Fatalf("add contact: %v", err)
+	}
+
+	owners, err := s.Contact().GetOwnersWithContact(t.Context(), []string{friend, stranger}, viewer)
+	if err != nil {
+		t.Fatalf("get owners with contact: %v", err)
+	}
+	if !slices.Equal(owners, []string{friend}) {
+		t.Fatalf("wait [%v], have %v", friend, owners)
+	}
+}
+
+func TestStorage_GetContacts(t *testing.T) {
+	s, err := p.New(CFG)
+	if err != nil {
+		t.Fatalf("could not construct receiver type: %v", err)
+	}
+
+	initData(t)
+	defer cleanupDB(t)
+
+	owner := InitProfiles[0].SubjectID
+	for _, prof := range InitProfiles[1:] {
+		if _, err := s.Contact().AddContact(t.Context(), owner, prof.SubjectID, ""); err != nil {
+			t.Fatalf("add contact: %v", err)
+		}
+	}
+
+	filter := storage.ContactPaginationFilter{
+		Limit:     1,
+		Asc:       false,
+		SortLabel: storage.ContactCreatedAtLabel,
+	}
+	first, err := s.Contact().GetContacts(t.Context(), owner, &filter)
+	if err != nil {
+		t.Fatalf("get contacts: %v", err)
+	}
+	if len(first) != 1 || first[0].ContactSubjectID != InitProfiles[2].SubjectID {
+		t.Fatalf("wait the newest contact first, have %+v", first)
+	}
+
+	filter.LastID = &first[0].ContactSubjectID
+	filter.LastSortValue = first[0].CreatedAt
+	second, err := s.Contact().GetContacts(t.Context(), owner, &filter)
+	if err != nil {
+		t.Fatalf("get contacts: %v", err)
+	}
+	if len(second) != 1 || second[0].ContactSubjectID != InitProfiles[1].SubjectID {
+		t.Fatalf("wait the older contact on the next page, have %+v", second)
+	}
+
+	if _, err := s.Profile().DeleteProfile(t.Context(), InitProfiles[2].SubjectID); err != nil {
+		t.Fatalf("delete profile: %v", err)
+	}
+
+	all, err := s.Contact().GetContacts(t.Context(), owner, &storage.ContactPaginationFilter{Limit: 10, SortLabel: storage.ContactCreatedAtLabel})
+	if err != nil {
+		t.Fatalf("get contacts: %v", err)
+	}
+	if len(all) != 1 || all[0].ContactSubjectID != InitProfiles[1].SubjectID {
+		t.Fatalf("contact with deleted profile is returned: %+v", all)
+	}
+}
+
+func TestStorage_DeleteContact(t *testing.T) {
+	s, err := p.New(CFG)
+	if err != nil {
+		t.Fatalf("could not construct receiver type: %v", err)
+	}
+
+	initData(t)
+	defer cleanupDB(t)
+
+	owner, target := InitProfiles[0].SubjectID, InitProfiles[1].SubjectID
+
+	if _, err := s.Contact().AddContact(t.Context(), owner, target, ""); err != nil {
+		t.Fatalf("add contact: %v", err)
+	}
+
+	if _, err := s.Contact().DeleteContact(t.Context(), owner, target); err != nil {
+		t.Fatalf("delete contact: %v", err)
+	}
+
+	if _, err := s.Contact().DeleteContact(t.Context(), owner, target); !errors.Is(err, storage.ErrNoRows) {
+		t.Fatalf("expected no rows for deleted contact, got: %v", err)
+	}
+}
+
+func TestStorage_GetProfilesFromAlias_ContactBoost(t *testing.T) {
+	s, err := p.New(CFG)
+	if err != nil {
+		t.Fatalf("could not construct receiver type: %v", err)
+	}
+
+	initData(t)
+	defer cleanupDB(t)
+
+	viewer := "viewer"
+	if _, err := s.Contact().AddContact(t.Context(), viewer, InitProfiles[0].SubjectID, ""); err != nil {
+		t.Fatalf("add contact: %v", err)
+	}
+
+	filter := storage.ProfilePaginationFilter{Limit: 10}
+	matches, err := s.Profile().GetProfilesFromAlias(t.Context(), viewer, "ali", &filter)
+	if err != nil {
+		t.Fatalf("get profiles from alias: %v", err)
+	}
+	if len(matches) != 3 || matches[0].Profile.SubjectID != InitProfiles[0].SubjectID {
+		t.Fatalf("wait the contact first, have %+v", matches)
+	}
+
+	// the boost is only for the viewer who has the contact
+	other, err := s.Profile().GetProfilesFromAlias(t.Context(), "other", "ali", &filter)
+	if err != nil {
+		t.Fatalf("get profiles from alias: %v", err)
+	}
+	if len(other) != 3 || other[2].Profile.SubjectID != InitProfiles[0].SubjectID {
+		t.Fatalf("wait the fuzzy match last, have %+v", other)
+	}
+}
+
+func TestStorage_GetProfilesFromAlias_Nickname(t *testing.T) {
+	s, err := p.New(CFG)
+	if err != nil {
+		t.Fatalf("could not construct receiver type: %v", err)
+	}
+
+	initData(t)
+	defer cleanupDB(t)
+
+	viewer := "viewer"
+	if _, err := s.Contact().AddContact(t.Context(), viewer, InitProfiles[1].SubjectID, "bestie"); err != nil {
+		t.Fatalf("add contact: %v", err)
+	}
+
+	filter := storage.ProfilePaginationFilter{Limit: 10}
+	profiles, err := s.Profile().GetProfilesFromAlias(t.Context(), viewer, "best", &filter)
+	if err != nil {
+		t.Fatalf("get profiles from alias: %v", err)
+	}
+	if len(profiles) != 1 || profiles[0].Profile.SubjectID != InitProfiles[1].SubjectID {
+		t.Fatalf("wait the contact found by nickname, have %+v", profiles)
+	}
+
+	// the nickname is seen only by the one who gave it
+	other, err := s.Profile().GetProfilesFromAlias(t.Context(), "other", "best", &filter)
+	if err != nil {
+		t.Fatalf("get profiles from alias: %v", err)
+	}
+	if len(other) != 0 {
+		t.Fatalf("nickname of another subject matched: %+v", other)
+	}
+}
+
+func TestStorage_DeleteSubjectContacts(t *testing.T) {
+	s, err := p.New(CFG)
+	if err != nil {
+		t.Fatalf("could not construct receiver type: %v", err)
+	}
+
+	initData(t)
+	defer cleanupDB(t)
+
+	deleted, friend, other := InitProfiles[0].SubjectID, InitProfiles[1].SubjectID, InitProfiles[2].SubjectID
+
+	pairs := [][2]string{{deleted, friend}, {friend, deleted}, {other, deleted}, {friend, other}}
+	for _, pair := range pairs {
+		if _, err := s.Contact().AddContact(t.Context(), pair[0], pair[1], "nick"); err != nil {
+			t.Fatalf("add contact: %v", err)
+		}
+	}
+
+	own, err := s.Contact().GetSubjectContacts(t.Context(), deleted)
+	if err != nil {
+		t.Fatalf("get subject contacts: %v", err)
+	}
+	if len(own) != 1 || own[0].ContactSubjectID != friend || own[0].Nickname != "nick" {
+		t.Fatalf("wait the own contact only, have %+v", own)
+	}
+
+	removed, err := s.Contact().DeleteSubjectContacts(t.Context(), deleted)
+	if err != nil {
+		t.Fatalf("delete subject contacts: %v", err)
+	}
+	if len(removed) != 3 {
+		t.Fatalf("wait 3 deleted contacts in both directions, have %+v", removed)
+	}
+
+	for _, pair := range pairs[:3] {
+		if _, err := s.Contact().GetContact(t.Context(), pair[0], pair[1]); !errors.Is(err, storage.ErrNoRows) {
+			t.Fatalf("contact %v -> %v is left: %v", pair[0], pair[1], err)
+		}
+	}
+	if _, err := s.Contact().GetContact(t.Context(), friend, other); err != nil {
+		t.Fatalf("contact of others is deleted: %v", err)
+	}
+}
@@ -0,0 +1,184 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/1ocknight/mess/profile/internal/model"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	deletedATIsNullDataExportFilter = fmt.Sprintf("%v %v", DataExportDeletedAtLabel, IsNullLabel)
+)
+
+func (s *Storage) doAndReturnDataExport(ctx context.Context, query string, args []interface{}) (*model.DataExport, error) {
+	var entity DataExportEntity
+	err := sqlx.GetContext(ctx, s.exec, &entity, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("db get: %w", err)
+	}
+
+	return entity.ToModel(), nil
+}
+
+func (s *Storage) doAndReturnDataExports(ctx context.Context, query string, args []interface{}) ([]*model.DataExport, error) {
+	var entities []*DataExportEntity
+	err := sqlx.SelectContext(ctx, s.exec, &entities, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("db select: %w", err)
+	}
+
+	return DataExportEntitiesToModels(entities), nil
+}
+
+func (s *Storage) AddDataExport(ctx context.Context, subjectID string) (*model.DataExport, error) {
+	query, args, err := sq.
+		Insert(DataExportTable).
+		Columns(DataExportSubjectIDLabel).
+		Values(subjectID).
+		Suffix(ReturningSuffix).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build sql: %w", err)
+	}
+
+	return s.doAndReturnDataExport(ctx, query, args)
+}
+
+func (s *Storage) GetDataExportByID(ctx context.Context, exportID int) (*model.DataExport, error) {
+	query, args, err := sq.
+		Select(AllLabelsSelect).
+		From(DataExportTable).
+		Where(sq.Eq{DataExportIDLabel: exportID}).
+		Where(sq.Expr(deletedATIsNullDataExportFilter)).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build sql: %w", err)
+	}
+
+	return s.doAndReturnDataExport(ctx, query, args)
+}
+
+func (s *Storage) GetDataExportsByIDs(ctx context.Context, exportIDs []int) ([]*model.DataExport, error) {
+	if len(exportIDs) == 0 {
+		return []*model.DataExport{}, nil
+	}
+
+	query, args, err := sq.
+		Select(AllLabelsSelect).
+		From(DataExportTable).
+		Where(sq.Eq{DataExportIDLabel: exportIDs}).
+		Where(sq.Expr(deletedATIsNullDataExportFilter)).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build sql: %w", err)
+	}
+
+	return s.doAndReturnDataExports(ctx, query, args)
+}
+
+// GetCollectedDataExport returns a collected export or an assembling one whose worker
+// has not finished it since assemblingBefore.
+func (s *Storage) GetCollectedDataExport(ctx context.Context, assemblingBefore time.Time) (*model.DataExport, error) {
+	query, args, err := sq.
+		Select(AllLabelsSelect).
+		From(DataExportTable).
+		Where(sq.Or{
+			sq.Eq{DataExportStatusLabel: model.DataExportCollected},
+			sq.And{
+				sq.Eq{DataExportStatusLabel: model.DataExportAssembling},
+				sq.Lt{DataExportUpdatedAtLabel: assemblingBefore},
+			},
+		}).
+		Where(sq.Expr(deletedATIsNullDataExportFilter)).
+		OrderBy(DataExportIDLabel).
+		Limit(1).
+		Suffix(SkipLocked).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build sql: %w", err)
+	}
+
+	return s.doAndReturnDataExport(ctx, query, args)
+}
+
+func (s *Storage) CollectDataExport(ctx context.Context, exportID int, chatPartKey string) (*model.DataExport, error) {
+	query, args, err := sq.
+		Update(DataExportTable).
+		Set(DataExportStatusLabel, model.DataExportCollected).
+		Set(DataExportChatPartKeyLabel, chatPartKey).
+		Set(DataExportUpdatedAtLabel, time.Now().UTC()).
+		Where(sq.Eq{DataExportIDLabel: exportID}).
+		Where(sq.Eq{DataExportStatusLabel: model.DataExportRequested}).
+		Where(sq.Expr(deletedATIsNullDataExportFilter)).
+		Suffix(ReturningSuffix).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build sql: %w", err)
+	}
+
+	return s.doAndReturnDataExport(ctx, query, args)
+}
+
+func (s *Storage) StartDataExport(ctx context.Context, exportID int) (*model.DataExport, error) {
+	query, args, err := sq.
+		Update(DataExportTable).
+		Set(DataExportStatusLabel, model.DataExportAssembling).
+		Set(DataExportUpdatedAtLabel, time.Now().UTC()).
+		Where(sq.Eq{DataExportIDLabel: exportID}).
+		Where(sq.Expr(deletedATIsNullDataExportFilter)).
+		Suffix(ReturningSuffix).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build sql: %w", err)
+	}
+
+	return s.doAndReturnDataExport(ctx, query, args)
+}
+
+func (s *Storage) CompleteDataExport(ctx context.Context, exportID int, objectKey string) (*model.DataExport, error) {
+	query, args, err := sq.
+		Update(DataExportTable).
+		Set(DataExportStatusLabel, model.DataExportDone).
+		Set(DataExportObjectKeyLabel, objectKey).
+		Set(DataExportUpdatedAtLabel, time.Now().UTC()).
+		Where(sq.Eq{DataExportIDLabel: exportID}).
+		Where(sq.Expr(deletedATIsNullDataExportFilter)).
+		Suffix(ReturningSuffix).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build sql: %w", err)
+	}
+
+	return s.doAndReturnDataExport(ctx, query, args)
+}
+
+func (s *Storage) FailDataExport(ctx context.Context, exportID int, reason string) (*model.DataExport, error) {
+	query, args, err := sq.
+		Update(DataExportTable).
+		Set(DataExportStatusLabel, model.DataExportFailed).
+		Set(DataExportErrorLabel, reason).
+		Set(DataExportUpdatedAtLabel, time.Now().UTC()).
+		Where(sq.Eq{DataExportIDLabel: exportID}).
+		Where(sq.NotEq{DataExportStatusLabel: []model.DataExportStatus{model.DataExportDone, model.DataExportFailed}}).
+		Where(sq.Expr(deletedATIsNullDataExportFilter)).
+		Suffix(ReturningSuffix).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build sql: %w", err)
+	}
+
+	return s.doAndReturnDataExport(ctx, query, args)
+}
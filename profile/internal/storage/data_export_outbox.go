@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/1ocknight/mess/profile/internal/model"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	deletedATIsNullDataExportOutboxFilter = fmt.Sprintf("%v %v", DataExportOutboxDeletedAtLabel, IsNullLabel)
+)
+
+func (s *Storage) AddDataExportOutbox(ctx context.Context, exportID int, operation model.DataExportOperation) (*model.DataExportOutbox, error) {
+	query, args, err := sq.
+		Insert(DataExportOutboxTable).
+		Columns(
+			DataExportOutboxExportIDLabel,
+			DataExportOutboxOperationLabel,
+		).
+		Values(exportID, operation).
+		Suffix(ReturningSuffix).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build sql: %w", err)
+	}
+
+	var entity DataExportOutboxEntity
+	err = sqlx.GetContext(ctx, s.exec, &entity, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("db get: %w", err)
+	}
+
+	return entity.ToModel(), nil
+}
+
+func (s *Storage) GetDataExportOutbox(ctx context.Context, limit int) ([]*model.DataExportOutbox, error) {
+	query, args, err := sq.
+		Select(AllLabelsSelect).
+		From(DataExportOutboxTable).
+		Where(sq.Expr(deletedATIsNullDataExportOutboxFilter)).
+		OrderBy(DataExportOutboxIDLabel).
+		Limit(uint64(limit)).
+		Suffix(SkipLocked).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build sql: %w", err)
+	}
+
+	var entities []*DataExportOutboxEntity
+	err = sqlx.SelectContext(ctx, s.exec, &entities, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("db select: %w", err)
+	}
+
+	return DataExportOutboxEntitiesToModels(entities), nil
+}
+
+func (s *Storage) DeleteDataExportOutbox(ctx context.Context, ids []int) ([]*model.DataExportOutbox, error) {
+	if len(ids) == 0 {
+		return []*model.DataExportOutbox{}, nil
+	}
+
+	query, args, err := sq.
+		Update(DataExportOutboxTable).
+		Set(DataExportOutboxDeletedAtLabel, time.Now().UTC()).
+		Where(sq.Eq{DataExportOutboxIDLabel: ids}).
+		Where(sq.Expr(deletedATIsNullDataExportOutboxFilter)).
+		Suffix(ReturningSuffix).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build sql: %w", err)
+	}
+
+	var entities []*DataExportOutboxEntity
+	err = sqlx.SelectContext(ctx, s.exec, &entities, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("db select: %w", err)
+	}
+
+	return DataExportOutboxEntitiesToModels(entities), nil
+}
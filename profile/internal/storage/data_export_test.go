@@ -0,0 +1,150 @@
+package storage_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/1ocknight/mess/profile/internal/model"
+	p "github.com/1ocknight/mess/profile/internal/storage"
+)
+
+func TestStorage_DataExport_Lifecycle(t *testing.T) {
+	s, err := p.New(CFG)
+	if err != nil {
+		t.Fatalf("could not construct receiver type: %v", err)
+	}
+	defer cleanupDB(t)
+
+	export, err := s.DataExport().AddDataExport(t.Context(), InitProfiles[0].SubjectID)
+	if err != nil {
+		t.Fatalf("add data export: %v", err)
+	}
+	if export.Status != model.DataExportRequested || export.SubjectID != InitProfiles[0].SubjectID {
+		t.Fatalf("not correct add, have: %v", export)
+	}
+
+	_, err = s.DataExport().GetCollectedDataExport(t.Context(), time.Now().UTC())
+	if !errors.Is(err, p.ErrNoRows) {
+		t.Fatalf("wait no rows, have: %v", err)
+	}
+
+	collected, err := s.DataExport().CollectDataExport(t.Context(), export.ID, "chat/part.zip")
+	if err != nil {
+		t.Fatalf("collect data export: %v", err)
+	}
+	if collected.Status != model.DataExportCollected || *collected.ChatPartKey != "chat/part.zip" {
+		t.Fatalf("not correct collect, have: %v", collected)
+	}
+
+	_, err = s.DataExport().CollectDataExport(t.Context(), export.ID, "chat/part.zip")
+	if !errors.Is(err, p.ErrNoRows) {
+		t.Fatalf("second collect must be no rows, have: %v", err)
+	}
+
+	pending, err := s.DataExport().GetCollectedDataExport(t.Context(), time.Now().UTC())
+	if err != nil {
+		t.Fatalf("get collected data export: %v", err)
+	}
+	if pending.ID != export.ID {
+		t.Fatalf("wait export %v, have: %v", export.ID, pending.ID)
+	}
+
+	done, err := s.DataExport().CompleteDataExport(t.Context(), export.ID, "data/export.zip")
+	if err != nil {
+		t.Fatalf("complete data export: %v", err)
+	}
+	if done.Status != model.DataExportDone || *done.ObjectKey != "data/export.zip" {
+		t.Fatalf("not correct complete, have: %v", done)
+	}
+
+	_, err = s.DataExport().FailDataExport(t.Context(), export.ID, "late failure")
+	if !errors.Is(err, p.ErrNoRows) {
+		t.Fatalf("fail after done must be no rows, have: %v", err)
+	}
+}
+
+func TestStorage_DataExportOutbox(t *testing.T) {
+	s, err := p.New(CFG)
+	if err != nil {
+		t.Fatalf("could not construct receiver type: %v", err)
+	}
+	defer cleanupDB(t)
+
+	export, err := s.DataExport().AddDataExport(t.Context(), InitProfiles[0].SubjectID)
+	if err != nil {
+		t.Fatalf("add data export: %v", err)
+	}
+
+	_, err = s.DataExportOutbox().AddDataExportOutbox(t.Context(), export.ID, model.DataExportRequestOperation)
+	if err != nil {
+		t.Fatalf("add outbox: %v", err)
+	}
+	_, err = s.DataExportOutbox().AddDataExportOutbox(t.Context(), export.ID, model.DataExportNotifyOperation)
+	if err != nil {
+		t.Fatalf("add outbox: %v", err)
+	}
+
+	outboxes, err := s.DataExportOutbox().GetDataExportOutbox(t.Context(), 10)
+	if err != nil {
+		t.Fatalf("get outbox: %v", err)
+	}
+	if len(outboxes) != 2 ||
+		outboxes[0].Operation != model.DataExportRequestOperation ||
+		outboxes[1].Operation != model.DataExportNotifyOperation {
+		t.Fatalf("not correct outbox: %v", outboxes)
+	}
+
+	deleted, err := s.DataExportOutbox().DeleteDataExportOutbox(t.Context(), model.GetDataExportOutboxIDs(outboxes))
+	if err != nil {
+		t.Fatalf("delete outbox: %v", err)
+	}
+	if len(deleted) != 2 {
+		t.Fatalf("wait 2 deleted, have: %v", len(deleted))
+	}
+
+	outboxes, err = s.DataExportOutbox().GetDataExportOutbox(t.Context(), 10)
+	if err != nil {
+		t.Fatalf("get outbox: %v", err)
+	}
+	if len(outboxes) != 0 {
+		t.Fatalf("wait empty outbox, have: %v", outboxes)
+	}
+}
+
+func TestStorage_StartDataExport(t *testing.T) {
+	s, err := p.New(CFG)
+	if err != nil {
+		t.Fatalf("could not construct receiver type: %v", err)
+	}
+	defer cleanupDB(t)
+
+	export, err := s.DataExport().AddDataExport(t.Context(), InitProfiles[0].SubjectID)
+	if err != nil {
+		t.Fatalf("add data export: %v", err)
+	}
+	if _, err := s.DataExport().CollectDataExport(t.Context(), export.ID, "chat/part.zip"); err != nil {
+		t.Fatalf("collect data export: %v", err)
+	}
+
+	started, err := s.DataExport().StartDataExport(t.Context(), export.ID)
+	if err != nil {
+		t.Fatalf("start data export: %v", err)
+	}
+	if started.Status != model.DataExportAssembling {
+		t.Fatalf("not assembling, have: %v", started)
+	}
+
+	_, err = s.DataExport().GetCollectedDataExport(t.Context(), started.UpdatedAt.Add(-time.Minute))
+	if !errors.Is(err, p.ErrNoRows) {
+		t.Fatalf("assembling export must be skipped, have: %v", err)
+	}
+
+	stale, err := s.DataExport().GetCollectedDataExport(t.Context(), started.UpdatedAt.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("get stale data export: %v", err)
+	}
+	if stale.ID != export.ID {
+		t.Fatalf("wait export %v, have: %v", export.ID, stale.ID)
+	}
+}
@@ -1,29 +1,80 @@
 package storage
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/1ocknight/mess/profile/internal/model"
 )
 
 type ProfileEntity struct {
-	SubjectID string     `db:"subject_id"`
-	Alias     string     `db:"alias"`
-	Version   int        `db:"version"`
-	UpdatedAt time.Time  `db:"updated_at"`
-	CreatedAt time.Time  `db:"created_at"`
-	DeletedAt *time.Time `db:"deleted_at"`
+	SubjectID           string             `db:"subject_id"`
+	Alias               string             `db:"alias"`
+	Username            *string            `db:"username"`
+	UsernameChangedAt   *time.Time         `db:"username_changed_at"`
+	DisplayName         *string            `db:"display_name"`
+	Bio                 *string            `db:"bio"`
+	StatusText          *string            `db:"status_text"`
+	StatusEmoji         *string            `db:"status_emoji"`
+	StatusExpiresAt     *time.Time         `db:"status_expires_at"`
+	Links               ProfileLinksEntity `db:"links"`
+	LastSeenAt          *time.Time         `db:"last_seen_at"`
+	SearchVisibility    string             `db:"search_visibility"`
+	AvatarVisibility    string             `db:"avatar_visibility"`
+	LastSeenVisibility  string             `db:"last_seen_visibility"`
+	NewChatPermission   string             `db:"new_chat_permission"`
+	Version             int                `db:"version"`
+	AvatarKey           *string            `db:"avatar_key"`
+	AvatarVersion       int                `db:"avatar_version"`
+	PendingAvatarKey    *string            `db:"pending_avatar_key"`
+	LegacyAvatarChecked bool               `db:"legacy_avatar_checked"`
+	UpdatedAt           time.Time          `db:"updated_at"`
+	CreatedAt           time.Time          `db:"created_at"`
+	DeletedAt           *time.Time         `db:"deleted_at"`
 }
 
 func (p *ProfileEntity) ToModel() *model.Profile {
-	return &model.Profile{
-		SubjectID: p.SubjectID,
-		Alias:     p.Alias,
-		Version:   p.Version,
-		UpdatedAt: p.UpdatedAt,
-		CreatedAt: p.CreatedAt,
-		DeletedAt: p.DeletedAt,
+	res := &model.Profile{
+		SubjectID:         p.SubjectID,
+		Alias:             p.Alias,
+		Username:          p.Username,
+		UsernameChangedAt: p.UsernameChangedAt,
+		Links:             p.Links.ToModel(),
+		LastSeenAt:        p.LastSeenAt,
+		Privacy: model.Privacy{
+			Search:   model.Audience(p.SearchVisibility),
+			Avatar:   model.Audience(p.AvatarVisibility),
+			LastSeen: model.Audience(p.LastSeenVisibility),
+			NewChat:  model.Audience(p.NewChatPermission),
+		},
+		Version:             p.Version,
+		AvatarKey:           p.AvatarKey,
+		AvatarVersion:       p.AvatarVersion,
+		PendingAvatarKey:    p.PendingAvatarKey,
+		LegacyAvatarChecked: p.LegacyAvatarChecked,
+		UpdatedAt:           p.UpdatedAt,
+		CreatedAt:           p.CreatedAt,
+		DeletedAt:           p.DeletedAt,
+	}
+	if p.DisplayName != nil {
+		res.DisplayName = *p.DisplayName
 	}
+	if p.Bio != nil {
+		res.Bio = *p.Bio
+	}
+	if p.StatusText != nil || p.StatusEmoji != nil {
+		res.Status = &model.ProfileStatus{ExpiresAt: p.StatusExpiresAt}
+		if p.StatusText != nil {
+			res.Status.Text = *p.StatusText
+		}
+		if p.StatusEmoji != nil {
+			res.Status.Emoji = *p.StatusEmoji
+		}
+	}
+
+	return res
 }
 
 func (p *ProfileEntity) Key() *string {
@@ -38,15 +89,66 @@ func ProfileEntitiesToModels(entities []*ProfileEntity) []*model.Profile {
 	return models
 }
 
+// ProfileMatchEntity is a row of the search, Rank is scanned as text to keep the exact numeric value.
+type ProfileMatchEntity struct {
+	ProfileEntity
+	Rank string `db:"search_rank"`
+}
+
+type ProfileLinkEntity struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// ProfileLinksEntity is stored as a jsonb array.
+type ProfileLinksEntity []ProfileLinkEntity
+
+func ProfileLinksModelToEntity(links []model.ProfileLink) ProfileLinksEntity {
+	res := make(ProfileLinksEntity, 0, len(links))
+	for _, link := range links {
+		res = append(res, ProfileLinkEntity{Title: link.Title, URL: link.URL})
+	}
+	return res
+}
+
+func (l ProfileLinksEntity) ToModel() []model.ProfileLink {
+	res := make([]model.ProfileLink, 0, len(l))
+	for _, link := range l {
+		res = append(res, model.ProfileLink{Title: link.Title, URL: link.URL})
+	}
+	return res
+}
+
+func (l ProfileLinksEntity) Value() (driver.Value, error) {
+	if l == nil {
+		l = ProfileLinksEntity{}
+	}
+	return json.Marshal(l)
+}
+
+func (l *ProfileLinksEntity) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, l)
+	case string:
+		return json.Unmarshal([]byte(v), l)
+	default:
+		return fmt.Errorf("scan links: unexpected type %T", src)
+	}
+}
+
 type AvatarOutboxEntity struct {
-	SubjectID string     `db:"subject_id"`
+	Key       string     `db:"key"`
 	CreatedAt time.Time  `db:"created_at"`
 	DeletedAt *time.Time `db:"deleted_at"`
 }
 
 func (p *AvatarOutboxEntity) ToModel() *model.AvatarOutbox {
 	return &model.AvatarOutbox{
-		SubjectID: p.SubjectID,
+		Key:       p.Key,
 		DeletedAt: p.DeletedAt,
 		CreatedAt: p.CreatedAt,
 	}
@@ -59,3 +161,135 @@ func AvatarOutboxEntitiesToModels(entities []*AvatarOutboxEntity) []*model.Avata
 	}
 	return models
 }
+
+type DataExportEntity struct {
+	ID          int        `db:"id"`
+	SubjectID   string     `db:"subject_id"`
+	Status      int        `db:"status"`
+	ChatPartKey *string    `db:"chat_part_key"`
+	ObjectKey   *string    `db:"object_key"`
+	Error       *string    `db:"error"`
+	CreatedAt   time.Time  `db:"created_at"`
+	UpdatedAt   time.Time  `db:"updated_at"`
+	DeletedAt   *time.Time `db:"deleted_at"`
+}
+
+func (e *DataExportEntity) ToModel() *model.DataExport {
+	return &model.DataExport{
+		ID:          e.ID,
+		SubjectID:   e.SubjectID,
+		Status:      model.DataExportStatus(e.Status),
+		ChatPartKey: e.ChatPartKey,
+		ObjectKey:   e.ObjectKey,
+		Error:       e.Error,
+		CreatedAt:   e.CreatedAt,
+		UpdatedAt:   e.UpdatedAt,
+		DeletedAt:   e.DeletedAt,
+	}
+}
+
+func DataExportEntitiesToModels(entities []*DataExportEntity) []*model.DataExport {
+	models := make([]*model.DataExport, 0, len(entities))
+	for _, entity := range entities {
+		models = append(models, entity.ToModel())
+	}
+	return models
+}
+
+type DataExportOutboxEntity struct {
+	ID        int        `db:"id"`
+	ExportID  int        `db:"export_id"`
+	Operation int        `db:"operation"`
+	CreatedAt time.Time  `db:"created_at"`
+	DeletedAt *time.Time `db:"deleted_at"`
+}
+
+func (e *DataExportOutboxEntity) ToModel() *model.DataExportOutbox {
+	return &model.DataExportOutbox{
+		ID:        e.ID,
+		ExportID:  e.ExportID,
+		Operation: model.DataExportOperation(e.Operation),
+		CreatedAt: e.CreatedAt,
+		DeletedAt: e.DeletedAt,
+	}
+}
+
+func DataExportOutboxEntitiesToModels(entities []*DataExportOutboxEntity) []*model.DataExportOutbox {
+	models := make([]*model.DataExportOutbox, 0, len(entities))
+	for _, entity := range entities {
+		models = append(models, entity.ToModel())
+	}
+	return models
+}
+
+type ProfileOutboxEntity struct {
+	ID        int        `db:"id"`
+	SubjectID string     `db:"subject_id"`
+	Operation int        `db:"operation"`
+	CreatedAt time.Time  `db:"created_at"`
+	DeletedAt *time.Time `db:"deleted_at"`
+}
+
+func (e *ProfileOutboxEntity) ToModel() *model.ProfileOutbox {
+	return &model.ProfileOutbox{
+		ID:        e.ID,
+		SubjectID: e.SubjectID,
+		Operation: model.ProfileOperation(e.Operation),
+		CreatedAt: e.CreatedAt,
+		DeletedAt: e.DeletedAt,
+	}
+}
+
+func ProfileOutboxEntitiesToModels(entities []*ProfileOutboxEntity) []*model.ProfileOutbox {
+	models := make([]*model.ProfileOutbox, 0, len(entities))
+	for _, entity := range entities {
+		models = append(models, entity.ToModel())
+	}
+	return models
+}
+
+type UsernameHoldEntity struct {
+	Username  string    `db:"username"`
+	SubjectID string    `db:"subject_id"`
+	HeldUntil time.Time `db:"held_until"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+func (u *UsernameHoldEntity) ToModel() *model.UsernameHold {
+	return &model.UsernameHold{
+		Username:  u.Username,
+		SubjectID: u.SubjectID,
+		HeldUntil: u.HeldUntil,
+		CreatedAt: u.CreatedAt,
+	}
+}
+
+type ContactEntity struct {
+	SubjectID        string    `db:"subject_id"`
+	ContactSubjectID string    `db:"contact_subject_id"`
+	Nickname         *string   `db:"nickname"`
+	CreatedAt        time.Time `db:"created_at"`
+	UpdatedAt        time.Time `db:"updated_at"`
+}
+
+func (c *ContactEntity) ToModel() *model.Contact {
+	res := &model.Contact{
+		SubjectID:        c.SubjectID,
+		ContactSubjectID: c.ContactSubjectID,
+		CreatedAt:        c.CreatedAt,
+		UpdatedAt:        c.UpdatedAt,
+	}
+	if c.Nickname != nil {
+		res.Nickname = *c.Nickname
+	}
+
+	return res
+}
+
+func ContactEntitiesToModels(entities []*ContactEntity) []*model.Contact {
+	models := make([]*model.Contact, 0, len(entities))
+	for _, entity := range entities {
+		models = append(models, entity.ToModel())
+	}
+	return models
+}
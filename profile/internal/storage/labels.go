@@ -12,25 +12,95 @@ const (
 type Table = string
 
 const (
-	ProfileTable         Table = "profile"
-	AvatarKeyOutboxTable Table = "avatar_outbox"
+	ProfileTable          Table = "profile"
+	AvatarKeyOutboxTable  Table = "avatar_outbox"
+	DataExportTable       Table = "data_export"
+	DataExportOutboxTable Table = "data_export_outbox"
+	UsernameHoldTable     Table = "username_hold"
+	ContactTable          Table = "contact"
+	ProfileOutboxTable    Table = "profile_outbox"
 )
 
 type Label = string
 
 // Profile
 const (
-	ProfileSubjectIDLabel Label = "subject_id"
-	ProfileAliasLabel     Label = "alias"
-	ProfileVersionLabel   Label = "version"
-	ProfileUpdatedAtLabel Label = "updated_at"
-	ProfileCreatedAtLabel Label = "created_at"
-	ProfileDeletedAtLabel Label = "deleted_at"
+	ProfileSubjectIDLabel        Label = "subject_id"
+	ProfileAliasLabel            Label = "alias"
+	ProfileUsernameLabel         Label = "username"
+	ProfileUsernameChangedLabel  Label = "username_changed_at"
+	ProfileDisplayNameLabel      Label = "display_name"
+	ProfileBioLabel              Label = "bio"
+	ProfileStatusTextLabel       Label = "status_text"
+	ProfileStatusEmojiLabel      Label = "status_emoji"
+	ProfileStatusExpiresAtLabel  Label = "status_expires_at"
+	ProfileLinksLabel            Label = "links"
+	ProfileLastSeenAtLabel       Label = "last_seen_at"
+	ProfileSearchVisLabel        Label = "search_visibility"
+	ProfileAvatarVisLabel        Label = "avatar_visibility"
+	ProfileLastSeenVisLabel      Label = "last_seen_visibility"
+	ProfileNewChatLabel          Label = "new_chat_permission"
+	ProfileVersionLabel          Label = "version"
+	ProfileAvatarKeyLabel        Label = "avatar_key"
+	ProfileAvatarVerLabel        Label = "avatar_version"
+	ProfilePendingAvatarKeyLabel Label = "pending_avatar_key"
+	ProfileLegacyAvatarLabel     Label = "legacy_avatar_checked"
+	ProfileUpdatedAtLabel        Label = "updated_at"
+	ProfileCreatedAtLabel        Label = "created_at"
+	ProfileDeletedAtLabel        Label = "deleted_at"
 )
 
 // AvatarKeyOutbox
 const (
-	AvatarKeyOutboxSubjectIDLabel Label = "subject_id"
+	AvatarKeyOutboxKeyLabel       Label = "key"
 	AvatarKeyOutboxDeletedAtLabel Label = "deleted_at"
 	AvatarKeyOutboxCreatedAtLabel Label = "created_at"
 )
+
+// DataExport
+const (
+	DataExportIDLabel          Label = "id"
+	DataExportSubjectIDLabel   Label = "subject_id"
+	DataExportStatusLabel      Label = "status"
+	DataExportChatPartKeyLabel Label = "chat_part_key"
+	DataExportObjectKeyLabel   Label = "object_key"
+	DataExportErrorLabel       Label = "error"
+	DataExportCreatedAtLabel   Label = "created_at"
+	DataExportUpdatedAtLabel   Label = "updated_at"
+	DataExportDeletedAtLabel   Label = "deleted_at"
+)
+
+// DataExportOutbox
+const (
+	DataExportOutboxIDLabel        Label = "id"
+	DataExportOutboxExportIDLabel  Label = "export_id"
+	DataExportOutboxOperationLabel Label = "operation"
+	DataExportOutboxCreatedAtLabel Label = "created_at"
+	DataExportOutboxDeletedAtLabel Label = "deleted_at"
+)
+
+// ProfileOutbox
+const (
+	ProfileOutboxIDLabel        Label = "id"
+	ProfileOutboxSubjectIDLabel Label = "subject_id"
+	ProfileOutboxOperationLabel Label = "operation"
+	ProfileOutboxCreatedAtLabel Label = "created_at"
+	ProfileOutboxDeletedAtLabel Label = "deleted_at"
+)
+
+// UsernameHold
+const (
+	UsernameHoldUsernameLabel  Label = "username"
+	UsernameHoldSubjectIDLabel Label = "subject_id"
+	UsernameHoldHeldUntilLabel Label = "held_until"
+	UsernameHoldCreatedAtLabel Label = "created_at"
+)
+
+// Contact
+const (
+	ContactSubjectIDLabel        Label = "subject_id"
+	ContactContactSubjectIDLabel Label = "contact_subject_id"
+	ContactNicknameLabel         Label = "nickname"
+	ContactCreatedAtLabel        Label = "created_at"
+	ContactUpdatedAtLabel        Label = "updated_at"
+)
@@ -41,13 +41,13 @@ var InitProfiles = []*model.Profile{
 
 var InitAvatarKeys = []*model.AvatarOutbox{
 	{
-		SubjectID: "subject_id1",
+		Key: "subject_id1/1-key",
 	},
 	{
-		SubjectID: "subject_id2",
+		Key: "subject_id2/1-key",
 	},
 	{
-		SubjectID: "subject_id3",
+		Key: "subject_id3/1-key",
 	},
 }
 
@@ -121,6 +121,26 @@ func cleanupDB(t *testing.T) {
 	if err != nil {
 		t.Fatalf("cleanup db: %v", err)
 	}
+
+	_, err = db.Exec(fmt.Sprintf("TRUNCATE TABLE %s, %s RESTART IDENTITY CASCADE", p.DataExportTable, p.DataExportOutboxTable))
+	if err != nil {
+		t.Fatalf("cleanup db: %v", err)
+	}
+
+	_, err = db.Exec(fmt.Sprintf("TRUNCATE TABLE %s RESTART IDENTITY CASCADE", p.UsernameHoldTable))
+	if err != nil {
+		t.Fatalf("cleanup db: %v", err)
+	}
+
+	_, err = db.Exec(fmt.Sprintf("TRUNCATE TABLE %s RESTART IDENTITY CASCADE", p.ContactTable))
+	if err != nil {
+		t.Fatalf("cleanup db: %v", err)
+	}
+
+	_, err = db.Exec(fmt.Sprintf("TRUNCATE TABLE %s RESTART IDENTITY CASCADE", p.ProfileOutboxTable))
+	if err != nil {
+		t.Fatalf("cleanup db: %v", err)
+	}
 }
 
 func initData(t *testing.T) {
@@ -139,7 +159,7 @@ func initData(t *testing.T) {
 	}
 
 	for _, k := range InitAvatarKeys {
-		_, err = s.AvatarOutbox().AddKey(t.Context(), k.SubjectID)
+		_, err = s.AvatarOutbox().AddKey(t.Context(), k.Key)
 		if err != nil {
 			t.Fatalf("init add: %v", err)
 		}
@@ -164,7 +184,7 @@ func TestStorage_Transaction_Commit(t *testing.T) {
 		t.Fatalf("delete avatar key: %v", err)
 	}
 
-	key, err := s.AvatarOutbox().AddKey(t.Context(), InitProfiles[0].SubjectID)
+	key, err := s.AvatarOutbox().AddKey(t.Context(), InitAvatarKeys[0].Key)
 	if err != nil {
 		t.Fatalf("add key: %v", err)
 	}
@@ -213,7 +233,7 @@ func TestStorage_Transaction_Rollback(t *testing.T) {
 		t.Fatalf("delete avatar key: %v", err)
 	}
 
-	_, err = s.AvatarOutbox().AddKey(t.Context(), InitProfiles[0].SubjectID)
+	_, err = s.AvatarOutbox().AddKey(t.Context(), InitAvatarKeys[0].Key)
 	if err != nil {
 		t.Fatalf("add key: %v", err)
 	}
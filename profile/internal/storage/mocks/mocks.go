@@ -7,6 +7,7 @@ package storagemocks
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	model "github.com/1ocknight/mess/profile/internal/model"
 	storage "github.com/1ocknight/mess/profile/internal/storage"
@@ -51,34 +52,49 @@ func (mr *MockProfileMockRecorder) AddProfile(ctx, subjID, alias interface{}) *g
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddProfile", reflect.TypeOf((*MockProfile)(nil).AddProfile), ctx, subjID, alias)
 }
 
-// DeleteAvatarKey mocks base method.
-func (m *MockProfile) DeleteAvatarKey(ctx context.Context, subjID string) (*model.Profile, error) {
+// DeleteProfile mocks base method.
+func (m *MockProfile) DeleteProfile(ctx context.Context, subjID string) (*model.Profile, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteAvatarKey", ctx, subjID)
+	ret := m.ctrl.Call(m, "DeleteProfile", ctx, subjID)
 	ret0, _ := ret[0].(*model.Profile)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// DeleteAvatarKey indicates an expected call of DeleteAvatarKey.
-func (mr *MockProfileMockRecorder) DeleteAvatarKey(ctx, subjID interface{}) *gomock.Call {
+// DeleteProfile indicates an expected call of DeleteProfile.
+func (mr *MockProfileMockRecorder) DeleteProfile(ctx, subjID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAvatarKey", reflect.TypeOf((*MockProfile)(nil).DeleteAvatarKey), ctx, subjID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteProfile", reflect.TypeOf((*MockProfile)(nil).DeleteProfile), ctx, subjID)
 }
 
-// DeleteProfile mocks base method.
-func (m *MockProfile) DeleteProfile(ctx context.Context, subjID string) (*model.Profile, error) {
+// GetDeletedSubjectIDs mocks base method.
+func (m *MockProfile) GetDeletedSubjectIDs(ctx context.Context, subjIDs []string) ([]string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteProfile", ctx, subjID)
-	ret0, _ := ret[0].(*model.Profile)
+	ret := m.ctrl.Call(m, "GetDeletedSubjectIDs", ctx, subjIDs)
+	ret0, _ := ret[0].([]string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// DeleteProfile indicates an expected call of DeleteProfile.
-func (mr *MockProfileMockRecorder) DeleteProfile(ctx, subjID interface{}) *gomock.Call {
+// GetDeletedSubjectIDs indicates an expected call of GetDeletedSubjectIDs.
+func (mr *MockProfileMockRecorder) GetDeletedSubjectIDs(ctx, subjIDs interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteProfile", reflect.TypeOf((*MockProfile)(nil).DeleteProfile), ctx, subjID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDeletedSubjectIDs", reflect.TypeOf((*MockProfile)(nil).GetDeletedSubjectIDs), ctx, subjIDs)
+}
+
+// GetLegacyAvatarUnchecked mocks base method.
+func (m *MockProfile) GetLegacyAvatarUnchecked(ctx context.Context, limit int) ([]*model.Profile, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLegacyAvatarUnchecked", ctx, limit)
+	ret0, _ := ret[0].([]*model.Profile)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLegacyAvatarUnchecked indicates an expected call of GetLegacyAvatarUnchecked.
+func (mr *MockProfileMockRecorder) GetLegacyAvatarUnchecked(ctx, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLegacyAvatarUnchecked", reflect.TypeOf((*MockProfile)(nil).GetLegacyAvatarUnchecked), ctx, limit)
 }
 
 // GetProfileFromSubjectID mocks base method.
@@ -96,49 +112,153 @@ func (mr *MockProfileMockRecorder) GetProfileFromSubjectID(ctx, subjID interface
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProfileFromSubjectID", reflect.TypeOf((*MockProfile)(nil).GetProfileFromSubjectID), ctx, subjID)
 }
 
+// GetProfileFromUsername mocks base method.
+func (m *MockProfile) GetProfileFromUsername(ctx context.Context, username string) (*model.Profile, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetProfileFromUsername", ctx, username)
+	ret0, _ := ret[0].(*model.Profile)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetProfileFromUsername indicates an expected call of GetProfileFromUsername.
+func (mr *MockProfileMockRecorder) GetProfileFromUsername(ctx, username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProfileFromUsername", reflect.TypeOf((*MockProfile)(nil).GetProfileFromUsername), ctx, username)
+}
+
 // GetProfilesFromAlias mocks base method.
-func (m *MockProfile) GetProfilesFromAlias(ctx context.Context, alias string, filter *storage.ProfilePaginationFilter) ([]*model.Profile, error) {
+func (m *MockProfile) GetProfilesFromAlias(ctx context.Context, viewerID, alias string, filter *storage.ProfilePaginationFilter) ([]*model.ProfileMatch, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetProfilesFromAlias", ctx, alias, filter)
-	ret0, _ := ret[0].([]*model.Profile)
+	ret := m.ctrl.Call(m, "GetProfilesFromAlias", ctx, viewerID, alias, filter)
+	ret0, _ := ret[0].([]*model.ProfileMatch)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetProfilesFromAlias indicates an expected call of GetProfilesFromAlias.
-func (mr *MockProfileMockRecorder) GetProfilesFromAlias(ctx, alias, filter interface{}) *gomock.Call {
+func (mr *MockProfileMockRecorder) GetProfilesFromAlias(ctx, viewerID, alias, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProfilesFromAlias", reflect.TypeOf((*MockProfile)(nil).GetProfilesFromAlias), ctx, viewerID, alias, filter)
+}
+
+// GetProfilesFromSubjectIDs mocks base method.
+func (m *MockProfile) GetProfilesFromSubjectIDs(ctx context.Context, subjIDs []string) ([]*model.Profile, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetProfilesFromSubjectIDs", ctx, subjIDs)
+	ret0, _ := ret[0].([]*model.Profile)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetProfilesFromSubjectIDs indicates an expected call of GetProfilesFromSubjectIDs.
+func (mr *MockProfileMockRecorder) GetProfilesFromSubjectIDs(ctx, subjIDs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProfilesFromSubjectIDs", reflect.TypeOf((*MockProfile)(nil).GetProfilesFromSubjectIDs), ctx, subjIDs)
+}
+
+// SetLegacyAvatarChecked mocks base method.
+func (m *MockProfile) SetLegacyAvatarChecked(ctx context.Context, subjectID string, key *string) (*model.Profile, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetLegacyAvatarChecked", ctx, subjectID, key)
+	ret0, _ := ret[0].(*model.Profile)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetLegacyAvatarChecked indicates an expected call of SetLegacyAvatarChecked.
+func (mr *MockProfileMockRecorder) SetLegacyAvatarChecked(ctx, subjectID, key interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProfilesFromAlias", reflect.TypeOf((*MockProfile)(nil).GetProfilesFromAlias), ctx, alias, filter)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetLegacyAvatarChecked", reflect.TypeOf((*MockProfile)(nil).SetLegacyAvatarChecked), ctx, subjectID, key)
 }
 
 // UpdateAvatarKey mocks base method.
-func (m *MockProfile) UpdateAvatarKey(ctx context.Context, subjID, avatarKey string) (*model.Profile, error) {
+func (m *MockProfile) UpdateAvatarKey(ctx context.Context, subjectID string, prevAvatarVersion int, key *string) (*model.Profile, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateAvatarKey", ctx, subjID, avatarKey)
+	ret := m.ctrl.Call(m, "UpdateAvatarKey", ctx, subjectID, prevAvatarVersion, key)
 	ret0, _ := ret[0].(*model.Profile)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // UpdateAvatarKey indicates an expected call of UpdateAvatarKey.
-func (mr *MockProfileMockRecorder) UpdateAvatarKey(ctx, subjID, avatarKey interface{}) *gomock.Call {
+func (mr *MockProfileMockRecorder) UpdateAvatarKey(ctx, subjectID, prevAvatarVersion, key interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAvatarKey", reflect.TypeOf((*MockProfile)(nil).UpdateAvatarKey), ctx, subjID, avatarKey)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAvatarKey", reflect.TypeOf((*MockProfile)(nil).UpdateAvatarKey), ctx, subjectID, prevAvatarVersion, key)
+}
+
+// UpdateLastSeen mocks base method.
+func (m *MockProfile) UpdateLastSeen(ctx context.Context, subjectID string, seenAt time.Time, minInterval time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateLastSeen", ctx, subjectID, seenAt, minInterval)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateLastSeen indicates an expected call of UpdateLastSeen.
+func (mr *MockProfileMockRecorder) UpdateLastSeen(ctx, subjectID, seenAt, minInterval interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateLastSeen", reflect.TypeOf((*MockProfile)(nil).UpdateLastSeen), ctx, subjectID, seenAt, minInterval)
+}
+
+// UpdatePendingAvatarKey mocks base method.
+func (m *MockProfile) UpdatePendingAvatarKey(ctx context.Context, subjectID string, key *string) (*model.Profile, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePendingAvatarKey", ctx, subjectID, key)
+	ret0, _ := ret[0].(*model.Profile)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdatePendingAvatarKey indicates an expected call of UpdatePendingAvatarKey.
+func (mr *MockProfileMockRecorder) UpdatePendingAvatarKey(ctx, subjectID, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePendingAvatarKey", reflect.TypeOf((*MockProfile)(nil).UpdatePendingAvatarKey), ctx, subjectID, key)
+}
+
+// UpdatePrivacy mocks base method.
+func (m *MockProfile) UpdatePrivacy(ctx context.Context, subjectID string, prevVersion int, privacy *model.Privacy) (*model.Profile, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePrivacy", ctx, subjectID, prevVersion, privacy)
+	ret0, _ := ret[0].(*model.Profile)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdatePrivacy indicates an expected call of UpdatePrivacy.
+func (mr *MockProfileMockRecorder) UpdatePrivacy(ctx, subjectID, prevVersion, privacy interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePrivacy", reflect.TypeOf((*MockProfile)(nil).UpdatePrivacy), ctx, subjectID, prevVersion, privacy)
 }
 
 // UpdateProfileMetadata mocks base method.
-func (m *MockProfile) UpdateProfileMetadata(ctx context.Context, subjectID string, prevVersion int, alias string) (*model.Profile, error) {
+func (m *MockProfile) UpdateProfileMetadata(ctx context.Context, subjectID string, prevVersion int, meta *model.ProfileMetadata) (*model.Profile, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateProfileMetadata", ctx, subjectID, prevVersion, alias)
+	ret := m.ctrl.Call(m, "UpdateProfileMetadata", ctx, subjectID, prevVersion, meta)
 	ret0, _ := ret[0].(*model.Profile)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // UpdateProfileMetadata indicates an expected call of UpdateProfileMetadata.
-func (mr *MockProfileMockRecorder) UpdateProfileMetadata(ctx, subjectID, prevVersion, alias interface{}) *gomock.Call {
+func (mr *MockProfileMockRecorder) UpdateProfileMetadata(ctx, subjectID, prevVersion, meta interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateProfileMetadata", reflect.TypeOf((*MockProfile)(nil).UpdateProfileMetadata), ctx, subjectID, prevVersion, alias)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateProfileMetadata", reflect.TypeOf((*MockProfile)(nil).UpdateProfileMetadata), ctx, subjectID, prevVersion, meta)
+}
+
+// UpdateUsername mocks base method.
+func (m *MockProfile) UpdateUsername(ctx context.Context, subjectID string, prevVersion int, username string) (*model.Profile, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUsername", ctx, subjectID, prevVersion, username)
+	ret0, _ := ret[0].(*model.Profile)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateUsername indicates an expected call of UpdateUsername.
+func (mr *MockProfileMockRecorder) UpdateUsername(ctx, subjectID, prevVersion, username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUsername", reflect.TypeOf((*MockProfile)(nil).UpdateUsername), ctx, subjectID, prevVersion, username)
 }
 
 // MockAvatarOutbox is a mock of AvatarOutbox interface.
@@ -165,18 +285,18 @@ func (m *MockAvatarOutbox) EXPECT() *MockAvatarOutboxMockRecorder {
 }
 
 // AddKey mocks base method.
-func (m *MockAvatarOutbox) AddKey(ctx context.Context, subjectID, key string) (*model.AvatarOutbox, error) {
+func (m *MockAvatarOutbox) AddKey(ctx context.Context, key string) (*model.AvatarOutbox, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "AddKey", ctx, subjectID, key)
+	ret := m.ctrl.Call(m, "AddKey", ctx, key)
 	ret0, _ := ret[0].(*model.AvatarOutbox)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // AddKey indicates an expected call of AddKey.
-func (mr *MockAvatarOutboxMockRecorder) AddKey(ctx, subjectID, key interface{}) *gomock.Call {
+func (mr *MockAvatarOutboxMockRecorder) AddKey(ctx, key interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddKey", reflect.TypeOf((*MockAvatarOutbox)(nil).AddKey), ctx, subjectID, key)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddKey", reflect.TypeOf((*MockAvatarOutbox)(nil).AddKey), ctx, key)
 }
 
 // DeleteKeys mocks base method.
@@ -209,6 +329,466 @@ func (mr *MockAvatarOutboxMockRecorder) GetKeys(ctx, limit interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetKeys", reflect.TypeOf((*MockAvatarOutbox)(nil).GetKeys), ctx, limit)
 }
 
+// MockDataExport is a mock of DataExport interface.
+type MockDataExport struct {
+	ctrl     *gomock.Controller
+	recorder *MockDataExportMockRecorder
+}
+
+// MockDataExportMockRecorder is the mock recorder for MockDataExport.
+type MockDataExportMockRecorder struct {
+	mock *MockDataExport
+}
+
+// NewMockDataExport creates a new mock instance.
+func NewMockDataExport(ctrl *gomock.Controller) *MockDataExport {
+	mock := &MockDataExport{ctrl: ctrl}
+	mock.recorder = &MockDataExportMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDataExport) EXPECT() *MockDataExportMockRecorder {
+	return m.recorder
+}
+
+// AddDataExport mocks base method.
+func (m *MockDataExport) AddDataExport(ctx context.Context, subjectID string) (*model.DataExport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddDataExport", ctx, subjectID)
+	ret0, _ := ret[0].(*model.DataExport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddDataExport indicates an expected call of AddDataExport.
+func (mr *MockDataExportMockRecorder) AddDataExport(ctx, subjectID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddDataExport", reflect.TypeOf((*MockDataExport)(nil).AddDataExport), ctx, subjectID)
+}
+
+// CollectDataExport mocks base method.
+func (m *MockDataExport) CollectDataExport(ctx context.Context, exportID int, chatPartKey string) (*model.DataExport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CollectDataExport", ctx, exportID, chatPartKey)
+	ret0, _ := ret[0].(*model.DataExport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CollectDataExport indicates an expected call of CollectDataExport.
+func (mr *MockDataExportMockRecorder) CollectDataExport(ctx, exportID, chatPartKey interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CollectDataExport", reflect.TypeOf((*MockDataExport)(nil).CollectDataExport), ctx, exportID, chatPartKey)
+}
+
+// CompleteDataExport mocks base method.
+func (m *MockDataExport) CompleteDataExport(ctx context.Context, exportID int, objectKey string) (*model.DataExport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CompleteDataExport", ctx, exportID, objectKey)
+	ret0, _ := ret[0].(*model.DataExport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CompleteDataExport indicates an expected call of CompleteDataExport.
+func (mr *MockDataExportMockRecorder) CompleteDataExport(ctx, exportID, objectKey interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompleteDataExport", reflect.TypeOf((*MockDataExport)(nil).CompleteDataExport), ctx, exportID, objectKey)
+}
+
+// FailDataExport mocks base method.
+func (m *MockDataExport) FailDataExport(ctx context.Context, exportID int, reason string) (*model.DataExport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FailDataExport", ctx, exportID, reason)
+	ret0, _ := ret[0].(*model.DataExport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FailDataExport indicates an expected call of FailDataExport.
+func (mr *MockDataExportMockRecorder) FailDataExport(ctx, exportID, reason interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FailDataExport", reflect.TypeOf((*MockDataExport)(nil).FailDataExport), ctx, exportID, reason)
+}
+
+// GetCollectedDataExport mocks base method.
+func (m *MockDataExport) GetCollectedDataExport(ctx context.Context, assemblingBefore time.Time) (*model.DataExport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCollectedDataExport", ctx, assemblingBefore)
+	ret0, _ := ret[0].(*model.DataExport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCollectedDataExport indicates an expected call of GetCollectedDataExport.
+func (mr *MockDataExportMockRecorder) GetCollectedDataExport(ctx, assemblingBefore interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCollectedDataExport", reflect.TypeOf((*MockDataExport)(nil).GetCollectedDataExport), ctx, assemblingBefore)
+}
+
+// GetDataExportByID mocks base method.
+func (m *MockDataExport) GetDataExportByID(ctx context.Context, exportID int) (*model.DataExport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDataExportByID", ctx, exportID)
+	ret0, _ := ret[0].(*model.DataExport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDataExportByID indicates an expected call of GetDataExportByID.
+func (mr *MockDataExportMockRecorder) GetDataExportByID(ctx, exportID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDataExportByID", reflect.TypeOf((*MockDataExport)(nil).GetDataExportByID), ctx, exportID)
+}
+
+// GetDataExportsByIDs mocks base method.
+func (m *MockDataExport) GetDataExportsByIDs(ctx context.Context, exportIDs []int) ([]*model.DataExport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDataExportsByIDs", ctx, exportIDs)
+	ret0, _ := ret[0].([]*model.DataExport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDataExportsByIDs indicates an expected call of GetDataExportsByIDs.
+func (mr *MockDataExportMockRecorder) GetDataExportsByIDs(ctx, exportIDs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDataExportsByIDs", reflect.TypeOf((*MockDataExport)(nil).GetDataExportsByIDs), ctx, exportIDs)
+}
+
+// StartDataExport mocks base method.
+func (m *MockDataExport) StartDataExport(ctx context.Context, exportID int) (*model.DataExport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StartDataExport", ctx, exportID)
+	ret0, _ := ret[0].(*model.DataExport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// StartDataExport indicates an expected call of StartDataExport.
+func (mr *MockDataExportMockRecorder) StartDataExport(ctx, exportID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartDataExport", reflect.TypeOf((*MockDataExport)(nil).StartDataExport), ctx, exportID)
+}
+
+// MockDataExportOutbox is a mock of DataExportOutbox interface.
+type MockDataExportOutbox struct {
+	ctrl     *gomock.Controller
+	recorder *MockDataExportOutboxMockRecorder
+}
+
+// MockDataExportOutboxMockRecorder is the mock recorder for MockDataExportOutbox.
+type MockDataExportOutboxMockRecorder struct {
+	mock *MockDataExportOutbox
+}
+
+// NewMockDataExportOutbox creates a new mock instance.
+func NewMockDataExportOutbox(ctrl *gomock.Controller) *MockDataExportOutbox {
+	mock := &MockDataExportOutbox{ctrl: ctrl}
+	mock.recorder = &MockDataExportOutboxMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDataExportOutbox) EXPECT() *MockDataExportOutboxMockRecorder {
+	return m.recorder
+}
+
+// AddDataExportOutbox mocks base method.
+func (m *MockDataExportOutbox) AddDataExportOutbox(ctx context.Context, exportID int, operation model.DataExportOperation) (*model.DataExportOutbox, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddDataExportOutbox", ctx, exportID, operation)
+	ret0, _ := ret[0].(*model.DataExportOutbox)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddDataExportOutbox indicates an expected call of AddDataExportOutbox.
+func (mr *MockDataExportOutboxMockRecorder) AddDataExportOutbox(ctx, exportID, operation interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddDataExportOutbox", reflect.TypeOf((*MockDataExportOutbox)(nil).AddDataExportOutbox), ctx, exportID, operation)
+}
+
+// DeleteDataExportOutbox mocks base method.
+func (m *MockDataExportOutbox) DeleteDataExportOutbox(ctx context.Context, ids []int) ([]*model.DataExportOutbox, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteDataExportOutbox", ctx, ids)
+	ret0, _ := ret[0].([]*model.DataExportOutbox)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteDataExportOutbox indicates an expected call of DeleteDataExportOutbox.
+func (mr *MockDataExportOutboxMockRecorder) DeleteDataExportOutbox(ctx, ids interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteDataExportOutbox", reflect.TypeOf((*MockDataExportOutbox)(nil).DeleteDataExportOutbox), ctx, ids)
+}
+
+// GetDataExportOutbox mocks base method.
+func (m *MockDataExportOutbox) GetDataExportOutbox(ctx context.Context, limit int) ([]*model.DataExportOutbox, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDataExportOutbox", ctx, limit)
+	ret0, _ := ret[0].([]*model.DataExportOutbox)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDataExportOutbox indicates an expected call of GetDataExportOutbox.
+func (mr *MockDataExportOutboxMockRecorder) GetDataExportOutbox(ctx, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDataExportOutbox", reflect.TypeOf((*MockDataExportOutbox)(nil).GetDataExportOutbox), ctx, limit)
+}
+
+// MockProfileOutbox is a mock of ProfileOutbox interface.
+type MockProfileOutbox struct {
+	ctrl     *gomock.Controller
+	recorder *MockProfileOutboxMockRecorder
+}
+
+// MockProfileOutboxMockRecorder is the mock recorder for MockProfileOutbox.
+type MockProfileOutboxMockRecorder struct {
+	mock *MockProfileOutbox
+}
+
+// NewMockProfileOutbox creates a new mock instance.
+func NewMockProfileOutbox(ctrl *gomock.Controller) *MockProfileOutbox {
+	mock := &MockProfileOutbox{ctrl: ctrl}
+	mock.recorder = &MockProfileOutboxMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockProfileOutbox) EXPECT() *MockProfileOutboxMockRecorder {
+	return m.recorder
+}
+
+// AddProfileOutbox mocks base method.
+func (m *MockProfileOutbox) AddProfileOutbox(ctx context.Context, subjectID string, operation model.ProfileOperation) (*model.ProfileOutbox, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddProfileOutbox", ctx, subjectID, operation)
+	ret0, _ := ret[0].(*model.ProfileOutbox)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddProfileOutbox indicates an expected call of AddProfileOutbox.
+func (mr *MockProfileOutboxMockRecorder) AddProfileOutbox(ctx, subjectID, operation interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddProfileOutbox", reflect.TypeOf((*MockProfileOutbox)(nil).AddProfileOutbox), ctx, subjectID, operation)
+}
+
+// DeleteProfileOutbox mocks base method.
+func (m *MockProfileOutbox) DeleteProfileOutbox(ctx context.Context, ids []int) ([]*model.ProfileOutbox, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteProfileOutbox", ctx, ids)
+	ret0, _ := ret[0].([]*model.ProfileOutbox)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteProfileOutbox indicates an expected call of DeleteProfileOutbox.
+func (mr *MockProfileOutboxMockRecorder) DeleteProfileOutbox(ctx, ids interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteProfileOutbox", reflect.TypeOf((*MockProfileOutbox)(nil).DeleteProfileOutbox), ctx, ids)
+}
+
+// GetProfileOutbox mocks base method.
+func (m *MockProfileOutbox) GetProfileOutbox(ctx context.Context, limit int) ([]*model.ProfileOutbox, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetProfileOutbox", ctx, limit)
+	ret0, _ := ret[0].([]*model.ProfileOutbox)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetProfileOutbox indicates an expected call of GetProfileOutbox.
+func (mr *MockProfileOutboxMockRecorder) GetProfileOutbox(ctx, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProfileOutbox", reflect.TypeOf((*MockProfileOutbox)(nil).GetProfileOutbox), ctx, limit)
+}
+
+// MockUsernameHold is a mock of UsernameHold interface.
+type MockUsernameHold struct {
+	ctrl     *gomock.Controller
+	recorder *MockUsernameHoldMockRecorder
+}
+
+// MockUsernameHoldMockRecorder is the mock recorder for MockUsernameHold.
+type MockUsernameHoldMockRecorder struct {
+	mock *MockUsernameHold
+}
+
+// NewMockUsernameHold creates a new mock instance.
+func NewMockUsernameHold(ctrl *gomock.Controller) *MockUsernameHold {
+	mock := &MockUsernameHold{ctrl: ctrl}
+	mock.recorder = &MockUsernameHoldMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUsernameHold) EXPECT() *MockUsernameHoldMockRecorder {
+	return m.recorder
+}
+
+// AddHold mocks base method.
+func (m *MockUsernameHold) AddHold(ctx context.Context, username, subjectID string, heldUntil time.Time) (*model.UsernameHold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddHold", ctx, username, subjectID, heldUntil)
+	ret0, _ := ret[0].(*model.UsernameHold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddHold indicates an expected call of AddHold.
+func (mr *MockUsernameHoldMockRecorder) AddHold(ctx, username, subjectID, heldUntil interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddHold", reflect.TypeOf((*MockUsernameHold)(nil).AddHold), ctx, username, subjectID, heldUntil)
+}
+
+// GetActiveHold mocks base method.
+func (m *MockUsernameHold) GetActiveHold(ctx context.Context, username string) (*model.UsernameHold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetActiveHold", ctx, username)
+	ret0, _ := ret[0].(*model.UsernameHold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetActiveHold indicates an expected call of GetActiveHold.
+func (mr *MockUsernameHoldMockRecorder) GetActiveHold(ctx, username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetActiveHold", reflect.TypeOf((*MockUsernameHold)(nil).GetActiveHold), ctx, username)
+}
+
+// MockContact is a mock of Contact interface.
+type MockContact struct {
+	ctrl     *gomock.Controller
+	recorder *MockContactMockRecorder
+}
+
+// MockContactMockRecorder is the mock recorder for MockContact.
+type MockContactMockRecorder struct {
+	mock *MockContact
+}
+
+// NewMockContact creates a new mock instance.
+func NewMockContact(ctrl *gomock.Controller) *MockContact {
+	mock := &MockContact{ctrl: ctrl}
+	mock.recorder = &MockContactMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockContact) EXPECT() *MockContactMockRecorder {
+	return m.recorder
+}
+
+// AddContact mocks base method.
+func (m *MockContact) AddContact(ctx context.Context, subjectID, contactSubjectID, nickname string) (*model.Contact, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddContact", ctx, subjectID, contactSubjectID, nickname)
+	ret0, _ := ret[0].(*model.Contact)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddContact indicates an expected call of AddContact.
+func (mr *MockContactMockRecorder) AddContact(ctx, subjectID, contactSubjectID, nickname interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddContact", reflect.TypeOf((*MockContact)(nil).AddContact), ctx, subjectID, contactSubjectID, nickname)
+}
+
+// DeleteContact mocks base method.
+func (m *MockContact) DeleteContact(ctx context.Context, subjectID, contactSubjectID string) (*model.Contact, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteContact", ctx, subjectID, contactSubjectID)
+	ret0, _ := ret[0].(*model.Contact)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteContact indicates an expected call of DeleteContact.
+func (mr *MockContactMockRecorder) DeleteContact(ctx, subjectID, contactSubjectID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteContact", reflect.TypeOf((*MockContact)(nil).DeleteContact), ctx, subjectID, contactSubjectID)
+}
+
+// DeleteSubjectContacts mocks base method.
+func (m *MockContact) DeleteSubjectContacts(ctx context.Context, subjectID string) ([]*model.Contact, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteSubjectContacts", ctx, subjectID)
+	ret0, _ := ret[0].([]*model.Contact)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteSubjectContacts indicates an expected call of DeleteSubjectContacts.
+func (mr *MockContactMockRecorder) DeleteSubjectContacts(ctx, subjectID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSubjectContacts", reflect.TypeOf((*MockContact)(nil).DeleteSubjectContacts), ctx, subjectID)
+}
+
+// GetContact mocks base method.
+func (m *MockContact) GetContact(ctx context.Context, subjectID, contactSubjectID string) (*model.Contact, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetContact", ctx, subjectID, contactSubjectID)
+	ret0, _ := ret[0].(*model.Contact)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetContact indicates an expected call of GetContact.
+func (mr *MockContactMockRecorder) GetContact(ctx, subjectID, contactSubjectID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetContact", reflect.TypeOf((*MockContact)(nil).GetContact), ctx, subjectID, contactSubjectID)
+}
+
+// GetContacts mocks base method.
+func (m *MockContact) GetContacts(ctx context.Context, subjectID string, filter *storage.ContactPaginationFilter) ([]*model.Contact, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetContacts", ctx, subjectID, filter)
+	ret0, _ := ret[0].([]*model.Contact)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetContacts indicates an expected call of GetContacts.
+func (mr *MockContactMockRecorder) GetContacts(ctx, subjectID, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetContacts", reflect.TypeOf((*MockContact)(nil).GetContacts), ctx, subjectID, filter)
+}
+
+// GetOwnersWithContact mocks base method.
+func (m *MockContact) GetOwnersWithContact(ctx context.Context, ownerIDs []string, contactSubjectID string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOwnersWithContact", ctx, ownerIDs, contactSubjectID)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOwnersWithContact indicates an expected call of GetOwnersWithContact.
+func (mr *MockContactMockRecorder) GetOwnersWithContact(ctx, ownerIDs, contactSubjectID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOwnersWithContact", reflect.TypeOf((*MockContact)(nil).GetOwnersWithContact), ctx, ownerIDs, contactSubjectID)
+}
+
+// GetSubjectContacts mocks base method.
+func (m *MockContact) GetSubjectContacts(ctx context.Context, subjectID string) ([]*model.Contact, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSubjectContacts", ctx, subjectID)
+	ret0, _ := ret[0].([]*model.Contact)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSubjectContacts indicates an expected call of GetSubjectContacts.
+func (mr *MockContactMockRecorder) GetSubjectContacts(ctx, subjectID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSubjectContacts", reflect.TypeOf((*MockContact)(nil).GetSubjectContacts), ctx, subjectID)
+}
+
 // MockService is a mock of Service interface.
 type MockService struct {
 	ctrl     *gomock.Controller
@@ -246,6 +826,48 @@ func (mr *MockServiceMockRecorder) AvatarOutbox() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AvatarOutbox", reflect.TypeOf((*MockService)(nil).AvatarOutbox))
 }
 
+// Contact mocks base method.
+func (m *MockService) Contact() storage.Contact {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Contact")
+	ret0, _ := ret[0].(storage.Contact)
+	return ret0
+}
+
+// Contact indicates an expected call of Contact.
+func (mr *MockServiceMockRecorder) Contact() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Contact", reflect.TypeOf((*MockService)(nil).Contact))
+}
+
+// DataExport mocks base method.
+func (m *MockService) DataExport() storage.DataExport {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DataExport")
+	ret0, _ := ret[0].(storage.DataExport)
+	return ret0
+}
+
+// DataExport indicates an expected call of DataExport.
+func (mr *MockServiceMockRecorder) DataExport() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DataExport", reflect.TypeOf((*MockService)(nil).DataExport))
+}
+
+// DataExportOutbox mocks base method.
+func (m *MockService) DataExportOutbox() storage.DataExportOutbox {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DataExportOutbox")
+	ret0, _ := ret[0].(storage.DataExportOutbox)
+	return ret0
+}
+
+// DataExportOutbox indicates an expected call of DataExportOutbox.
+func (mr *MockServiceMockRecorder) DataExportOutbox() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DataExportOutbox", reflect.TypeOf((*MockService)(nil).DataExportOutbox))
+}
+
 // Profile mocks base method.
 func (m *MockService) Profile() storage.Profile {
 	m.ctrl.T.Helper()
@@ -260,6 +882,34 @@ func (mr *MockServiceMockRecorder) Profile() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Profile", reflect.TypeOf((*MockService)(nil).Profile))
 }
 
+// ProfileOutbox mocks base method.
+func (m *MockService) ProfileOutbox() storage.ProfileOutbox {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ProfileOutbox")
+	ret0, _ := ret[0].(storage.ProfileOutbox)
+	return ret0
+}
+
+// ProfileOutbox indicates an expected call of ProfileOutbox.
+func (mr *MockServiceMockRecorder) ProfileOutbox() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProfileOutbox", reflect.TypeOf((*MockService)(nil).ProfileOutbox))
+}
+
+// UsernameHold mocks base method.
+func (m *MockService) UsernameHold() storage.UsernameHold {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UsernameHold")
+	ret0, _ := ret[0].(storage.UsernameHold)
+	return ret0
+}
+
+// UsernameHold indicates an expected call of UsernameHold.
+func (mr *MockServiceMockRecorder) UsernameHold() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UsernameHold", reflect.TypeOf((*MockService)(nil).UsernameHold))
+}
+
 // WithTransaction mocks base method.
 func (m *MockService) WithTransaction(ctx context.Context) (storage.ServiceTransaction, error) {
 	m.ctrl.T.Helper()
@@ -326,6 +976,48 @@ func (mr *MockServiceTransactionMockRecorder) Commit() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Commit", reflect.TypeOf((*MockServiceTransaction)(nil).Commit))
 }
 
+// Contact mocks base method.
+func (m *MockServiceTransaction) Contact() storage.Contact {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Contact")
+	ret0, _ := ret[0].(storage.Contact)
+	return ret0
+}
+
+// Contact indicates an expected call of Contact.
+func (mr *MockServiceTransactionMockRecorder) Contact() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Contact", reflect.TypeOf((*MockServiceTransaction)(nil).Contact))
+}
+
+// DataExport mocks base method.
+func (m *MockServiceTransaction) DataExport() storage.DataExport {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DataExport")
+	ret0, _ := ret[0].(storage.DataExport)
+	return ret0
+}
+
+// DataExport indicates an expected call of DataExport.
+func (mr *MockServiceTransactionMockRecorder) DataExport() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DataExport", reflect.TypeOf((*MockServiceTransaction)(nil).DataExport))
+}
+
+// DataExportOutbox mocks base method.
+func (m *MockServiceTransaction) DataExportOutbox() storage.DataExportOutbox {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DataExportOutbox")
+	ret0, _ := ret[0].(storage.DataExportOutbox)
+	return ret0
+}
+
+// DataExportOutbox indicates an expected call of DataExportOutbox.
+func (mr *MockServiceTransactionMockRecorder) DataExportOutbox() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DataExportOutbox", reflect.TypeOf((*MockServiceTransaction)(nil).DataExportOutbox))
+}
+
 // Profile mocks base method.
 func (m *MockServiceTransaction) Profile() storage.Profile {
 	m.ctrl.T.Helper()
@@ -340,6 +1032,20 @@ func (mr *MockServiceTransactionMockRecorder) Profile() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Profile", reflect.TypeOf((*MockServiceTransaction)(nil).Profile))
 }
 
+// ProfileOutbox mocks base method.
+func (m *MockServiceTransaction) ProfileOutbox() storage.ProfileOutbox {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ProfileOutbox")
+	ret0, _ := ret[0].(storage.ProfileOutbox)
+	return ret0
+}
+
+// ProfileOutbox indicates an expected call of ProfileOutbox.
+func (mr *MockServiceTransactionMockRecorder) ProfileOutbox() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProfileOutbox", reflect.TypeOf((*MockServiceTransaction)(nil).ProfileOutbox))
+}
+
 // Rollback mocks base method.
 func (m *MockServiceTransaction) Rollback() error {
 	m.ctrl.T.Helper()
@@ -353,3 +1059,17 @@ func (mr *MockServiceTransactionMockRecorder) Rollback() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Rollback", reflect.TypeOf((*MockServiceTransaction)(nil).Rollback))
 }
+
+// UsernameHold mocks base method.
+func (m *MockServiceTransaction) UsernameHold() storage.UsernameHold {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UsernameHold")
+	ret0, _ := ret[0].(storage.UsernameHold)
+	return ret0
+}
+
+// UsernameHold indicates an expected call of UsernameHold.
+func (mr *MockServiceTransactionMockRecorder) UsernameHold() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UsernameHold", reflect.TypeOf((*MockServiceTransaction)(nil).UsernameHold))
+}
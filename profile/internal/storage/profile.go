@@ -3,10 +3,10 @@ package storage
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/1ocknight/mess/profile/internal/model"
-	"github.com/1ocknight/mess/shared/postgres"
 
 	sq "github.com/Masterminds/squirrel"
 
@@ -15,6 +15,10 @@ import (
 
 var (
 	deletedATIsNullProfileFilter = fmt.Sprintf("%v %v", ProfileDeletedAtLabel, IsNullLabel)
+	lowerUsernameFilter          = fmt.Sprintf("lower(%v) = lower(?)", ProfileUsernameLabel)
+	// usernameChangedAtValue moves the change time only when the username differs not just in the letter case
+	usernameChangedAtValue = fmt.Sprintf("CASE WHEN lower(%v) IS DISTINCT FROM lower(?) THEN ? ELSE %v END",
+		ProfileUsernameLabel, ProfileUsernameChangedLabel)
 )
 
 func (s *Storage) doAndReturnProfile(ctx context.Context, query string, args []interface{}) (*model.Profile, error) {
@@ -73,33 +77,245 @@ func (s *Storage) GetProfileFromSubjectID(ctx context.Context, subjID string) (*
 	return s.doAndReturnProfile(ctx, query, args)
 }
 
-func (s *Storage) GetProfilesFromAlias(ctx context.Context, alias string, filter *ProfilePaginationFilter) ([]*model.Profile, error) {
-	b := sq.
+// GetProfileFromUsername finds the profile by exact username, the case does not matter.
+func (s *Storage) GetProfileFromUsername(ctx context.Context, username string) (*model.Profile, error) {
+	query, args, err := sq.
+		Select(AllLabelsSelect).
+		From(ProfileTable).
+		Where(sq.Expr(lowerUsernameFilter, username)).
+		Where(sq.Expr(deletedATIsNullProfileFilter)).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build sql: %w", err)
+	}
+
+	return s.doAndReturnProfile(ctx, query, args)
+}
+
+// GetProfilesFromSubjectIDs returns alive profiles in no particular order, unknown ids are skipped.
+func (s *Storage) GetProfilesFromSubjectIDs(ctx context.Context, subjIDs []string) ([]*model.Profile, error) {
+	query, args, err := sq.
 		Select(AllLabelsSelect).
 		From(ProfileTable).
-		Where(sq.Like{ProfileAliasLabel: alias + "%"}).
+		Where(sq.Eq{ProfileSubjectIDLabel: subjIDs}).
+		Where(sq.Expr(deletedATIsNullProfileFilter)).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build sql: %w", err)
+	}
+
+	return s.doAndReturnProfiles(ctx, query, args)
+}
+
+// GetDeletedSubjectIDs returns the ids that have a deleted profile, the order and duplicates are not kept.
+func (s *Storage) GetDeletedSubjectIDs(ctx context.Context, subjIDs []string) ([]string, error) {
+	query, args, err := sq.
+		Select(ProfileSubjectIDLabel).
+		Distinct().
+		From(ProfileTable).
+		Where(sq.Eq{ProfileSubjectIDLabel: subjIDs}).
+		Where(sq.NotEq{ProfileDeletedAtLabel: nil}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build sql: %w", err)
+	}
+
+	var ids []string
+	if err := sqlx.SelectContext(ctx, s.exec, &ids, query, args...); err != nil {
+		return nil, fmt.Errorf("db select: %w", err)
+	}
+
+	return ids, nil
+}
+
+// Boosts are added to the trigram similarity of the alias or the nickname, which is from 0 to 1.
+const (
+	SearchExactBoost   = 1
+	SearchPrefixBoost  = 0.5
+	SearchContactBoost = 1
+)
+
+const searchRankLabel Label = "search_rank"
+
+var (
+	searchContactJoin = fmt.Sprintf("%v ON %v.%v = ? AND %v.%v = %v.%v",
+		ContactTable,
+		ContactTable, ContactSubjectIDLabel,
+		ContactTable, ContactContactSubjectIDLabel, ProfileTable, ProfileSubjectIDLabel,
+	)
+
+	searchAlias    = fmt.Sprintf("lower(%v.%v)", ProfileTable, ProfileAliasLabel)
+	searchNickname = fmt.Sprintf("lower(%v.%v)", ContactTable, ContactNicknameLabel)
+
+	// the alias and the nickname are matched in separate branches of a union, an OR of the two
+	// across the join could not use the trigram index of either and scanned every profile
+	searchAliasFilter    = fmt.Sprintf("(%[1]v %% lower(?) OR %[1]v LIKE lower(?))", searchAlias)
+	searchNicknameFilter = fmt.Sprintf("(%[1]v %% lower(?) OR %[1]v LIKE lower(?))", searchNickname)
+	searchCandidateJoin  = fmt.Sprintf("%[1]v ON %[1]v.%[2]v = candidates.%[2]v", ProfileTable, ProfileSubjectIDLabel)
+	// the rank is rounded to numeric, so the value in the cursor compares equal to the one computed again
+	searchRankSelect = fmt.Sprintf(`ROUND((
+		GREATEST(similarity(%[1]v, lower(?)), COALESCE(similarity(%[2]v, lower(?)), 0))
+		+ CASE WHEN %[1]v = lower(?) OR %[2]v = lower(?) THEN %[3]v ELSE 0 END
+		+ CASE WHEN %[1]v LIKE lower(?) OR %[2]v LIKE lower(?) THEN %[4]v ELSE 0 END
+		+ CASE WHEN %[5]v.%[6]v IS NOT NULL THEN %[7]v ELSE 0 END
+	)::numeric, 6) AS %[8]v`,
+		searchAlias, searchNickname, SearchExactBoost, SearchPrefixBoost,
+		ContactTable, ContactContactSubjectIDLabel, SearchContactBoost, searchRankLabel)
+
+	likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+)
+
+// GetProfilesFromAlias is a case-insensitive fuzzy search by alias and by the nickname the viewer gave
+// the profile, profiles hidden from the search are skipped. Profiles are ordered by the rank, the most relevant
+// first when filter.Asc is false, and then by subject_id, filter.LastSortValue is the rank of the last profile.
+func (s *Storage) GetProfilesFromAlias(ctx context.Context, viewerID string, alias string, filter *ProfilePaginationFilter) ([]*model.ProfileMatch, error) {
+	order := AscSortLabel
+	cmp := ">"
+	if !filter.Asc {
+		order = DescSortLabel
+		cmp = "<"
+	}
+
+	prefix := likeEscaper.Replace(alias) + "%"
+
+	// profiles matched by alias go through the trigram index, the contacts of the viewer matched
+	// by nickname through the primary key of contact
+	byNickname := sq.
+		Select(fmt.Sprintf("%v AS %v", ContactContactSubjectIDLabel, ProfileSubjectIDLabel)).
+		From(ContactTable).
+		Where(sq.Eq{ContactSubjectIDLabel: viewerID}).
+		Where(sq.Expr(searchNicknameFilter, alias, prefix))
+	candidates := sq.
+		Select(ProfileSubjectIDLabel).
+		From(ProfileTable).
+		Where(sq.Expr(searchAliasFilter, alias, prefix)).
+		Where(sq.Expr(deletedATIsNullProfileFilter)).
+		SuffixExpr(byNickname.Prefix("UNION"))
+
+	found := sq.
+		Select(fmt.Sprintf("%v.%v", ProfileTable, AllLabelsSelect)).
+		Column(searchRankSelect, alias, alias, alias, alias, prefix, prefix).
+		FromSelect(candidates, "candidates").
+		Join(searchCandidateJoin).
+		LeftJoin(searchContactJoin, viewerID).
+		Where(sq.Eq{ProfileSearchVisLabel: model.AudienceEveryone}).
 		Where(sq.Expr(deletedATIsNullProfileFilter))
 
-	storageFilter := &postgres.PaginationFilter[string]{
-		Limit:     filter.Limit,
-		Asc:       filter.Asc,
-		SortLabel: filter.SortLabel,
-		IDLabel:   ProfileSubjectIDLabel,
-		LastID:    filter.LastID,
+	b := sq.
+		Select(AllLabelsSelect).
+		FromSelect(found, "found").
+		OrderBy(
+			fmt.Sprintf("%v %v", searchRankLabel, order),
+			fmt.Sprintf("%v %v", ProfileSubjectIDLabel, order),
+		)
+
+	if filter.LastID != nil {
+		b = b.Where(sq.Expr(
+			fmt.Sprintf("(%v, %v) %v (?::numeric, ?)", searchRankLabel, ProfileSubjectIDLabel, cmp),
+			filter.LastSortValue, *filter.LastID,
+		))
 	}
 
-	query, args, err := postgres.MakeQueryWithPagination(ctx, b, storageFilter)
+	query, args, err := b.
+		Limit(uint64(filter.Limit)).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
 	if err != nil {
 		return nil, fmt.Errorf("build sql: %w", err)
 	}
 
-	return s.doAndReturnProfiles(ctx, query, args)
+	var entities []*ProfileMatchEntity
+	if err := sqlx.SelectContext(ctx, s.exec, &entities, query, args...); err != nil {
+		return nil, fmt.Errorf("db select: %w", err)
+	}
+
+	res := make([]*model.ProfileMatch, 0, len(entities))
+	for _, entity := range entities {
+		res = append(res, &model.ProfileMatch{Profile: entity.ToModel(), Rank: entity.Rank})
+	}
+
+	return res, nil
+}
+
+// UpdateProfileMetadata replaces everything the subject edits at once, empty fields become NULL.
+func (s *Storage) UpdateProfileMetadata(ctx context.Context, subjectID string, prevVersion int, meta *model.ProfileMetadata) (*model.Profile, error) {
+	var statusText, statusEmoji *string
+	var statusExpiresAt *time.Time
+	if meta.Status != nil {
+		statusText, statusEmoji = nullString(meta.Status.Text), nullString(meta.Status.Emoji)
+		statusExpiresAt = meta.Status.ExpiresAt
+	}
+
+	query, args, err := sq.
+		Update(ProfileTable).
+		Set(ProfileAliasLabel, meta.Alias).
+		Set(ProfileDisplayNameLabel, nullString(meta.DisplayName)).
+		Set(ProfileBioLabel, nullString(meta.Bio)).
+		Set(ProfileStatusTextLabel, statusText).
+		Set(ProfileStatusEmojiLabel, statusEmoji).
+		Set(ProfileStatusExpiresAtLabel, statusExpiresAt).
+		Set(ProfileLinksLabel, ProfileLinksModelToEntity(meta.Links)).
+		Set(ProfileVersionLabel, prevVersion+1).
+		Set(ProfileUpdatedAtLabel, time.Now().UTC()).
+		Where(sq.Eq{ProfileSubjectIDLabel: subjectID}).
+		Where(sq.Eq{ProfileVersionLabel: prevVersion}).
+		Where(sq.Expr(deletedATIsNullProfileFilter)).
+		Suffix(ReturningSuffix).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build sql: %w", err)
+	}
+
+	return s.doAndReturnProfile(ctx, query, args)
+}
+
+func nullString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// UpdateUsername sets the username with the profile version check, a username of another alive profile
+// gives ErrUniqueViolation. The username change time is kept when only the letter case changes.
+func (s *Storage) UpdateUsername(ctx context.Context, subjectID string, prevVersion int, username string) (*model.Profile, error) {
+	now := time.Now().UTC()
+	query, args, err := sq.
+		Update(ProfileTable).
+		Set(ProfileUsernameLabel, username).
+		Set(ProfileUsernameChangedLabel, sq.Expr(usernameChangedAtValue, username, now)).
+		Set(ProfileVersionLabel, prevVersion+1).
+		Set(ProfileUpdatedAtLabel, now).
+		Where(sq.Eq{ProfileSubjectIDLabel: subjectID}).
+		Where(sq.Eq{ProfileVersionLabel: prevVersion}).
+		Where(sq.Expr(deletedATIsNullProfileFilter)).
+		Suffix(ReturningSuffix).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build sql: %w", err)
+	}
+
+	profile, err := s.doAndReturnProfile(ctx, query, args)
+	if isUniqueViolation(err) {
+		return nil, fmt.Errorf("%w: %w", ErrUniqueViolation, err)
+	}
+
+	return profile, err
 }
 
-func (s *Storage) UpdateProfileMetadata(ctx context.Context, subjectID string, prevVersion int, alias string) (*model.Profile, error) {
+// UpdatePrivacy replaces the privacy settings with the profile version check.
+func (s *Storage) UpdatePrivacy(ctx context.Context, subjectID string, prevVersion int, privacy *model.Privacy) (*model.Profile, error) {
 	query, args, err := sq.
 		Update(ProfileTable).
-		Set(ProfileAliasLabel, alias).
+		Set(ProfileSearchVisLabel, privacy.Search).
+		Set(ProfileAvatarVisLabel, privacy.Avatar).
+		Set(ProfileLastSeenVisLabel, privacy.LastSeen).
+		Set(ProfileNewChatLabel, privacy.NewChat).
 		Set(ProfileVersionLabel, prevVersion+1).
 		Set(ProfileUpdatedAtLabel, time.Now().UTC()).
 		Where(sq.Eq{ProfileSubjectIDLabel: subjectID}).
@@ -115,6 +331,112 @@ func (s *Storage) UpdateProfileMetadata(ctx context.Context, subjectID string, p
 	return s.doAndReturnProfile(ctx, query, args)
 }
 
+// UpdateLastSeen is not a change of the profile, the version and updated_at stay as they are.
+// The time is moved only if the stored one is older than minInterval, so replicas write it once an interval.
+func (s *Storage) UpdateLastSeen(ctx context.Context, subjectID string, seenAt time.Time, minInterval time.Duration) error {
+	query, args, err := sq.
+		Update(ProfileTable).
+		Set(ProfileLastSeenAtLabel, seenAt).
+		Where(sq.Eq{ProfileSubjectIDLabel: subjectID}).
+		Where(sq.Or{
+			sq.Eq{ProfileLastSeenAtLabel: nil},
+			sq.Lt{ProfileLastSeenAtLabel: seenAt.Add(-minInterval)},
+		}).
+		Where(sq.Expr(deletedATIsNullProfileFilter)).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("build sql: %w", err)
+	}
+
+	if _, err := s.exec.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("db exec: %w", err)
+	}
+
+	return nil
+}
+
+// GetLegacyAvatarUnchecked locks profiles, deleted ones too, whose legacy avatar is not checked yet.
+func (s *Storage) GetLegacyAvatarUnchecked(ctx context.Context, limit int) ([]*model.Profile, error) {
+	query, args, err := sq.
+		Select(AllLabelsSelect).
+		From(ProfileTable).
+		Where(sq.Eq{ProfileLegacyAvatarLabel: false}).
+		OrderBy(ProfileSubjectIDLabel).
+		Limit(uint64(limit)).
+		Suffix(SkipLocked).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build sql: %w", err)
+	}
+
+	return s.doAndReturnProfiles(ctx, query, args)
+}
+
+// SetLegacyAvatarChecked marks the legacy avatar as checked, a not nil key becomes the avatar
+// of a profile without one. Like UpdateLastSeen it is not a change of the profile.
+func (s *Storage) SetLegacyAvatarChecked(ctx context.Context, subjectID string, key *string) (*model.Profile, error) {
+	b := sq.
+		Update(ProfileTable).
+		Set(ProfileLegacyAvatarLabel, true).
+		Where(sq.Eq{ProfileSubjectIDLabel: subjectID})
+	if key != nil {
+		b = b.Set(ProfileAvatarKeyLabel, sq.Expr(fmt.Sprintf("COALESCE(%v, ?)", ProfileAvatarKeyLabel), *key))
+	}
+
+	query, args, err := b.
+		Suffix(ReturningSuffix).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build sql: %w", err)
+	}
+
+	return s.doAndReturnProfile(ctx, query, args)
+}
+
+// UpdateAvatarKey replaces the avatar of the subject, nil key removes it. The avatar version is checked like
+// the profile version, so two changes at once do not lose an old key that has to be deleted.
+// A pending upload is dropped with any change, its processing then finds it stale.
+func (s *Storage) UpdateAvatarKey(ctx context.Context, subjectID string, prevAvatarVersion int, key *string) (*model.Profile, error) {
+	query, args, err := sq.
+		Update(ProfileTable).
+		Set(ProfileAvatarKeyLabel, key).
+		Set(ProfilePendingAvatarKeyLabel, nil).
+		Set(ProfileAvatarVerLabel, prevAvatarVersion+1).
+		Set(ProfileUpdatedAtLabel, time.Now().UTC()).
+		Where(sq.Eq{ProfileSubjectIDLabel: subjectID}).
+		Where(sq.Eq{ProfileAvatarVerLabel: prevAvatarVersion}).
+		Where(sq.Expr(deletedATIsNullProfileFilter)).
+		Suffix(ReturningSuffix).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build sql: %w", err)
+	}
+
+	return s.doAndReturnProfile(ctx, query, args)
+}
+
+// UpdatePendingAvatarKey remembers the upload that waits for processing, a newer upload replaces it.
+func (s *Storage) UpdatePendingAvatarKey(ctx context.Context, subjectID string, key *string) (*model.Profile, error) {
+	query, args, err := sq.
+		Update(ProfileTable).
+		Set(ProfilePendingAvatarKeyLabel, key).
+		Set(ProfileUpdatedAtLabel, time.Now().UTC()).
+		Where(sq.Eq{ProfileSubjectIDLabel: subjectID}).
+		Where(sq.Expr(deletedATIsNullProfileFilter)).
+		Suffix(ReturningSuffix).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build sql: %w", err)
+	}
+
+	return s.doAndReturnProfile(ctx, query, args)
+}
+
 func (s *Storage) DeleteProfile(ctx context.Context, subjID string) (*model.Profile, error) {
 	query, args, err := sq.
 		Update(ProfileTable).
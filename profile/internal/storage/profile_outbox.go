@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/1ocknight/mess/profile/internal/model"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	deletedATIsNullProfileOutboxFilter = fmt.Sprintf("%v %v", ProfileOutboxDeletedAtLabel, IsNullLabel)
+	// a row waits while an older row of the same subject is pending, so only one replica at a time
+	// publishes the events of a subject and they keep the order
+	oldestOfSubjectProfileOutboxFilter = fmt.Sprintf(
+		"NOT EXISTS (SELECT 1 FROM %v AS older WHERE older.%v = %v.%v AND older.%v < %v.%v AND older.%v %v)",
+		ProfileOutboxTable,
+		ProfileOutboxSubjectIDLabel, ProfileOutboxTable, ProfileOutboxSubjectIDLabel,
+		ProfileOutboxIDLabel, ProfileOutboxTable, ProfileOutboxIDLabel,
+		ProfileOutboxDeletedAtLabel, IsNullLabel,
+	)
+)
+
+func (s *Storage) AddProfileOutbox(ctx context.Context, subjectID string, operation model.ProfileOperation) (*model.ProfileOutbox, error) {
+	query, args, err := sq.
+		Insert(ProfileOutboxTable).
+		Columns(
+			ProfileOutboxSubjectIDLabel,
+			ProfileOutboxOperationLabel,
+		).
+		Values(subjectID, operation).
+		Suffix(ReturningSuffix).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build sql: %w", err)
+	}
+
+	var entity ProfileOutboxEntity
+	err = sqlx.GetContext(ctx, s.exec, &entity, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("db get: %w", err)
+	}
+
+	return entity.ToModel(), nil
+}
+
+// GetProfileOutbox locks the oldest pending row of each subject. A row locked by another replica is skipped
+// together with the later rows of its subject, they are taken after it is published.
+func (s *Storage) GetProfileOutbox(ctx context.Context, limit int) ([]*model.ProfileOutbox, error) {
+	query, args, err := sq.
+		Select(AllLabelsSelect).
+		From(ProfileOutboxTable).
+		Where(sq.Expr(deletedATIsNullProfileOutboxFilter)).
+		Where(sq.Expr(oldestOfSubjectProfileOutboxFilter)).
+		OrderBy(ProfileOutboxIDLabel).
+		Limit(uint64(limit)).
+		Suffix(SkipLocked).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build sql: %w", err)
+	}
+
+	var entities []*ProfileOutboxEntity
+	err = sqlx.SelectContext(ctx, s.exec, &entities, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("db select: %w", err)
+	}
+
+	return ProfileOutboxEntitiesToModels(entities), nil
+}
+
+func (s *Storage) DeleteProfileOutbox(ctx context.Context, ids []int) ([]*model.ProfileOutbox, error) {
+	if len(ids) == 0 {
+		return []*model.ProfileOutbox{}, nil
+	}
+
+	query, args, err := sq.
+		Update(ProfileOutboxTable).
+		Set(ProfileOutboxDeletedAtLabel, time.Now().UTC()).
+		Where(sq.Eq{ProfileOutboxIDLabel: ids}).
+		Where(sq.Expr(deletedATIsNullProfileOutboxFilter)).
+		Suffix(ReturningSuffix).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build sql: %w", err)
+	}
+
+	var entities []*ProfileOutboxEntity
+	err = sqlx.SelectContext(ctx, s.exec, &entities, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("db select: %w", err)
+	}
+
+	return ProfileOutboxEntitiesToModels(entities), nil
+}
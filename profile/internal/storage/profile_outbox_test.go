@@ -0,0 +1,62 @@
+package storage_test
+
+import (
+	"testing"
+
+	"github.com/1ocknight/mess/profile/internal/model"
+	p "github.com/1ocknight/mess/profile/internal/storage"
+)
+
+func TestStorage_ProfileOutbox(t *testing.T) {
+	s, err := p.New(CFG)
+	if err != nil {
+		t.Fatalf("could not construct receiver type: %v", err)
+	}
+	defer cleanupDB(t)
+
+	operations := []model.ProfileOperation{
+		model.ProfileCreatedOperation,
+		model.ProfileUpdatedOperation,
+		model.ProfileDeletedOperation,
+	}
+	for _, op := range operations {
+		if _, err := s.ProfileOutbox().AddProfileOutbox(t.Context(), InitProfiles[0].SubjectID, op); err != nil {
+			t.Fatalf("add outbox: %v", err)
+		}
+	}
+	if _, err := s.ProfileOutbox().AddProfileOutbox(t.Context(), InitProfiles[1].SubjectID, model.ProfileUpdatedOperation); err != nil {
+		t.Fatalf("add outbox: %v", err)
+	}
+
+	// only the oldest pending row of a subject is taken, the later ones wait for it
+	for _, op := range operations {
+		outboxes, err := s.ProfileOutbox().GetProfileOutbox(t.Context(), 10)
+		if err != nil {
+			t.Fatalf("get outbox: %v", err)
+		}
+		if len(outboxes) == 0 || outboxes[0].Operation != op || outboxes[0].SubjectID != InitProfiles[0].SubjectID {
+			t.Fatalf("wait %v operation first, have: %v", op, outboxes)
+		}
+		for _, out := range outboxes[1:] {
+			if out.SubjectID == InitProfiles[0].SubjectID {
+				t.Fatalf("two rows of one subject: %v", outboxes)
+			}
+		}
+
+		deleted, err := s.ProfileOutbox().DeleteProfileOutbox(t.Context(), []int{outboxes[0].ID})
+		if err != nil {
+			t.Fatalf("delete outbox: %v", err)
+		}
+		if len(deleted) != 1 {
+			t.Fatalf("wait 1 deleted, have: %v", len(deleted))
+		}
+	}
+
+	outboxes, err := s.ProfileOutbox().GetProfileOutbox(t.Context(), 10)
+	if err != nil {
+		t.Fatalf("get outbox: %v", err)
+	}
+	if len(outboxes) != 1 || outboxes[0].SubjectID != InitProfiles[1].SubjectID {
+		t.Fatalf("wait only the row of the second subject, have: %v", outboxes)
+	}
+}
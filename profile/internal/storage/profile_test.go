@@ -3,6 +3,7 @@ package storage_test
 import (
 	"database/sql"
 	"errors"
+	"time"
 
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 
@@ -53,9 +54,11 @@ func TestStorage_UpdateProfileMetadata(t *testing.T) {
 	initData(t)
 	defer cleanupDB(t)
 
+	expiresAt := time.Now().UTC().Add(time.Hour).Truncate(time.Microsecond)
+
 	type temp struct {
 		SubjectID   string
-		Alias       string
+		Meta        *model.ProfileMetadata
 		PrevVersion int
 	}
 
@@ -68,7 +71,7 @@ func TestStorage_UpdateProfileMetadata(t *testing.T) {
 			name: "successful update",
 			profile: temp{
 				SubjectID:   InitProfiles[0].SubjectID,
-				Alias:       "new_alias",
+				Meta:        &model.ProfileMetadata{Alias: "new_alias"},
 				PrevVersion: 1,
 			},
 			wantErr: false,
@@ -77,16 +80,40 @@ func TestStorage_UpdateProfileMetadata(t *testing.T) {
 			name: "non version update",
 			profile: temp{
 				SubjectID:   InitProfiles[0].SubjectID,
-				Alias:       "another_alias",
+				Meta:        &model.ProfileMetadata{Alias: "another_alias"},
 				PrevVersion: 1,
 			},
 			wantErr: true,
 		},
+		{
+			name: "update details",
+			profile: temp{
+				SubjectID: InitProfiles[0].SubjectID,
+				Meta: &model.ProfileMetadata{
+					Alias:       "new_alias",
+					DisplayName: "Display Name",
+					Bio:         "bio",
+					Status:      &model.ProfileStatus{Text: "busy", Emoji: "🔥", ExpiresAt: &expiresAt},
+					Links:       []model.ProfileLink{{Title: "site", URL: "https://example.com"}},
+				},
+				PrevVersion: 2,
+			},
+			wantErr: false,
+		},
+		{
+			name: "clear details",
+			profile: temp{
+				SubjectID:   InitProfiles[0].SubjectID,
+				Meta:        &model.ProfileMetadata{Alias: "new_alias"},
+				PrevVersion: 3,
+			},
+			wantErr: false,
+		},
 		{
 			name: "update non-existing profile",
 			profile: temp{
 				SubjectID:   "non_existing_subject_id",
-				Alias:       "alias",
+				Meta:        &model.ProfileMetadata{Alias: "alias"},
 				PrevVersion: 1,
 			},
 			wantErr: true,
@@ -94,7 +121,7 @@ func TestStorage_UpdateProfileMetadata(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			updatedProfile, gotErr := s.Profile().UpdateProfileMetadata(t.Context(), tt.profile.SubjectID, tt.profile.PrevVersion, tt.profile.Alias)
+			updatedProfile, gotErr := s.Profile().UpdateProfileMetadata(t.Context(), tt.profile.SubjectID, tt.profile.PrevVersion, tt.profile.Meta)
 			if gotErr != nil {
 				if !tt.wantErr {
 					t.Errorf("UpdateProfile() failed: %v", gotErr)
@@ -105,15 +132,33 @@ func TestStorage_UpdateProfileMetadata(t *testing.T) {
 				t.Fatal("UpdateProfile() succeeded unexpectedly")
 			}
 
-			if updatedProfile.Alias != tt.profile.Alias ||
+			meta := tt.profile.Meta
+			if updatedProfile.Alias != meta.Alias ||
+				updatedProfile.DisplayName != meta.DisplayName ||
+				updatedProfile.Bio != meta.Bio ||
+				len(updatedProfile.Links) != len(meta.Links) ||
 				updatedProfile.Version != tt.profile.PrevVersion+1 {
 				t.Errorf("Profile not updated correctly new: %v, prev: %v", updatedProfile, tt.profile)
 			}
+
+			if (updatedProfile.Status == nil) != (meta.Status == nil) {
+				t.Fatalf("status = %v, want %v", updatedProfile.Status, meta.Status)
+			}
+			if meta.Status != nil && (updatedProfile.Status.Text != meta.Status.Text ||
+				updatedProfile.Status.Emoji != meta.Status.Emoji ||
+				!updatedProfile.Status.ExpiresAt.Equal(*meta.Status.ExpiresAt)) {
+				t.Errorf("status = %v, want %v", updatedProfile.Status, meta.Status)
+			}
+			for i, link := range meta.Links {
+				if updatedProfile.Links[i] != link {
+					t.Errorf("link %v = %v, want %v", i, updatedProfile.Links[i], link)
+				}
+			}
 		})
 	}
 }
 
-func TestStorage_GetProfilesFromAlias_PaginationForward(t *testing.T) {
+func TestStorage_GetProfilesFromAlias_Rank(t *testing.T) {
 	s, err := p.New(CFG)
 	if err != nil {
 		t.Fatalf("could not construct receiver type: %v", err)
@@ -122,28 +167,79 @@ func TestStorage_GetProfilesFromAlias_PaginationForward(t *testing.T) {
 	initData(t)
 	defer cleanupDB(t)
 
-	filer := storage.ProfilePaginationFilter{
-		Limit:     10,
-		Asc:       true,
-		SortLabel: storage.ProfileAliasLabel,
-		LastID:    &InitProfiles[0].SubjectID,
+	tests := []struct {
+		name  string
+		alias string
+		want  []string
+	}{
+		{"exact first", "alias", []string{InitProfiles[1].SubjectID, InitProfiles[2].SubjectID}},
+		{"case insensitive", "ALIAS", []string{InitProfiles[1].SubjectID, InitProfiles[2].SubjectID}},
+		{"prefix before fuzzy", "ali", []string{InitProfiles[1].SubjectID, InitProfiles[2].SubjectID, InitProfiles[0].SubjectID}},
+		{"typo", "aliass", []string{InitProfiles[1].SubjectID, InitProfiles[2].SubjectID}},
+		{"like wildcards are escaped", "___", nil},
 	}
 
-	profiles, err := s.Profile().GetProfilesFromAlias(t.Context(), "al", &filer)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := storage.ProfilePaginationFilter{Limit: 10}
+			matches, err := s.Profile().GetProfilesFromAlias(t.Context(), "viewer", tt.alias, &filter)
+			if err != nil {
+				t.Fatalf("get profiles from alias: %v", err)
+			}
+
+			if len(matches) != len(tt.want) {
+				t.Fatalf("wait %v profiles, have %v", len(tt.want), len(matches))
+			}
+			for i, match := range matches {
+				if match.Profile.SubjectID != tt.want[i] {
+					t.Fatalf("profile %v: wait %v, have %v", i, tt.want[i], match.Profile.SubjectID)
+				}
+			}
+		})
+	}
+}
+
+func TestStorage_GetProfilesFromAlias_Pagination(t *testing.T) {
+	s, err := p.New(CFG)
+	if err != nil {
+		t.Fatalf("could not construct receiver type: %v", err)
+	}
+
+	initData(t)
+	defer cleanupDB(t)
+
+	filter := storage.ProfilePaginationFilter{Limit: 1}
+	first, err := s.Profile().GetProfilesFromAlias(t.Context(), "viewer", "ali", &filter)
 	if err != nil {
 		t.Fatalf("get profiles from alias: %v", err)
 	}
+	if len(first) != 1 || first[0].Profile.SubjectID != InitProfiles[1].SubjectID {
+		t.Fatalf("unexpected first page: %+v", first)
+	}
 
-	if len(profiles) != 2 {
-		t.Fatalf("not wait len: %v, wait 2", len(profiles))
+	filter.LastID = &first[0].Profile.SubjectID
+	filter.LastSortValue = first[0].Rank
+	next, err := s.Profile().GetProfilesFromAlias(t.Context(), "viewer", "ali", &filter)
+	if err != nil {
+		t.Fatalf("get profiles from alias: %v", err)
+	}
+	if len(next) != 1 || next[0].Profile.SubjectID != InitProfiles[2].SubjectID {
+		t.Fatalf("unexpected next page: %+v", next)
 	}
 
-	if profiles[1].SubjectID != InitProfiles[2].SubjectID {
-		t.Fatalf("not equal profiles: %v, want %v", profiles[2].SubjectID, InitProfiles[2].SubjectID)
+	filter.LastID = &next[0].Profile.SubjectID
+	filter.LastSortValue = next[0].Rank
+	filter.Asc = true
+	prev, err := s.Profile().GetProfilesFromAlias(t.Context(), "viewer", "ali", &filter)
+	if err != nil {
+		t.Fatalf("get profiles from alias: %v", err)
+	}
+	if len(prev) != 1 || prev[0].Profile.SubjectID != InitProfiles[1].SubjectID || prev[0].Rank != first[0].Rank {
+		t.Fatalf("unexpected previous page: %+v", prev)
 	}
 }
 
-func TestStorage_GetProfilesFromAlias_PaginationBack(t *testing.T) {
+func TestStorage_DeleteProfile(t *testing.T) {
 	s, err := p.New(CFG)
 	if err != nil {
 		t.Fatalf("could not construct receiver type: %v", err)
@@ -152,28 +248,65 @@ func TestStorage_GetProfilesFromAlias_PaginationBack(t *testing.T) {
 	initData(t)
 	defer cleanupDB(t)
 
-	filer := storage.ProfilePaginationFilter{
-		Limit:     10,
-		Asc:       false,
-		SortLabel: storage.ProfileAliasLabel,
-		LastID:    &InitProfiles[2].SubjectID,
-	}
+	delID := InitProfiles[0].SubjectID
 
-	profiles, err := s.Profile().GetProfilesFromAlias(t.Context(), "al", &filer)
+	prof, err := s.Profile().DeleteProfile(t.Context(), delID)
 	if err != nil {
+		t.Fatalf("delete profile subject id: %v", err)
+	}
+	if prof == nil {
+		t.Fatalf("expected nil profile on delete non-existing, got %+v", prof)
+	}
+
+	res, err := s.Profile().GetProfileFromSubjectID(t.Context(), prof.SubjectID)
+	if err != nil && !errors.Is(err, storage.ErrNoRows) {
 		t.Fatalf("get profiles from alias: %v", err)
 	}
+	if res != nil {
+		t.Fatalf("not delete profile: %v", res)
+	}
 
-	if len(profiles) != 2 {
-		t.Fatalf("not wait len: %v, wait 2", len(profiles))
+	_, err = s.Profile().DeleteProfile(t.Context(), "not")
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("delete profile subject id: %v", err)
+	}
+}
+
+func TestStorage_UpdateAvatarKey(t *testing.T) {
+	s, err := p.New(CFG)
+	if err != nil {
+		t.Fatalf("could not construct receiver type: %v", err)
+	}
+
+	initData(t)
+	defer cleanupDB(t)
+
+	subjID := InitProfiles[0].SubjectID
+	key := subjID + "/1-hash"
+
+	prof, err := s.Profile().UpdateAvatarKey(t.Context(), subjID, 0, &key)
+	if err != nil {
+		t.Fatalf("update avatar key: %v", err)
+	}
+	if prof.AvatarKey == nil || *prof.AvatarKey != key || prof.AvatarVersion != 1 {
+		t.Fatalf("not updated avatar key: %+v", prof)
 	}
 
-	if profiles[1].SubjectID != InitProfiles[0].SubjectID {
-		t.Fatalf("not equal profiles: %v, want %v", profiles[1].SubjectID, InitProfiles[0].SubjectID)
+	_, err = s.Profile().UpdateAvatarKey(t.Context(), subjID, 0, nil)
+	if !errors.Is(err, storage.ErrNoRows) {
+		t.Fatalf("expected no rows on stale avatar version, got: %v", err)
+	}
+
+	prof, err = s.Profile().UpdateAvatarKey(t.Context(), subjID, 1, nil)
+	if err != nil {
+		t.Fatalf("remove avatar key: %v", err)
+	}
+	if prof.AvatarKey != nil || prof.AvatarVersion != 2 {
+		t.Fatalf("not removed avatar key: %+v", prof)
 	}
 }
 
-func TestStorage_GetProfilesFromAlias_WithoutPagination(t *testing.T) {
+func TestStorage_UpdatePendingAvatarKey(t *testing.T) {
 	s, err := p.New(CFG)
 	if err != nil {
 		t.Fatalf("could not construct receiver type: %v", err)
@@ -182,27 +315,108 @@ func TestStorage_GetProfilesFromAlias_WithoutPagination(t *testing.T) {
 	initData(t)
 	defer cleanupDB(t)
 
-	filer := storage.ProfilePaginationFilter{
-		Limit:     10,
-		Asc:       true,
-		SortLabel: storage.ProfileAliasLabel,
+	subjID := InitProfiles[0].SubjectID
+	key := subjID + "/1-hash"
+
+	prof, err := s.Profile().UpdatePendingAvatarKey(t.Context(), subjID, &key)
+	if err != nil {
+		t.Fatalf("update pending avatar key: %v", err)
+	}
+	if prof.PendingAvatarKey == nil || *prof.PendingAvatarKey != key || prof.AvatarKey != nil || prof.AvatarVersion != 0 {
+		t.Fatalf("not updated pending avatar key: %+v", prof)
+	}
+
+	prof, err = s.Profile().UpdateAvatarKey(t.Context(), subjID, 0, &key)
+	if err != nil {
+		t.Fatalf("update avatar key: %v", err)
+	}
+	if prof.PendingAvatarKey != nil || prof.AvatarKey == nil || *prof.AvatarKey != key {
+		t.Fatalf("pending avatar key is not dropped: %+v", prof)
+	}
+
+	_, err = s.Profile().UpdatePendingAvatarKey(t.Context(), "missing", &key)
+	if !errors.Is(err, storage.ErrNoRows) {
+		t.Fatalf("expected no rows for missing profile, got: %v", err)
 	}
+}
 
-	profiles, err := s.Profile().GetProfilesFromAlias(t.Context(), "al", &filer)
+func TestStorage_UpdatePrivacy(t *testing.T) {
+	s, err := p.New(CFG)
+	if err != nil {
+		t.Fatalf("could not construct receiver type: %v", err)
+	}
+
+	initData(t)
+	defer cleanupDB(t)
+
+	hidden := InitProfiles[1]
+	privacy := model.DefaultPrivacy
+	privacy.Search = model.AudienceNobody
+	privacy.NewChat = model.AudienceContacts
+
+	prof, err := s.Profile().UpdatePrivacy(t.Context(), hidden.SubjectID, hidden.Version, &privacy)
+	if err != nil {
+		t.Fatalf("update privacy: %v", err)
+	}
+	if prof.Privacy != privacy || prof.Version != hidden.Version+1 {
+		t.Fatalf("privacy not updated: %+v", prof)
+	}
+
+	if _, err := s.Profile().UpdatePrivacy(t.Context(), hidden.SubjectID, hidden.Version, &privacy); !errors.Is(err, storage.ErrNoRows) {
+		t.Fatalf("expected no rows for old version, got: %v", err)
+	}
+
+	filter := storage.ProfilePaginationFilter{Limit: 10}
+	profiles, err := s.Profile().GetProfilesFromAlias(t.Context(), "viewer", "alias", &filter)
 	if err != nil {
 		t.Fatalf("get profiles from alias: %v", err)
 	}
+	for _, found := range profiles {
+		if found.Profile.SubjectID == hidden.SubjectID {
+			t.Fatalf("hidden profile found by search")
+		}
+	}
+	if len(profiles) != 1 {
+		t.Fatalf("wait 1 profile, have %v", len(profiles))
+	}
+}
+
+func TestStorage_UpdateLastSeen(t *testing.T) {
+	s, err := p.New(CFG)
+	if err != nil {
+		t.Fatalf("could not construct receiver type: %v", err)
+	}
+
+	initData(t)
+	defer cleanupDB(t)
 
-	if len(profiles) != 3 {
-		t.Fatalf("not wait len: %v, wait 3", len(profiles))
+	subjectID := InitProfiles[0].SubjectID
+	seenAt := time.Now().UTC().Truncate(time.Microsecond)
+	if err := s.Profile().UpdateLastSeen(t.Context(), subjectID, seenAt, time.Minute); err != nil {
+		t.Fatalf("update last seen: %v", err)
+	}
+	prof, err := s.Profile().GetProfileFromSubjectID(t.Context(), subjectID)
+	if err != nil {
+		t.Fatalf("get profile: %v", err)
+	}
+	if prof.LastSeenAt == nil || !prof.LastSeenAt.Equal(seenAt) || prof.Version != InitProfiles[0].Version {
+		t.Fatalf("last seen not updated or version changed: %+v", prof)
 	}
 
-	if profiles[2].SubjectID != InitProfiles[2].SubjectID {
-		t.Fatalf("not equal profiles: %v, want %v", profiles[2].SubjectID, InitProfiles[2].SubjectID)
+	// a write within the interval is skipped
+	if err := s.Profile().UpdateLastSeen(t.Context(), subjectID, seenAt.Add(30*time.Second), time.Minute); err != nil {
+		t.Fatalf("update last seen: %v", err)
+	}
+	prof, err = s.Profile().GetProfileFromSubjectID(t.Context(), subjectID)
+	if err != nil {
+		t.Fatalf("get profile: %v", err)
+	}
+	if !prof.LastSeenAt.Equal(seenAt) {
+		t.Fatalf("wait %v, have %v", seenAt, prof.LastSeenAt)
 	}
 }
 
-func TestStorage_DeleteProfile(t *testing.T) {
+func TestStorage_LegacyAvatar(t *testing.T) {
 	s, err := p.New(CFG)
 	if err != nil {
 		t.Fatalf("could not construct receiver type: %v", err)
@@ -211,26 +425,147 @@ func TestStorage_DeleteProfile(t *testing.T) {
 	initData(t)
 	defer cleanupDB(t)
 
-	delID := InitProfiles[0].SubjectID
+	// profiles created after the migration never had a legacy avatar
+	unchecked, err := s.Profile().GetLegacyAvatarUnchecked(t.Context(), 10)
+	if err != nil {
+		t.Fatalf("get legacy avatar unchecked: %v", err)
+	}
+	if len(unchecked) != 0 {
+		t.Fatalf("wait no unchecked profiles, have %v", unchecked)
+	}
 
-	prof, err := s.Profile().DeleteProfile(t.Context(), delID)
+	subjectID := InitProfiles[0].SubjectID
+	prof, err := s.Profile().SetLegacyAvatarChecked(t.Context(), subjectID, &subjectID)
 	if err != nil {
-		t.Fatalf("delete profile subject id: %v", err)
+		t.Fatalf("set legacy avatar checked: %v", err)
 	}
-	if prof == nil {
-		t.Fatalf("expected nil profile on delete non-existing, got %+v", prof)
+	if prof.AvatarKey == nil || *prof.AvatarKey != subjectID || !prof.LegacyAvatarChecked || prof.Version != InitProfiles[0].Version {
+		t.Fatalf("legacy avatar not set or version changed: %+v", prof)
 	}
 
-	res, err := s.Profile().GetProfileFromSubjectID(t.Context(), prof.SubjectID)
-	if err != nil && !errors.Is(err, storage.ErrNoRows) {
-		t.Fatalf("get profiles from alias: %v", err)
+	// an existing avatar is kept
+	other := "other"
+	prof, err = s.Profile().SetLegacyAvatarChecked(t.Context(), subjectID, &other)
+	if err != nil {
+		t.Fatalf("set legacy avatar checked: %v", err)
 	}
-	if res != nil {
-		t.Fatalf("not delete profile: %v", res)
+	if *prof.AvatarKey != subjectID {
+		t.Fatalf("wait avatar %v, have %v", subjectID, *prof.AvatarKey)
 	}
+}
 
-	_, err = s.Profile().DeleteProfile(t.Context(), "not")
-	if err != nil && !errors.Is(err, sql.ErrNoRows) {
-		t.Fatalf("delete profile subject id: %v", err)
+func TestStorage_UpdateUsername(t *testing.T) {
+	s, err := p.New(CFG)
+	if err != nil {
+		t.Fatalf("could not construct receiver type: %v", err)
+	}
+
+	initData(t)
+	defer cleanupDB(t)
+
+	first, second := InitProfiles[0], InitProfiles[1]
+
+	prof, err := s.Profile().UpdateUsername(t.Context(), first.SubjectID, first.Version, "John_Doe")
+	if err != nil {
+		t.Fatalf("update username: %v", err)
+	}
+	if prof.Username == nil || *prof.Username != "John_Doe" || prof.UsernameChangedAt == nil || prof.Version != first.Version+1 {
+		t.Fatalf("not updated username: %+v", prof)
+	}
+
+	found, err := s.Profile().GetProfileFromUsername(t.Context(), "john_doe")
+	if err != nil {
+		t.Fatalf("get profile from username: %v", err)
+	}
+	if found.SubjectID != first.SubjectID {
+		t.Fatalf("wait %v, have %v", first.SubjectID, found.SubjectID)
+	}
+
+	_, err = s.Profile().UpdateUsername(t.Context(), second.SubjectID, second.Version, "JOHN_DOE")
+	if !errors.Is(err, storage.ErrUniqueViolation) {
+		t.Fatalf("expected unique violation, got: %v", err)
+	}
+
+	_, err = s.Profile().UpdateUsername(t.Context(), first.SubjectID, first.Version, "other_name")
+	if !errors.Is(err, storage.ErrNoRows) {
+		t.Fatalf("expected no rows on stale version, got: %v", err)
+	}
+
+	caseOnly, err := s.Profile().UpdateUsername(t.Context(), first.SubjectID, prof.Version, "JOHN_doe")
+	if err != nil {
+		t.Fatalf("update username case: %v", err)
+	}
+	if *caseOnly.Username != "JOHN_doe" || !caseOnly.UsernameChangedAt.Equal(*prof.UsernameChangedAt) {
+		t.Fatalf("case only change moved the change time: wait %v, have %v", prof.UsernameChangedAt, caseOnly.UsernameChangedAt)
+	}
+
+	renamed, err := s.Profile().UpdateUsername(t.Context(), first.SubjectID, caseOnly.Version, "other_name")
+	if err != nil {
+		t.Fatalf("update username: %v", err)
+	}
+	if !renamed.UsernameChangedAt.After(*prof.UsernameChangedAt) {
+		t.Fatalf("change time is not moved: %v, previous %v", renamed.UsernameChangedAt, prof.UsernameChangedAt)
+	}
+}
+
+func TestStorage_UsernameHold(t *testing.T) {
+	s, err := p.New(CFG)
+	if err != nil {
+		t.Fatalf("could not construct receiver type: %v", err)
+	}
+
+	defer cleanupDB(t)
+
+	_, err = s.UsernameHold().AddHold(t.Context(), "John_Doe", "subj", time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("add hold: %v", err)
+	}
+	_, err = s.UsernameHold().GetActiveHold(t.Context(), "john_doe")
+	if !errors.Is(err, storage.ErrNoRows) {
+		t.Fatalf("expected no rows for expired hold, got: %v", err)
+	}
+
+	_, err = s.UsernameHold().AddHold(t.Context(), "John_Doe", "other", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("add hold: %v", err)
+	}
+	hold, err := s.UsernameHold().GetActiveHold(t.Context(), "JOHN_DOE")
+	if err != nil {
+		t.Fatalf("get active hold: %v", err)
+	}
+	if hold.Username != "john_doe" || hold.SubjectID != "other" {
+		t.Fatalf("unexpected hold: %+v", hold)
+	}
+}
+
+func TestStorage_GetProfilesFromSubjectIDs(t *testing.T) {
+	s, err := p.New(CFG)
+	if err != nil {
+		t.Fatalf("could not construct receiver type: %v", err)
+	}
+
+	initData(t)
+	defer cleanupDB(t)
+
+	deleted := InitProfiles[2].SubjectID
+	if _, err := s.Profile().DeleteProfile(t.Context(), deleted); err != nil {
+		t.Fatalf("delete profile: %v", err)
+	}
+
+	ids := []string{InitProfiles[0].SubjectID, InitProfiles[1].SubjectID, deleted, "unknown"}
+	profiles, err := s.Profile().GetProfilesFromSubjectIDs(t.Context(), ids)
+	if err != nil {
+		t.Fatalf("get profiles from subject ids: %v", err)
+	}
+	if len(profiles) != 2 {
+		t.Fatalf("wait 2 alive profiles, have %v", len(profiles))
+	}
+
+	gone, err := s.Profile().GetDeletedSubjectIDs(t.Context(), []string{deleted, "unknown"})
+	if err != nil {
+		t.Fatalf("get deleted subject ids: %v", err)
+	}
+	if len(gone) != 1 || gone[0] != deleted {
+		t.Fatalf("wait only %v deleted, have %v", deleted, gone)
 	}
 }
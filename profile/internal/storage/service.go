@@ -3,54 +3,137 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/1ocknight/mess/profile/internal/model"
 	"github.com/1ocknight/mess/shared/postgres"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
+// ProfilePaginationFilter pages the search, the sort is always by the rank.
 type ProfilePaginationFilter struct {
-	LastID    *string
-	Limit     int
-	Asc       bool
-	SortLabel string
+	LastID        *string
+	LastSortValue any
+	Limit         int
+	Asc           bool
+}
+
+type ContactPaginationFilter struct {
+	LastID        *string
+	LastSortValue any
+	Limit         int
+	Asc           bool
+	SortLabel     string
 }
 
 type Profile interface {
 	AddProfile(ctx context.Context, subjID string, alias string) (*model.Profile, error)
 
 	GetProfileFromSubjectID(ctx context.Context, subjID string) (*model.Profile, error)
-	GetProfilesFromAlias(ctx context.Context, alias string, filter *ProfilePaginationFilter) ([]*model.Profile, error)
+	GetProfilesFromSubjectIDs(ctx context.Context, subjIDs []string) ([]*model.Profile, error)
+	GetDeletedSubjectIDs(ctx context.Context, subjIDs []string) ([]string, error)
+	GetProfilesFromAlias(ctx context.Context, viewerID string, alias string, filter *ProfilePaginationFilter) ([]*model.ProfileMatch, error)
+
+	GetProfileFromUsername(ctx context.Context, username string) (*model.Profile, error)
+
+	UpdateProfileMetadata(ctx context.Context, subjectID string, prevVersion int, meta *model.ProfileMetadata) (*model.Profile, error)
+	UpdateUsername(ctx context.Context, subjectID string, prevVersion int, username string) (*model.Profile, error)
+	UpdateAvatarKey(ctx context.Context, subjectID string, prevAvatarVersion int, key *string) (*model.Profile, error)
+	UpdatePendingAvatarKey(ctx context.Context, subjectID string, key *string) (*model.Profile, error)
+	UpdatePrivacy(ctx context.Context, subjectID string, prevVersion int, privacy *model.Privacy) (*model.Profile, error)
+	UpdateLastSeen(ctx context.Context, subjectID string, seenAt time.Time, minInterval time.Duration) error
 
-	UpdateProfileMetadata(ctx context.Context, subjectID string, prevVersion int, alias string) (*model.Profile, error)
+	GetLegacyAvatarUnchecked(ctx context.Context, limit int) ([]*model.Profile, error)
+	SetLegacyAvatarChecked(ctx context.Context, subjectID string, key *string) (*model.Profile, error)
 
 	DeleteProfile(ctx context.Context, subjID string) (*model.Profile, error)
 }
 
 type AvatarOutbox interface {
 	GetKeys(ctx context.Context, limit int) ([]*model.AvatarOutbox, error)
-	AddKey(ctx context.Context, subjectID string) (*model.AvatarOutbox, error)
-	DeleteKeys(ctx context.Context, ids []string) ([]*model.AvatarOutbox, error)
+	AddKey(ctx context.Context, key string) (*model.AvatarOutbox, error)
+	DeleteKeys(ctx context.Context, keys []string) ([]*model.AvatarOutbox, error)
+}
+
+type DataExport interface {
+	AddDataExport(ctx context.Context, subjectID string) (*model.DataExport, error)
+
+	GetDataExportByID(ctx context.Context, exportID int) (*model.DataExport, error)
+	GetDataExportsByIDs(ctx context.Context, exportIDs []int) ([]*model.DataExport, error)
+	GetCollectedDataExport(ctx context.Context, assemblingBefore time.Time) (*model.DataExport, error)
+
+	CollectDataExport(ctx context.Context, exportID int, chatPartKey string) (*model.DataExport, error)
+	StartDataExport(ctx context.Context, exportID int) (*model.DataExport, error)
+	CompleteDataExport(ctx context.Context, exportID int, objectKey string) (*model.DataExport, error)
+	FailDataExport(ctx context.Context, exportID int, reason string) (*model.DataExport, error)
+}
+
+type DataExportOutbox interface {
+	AddDataExportOutbox(ctx context.Context, exportID int, operation model.DataExportOperation) (*model.DataExportOutbox, error)
+	GetDataExportOutbox(ctx context.Context, limit int) ([]*model.DataExportOutbox, error)
+	DeleteDataExportOutbox(ctx context.Context, ids []int) ([]*model.DataExportOutbox, error)
+}
+
+type ProfileOutbox interface {
+	AddProfileOutbox(ctx context.Context, subjectID string, operation model.ProfileOperation) (*model.ProfileOutbox, error)
+	GetProfileOutbox(ctx context.Context, limit int) ([]*model.ProfileOutbox, error)
+	DeleteProfileOutbox(ctx context.Context, ids []int) ([]*model.ProfileOutbox, error)
+}
+
+type UsernameHold interface {
+	GetActiveHold(ctx context.Context, username string) (*model.UsernameHold, error)
+	AddHold(ctx context.Context, username string, subjectID string, heldUntil time.Time) (*model.UsernameHold, error)
+}
+
+type Contact interface {
+	AddContact(ctx context.Context, subjectID string, contactSubjectID string, nickname string) (*model.Contact, error)
+	GetContact(ctx context.Context, subjectID string, contactSubjectID string) (*model.Contact, error)
+	GetOwnersWithContact(ctx context.Context, ownerIDs []string, contactSubjectID string) ([]string, error)
+	GetContacts(ctx context.Context, subjectID string, filter *ContactPaginationFilter) ([]*model.Contact, error)
+	DeleteContact(ctx context.Context, subjectID string, contactSubjectID string) (*model.Contact, error)
+	GetSubjectContacts(ctx context.Context, subjectID string) ([]*model.Contact, error)
+	DeleteSubjectContacts(ctx context.Context, subjectID string) ([]*model.Contact, error)
 }
 
 type Service interface {
 	WithTransaction(ctx context.Context) (ServiceTransaction, error)
 	Profile() Profile
 	AvatarOutbox() AvatarOutbox
+	DataExport() DataExport
+	DataExportOutbox() DataExportOutbox
+	ProfileOutbox() ProfileOutbox
+	UsernameHold() UsernameHold
+	Contact() Contact
 }
 
 type ServiceTransaction interface {
 	Profile() Profile
 	AvatarOutbox() AvatarOutbox
+	DataExport() DataExport
+	DataExportOutbox() DataExportOutbox
+	ProfileOutbox() ProfileOutbox
+	UsernameHold() UsernameHold
+	Contact() Contact
 	Commit() error
 	Rollback() error
 }
 
 var (
 	ErrNoRows = sql.ErrNoRows
+	// ErrUniqueViolation is returned when a unique index rejects the change
+	ErrUniqueViolation = fmt.Errorf("unique violation")
 )
 
+const uniqueViolationCode = "23505"
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolationCode
+}
+
 type Storage struct {
 	db   *sqlx.DB
 	exec sqlx.ExtContext
@@ -98,6 +181,41 @@ func (s *Storage) AvatarOutbox() AvatarOutbox {
 	}
 }
 
+func (s *Storage) DataExport() DataExport {
+	return &Storage{
+		db:   s.db,
+		exec: s.exec,
+	}
+}
+
+func (s *Storage) DataExportOutbox() DataExportOutbox {
+	return &Storage{
+		db:   s.db,
+		exec: s.exec,
+	}
+}
+
+func (s *Storage) ProfileOutbox() ProfileOutbox {
+	return &Storage{
+		db:   s.db,
+		exec: s.exec,
+	}
+}
+
+func (s *Storage) UsernameHold() UsernameHold {
+	return &Storage{
+		db:   s.db,
+		exec: s.exec,
+	}
+}
+
+func (s *Storage) Contact() Contact {
+	return &Storage{
+		db:   s.db,
+		exec: s.exec,
+	}
+}
+
 func (s *Storage) Commit() error {
 	tx, ok := s.exec.(*sqlx.Tx)
 	if !ok {
@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/1ocknight/mess/profile/internal/model"
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	heldUntilActiveFilter = fmt.Sprintf("%v > NOW()", UsernameHoldHeldUntilLabel)
+)
+
+// GetActiveHold returns the hold of the username that has not expired yet.
+func (s *Storage) GetActiveHold(ctx context.Context, username string) (*model.UsernameHold, error) {
+	query, args, err := sq.
+		Select(AllLabelsSelect).
+		From(UsernameHoldTable).
+		Where(sq.Eq{UsernameHoldUsernameLabel: strings.ToLower(username)}).
+		Where(sq.Expr(heldUntilActiveFilter)).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build sql: %w", err)
+	}
+
+	var entity UsernameHoldEntity
+	if err := sqlx.GetContext(ctx, s.exec, &entity, query, args...); err != nil {
+		return nil, fmt.Errorf("db get: %w", err)
+	}
+
+	return entity.ToModel(), nil
+}
+
+// AddHold holds the released username for its last owner, an expired hold of the same username is replaced.
+func (s *Storage) AddHold(ctx context.Context, username string, subjectID string, heldUntil time.Time) (*model.UsernameHold, error) {
+	query, args, err := sq.
+		Insert(UsernameHoldTable).
+		Columns(
+			UsernameHoldUsernameLabel,
+			UsernameHoldSubjectIDLabel,
+			UsernameHoldHeldUntilLabel,
+			UsernameHoldCreatedAtLabel,
+		).
+		Values(strings.ToLower(username), subjectID, heldUntil, time.Now().UTC()).
+		Suffix(fmt.Sprintf(
+			"ON CONFLICT (%v) DO UPDATE SET %v = EXCLUDED.%v, %v = EXCLUDED.%v, %v = EXCLUDED.%v %v",
+			UsernameHoldUsernameLabel,
+			UsernameHoldSubjectIDLabel, UsernameHoldSubjectIDLabel,
+			UsernameHoldHeldUntilLabel, UsernameHoldHeldUntilLabel,
+			UsernameHoldCreatedAtLabel, UsernameHoldCreatedAtLabel,
+			ReturningSuffix,
+		)).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build sql: %w", err)
+	}
+
+	var entity UsernameHoldEntity
+	if err := sqlx.GetContext(ctx, s.exec, &entity, query, args...); err != nil {
+		return nil, fmt.Errorf("db get: %w", err)
+	}
+
+	return entity.ToModel(), nil
+}
@@ -8,17 +8,20 @@ import (
 
 	"github.com/1ocknight/mess/profile/internal/domain"
 	"github.com/1ocknight/mess/profile/internal/model"
+	"github.com/1ocknight/mess/shared/cursor"
 	httpdto "github.com/1ocknight/mess/shared/dto/http"
 	"github.com/gin-gonic/gin"
 )
 
 type Handler struct {
-	domain domain.Service
+	domain  domain.Service
+	cursors cursor.Service
 }
 
-func NewHandler(domain domain.Service) *Handler {
+func NewHandler(domain domain.Service, cursors cursor.Service) *Handler {
 	return &Handler{
-		domain: domain,
+		domain:  domain,
+		cursors: cursors,
 	}
 }
 
@@ -45,30 +48,31 @@ func (h *Handler) GetProfile(c *gin.Context) {
 		}
 	}
 
-	c.JSON(http.StatusOK, httpdto.ProfileResponse{
-		SubjectID: profile.SubjectID,
-		Alias:     profile.Alias,
-		AvatarURL: url,
-		Version:   profile.Version,
-	})
+	c.JSON(http.StatusOK, ProfileModelToDTO(profile, url))
+}
+
+func (h *Handler) GetProfileByUsername(c *gin.Context) {
+	profile, url, err := h.domain.GetProfileFromUsername(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ProfileModelToDTO(profile, url))
 }
 
 func (h *Handler) GetProfiles(c *gin.Context) {
 	alias := c.Query("alias")
 	sLimit := c.Query("limit")
-	before := c.Query("before")
-	after := c.Query("after")
-
-	if after != "" && before != "" {
-		h.sendError(c, fmt.Errorf("%w, wait after or before null", InvalidRequestError))
-	}
+	sCursor := c.Query("cursor")
 
 	var limit int
 	var err error
 	if sLimit != "" {
 		limit, err = strconv.Atoi(sLimit)
-		if err != nil {
-			h.sendError(c, fmt.Errorf("%w, atoi: %w", InvalidRequestError, err))
+		if err != nil || limit < 0 {
+			h.sendError(c, fmt.Errorf("%w, invalid limit", InvalidRequestError))
+			return
 		}
 	}
 
@@ -76,17 +80,15 @@ func (h *Handler) GetProfiles(c *gin.Context) {
 		Limit: limit,
 	}
 
-	if after != "" {
-		filter.Direction = domain.DirectionAfter
-		filter.LastSubjectID = &after
-	}
-
-	if before != "" {
-		filter.Direction = domain.DirectionBefore
-		filter.LastSubjectID = &before
+	if sCursor != "" {
+		filter.Cursor, err = h.cursors.Decode(sCursor)
+		if err != nil {
+			h.sendError(c, fmt.Errorf("%w, decode cursor: %w", InvalidRequestError, err))
+			return
+		}
 	}
 
-	profiles, urls, err := h.domain.GetProfilesFromAlias(c.Request.Context(), alias, &filter)
+	profiles, urls, page, err := h.domain.GetProfilesFromAlias(c.Request.Context(), alias, &filter)
 	if err != nil {
 		h.sendError(c, err)
 		return
@@ -99,17 +101,45 @@ func (h *Handler) GetProfiles(c *gin.Context) {
 
 	res := make([]*httpdto.ProfileResponse, 0, len(profiles))
 	for _, profile := range profiles {
-		res = append(res, &httpdto.ProfileResponse{
-			SubjectID: profile.SubjectID,
-			Alias:     profile.Alias,
-			AvatarURL: urls[profile.SubjectID],
-			Version:   profile.Version,
-		})
+		res = append(res, ProfileModelToDTO(profile, urls[profile.SubjectID]))
 	}
 
-	c.JSON(http.StatusOK, httpdto.ProfilesResponse{
+	resp := httpdto.ProfilesResponse{
 		Profiles: res,
-	})
+	}
+
+	resp.NextCursor, resp.PrevCursor, err = cursor.EncodePage(h.cursors, page)
+	if err != nil {
+		h.sendError(c, fmt.Errorf("encode page: %w", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *Handler) GetProfilesBatch(c *gin.Context) {
+	var req *httpdto.ProfilesBatchRequest
+	if err := c.BindJSON(&req); err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	batch, err := h.domain.GetProfilesBatch(c.Request.Context(), req.SubjectIDs)
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	resp := httpdto.ProfilesBatchResponse{
+		Profiles: make([]*httpdto.ProfileResponse, 0, len(batch.Profiles)),
+		NotFound: append([]string{}, batch.NotFound...),
+		Deleted:  append([]string{}, batch.Deleted...),
+	}
+	for _, profile := range batch.Profiles {
+		resp.Profiles = append(resp.Profiles, ProfileModelToDTO(profile, batch.AvatarURLs[profile.SubjectID]))
+	}
+
+	c.JSON(http.StatusOK, resp)
 }
 
 func (h *Handler) AddProfile(c *gin.Context) {
@@ -125,12 +155,7 @@ func (h *Handler) AddProfile(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusCreated, httpdto.ProfileResponse{
-		SubjectID: profile.SubjectID,
-		Alias:     profile.Alias,
-		AvatarURL: url,
-		Version:   profile.Version,
-	})
+	c.JSON(http.StatusCreated, ProfileModelToDTO(profile, url))
 }
 
 func (h *Handler) UpdateProfileMetadata(c *gin.Context) {
@@ -140,29 +165,72 @@ func (h *Handler) UpdateProfileMetadata(c *gin.Context) {
 		return
 	}
 
-	profile, url, err := h.domain.UpdateProfileMetadata(c.Request.Context(), req.Version, req.Alias)
+	profile, url, err := h.domain.UpdateProfileMetadata(c.Request.Context(), req.Version, ProfileMetadataDTOToModel(req))
 	if err != nil {
 		h.sendError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, httpdto.ProfileResponse{
-		SubjectID: profile.SubjectID,
-		Alias:     profile.Alias,
-		AvatarURL: url,
-		Version:   profile.Version,
-	})
+	c.JSON(http.StatusOK, ProfileModelToDTO(profile, url))
+}
+
+func (h *Handler) UpdateUsername(c *gin.Context) {
+	var req *httpdto.UpdateUsernameRequest
+	if err := c.BindJSON(&req); err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	profile, url, err := h.domain.UpdateUsername(c.Request.Context(), req.Version, req.Username)
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ProfileModelToDTO(profile, url))
+}
+
+func (h *Handler) UpdatePrivacy(c *gin.Context) {
+	var req *httpdto.UpdatePrivacyRequest
+	if err := c.BindJSON(&req); err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	profile, url, err := h.domain.UpdatePrivacy(c.Request.Context(), req.Version, PrivacyDTOToModel(&req.ProfilePrivacy))
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ProfileModelToDTO(profile, url))
 }
 
 func (h *Handler) UploadAvatar(c *gin.Context) {
-	url, err := h.domain.UploadAvatar(c.Request.Context())
+	var req *httpdto.UploadAvatarRequest
+	if err := c.BindJSON(&req); err != nil {
+		h.sendError(c, err)
+		return
+	}
+	if !isSHA256(req.SHA256) {
+		h.sendError(c, fmt.Errorf("%w, invalid sha256", InvalidRequestError))
+		return
+	}
+
+	if req.ContentType == "" {
+		h.sendError(c, fmt.Errorf("%w, empty content type", InvalidRequestError))
+		return
+	}
+
+	upload, err := h.domain.UploadAvatar(c.Request.Context(), req.ContentType, req.SHA256)
 	if err != nil {
 		h.sendError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, httpdto.UploadAvatarResponse{
-		UploadURL: url,
+		UploadURL: upload.URL,
+		Fields:    upload.Fields,
 	})
 }
 
@@ -183,21 +251,138 @@ func (h *Handler) DeleteProfile(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, httpdto.ProfileResponse{
-		SubjectID: profile.SubjectID,
-		Alias:     profile.Alias,
-		AvatarURL: url,
-		Version:   profile.Version,
-	})
+	c.JSON(http.StatusOK, ProfileModelToDTO(profile, url))
+}
+
+func (h *Handler) AddContact(c *gin.Context) {
+	var req *httpdto.AddContactRequest
+	if err := c.BindJSON(&req); err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	target := &domain.ContactTarget{
+		SubjectID: req.SubjectID,
+		Username:  req.Username,
+	}
+
+	contact, url, err := h.domain.AddContact(c.Request.Context(), target, req.Nickname)
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ContactModelToDTO(contact, url))
+}
+
+func (h *Handler) GetContacts(c *gin.Context) {
+	sLimit := c.Query("limit")
+	sCursor := c.Query("cursor")
+
+	var limit int
+	var err error
+	if sLimit != "" {
+		limit, err = strconv.Atoi(sLimit)
+		if err != nil || limit < 0 {
+			h.sendError(c, fmt.Errorf("%w, invalid limit", InvalidRequestError))
+			return
+		}
+	}
+
+	filter := domain.ContactPaginationFilter{
+		Limit: limit,
+	}
+
+	if sCursor != "" {
+		filter.Cursor, err = h.cursors.Decode(sCursor)
+		if err != nil {
+			h.sendError(c, fmt.Errorf("%w, decode cursor: %w", InvalidRequestError, err))
+			return
+		}
+	}
+
+	contacts, urls, page, err := h.domain.GetContacts(c.Request.Context(), &filter)
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	if len(contacts) == 0 {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	res := make([]*httpdto.ContactResponse, 0, len(contacts))
+	for _, contact := range contacts {
+		res = append(res, ContactModelToDTO(contact, urls[contact.ContactSubjectID]))
+	}
+
+	resp := httpdto.ContactsResponse{
+		Contacts: res,
+	}
+
+	resp.NextCursor, resp.PrevCursor, err = cursor.EncodePage(h.cursors, page)
+	if err != nil {
+		h.sendError(c, fmt.Errorf("encode page: %w", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *Handler) DeleteContact(c *gin.Context) {
+	contact, err := h.domain.DeleteContact(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ContactModelToDTO(contact, ""))
+}
+
+func (h *Handler) RequestDataExport(c *gin.Context) {
+	export, err := h.domain.RequestDataExport(c.Request.Context())
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, DataExportModelToDTO(export, ""))
+}
+
+func (h *Handler) GetDataExport(c *gin.Context) {
+	exportID, err := strconv.Atoi(c.Param("export_id"))
+	if err != nil {
+		h.sendError(c, fmt.Errorf("%w, invalid export id", InvalidRequestError))
+		return
+	}
+
+	export, url, err := h.domain.GetDataExport(c.Request.Context(), exportID)
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, DataExportModelToDTO(export, url))
 }
 
 func (h *Handler) sendError(c *gin.Context, err error) {
 	var code int
 
-	if errors.Is(err, InvalidRequestError) {
+	if errors.Is(err, InvalidRequestError) || errors.Is(err, domain.ErrAvatarContentType) || errors.Is(err, domain.ErrInvalidUsername) ||
+		errors.Is(err, domain.ErrInvalidProfile) || errors.Is(err, domain.ErrInvalidContact) || errors.Is(err, domain.ErrInvalidSearch) ||
+		errors.Is(err, domain.ErrInvalidBatch) || errors.Is(err, cursor.ErrInvalidCursor) {
 		code = http.StatusBadRequest
 	}
 
+	if errors.Is(err, domain.ErrUsernameTaken) {
+		code = http.StatusConflict
+	}
+
+	if errors.Is(err, domain.ErrUsernameCooldown) {
+		code = http.StatusTooManyRequests
+	}
+
 	if errors.Is(err, domain.ErrNotFound) {
 		code = http.StatusNoContent
 	}
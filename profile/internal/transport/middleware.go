@@ -3,16 +3,28 @@ package transport
 import (
 	"fmt"
 	"net/http"
+	"slices"
+	"strings"
 
 	"github.com/1ocknight/mess/profile/internal/ctxkey"
+	"github.com/1ocknight/mess/profile/internal/domain"
 	"github.com/1ocknight/mess/profile/internal/loglables"
 	"github.com/1ocknight/mess/shared/auth"
 	"github.com/1ocknight/mess/shared/logger"
 	"github.com/1ocknight/mess/shared/requestmeta"
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/uuid"
 )
 
+const (
+	// azpClaim is the client the token was issued to
+	azpClaim = "azp"
+	// clientIDClaim is set by keycloak only in the tokens of a service account, older versions name it clientId
+	clientIDClaim       = "client_id"
+	legacyClientIDClaim = "clientId"
+)
+
 func InitLoggerMiddleware(lg logger.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ctx := ctxkey.WithLogger(c.Request.Context(), lg)
@@ -94,3 +106,66 @@ func InitSubjectMiddleware(auth auth.Service) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// LastSeenMiddleware records any authenticated request as activity of the subject,
+// a failed write is only logged, the request does not depend on it.
+// Service accounts are skipped, their calls are not activity of a user.
+func LastSeenMiddleware(domain domain.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, err := serviceAccountClient(c.GetHeader("Authorization")); err == nil {
+			c.Next()
+			return
+		}
+
+		if err := domain.TouchLastSeen(c.Request.Context()); err != nil {
+			lg, lgErr := ctxkey.ExtractLogger(c.Request.Context())
+			if lgErr == nil {
+				lg.Error(fmt.Errorf("touch last seen: %w", err))
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// ServiceAccountMiddleware lets in only the service accounts of the clients, an end user token issued
+// to the same client has no client id claim. It runs after InitSubjectMiddleware, so the token is already verified.
+func ServiceAccountMiddleware(clients []string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		client, err := serviceAccountClient(c.GetHeader("Authorization"))
+		if err != nil {
+			c.AbortWithError(http.StatusForbidden, fmt.Errorf("service account client: %w", err))
+			return
+		}
+
+		if !slices.Contains(clients, client) {
+			c.AbortWithError(http.StatusForbidden, fmt.Errorf("client %v is not allowed", client))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func serviceAccountClient(header string) (string, error) {
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok {
+		return "", fmt.Errorf("not a bearer token")
+	}
+
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(token, claims); err != nil {
+		return "", fmt.Errorf("parse unverified: %w", err)
+	}
+
+	azp, _ := claims[azpClaim].(string)
+	clientID, _ := claims[clientIDClaim].(string)
+	if clientID == "" {
+		clientID, _ = claims[legacyClientIDClaim].(string)
+	}
+	if azp == "" || clientID != azp {
+		return "", fmt.Errorf("not a service account token")
+	}
+
+	return azp, nil
+}
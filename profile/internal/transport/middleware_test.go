@@ -0,0 +1,95 @@
+package transport
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/1ocknight/mess/profile/internal/domain"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func TestServiceAccountMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name   string
+		claims jwt.MapClaims
+		status int
+	}{
+		{"service account", jwt.MapClaims{"azp": "chat", "client_id": "chat"}, http.StatusOK},
+		{"legacy service account", jwt.MapClaims{"azp": "chat", "clientId": "chat"}, http.StatusOK},
+		{"end user of the same client", jwt.MapClaims{"azp": "chat", "sub": "subj"}, http.StatusForbidden},
+		{"other client", jwt.MapClaims{"azp": "front", "client_id": "front"}, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, tt.claims).SignedString([]byte("key"))
+		if err != nil {
+			t.Fatalf("%v: sign: %v", tt.name, err)
+		}
+
+		r := gin.New()
+		r.Use(ServiceAccountMiddleware([]string{"chat"}))
+		r.GET("/rpc", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+		req := httptest.NewRequest(http.MethodGet, "/rpc", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		if rec.Code != tt.status {
+			t.Errorf("%v: wait %v, have %v", tt.name, tt.status, rec.Code)
+		}
+	}
+}
+
+type touchDomain struct {
+	domain.Service
+	touched int
+}
+
+func (d *touchDomain) TouchLastSeen(context.Context) error {
+	d.touched++
+	return nil
+}
+
+func TestLastSeenMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name    string
+		claims  jwt.MapClaims
+		touched int
+	}{
+		{"end user", jwt.MapClaims{"azp": "front", "sub": "subj"}, 1},
+		{"service account", jwt.MapClaims{"azp": "chat", "client_id": "chat"}, 0},
+		{"legacy service account", jwt.MapClaims{"azp": "chat", "clientId": "chat"}, 0},
+	}
+
+	for _, tt := range tests {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, tt.claims).SignedString([]byte("key"))
+		if err != nil {
+			t.Fatalf("%v: sign: %v", tt.name, err)
+		}
+
+		d := &touchDomain{}
+		r := gin.New()
+		r.Use(LastSeenMiddleware(d))
+		r.POST("/profiles/batch", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+		req := httptest.NewRequest(http.MethodPost, "/profiles/batch", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("%v: wait %v, have %v", tt.name, http.StatusOK, rec.Code)
+		}
+		if d.touched != tt.touched {
+			t.Errorf("%v: wait touched %v, have %v", tt.name, tt.touched, d.touched)
+		}
+	}
+}
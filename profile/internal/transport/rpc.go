@@ -0,0 +1,84 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/1ocknight/mess/profile/internal/domain"
+	"github.com/1ocknight/mess/shared/auth"
+	httpdto "github.com/1ocknight/mess/shared/dto/http"
+	"github.com/1ocknight/mess/shared/logger"
+	"github.com/gin-gonic/gin"
+)
+
+// RPCConfig is the internal server, ServiceClients are the keycloak clients whose service accounts may call it.
+type RPCConfig struct {
+	Config         `yaml:",inline"`
+	ServiceClients []string `yaml:"service_clients"`
+}
+
+// RPCServer is the internal API for other services, it is not exposed outside of the cluster,
+// so the subjects in the requests are trusted. Callers authenticate with their service account,
+// a token of an end user gets 403.
+type RPCServer struct {
+	cfg    *Config
+	srv    *gin.Engine
+	httpSv *http.Server
+}
+
+func NewRPCServer(cfg RPCConfig, lg logger.Logger, domain domain.Service, auth auth.Service) *RPCServer {
+	h := NewHandler(domain, nil)
+
+	if !cfg.DebugMode {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	r := gin.New()
+
+	r.Use(InitLoggerMiddleware(lg))
+	r.Use(SetRequestMetadataMiddleware())
+	r.Use(LogResponseMiddleware())
+	r.Use(InitSubjectMiddleware(auth))
+	r.Use(ServiceAccountMiddleware(cfg.ServiceClients))
+
+	r.GET("/rpc/chat_permission", h.RPCChatPermission)
+
+	return &RPCServer{
+		cfg: &cfg.Config,
+		srv: r,
+	}
+}
+
+func (s *RPCServer) Run() error {
+	addr := fmt.Sprintf("%s:%s", s.cfg.Host, s.cfg.Port)
+
+	s.httpSv = &http.Server{
+		Addr:    addr,
+		Handler: s.srv,
+	}
+
+	return s.httpSv.ListenAndServe()
+}
+
+func (s *RPCServer) Stop(ctx context.Context) error {
+	return s.httpSv.Shutdown(ctx)
+}
+
+// RPCChatPermission answers whether the subject from can start a new chat with the subject to.
+func (h *Handler) RPCChatPermission(c *gin.Context) {
+	from := c.Query("from")
+	to := c.Query("to")
+	if from == "" || to == "" {
+		h.sendError(c, fmt.Errorf("%w, empty from or to", InvalidRequestError))
+		return
+	}
+
+	allowed, err := h.domain.CanStartChat(c.Request.Context(), from, to)
+	if err != nil {
+		h.sendError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, httpdto.ChatPermissionResponse{Allowed: allowed})
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/1ocknight/mess/profile/internal/domain"
 	"github.com/1ocknight/mess/shared/auth"
+	"github.com/1ocknight/mess/shared/cursor"
 	"github.com/1ocknight/mess/shared/logger"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -25,8 +26,8 @@ type HTTPServer struct {
 	httpSv *http.Server
 }
 
-func NewServer(cfg Config, lg logger.Logger, domain domain.Service, auth auth.Service) *HTTPServer {
-	h := NewHandler(domain)
+func NewServer(cfg Config, lg logger.Logger, domain domain.Service, auth auth.Service, cursors cursor.Service) *HTTPServer {
+	h := NewHandler(domain, cursors)
 
 	if !cfg.DebugMode {
 		gin.SetMode(gin.ReleaseMode)
@@ -47,20 +48,32 @@ func NewServer(cfg Config, lg logger.Logger, domain domain.Service, auth auth.Se
 	r.Use(SetRequestMetadataMiddleware())
 	r.Use(LogResponseMiddleware())
 	r.Use(InitSubjectMiddleware(auth))
+	r.Use(LastSeenMiddleware(domain))
 
 	r.GET("/profile", h.GetProfile)
 	r.GET("/profile/:id", h.GetProfile)
+	r.GET("/profile/by-username/:name", h.GetProfileByUsername)
 
 	r.GET("/profiles", h.GetProfiles)
+	r.POST("/profiles/batch", h.GetProfilesBatch)
 
 	r.POST("/profile", h.AddProfile)
 
 	r.PUT("/profile", h.UpdateProfileMetadata)
+	r.PUT("/profile/username", h.UpdateUsername)
+	r.PUT("/profile/privacy", h.UpdatePrivacy)
 	r.PUT("/avatar", h.UploadAvatar)
 
 	r.DELETE("/avatar", h.DeleteAvatar)
 	r.DELETE("/profile", h.DeleteProfile)
 
+	r.GET("/contacts", h.GetContacts)
+	r.POST("/contacts", h.AddContact)
+	r.DELETE("/contacts/:id", h.DeleteContact)
+
+	r.POST("/data-export", h.RequestDataExport)
+	r.GET("/data-export/:export_id", h.GetDataExport)
+
 	return &HTTPServer{
 		cfg: &cfg,
 		srv: r,
@@ -0,0 +1,120 @@
+package transport
+
+import (
+	"crypto/sha256"
+	"time"
+
+	"github.com/1ocknight/mess/profile/internal/model"
+	httpdto "github.com/1ocknight/mess/shared/dto/http"
+)
+
+func ProfileModelToDTO(profile *model.Profile, url string) *httpdto.ProfileResponse {
+	res := &httpdto.ProfileResponse{
+		SubjectID: profile.SubjectID,
+		Alias:     profile.Alias,
+		AvatarURL: url,
+		Version:   profile.Version,
+	}
+	if profile.Username != nil {
+		res.Username = *profile.Username
+	}
+	res.DisplayName = profile.DisplayName
+	res.Bio = profile.Bio
+	if profile.Status.IsActive(time.Now()) {
+		res.Status = &httpdto.ProfileStatus{
+			Text:      profile.Status.Text,
+			Emoji:     profile.Status.Emoji,
+			ExpiresAt: profile.Status.ExpiresAt,
+		}
+	}
+	for _, link := range profile.Links {
+		res.Links = append(res.Links, httpdto.ProfileLink{Title: link.Title, URL: link.URL})
+	}
+	res.LastSeenAt = profile.LastSeenAt
+	// domain.ForViewer clears the privacy for everyone but the owner
+	if profile.Privacy != (model.Privacy{}) {
+		res.Privacy = &httpdto.ProfilePrivacy{
+			Search:   string(profile.Privacy.Search),
+			Avatar:   string(profile.Privacy.Avatar),
+			LastSeen: string(profile.Privacy.LastSeen),
+			NewChat:  string(profile.Privacy.NewChat),
+		}
+	}
+
+	return res
+}
+
+func PrivacyDTOToModel(privacy *httpdto.ProfilePrivacy) *model.Privacy {
+	return &model.Privacy{
+		Search:   model.Audience(privacy.Search),
+		Avatar:   model.Audience(privacy.Avatar),
+		LastSeen: model.Audience(privacy.LastSeen),
+		NewChat:  model.Audience(privacy.NewChat),
+	}
+}
+
+func ProfileMetadataDTOToModel(req *httpdto.UpdateProfileMetadataRequest) *model.ProfileMetadataPatch {
+	res := &model.ProfileMetadataPatch{
+		Alias:       req.Alias,
+		DisplayName: req.DisplayName,
+		Bio:         req.Bio,
+		SetStatus:   req.Status.Set,
+	}
+	if status := req.Status.Value; status != nil {
+		res.Status = &model.ProfileStatus{
+			Text:      status.Text,
+			Emoji:     status.Emoji,
+			ExpiresAt: status.ExpiresAt,
+		}
+	}
+	if req.Links != nil {
+		links := make([]model.ProfileLink, 0, len(*req.Links))
+		for _, link := range *req.Links {
+			links = append(links, model.ProfileLink{Title: link.Title, URL: link.URL})
+		}
+		res.Links = &links
+	}
+
+	return res
+}
+
+func ContactModelToDTO(contact *model.Contact, url string) *httpdto.ContactResponse {
+	res := &httpdto.ContactResponse{
+		SubjectID: contact.ContactSubjectID,
+		Nickname:  contact.Nickname,
+		CreatedAt: contact.CreatedAt,
+	}
+	if contact.Profile != nil {
+		res.Profile = ProfileModelToDTO(contact.Profile, url)
+	}
+
+	return res
+}
+
+func DataExportModelToDTO(export *model.DataExport, url string) *httpdto.DataExportResponse {
+	res := &httpdto.DataExportResponse{
+		ID:          export.ID,
+		Status:      export.Status.String(),
+		DownloadURL: url,
+		CreatedAt:   export.CreatedAt,
+	}
+	if export.Error != nil {
+		res.Error = *export.Error
+	}
+
+	return res
+}
+
+// isSHA256 accepts a lowercase hex digest, the same string is used in the object key.
+func isSHA256(s string) bool {
+	if len(s) != sha256.Size*2 {
+		return false
+	}
+	for _, r := range s {
+		if !('0' <= r && r <= '9' || 'a' <= r && r <= 'f') {
+			return false
+		}
+	}
+
+	return true
+}
@@ -22,16 +22,19 @@ type AvatarDeleterConfig struct {
 }
 
 type AvatarDeleter struct {
-	CFG     AvatarDeleterConfig
-	Avatar  avatar.Service
-	Storage storage.Service
+	CFG AvatarDeleterConfig
+	// ThumbnailSizes are the sizes stored by AvatarProcessor, they are deleted together with the avatar
+	ThumbnailSizes []int
+	Avatar         avatar.Service
+	Storage        storage.Service
 }
 
-func NewAvatarDeleter(cfg AvatarDeleterConfig, avatar avatar.Service, storage storage.Service) *AvatarDeleter {
+func NewAvatarDeleter(cfg AvatarDeleterConfig, thumbnailSizes []int, avatar avatar.Service, storage storage.Service) *AvatarDeleter {
 	return &AvatarDeleter{
-		CFG:     cfg,
-		Avatar:  avatar,
-		Storage: storage,
+		CFG:            cfg,
+		ThumbnailSizes: thumbnailSizes,
+		Avatar:         avatar,
+		Storage:        storage,
 	}
 }
 
@@ -57,7 +60,7 @@ func (ad *AvatarDeleter) Delete(ctx context.Context) error {
 			break
 		}
 
-		if err = ad.Avatar.DeleteObjects(ctx, model.GetOutboxIDs(keys)); err != nil {
+		if err = ad.Avatar.DeleteObjects(ctx, avatar.WithThumbnails(model.GetOutboxIDs(keys), ad.ThumbnailSizes)); err != nil {
 			return fmt.Errorf("avatar delete objects: %w", err)
 		}
 
@@ -0,0 +1,109 @@
+package workers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"slices"
+
+	"golang.org/x/image/draw"
+
+	_ "image/gif"
+)
+
+const (
+	avatarJPEGQuality = 90
+
+	jpegContentType = "image/jpeg"
+	pngContentType  = "image/png"
+)
+
+var (
+	AvatarTooLargeError    = fmt.Errorf("avatar is too large")
+	AvatarContentTypeError = fmt.Errorf("avatar content type is not allowed")
+	AvatarDimensionsError  = fmt.Errorf("avatar dimensions are out of range")
+	AvatarDecodeError      = fmt.Errorf("avatar is not a valid image")
+)
+
+// isAvatarRejected tells errors of a bad upload from errors worth a retry.
+func isAvatarRejected(err error) bool {
+	return errors.Is(err, AvatarTooLargeError) ||
+		errors.Is(err, AvatarContentTypeError) ||
+		errors.Is(err, AvatarDimensionsError) ||
+		errors.Is(err, AvatarDecodeError)
+}
+
+type avatarImage struct {
+	size        int
+	body        []byte
+	contentType string
+}
+
+// checkAvatarDimensions reads only the header, so a huge image is rejected before it is decoded.
+// A few kilobytes of png can claim maxSide x maxSide, maxPixels keeps the decoded image small.
+func checkAvatarDimensions(data []byte, minSide int, maxSide int, maxPixels int) error {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("%w: %w", AvatarDecodeError, err)
+	}
+	if min(cfg.Width, cfg.Height) < minSide || max(cfg.Width, cfg.Height) > maxSide {
+		return fmt.Errorf("%w: %vx%v", AvatarDimensionsError, cfg.Width, cfg.Height)
+	}
+	if cfg.Width*cfg.Height > maxPixels {
+		return fmt.Errorf("%w: %vx%v is more than %v pixels", AvatarDimensionsError, cfg.Width, cfg.Height, maxPixels)
+	}
+
+	return nil
+}
+
+// processAvatar crops the centered square and encodes it in every size. Encoding from decoded pixels
+// drops EXIF and any other metadata of the upload. JPEG stays JPEG, everything else becomes PNG.
+func processAvatar(data []byte, sizes []int) ([]*avatarImage, error) {
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", AvatarDecodeError, err)
+	}
+
+	b := img.Bounds()
+	side := min(b.Dx(), b.Dy())
+	square := image.Rect(0, 0, side, side).Add(image.Pt(b.Min.X+(b.Dx()-side)/2, b.Min.Y+(b.Dy()-side)/2))
+
+	// smaller sizes are made from the bigger ones, it is much cheaper than going back to the source
+	sizes = slices.Clone(sizes)
+	slices.SortFunc(sizes, func(a, b int) int { return b - a })
+
+	res := make([]*avatarImage, 0, len(sizes))
+	var src image.Image = img
+	rect := square
+	for _, size := range sizes {
+		resized := resizeSquare(src, rect, size)
+
+		var buf bytes.Buffer
+		contentType := pngContentType
+		if format == "jpeg" {
+			contentType = jpegContentType
+			err = jpeg.Encode(&buf, resized, &jpeg.Options{Quality: avatarJPEGQuality})
+		} else {
+			err = png.Encode(&buf, resized)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("encode %v: %w", size, err)
+		}
+
+		res = append(res, &avatarImage{size: size, body: buf.Bytes(), contentType: contentType})
+		src, rect = resized, resized.Bounds()
+	}
+
+	return res, nil
+}
+
+// resizeSquare scales the square of src with Catmull-Rom, it reads the pixel buffers of the decoded
+// formats directly instead of going through At for every pixel.
+func resizeSquare(src image.Image, r image.Rectangle, size int) *image.NRGBA {
+	dst := image.NewNRGBA(image.Rect(0, 0, size, size))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, r, draw.Src, nil)
+	return dst
+}
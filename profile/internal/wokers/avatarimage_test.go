@@ -0,0 +1,81 @@
+package workers
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func encodePNG(t *testing.T, w int, h int) []byte {
+	t.Helper()
+
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestCheckAvatarDimensions(t *testing.T) {
+	data := encodePNG(t, 200, 100)
+
+	tests := []struct {
+		name      string
+		minSide   int
+		maxSide   int
+		maxPixels int
+		wantErr   error
+	}{
+		{name: "in range", minSide: 64, maxSide: 256, maxPixels: 20000},
+		{name: "too small", minSide: 128, maxSide: 256, maxPixels: 20000, wantErr: AvatarDimensionsError},
+		{name: "too long side", minSide: 64, maxSide: 128, maxPixels: 20000, wantErr: AvatarDimensionsError},
+		{name: "too many pixels", minSide: 64, maxSide: 256, maxPixels: 19999, wantErr: AvatarDimensionsError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkAvatarDimensions(data, tt.minSide, tt.maxSide, tt.maxPixels)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("wait %v, have %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	if err := checkAvatarDimensions([]byte("not an image"), 1, 1, 1); !errors.Is(err, AvatarDecodeError) {
+		t.Fatalf("wait %v, have %v", AvatarDecodeError, err)
+	}
+}
+
+func TestProcessAvatar(t *testing.T) {
+	images, err := processAvatar(encodePNG(t, 200, 100), []int{32, 64})
+	if err != nil {
+		t.Fatalf("process avatar: %v", err)
+	}
+
+	// the bigger size comes first, smaller ones are made from it
+	if len(images) != 2 || images[0].size != 64 || images[1].size != 32 {
+		t.Fatalf("wait sizes 64 and 32, have %v", images)
+	}
+	for _, img := range images {
+		if img.contentType != pngContentType {
+			t.Fatalf("wait %v, have %v", pngContentType, img.contentType)
+		}
+		cfg, err := png.DecodeConfig(bytes.NewReader(img.body))
+		if err != nil {
+			t.Fatalf("decode config: %v", err)
+		}
+		if cfg.Width != img.size || cfg.Height != img.size {
+			t.Fatalf("wait %vx%v, have %vx%v", img.size, img.size, cfg.Width, cfg.Height)
+		}
+	}
+}
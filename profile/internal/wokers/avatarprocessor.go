@@ -0,0 +1,355 @@
+package workers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/1ocknight/mess/profile/internal/adapter/avatar"
+	"github.com/1ocknight/mess/profile/internal/ctxkey"
+	"github.com/1ocknight/mess/profile/internal/loglables"
+	"github.com/1ocknight/mess/profile/internal/model"
+	"github.com/1ocknight/mess/profile/internal/storage"
+	mqdto "github.com/1ocknight/mess/shared/dto/mq"
+	"github.com/1ocknight/mess/shared/messagequeue"
+	"github.com/1ocknight/mess/shared/messagequeue/kafka"
+	"github.com/1ocknight/mess/shared/s3client"
+)
+
+type AvatarProcessorConfig struct {
+	UploadKafka kafka.ConsumerConfig `yaml:"upload_kafka"`
+	EventKafka  kafka.ProducerConfig `yaml:"event_kafka"`
+	// uploads with a shorter side than MinDimension or a longer side than MaxDimension are rejected
+	MinDimension int `yaml:"min_dimension"`
+	MaxDimension int `yaml:"max_dimension"`
+	// MaxPixels bounds width*height read from the header, the decoded image takes about 4 bytes per pixel
+	MaxPixels int `yaml:"max_pixels"`
+	// Size is the side of the main image, ThumbnailSizes are stored next to it under avatar.ThumbnailKey
+	Size           int           `yaml:"size"`
+	ThumbnailSizes []int         `yaml:"thumbnail_sizes"`
+	Delay          time.Duration `yaml:"delay"`
+	// MaxAttempts of an upload, then it is rejected and the message is committed
+	MaxAttempts int `yaml:"max_attempts"`
+}
+
+const (
+	// DefaultAvatarMaxPixels is 4096x4096, 64MB when decoded
+	DefaultAvatarMaxPixels   = 4096 * 4096
+	DefaultAvatarMaxAttempts = 3
+)
+
+var (
+	AvatarProcessingError = fmt.Errorf("avatar processing failed, upload it again")
+)
+
+// AvatarProcessor handles bucket notifications about raw uploads: it validates them, stores the cropped
+// image with thumbnails, makes it the avatar of the profile and tells the subject about the result.
+type AvatarProcessor struct {
+	CFG            AvatarProcessorConfig
+	Limits         avatar.UploadConfig
+	UploadConsumer messagequeue.Consumer
+	EventProducer  messagequeue.Producer
+	Storage        storage.Service
+	Avatar         avatar.Service
+}
+
+func NewAvatarProcessor(cfg AvatarProcessorConfig, limits avatar.UploadConfig, s storage.Service, avatar avatar.Service) *AvatarProcessor {
+	if cfg.MaxPixels <= 0 {
+		cfg.MaxPixels = DefaultAvatarMaxPixels
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = DefaultAvatarMaxAttempts
+	}
+
+	return &AvatarProcessor{
+		CFG:            cfg,
+		Limits:         limits,
+		UploadConsumer: kafka.NewConsumer(cfg.UploadKafka),
+		EventProducer:  kafka.NewProducer(cfg.EventKafka),
+		Storage:        s,
+		Avatar:         avatar,
+	}
+}
+
+// Process commits every message it read, a message is never left to hold the partition:
+// a broken one is skipped and an upload that keeps failing is rejected after MaxAttempts.
+func (ap *AvatarProcessor) Process(ctx context.Context) error {
+	lg, err := ctxkey.ExtractLogger(ctx)
+	if err != nil {
+		return fmt.Errorf("extract logger: %w", err)
+	}
+
+	mqMsg, err := ap.UploadConsumer.ReadMessage(ctx)
+	if err != nil {
+		return fmt.Errorf("read message: %w", err)
+	}
+
+	var event s3client.UploadEventMinIO
+	if err := json.Unmarshal(mqMsg.Value(), &event); err != nil {
+		lg.Error(fmt.Errorf("unmarshal, skip message: %w", err))
+		return ap.commit(ctx, mqMsg)
+	}
+
+	for _, record := range event.Records {
+		if err := ap.processWithRetries(ctx, &record); err != nil {
+			return fmt.Errorf("process %v: %w", record.GetKey(), err)
+		}
+	}
+
+	return ap.commit(ctx, mqMsg)
+}
+
+// processWithRetries returns an error only when ctx is done, the message is read again after a restart.
+func (ap *AvatarProcessor) processWithRetries(ctx context.Context, record *s3client.UploadRecordMinIO) error {
+	lg, err := ctxkey.ExtractLogger(ctx)
+	if err != nil {
+		return fmt.Errorf("extract logger: %w", err)
+	}
+
+	for attempt := 1; ; attempt++ {
+		last := attempt >= ap.CFG.MaxAttempts
+		err := ap.processRecord(ctx, record, last)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		lg.Error(fmt.Errorf("process %v, attempt %v: %w", record.GetKey(), attempt, err))
+		if last {
+			lg.Info("give up upload after last attempt")
+			return nil
+		}
+
+		select {
+		case <-time.After(ap.CFG.Delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (ap *AvatarProcessor) commit(ctx context.Context, mqMsg messagequeue.Message) error {
+	if err := ap.UploadConsumer.Commit(ctx, mqMsg); err != nil {
+		return fmt.Errorf("commit message: %w", err)
+	}
+	return nil
+}
+
+// processRecord on the last attempt rejects the upload that could not be stored, the client can upload it again.
+func (ap *AvatarProcessor) processRecord(ctx context.Context, record *s3client.UploadRecordMinIO, last bool) error {
+	lg, err := ctxkey.ExtractLogger(ctx)
+	if err != nil {
+		return fmt.Errorf("extract logger: %w", err)
+	}
+
+	uploadKey := record.GetKey()
+	key, ok := avatar.KeyFromUpload(uploadKey)
+	if !ok {
+		return nil
+	}
+	subjectID, _, _ := strings.Cut(key, "/")
+	lg = lg.With(loglables.SubjectID, subjectID).With(loglables.AvatarKey, key)
+
+	prof, err := ap.Storage.Profile().GetProfileFromSubjectID(ctx, subjectID)
+	if errors.Is(err, storage.ErrNoRows) {
+		lg.Info("profile not found, drop upload")
+		return ap.deleteUpload(ctx, uploadKey)
+	}
+	if err != nil {
+		return fmt.Errorf("get profile from subject id: %w", err)
+	}
+
+	// the message is redelivered after the avatar was already activated
+	if prof.AvatarKey != nil && *prof.AvatarKey == key {
+		return ap.deleteUpload(ctx, uploadKey)
+	}
+	if prof.PendingAvatarKey == nil || *prof.PendingAvatarKey != key {
+		lg.Info("stale upload, drop it")
+		return ap.deleteUpload(ctx, uploadKey)
+	}
+
+	event := mqdto.AvatarProcessed{
+		SubjectID: subjectID,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	rejectErr := ap.store(ctx, record, uploadKey, key)
+	if rejectErr != nil && !isAvatarRejected(rejectErr) {
+		if !last {
+			return fmt.Errorf("store: %w", rejectErr)
+		}
+		lg.Error(fmt.Errorf("store: %w", rejectErr))
+		rejectErr = AvatarProcessingError
+	}
+
+	if rejectErr != nil {
+		if _, err := ap.Storage.Profile().UpdatePendingAvatarKey(ctx, subjectID, nil); err != nil {
+			return fmt.Errorf("update pending avatar key: %w", err)
+		}
+		event.Status = mqdto.AvatarRejected
+		event.Reason = rejectErr.Error()
+		lg.With(loglables.MessageLabel, rejectErr.Error()).Info("avatar rejected")
+	} else {
+		url, activated, err := ap.activate(ctx, prof, key)
+		if err != nil {
+			return fmt.Errorf("activate: %w", err)
+		}
+		if !activated {
+			lg.Info("profile changed while processing, drop upload")
+			return ap.deleteUpload(ctx, uploadKey)
+		}
+		event.Status = mqdto.AvatarActive
+		event.AvatarURL = url
+		lg.Info("avatar activated")
+	}
+
+	if err := ap.deleteUpload(ctx, uploadKey); err != nil {
+		return err
+	}
+
+	val, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+	if err := ap.EventProducer.Publish(ctx, &messagequeue.KeyValPair{Key: []byte(subjectID), Val: val}); err != nil {
+		return fmt.Errorf("publish: %w", err)
+	}
+
+	return nil
+}
+
+// store validates the upload and puts the processed images, see isAvatarRejected for errors that reject the upload.
+func (ap *AvatarProcessor) store(ctx context.Context, record *s3client.UploadRecordMinIO, uploadKey string, key string) error {
+	if ap.Limits.MaxSizeBytes > 0 && record.S3.Object.Size > ap.Limits.MaxSizeBytes {
+		return AvatarTooLargeError
+	}
+
+	body, _, err := ap.Avatar.GetAvatar(ctx, uploadKey)
+	if err != nil {
+		return fmt.Errorf("get avatar: %w", err)
+	}
+	defer body.Close()
+
+	// the size in the notification is not trusted, at most one extra byte is read to notice a bigger object
+	reader := io.Reader(body)
+	if ap.Limits.MaxSizeBytes > 0 {
+		reader = io.LimitReader(body, ap.Limits.MaxSizeBytes+1)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return fmt.Errorf("read avatar: %w", err)
+	}
+	if ap.Limits.MaxSizeBytes > 0 && int64(len(data)) > ap.Limits.MaxSizeBytes {
+		return AvatarTooLargeError
+	}
+
+	contentType := http.DetectContentType(data)
+	if len(ap.Limits.ContentTypes) != 0 && !slices.Contains(ap.Limits.ContentTypes, contentType) {
+		return fmt.Errorf("%w: %v", AvatarContentTypeError, contentType)
+	}
+
+	if err := checkAvatarDimensions(data, ap.CFG.MinDimension, ap.CFG.MaxDimension, ap.CFG.MaxPixels); err != nil {
+		return err
+	}
+
+	images, err := processAvatar(data, append([]int{ap.CFG.Size}, ap.CFG.ThumbnailSizes...))
+	if err != nil {
+		return err
+	}
+
+	for _, img := range images {
+		imgKey := key
+		if img.size != ap.CFG.Size {
+			imgKey = avatar.ThumbnailKey(key, img.size)
+		}
+		if err := ap.Avatar.Put(ctx, imgKey, bytes.NewReader(img.body), int64(len(img.body)), img.contentType); err != nil {
+			return fmt.Errorf("put %v: %w", imgKey, err)
+		}
+	}
+
+	return nil
+}
+
+// activate makes the processed key the avatar, false means the profile was changed in the meantime
+// and the stored images are queued for deletion instead.
+func (ap *AvatarProcessor) activate(ctx context.Context, prof *model.Profile, key string) (string, bool, error) {
+	tx, err := ap.Storage.WithTransaction(ctx)
+	if err != nil {
+		return "", false, fmt.Errorf("with transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Profile().UpdateAvatarKey(ctx, prof.SubjectID, prof.AvatarVersion, &key)
+	if errors.Is(err, storage.ErrNoRows) {
+		if _, err := ap.Storage.AvatarOutbox().AddKey(ctx, key); err != nil {
+			return "", false, fmt.Errorf("add key: %w", err)
+		}
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("update avatar key: %w", err)
+	}
+
+	if prof.AvatarKey != nil {
+		if _, err := tx.AvatarOutbox().AddKey(ctx, *prof.AvatarKey); err != nil {
+			return "", false, fmt.Errorf("add key: %w", err)
+		}
+	}
+
+	if _, err := tx.ProfileOutbox().AddProfileOutbox(ctx, prof.SubjectID, model.ProfileUpdatedOperation); err != nil {
+		return "", false, fmt.Errorf("add profile outbox: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", false, fmt.Errorf("commit: %w", err)
+	}
+
+	url, err := ap.Avatar.GetAvatarURL(ctx, key)
+	if err != nil {
+		return "", false, fmt.Errorf("get avatar url: %w", err)
+	}
+
+	return url, true, nil
+}
+
+func (ap *AvatarProcessor) deleteUpload(ctx context.Context, uploadKey string) error {
+	if err := ap.Avatar.DeleteObjects(ctx, []string{uploadKey}); err != nil {
+		return fmt.Errorf("delete upload: %w", err)
+	}
+	return nil
+}
+
+func (ap *AvatarProcessor) Start(ctx context.Context) error {
+	lg, err := ctxkey.ExtractLogger(ctx)
+	if err != nil {
+		return fmt.Errorf("extract logger: %w", err)
+	}
+
+	go func() {
+		for {
+			err := ap.Process(ctx)
+			if err == nil {
+				continue
+			}
+
+			lg.Error(fmt.Errorf("process: %w", err))
+
+			select {
+			case <-time.After(ap.CFG.Delay):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return nil
+}
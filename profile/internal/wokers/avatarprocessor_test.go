@@ -0,0 +1,131 @@
+package workers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/1ocknight/mess/profile/internal/adapter/avatar"
+	avatarmocks "github.com/1ocknight/mess/profile/internal/adapter/avatar/mocks"
+	"github.com/1ocknight/mess/profile/internal/ctxkey"
+	"github.com/1ocknight/mess/profile/internal/model"
+	storagemocks "github.com/1ocknight/mess/profile/internal/storage/mocks"
+	mqdto "github.com/1ocknight/mess/shared/dto/mq"
+	"github.com/1ocknight/mess/shared/logger"
+	"github.com/1ocknight/mess/shared/messagequeue"
+	mqmocks "github.com/1ocknight/mess/shared/messagequeue/mocks"
+	"github.com/1ocknight/mess/shared/s3client"
+)
+
+type fakeProducer struct {
+	pairs []*messagequeue.KeyValPair
+}
+
+func (p *fakeProducer) Publish(ctx context.Context, pair *messagequeue.KeyValPair) error {
+	p.pairs = append(p.pairs, pair)
+	return nil
+}
+
+func (p *fakeProducer) BatchPublish(ctx context.Context, pairs []*messagequeue.KeyValPair) error {
+	p.pairs = append(p.pairs, pairs...)
+	return nil
+}
+
+func (p *fakeProducer) Close() error {
+	return nil
+}
+
+type avatarProcessorEnv struct {
+	ctx      context.Context
+	ap       *AvatarProcessor
+	consumer *mqmocks.MockConsumer
+	producer *fakeProducer
+	profile  *storagemocks.MockProfile
+	avatar   *avatarmocks.MockService
+}
+
+func newAvatarProcessorEnv(t *testing.T) *avatarProcessorEnv {
+	ctrl := gomock.NewController(t)
+
+	storage := storagemocks.NewMockService(ctrl)
+	profile := storagemocks.NewMockProfile(ctrl)
+	storage.EXPECT().Profile().Return(profile).AnyTimes()
+
+	env := &avatarProcessorEnv{
+		ctx:      ctxkey.WithLogger(t.Context(), logger.New(slog.NewJSONHandler(io.Discard, nil))),
+		consumer: mqmocks.NewMockConsumer(ctrl),
+		producer: &fakeProducer{},
+		profile:  profile,
+		avatar:   avatarmocks.NewMockService(ctrl),
+	}
+	env.ap = &AvatarProcessor{
+		CFG:            AvatarProcessorConfig{MaxAttempts: 2},
+		UploadConsumer: env.consumer,
+		EventProducer:  env.producer,
+		Storage:        storage,
+		Avatar:         env.avatar,
+	}
+
+	return env
+}
+
+func newMessage(t *testing.T, value []byte) *mqmocks.MockMessage {
+	msg := mqmocks.NewMockMessage(gomock.NewController(t))
+	msg.EXPECT().Value().Return(value).AnyTimes()
+	return msg
+}
+
+func TestAvatarProcessor_Process_SkipsBrokenMessage(t *testing.T) {
+	env := newAvatarProcessorEnv(t)
+
+	msg := newMessage(t, []byte("not json"))
+	env.consumer.EXPECT().ReadMessage(env.ctx).Return(msg, nil)
+	env.consumer.EXPECT().Commit(env.ctx, msg).Return(nil)
+
+	if err := env.ap.Process(env.ctx); err != nil {
+		t.Fatalf("process: %v", err)
+	}
+}
+
+func TestAvatarProcessor_Process_RejectsAfterLastAttempt(t *testing.T) {
+	env := newAvatarProcessorEnv(t)
+
+	key := "subj/1-abc"
+	uploadKey := avatar.UploadKey(key)
+
+	var record s3client.UploadRecordMinIO
+	record.S3.Object.Key = uploadKey
+	value, err := json.Marshal(s3client.UploadEventMinIO{Records: []s3client.UploadRecordMinIO{record}})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	msg := newMessage(t, value)
+
+	prof := &model.Profile{SubjectID: "subj", PendingAvatarKey: &key}
+	env.consumer.EXPECT().ReadMessage(env.ctx).Return(msg, nil)
+	env.profile.EXPECT().GetProfileFromSubjectID(env.ctx, "subj").Return(prof, nil).Times(2)
+	env.avatar.EXPECT().GetAvatar(env.ctx, uploadKey).Return(nil, "", errors.New("s3 is down")).Times(2)
+	env.profile.EXPECT().UpdatePendingAvatarKey(env.ctx, "subj", gomock.Nil()).Return(prof, nil)
+	env.avatar.EXPECT().DeleteObjects(env.ctx, []string{uploadKey}).Return(nil)
+	env.consumer.EXPECT().Commit(env.ctx, msg).Return(nil)
+
+	if err := env.ap.Process(env.ctx); err != nil {
+		t.Fatalf("process: %v", err)
+	}
+
+	if len(env.producer.pairs) != 1 {
+		t.Fatalf("wait 1 event, have %v", len(env.producer.pairs))
+	}
+	var event mqdto.AvatarProcessed
+	if err := json.Unmarshal(env.producer.pairs[0].Val, &event); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if event.Status != mqdto.AvatarRejected || event.Reason != AvatarProcessingError.Error() {
+		t.Fatalf("wait rejected with %q, have %v %q", AvatarProcessingError, event.Status, event.Reason)
+	}
+}
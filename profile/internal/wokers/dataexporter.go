@@ -0,0 +1,561 @@
+package workers
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"time"
+
+	"github.com/1ocknight/mess/profile/internal/adapter/archive"
+	"github.com/1ocknight/mess/profile/internal/adapter/avatar"
+	"github.com/1ocknight/mess/profile/internal/ctxkey"
+	"github.com/1ocknight/mess/profile/internal/loglables"
+	"github.com/1ocknight/mess/profile/internal/model"
+	"github.com/1ocknight/mess/profile/internal/storage"
+	mqdto "github.com/1ocknight/mess/shared/dto/mq"
+	"github.com/1ocknight/mess/shared/messagequeue"
+	"github.com/1ocknight/mess/shared/messagequeue/kafka"
+)
+
+const (
+	dataExportProfileName  = "profile.json"
+	dataExportContactsName = "contacts.json"
+	dataExportAvatarName   = "avatar"
+	dataExportChatName     = "chat.zip"
+	dataExportContentType  = "application/zip"
+)
+
+type DataExporterConfig struct {
+	RequestKafka    kafka.ProducerConfig `yaml:"request_kafka"`
+	EventKafka      kafka.ProducerConfig `yaml:"event_kafka"`
+	PartKafka       kafka.ConsumerConfig `yaml:"part_kafka"`
+	OutboxLimit     int                  `yaml:"outbox_limit"`
+	Delay           time.Duration        `yaml:"delay"`
+	AssembleTimeout time.Duration        `yaml:"assemble_timeout"`
+}
+
+const (
+	DefaultDataExportAssembleTimeout = 10 * time.Minute
+	// DataExportFailedReason is stored for the subject instead of the internal error of the assembling or of a part
+	DataExportFailedReason = "assemble failed"
+)
+
+// DataExporter drives the subject data export: it asks other services for their parts,
+// waits for them, assembles the final archive and notifies the subject.
+type DataExporter struct {
+	CFG             DataExporterConfig
+	RequestProducer messagequeue.Producer
+	EventProducer   messagequeue.Producer
+	PartConsumer    messagequeue.Consumer
+	Storage         storage.Service
+	Avatar          avatar.Service
+	Archive         archive.Service
+}
+
+func NewDataExporter(cfg DataExporterConfig, s storage.Service, avatar avatar.Service, archive archive.Service) *DataExporter {
+	if cfg.AssembleTimeout <= 0 {
+		cfg.AssembleTimeout = DefaultDataExportAssembleTimeout
+	}
+
+	return &DataExporter{
+		CFG:             cfg,
+		RequestProducer: kafka.NewProducer(cfg.RequestKafka),
+		EventProducer:   kafka.NewProducer(cfg.EventKafka),
+		PartConsumer:    kafka.NewConsumer(cfg.PartKafka),
+		Storage:         s,
+		Avatar:          avatar,
+		Archive:         archive,
+	}
+}
+
+var (
+	NoDataExportOutboxError = fmt.Errorf("no data export outbox")
+	NoDataExportsError      = fmt.Errorf("no collected data exports")
+)
+
+func DataExportObjectKey(export *model.DataExport) string {
+	return fmt.Sprintf("profile/%s/%d.zip", export.SubjectID, export.ID)
+}
+
+type exportProfile struct {
+	SubjectID string    `json:"subject_id"`
+	Alias     string    `json:"alias"`
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type exportContact struct {
+	SubjectID string    `json:"subject_id"`
+	Nickname  string    `json:"nickname,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (de *DataExporter) Publish(ctx context.Context) ([]int, error) {
+	lg, err := ctxkey.ExtractLogger(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("extract logger: %w", err)
+	}
+
+	tx, err := de.Storage.WithTransaction(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("with transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	outboxes, err := tx.DataExportOutbox().GetDataExportOutbox(ctx, de.CFG.OutboxLimit)
+	if err != nil {
+		return nil, fmt.Errorf("get data export outbox: %w", err)
+	}
+	if len(outboxes) == 0 {
+		return nil, NoDataExportOutboxError
+	}
+
+	exports, err := tx.DataExport().GetDataExportsByIDs(ctx, model.GetDataExportIDsFromOutboxes(outboxes))
+	if err != nil {
+		return nil, fmt.Errorf("get data exports by ids: %w", err)
+	}
+
+	exportsMap := make(map[int]*model.DataExport, len(exports))
+	for _, export := range exports {
+		exportsMap[export.ID] = export
+	}
+
+	requests := make([]*messagequeue.KeyValPair, 0, len(outboxes))
+	events := make([]*messagequeue.KeyValPair, 0, len(outboxes))
+	for _, out := range outboxes {
+		export, ok := exportsMap[out.ExportID]
+		if !ok {
+			lg.With(loglables.DataExportOutbox, *out).Error(fmt.Errorf("data export not found"))
+			continue
+		}
+
+		switch out.Operation {
+		case model.DataExportRequestOperation:
+			val, err := json.Marshal(mqdto.DataExportRequest{
+				ExportID:  export.ID,
+				SubjectID: export.SubjectID,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("marshal: %w", err)
+			}
+			requests = append(requests, &messagequeue.KeyValPair{Key: []byte(export.SubjectID), Val: val})
+		case model.DataExportNotifyOperation:
+			status := mqdto.DataExportDone
+			if export.Status == model.DataExportFailed {
+				status = mqdto.DataExportFailed
+			}
+			val, err := json.Marshal(mqdto.DataExport{
+				ExportID:  export.ID,
+				SubjectID: export.SubjectID,
+				Status:    status,
+				CreatedAt: export.CreatedAt,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("marshal: %w", err)
+			}
+			events = append(events, &messagequeue.KeyValPair{Key: []byte(export.SubjectID), Val: val})
+		default:
+			lg.With(loglables.DataExportOutbox, *out).Error(fmt.Errorf("unknown operation"))
+		}
+	}
+
+	if len(requests) != 0 {
+		if err := de.RequestProducer.BatchPublish(ctx, requests); err != nil {
+			return nil, fmt.Errorf("publish requests: %w", err)
+		}
+	}
+
+	if len(events) != 0 {
+		if err := de.EventProducer.BatchPublish(ctx, events); err != nil {
+			return nil, fmt.Errorf("publish events: %w", err)
+		}
+	}
+
+	ids := model.GetDataExportOutboxIDs(outboxes)
+	if _, err := tx.DataExportOutbox().DeleteDataExportOutbox(ctx, ids); err != nil {
+		return nil, fmt.Errorf("delete data export outbox: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+
+	return ids, nil
+}
+
+func (de *DataExporter) Collect(ctx context.Context) error {
+	lg, err := ctxkey.ExtractLogger(ctx)
+	if err != nil {
+		return fmt.Errorf("extract logger: %w", err)
+	}
+
+	mqMsg, err := de.PartConsumer.ReadMessage(ctx)
+	if err != nil {
+		return fmt.Errorf("read message: %w", err)
+	}
+
+	var part mqdto.DataExportPart
+	if err := json.Unmarshal(mqMsg.Value(), &part); err != nil {
+		lg.Error(fmt.Errorf("unmarshal data export part, skip: %w", err))
+		if err := de.PartConsumer.Commit(ctx, mqMsg); err != nil {
+			return fmt.Errorf("commit message: %w", err)
+		}
+		return nil
+	}
+
+	tx, err := de.Storage.WithTransaction(ctx)
+	if err != nil {
+		return fmt.Errorf("with transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var export *model.DataExport
+	if part.Error != "" {
+		// the reason from the service is only logged, the subject gets the generic one
+		lg.With(loglables.DataExport, part).Error(fmt.Errorf("data export part of %v failed: %v", part.Service, part.Error))
+		export, err = tx.DataExport().FailDataExport(ctx, part.ExportID, DataExportFailedReason)
+	} else {
+		export, err = tx.DataExport().CollectDataExport(ctx, part.ExportID, part.Key)
+	}
+	if errors.Is(err, storage.ErrNoRows) {
+		if err := de.PartConsumer.Commit(ctx, mqMsg); err != nil {
+			return fmt.Errorf("commit message: %w", err)
+		}
+		lg.Info("data export already collected")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("update data export: %w", err)
+	}
+
+	if export.Status == model.DataExportFailed {
+		if _, err := tx.DataExportOutbox().AddDataExportOutbox(ctx, export.ID, model.DataExportNotifyOperation); err != nil {
+			return fmt.Errorf("add data export outbox: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+
+	if err := de.PartConsumer.Commit(ctx, mqMsg); err != nil {
+		return fmt.Errorf("commit message: %w", err)
+	}
+
+	lg.With(loglables.DataExport, *export).Info("data export part collected")
+
+	return nil
+}
+
+func (de *DataExporter) Assemble(ctx context.Context) (*model.DataExport, error) {
+	lg, err := ctxkey.ExtractLogger(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("extract logger: %w", err)
+	}
+
+	export, err := de.claim(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	key := DataExportObjectKey(export)
+	buildErr := de.build(ctx, export, key)
+
+	tx, err := de.Storage.WithTransaction(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("with transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if buildErr != nil {
+		export, err = tx.DataExport().FailDataExport(ctx, export.ID, DataExportFailedReason)
+		if err != nil {
+			return nil, fmt.Errorf("fail data export: %w", err)
+		}
+	} else {
+		export, err = tx.DataExport().CompleteDataExport(ctx, export.ID, key)
+		if err != nil {
+			return nil, fmt.Errorf("complete data export: %w", err)
+		}
+	}
+
+	if _, err := tx.DataExportOutbox().AddDataExportOutbox(ctx, export.ID, model.DataExportNotifyOperation); err != nil {
+		return nil, fmt.Errorf("add data export outbox: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+
+	if buildErr != nil {
+		return export, fmt.Errorf("build data export %v: %w", export.ID, buildErr)
+	}
+
+	if export.ChatPartKey != nil {
+		if err := de.Archive.Delete(ctx, *export.ChatPartKey); err != nil {
+			lg.Error(fmt.Errorf("delete chat part: %w", err))
+		}
+	}
+
+	return export, nil
+}
+
+// claim marks a collected export as assembling and commits, so the S3 reads and the upload
+// run without holding the row lock.
+func (de *DataExporter) claim(ctx context.Context) (*model.DataExport, error) {
+	tx, err := de.Storage.WithTransaction(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("with transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	export, err := tx.DataExport().GetCollectedDataExport(ctx, time.Now().UTC().Add(-de.CFG.AssembleTimeout))
+	if errors.Is(err, storage.ErrNoRows) {
+		return nil, NoDataExportsError
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get collected data export: %w", err)
+	}
+
+	export, err = tx.DataExport().StartDataExport(ctx, export.ID)
+	if err != nil {
+		return nil, fmt.Errorf("start data export: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+
+	return export, nil
+}
+
+func (de *DataExporter) build(ctx context.Context, export *model.DataExport, key string) error {
+	file, err := os.CreateTemp("", "data-export-*.zip")
+	if err != nil {
+		return fmt.Errorf("create temp: %w", err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	zw := zip.NewWriter(file)
+
+	profile, err := de.Storage.Profile().GetProfileFromSubjectID(ctx, export.SubjectID)
+	if err != nil && !errors.Is(err, storage.ErrNoRows) {
+		return fmt.Errorf("get profile from subject id: %w", err)
+	}
+	if err == nil {
+		if err := writeProfile(zw, profile); err != nil {
+			return fmt.Errorf("write %v: %w", dataExportProfileName, err)
+		}
+	}
+
+	if err == nil && profile.AvatarKey != nil {
+		if err := de.writeAvatar(ctx, zw, *profile.AvatarKey); err != nil {
+			return fmt.Errorf("write %v: %w", dataExportAvatarName, err)
+		}
+	}
+
+	contacts, err := de.Storage.Contact().GetSubjectContacts(ctx, export.SubjectID)
+	if err != nil {
+		return fmt.Errorf("get subject contacts: %w", err)
+	}
+	if err := writeContacts(zw, contacts); err != nil {
+		return fmt.Errorf("write %v: %w", dataExportContactsName, err)
+	}
+
+	if export.ChatPartKey != nil {
+		if err := de.writePart(ctx, zw, dataExportChatName, *export.ChatPartKey); err != nil {
+			return fmt.Errorf("write %v: %w", dataExportChatName, err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("close zip: %w", err)
+	}
+
+	size, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return fmt.Errorf("seek: %w", err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("seek: %w", err)
+	}
+
+	if err := de.Archive.Upload(ctx, key, file, size, dataExportContentType); err != nil {
+		return fmt.Errorf("upload: %w", err)
+	}
+
+	return nil
+}
+
+func writeProfile(zw *zip.Writer, profile *model.Profile) error {
+	w, err := zw.Create(dataExportProfileName)
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	err = enc.Encode(exportProfile{
+		SubjectID: profile.SubjectID,
+		Alias:     profile.Alias,
+		Version:   profile.Version,
+		CreatedAt: profile.CreatedAt,
+		UpdatedAt: profile.UpdatedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("encode: %w", err)
+	}
+
+	return nil
+}
+
+func writeContacts(zw *zip.Writer, contacts []*model.Contact) error {
+	w, err := zw.Create(dataExportContactsName)
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+
+	res := make([]exportContact, 0, len(contacts))
+	for _, c := range contacts {
+		res = append(res, exportContact{
+			SubjectID: c.ContactSubjectID,
+			Nickname:  c.Nickname,
+			CreatedAt: c.CreatedAt,
+			UpdatedAt: c.UpdatedAt,
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(res); err != nil {
+		return fmt.Errorf("encode: %w", err)
+	}
+
+	return nil
+}
+
+func (de *DataExporter) writeAvatar(ctx context.Context, zw *zip.Writer, key string) error {
+	body, contentType, err := de.Avatar.GetAvatar(ctx, key)
+	if errors.Is(err, avatar.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("get avatar: %w", err)
+	}
+	defer body.Close()
+
+	name := dataExportAvatarName
+	if exts, _ := mime.ExtensionsByType(contentType); len(exts) != 0 {
+		name += exts[0]
+	}
+
+	w, err := zw.Create(name)
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	if _, err := io.Copy(w, body); err != nil {
+		return fmt.Errorf("copy: %w", err)
+	}
+
+	return nil
+}
+
+func (de *DataExporter) writePart(ctx context.Context, zw *zip.Writer, name string, key string) error {
+	body, err := de.Archive.Download(ctx, key)
+	if err != nil {
+		return fmt.Errorf("download: %w", err)
+	}
+	defer body.Close()
+
+	w, err := zw.Create(name)
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	if _, err := io.Copy(w, body); err != nil {
+		return fmt.Errorf("copy: %w", err)
+	}
+
+	return nil
+}
+
+func (de *DataExporter) Start(ctx context.Context) error {
+	lg, err := ctxkey.ExtractLogger(ctx)
+	if err != nil {
+		return fmt.Errorf("extract logger: %w", err)
+	}
+
+	go func() {
+		ticker := time.NewTicker(de.CFG.Delay)
+		defer ticker.Stop()
+		defer de.RequestProducer.Close()
+		defer de.EventProducer.Close()
+
+		for {
+			ids, err := de.Publish(ctx)
+			if err == nil {
+				lg.With(loglables.DataExportOutbox, ids).Info("publish data export outbox")
+				continue
+			}
+			if !errors.Is(err, NoDataExportOutboxError) {
+				lg.Error(fmt.Errorf("publish: %w", err))
+			}
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	go func() {
+		defer de.PartConsumer.Close()
+
+		for {
+			err := de.Collect(ctx)
+			if err == nil {
+				continue
+			}
+
+			lg.Error(fmt.Errorf("collect: %w", err))
+
+			select {
+			case <-time.After(de.CFG.Delay):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	go func() {
+		ticker := time.NewTicker(de.CFG.Delay)
+		defer ticker.Stop()
+
+		for {
+			export, err := de.Assemble(ctx)
+			if err == nil {
+				lg.With(loglables.DataExport, *export).Info("data export assembled")
+				continue
+			}
+			if !errors.Is(err, NoDataExportsError) {
+				lg.Error(fmt.Errorf("assemble: %w", err))
+			}
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return nil
+}
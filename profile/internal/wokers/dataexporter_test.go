@@ -0,0 +1,59 @@
+package workers
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/1ocknight/mess/profile/internal/ctxkey"
+	"github.com/1ocknight/mess/profile/internal/model"
+	storagemocks "github.com/1ocknight/mess/profile/internal/storage/mocks"
+	mqdto "github.com/1ocknight/mess/shared/dto/mq"
+	"github.com/1ocknight/mess/shared/logger"
+	mqmocks "github.com/1ocknight/mess/shared/messagequeue/mocks"
+)
+
+func TestDataExporter_Collect_FailedPart(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	ctx := ctxkey.WithLogger(t.Context(), logger.New(slog.NewJSONHandler(io.Discard, nil)))
+
+	value, err := json.Marshal(mqdto.DataExportPart{
+		ExportID:  1,
+		SubjectID: "subject",
+		Service:   "chat",
+		Error:     "pq: relation \"message\" does not exist",
+	})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	msg := newMessage(t, value)
+	consumer := mqmocks.NewMockConsumer(ctrl)
+	consumer.EXPECT().ReadMessage(ctx).Return(msg, nil)
+	consumer.EXPECT().Commit(ctx, msg).Return(nil)
+
+	storage := storagemocks.NewMockService(ctrl)
+	tx := storagemocks.NewMockServiceTransaction(ctrl)
+	export := storagemocks.NewMockDataExport(ctrl)
+	outbox := storagemocks.NewMockDataExportOutbox(ctrl)
+	storage.EXPECT().WithTransaction(gomock.Any()).Return(tx, nil)
+	tx.EXPECT().DataExport().Return(export).AnyTimes()
+	tx.EXPECT().DataExportOutbox().Return(outbox).AnyTimes()
+	tx.EXPECT().Commit().Return(nil)
+	tx.EXPECT().Rollback().Return(nil)
+
+	// only the generic reason is stored, the service error stays in the logs
+	export.EXPECT().FailDataExport(ctx, 1, DataExportFailedReason).
+		Return(&model.DataExport{ID: 1, SubjectID: "subject", Status: model.DataExportFailed}, nil)
+	outbox.EXPECT().AddDataExportOutbox(ctx, 1, model.DataExportNotifyOperation).Return(&model.DataExportOutbox{}, nil)
+
+	de := &DataExporter{
+		PartConsumer: consumer,
+		Storage:      storage,
+	}
+	if err := de.Collect(ctx); err != nil {
+		t.Fatalf("collect: %v", err)
+	}
+}
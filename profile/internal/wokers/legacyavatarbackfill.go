@@ -0,0 +1,139 @@
+package workers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/1ocknight/mess/profile/internal/adapter/avatar"
+	"github.com/1ocknight/mess/profile/internal/ctxkey"
+	"github.com/1ocknight/mess/profile/internal/loglables"
+	"github.com/1ocknight/mess/profile/internal/model"
+	"github.com/1ocknight/mess/profile/internal/storage"
+)
+
+const (
+	LegacyAvatarBackfillLimit = 100
+
+	DefaultLegacyAvatarBackfillDelay = 10 * time.Second
+)
+
+type LegacyAvatarBackfillConfig struct {
+	// Delay is the pause after a failed batch
+	Delay time.Duration `yaml:"delay"`
+}
+
+// LegacyAvatarBackfill is a one-off job for the profiles from before the versioned avatar keys. An object
+// under the bare subject id becomes the avatar of a profile without one, or is queued for deletion if
+// the profile is deleted or has a newer avatar. The job stops once every profile is checked.
+type LegacyAvatarBackfill struct {
+	CFG     LegacyAvatarBackfillConfig
+	Avatar  avatar.Service
+	Storage storage.Service
+}
+
+func NewLegacyAvatarBackfill(cfg LegacyAvatarBackfillConfig, avatar avatar.Service, storage storage.Service) *LegacyAvatarBackfill {
+	if cfg.Delay <= 0 {
+		cfg.Delay = DefaultLegacyAvatarBackfillDelay
+	}
+
+	return &LegacyAvatarBackfill{
+		CFG:     cfg,
+		Avatar:  avatar,
+		Storage: storage,
+	}
+}
+
+// Backfill checks one batch of profiles and returns how many were checked, zero means the job is done.
+func (lab *LegacyAvatarBackfill) Backfill(ctx context.Context) (int, error) {
+	lg, err := ctxkey.ExtractLogger(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("extract logger: %w", err)
+	}
+
+	tx, err := lab.Storage.WithTransaction(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("with transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	profiles, err := tx.Profile().GetLegacyAvatarUnchecked(ctx, LegacyAvatarBackfillLimit)
+	if err != nil {
+		return 0, fmt.Errorf("get legacy avatar unchecked: %w", err)
+	}
+
+	var restored, queued []string
+	for _, prof := range profiles {
+		key := prof.LegacyAvatarKey()
+		exists, err := lab.Avatar.Exists(ctx, key)
+		if err != nil {
+			return 0, fmt.Errorf("avatar exists: %w", err)
+		}
+
+		var avatarKey *string
+		switch {
+		case !exists, prof.AvatarKey != nil && *prof.AvatarKey == key:
+		case prof.DeletedAt == nil && prof.AvatarKey == nil:
+			avatarKey = &key
+			restored = append(restored, prof.SubjectID)
+		default:
+			if _, err := tx.AvatarOutbox().AddKey(ctx, key); err != nil {
+				return 0, fmt.Errorf("add key: %w", err)
+			}
+			queued = append(queued, key)
+		}
+
+		if _, err := tx.Profile().SetLegacyAvatarChecked(ctx, prof.SubjectID, avatarKey); err != nil {
+			return 0, fmt.Errorf("set legacy avatar checked: %w", err)
+		}
+		if avatarKey != nil {
+			// the avatar appears for the chat partners
+			if _, err := tx.ProfileOutbox().AddProfileOutbox(ctx, prof.SubjectID, model.ProfileUpdatedOperation); err != nil {
+				return 0, fmt.Errorf("add profile outbox: %w", err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit: %w", err)
+	}
+
+	if len(profiles) != 0 {
+		lg.With(loglables.Profiles, len(profiles)).
+			With(loglables.RestoredAvatars, restored).
+			With(loglables.DeletedAvatarKeys, queued).
+			Info("legacy avatars checked")
+	}
+
+	return len(profiles), nil
+}
+
+func (lab *LegacyAvatarBackfill) Start(ctx context.Context) error {
+	lg, err := ctxkey.ExtractLogger(ctx)
+	if err != nil {
+		return fmt.Errorf("extract logger: %w", err)
+	}
+
+	go func() {
+		for {
+			n, err := lab.Backfill(ctx)
+			if err == nil && n == 0 {
+				lg.Info("every legacy avatar is checked")
+				return
+			}
+			if err == nil {
+				continue
+			}
+
+			lg.Error(fmt.Errorf("backfill: %w", err))
+
+			select {
+			case <-time.After(lab.CFG.Delay):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return nil
+}
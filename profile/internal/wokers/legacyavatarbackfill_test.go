@@ -0,0 +1,69 @@
+package workers
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+
+	avatarmocks "github.com/1ocknight/mess/profile/internal/adapter/avatar/mocks"
+	"github.com/1ocknight/mess/profile/internal/ctxkey"
+	"github.com/1ocknight/mess/profile/internal/model"
+	storagemocks "github.com/1ocknight/mess/profile/internal/storage/mocks"
+	"github.com/1ocknight/mess/shared/logger"
+)
+
+func TestLegacyAvatarBackfill_Backfill(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	ctx := ctxkey.WithLogger(t.Context(), logger.New(slog.NewJSONHandler(io.Discard, nil)))
+
+	storage := storagemocks.NewMockService(ctrl)
+	tx := storagemocks.NewMockServiceTransaction(ctrl)
+	profile := storagemocks.NewMockProfile(ctrl)
+	outbox := storagemocks.NewMockAvatarOutbox(ctrl)
+	pOut := storagemocks.NewMockProfileOutbox(ctrl)
+	av := avatarmocks.NewMockService(ctrl)
+	storage.EXPECT().WithTransaction(gomock.Any()).Return(tx, nil)
+	tx.EXPECT().Profile().Return(profile).AnyTimes()
+	tx.EXPECT().AvatarOutbox().Return(outbox).AnyTimes()
+	tx.EXPECT().ProfileOutbox().Return(pOut).AnyTimes()
+	tx.EXPECT().Commit().Return(nil)
+	tx.EXPECT().Rollback().Return(nil)
+
+	newer := "newer/1-hash"
+	deletedAt := time.Now()
+	profiles := []*model.Profile{
+		{SubjectID: "restored"},
+		{SubjectID: "missing"},
+		{SubjectID: "newer", AvatarKey: &newer},
+		{SubjectID: "deleted", DeletedAt: &deletedAt},
+	}
+	profile.EXPECT().GetLegacyAvatarUnchecked(ctx, LegacyAvatarBackfillLimit).Return(profiles, nil)
+
+	av.EXPECT().Exists(ctx, "restored").Return(true, nil)
+	av.EXPECT().Exists(ctx, "missing").Return(false, nil)
+	av.EXPECT().Exists(ctx, "newer").Return(true, nil)
+	av.EXPECT().Exists(ctx, "deleted").Return(true, nil)
+
+	// the object becomes the avatar of the profile without one
+	restored := "restored"
+	profile.EXPECT().SetLegacyAvatarChecked(ctx, "restored", &restored).Return(&model.Profile{}, nil)
+	pOut.EXPECT().AddProfileOutbox(ctx, "restored", model.ProfileUpdatedOperation).Return(&model.ProfileOutbox{}, nil)
+	profile.EXPECT().SetLegacyAvatarChecked(ctx, "missing", nil).Return(&model.Profile{}, nil)
+	// an object replaced by a newer avatar or left by a deleted profile is queued for deletion
+	outbox.EXPECT().AddKey(ctx, "newer").Return(&model.AvatarOutbox{}, nil)
+	profile.EXPECT().SetLegacyAvatarChecked(ctx, "newer", nil).Return(&model.Profile{}, nil)
+	outbox.EXPECT().AddKey(ctx, "deleted").Return(&model.AvatarOutbox{}, nil)
+	profile.EXPECT().SetLegacyAvatarChecked(ctx, "deleted", nil).Return(&model.Profile{}, nil)
+
+	lab := NewLegacyAvatarBackfill(LegacyAvatarBackfillConfig{}, av, storage)
+	n, err := lab.Backfill(ctx)
+	if err != nil {
+		t.Fatalf("backfill: %v", err)
+	}
+	if n != len(profiles) {
+		t.Fatalf("wait %v checked, have %v", len(profiles), n)
+	}
+}
@@ -8,7 +8,9 @@ import (
 	"time"
 
 	"github.com/1ocknight/mess/profile/internal/ctxkey"
+	"github.com/1ocknight/mess/profile/internal/domain"
 	"github.com/1ocknight/mess/profile/internal/loglables"
+	"github.com/1ocknight/mess/profile/internal/model"
 	"github.com/1ocknight/mess/profile/internal/storage"
 	"github.com/1ocknight/mess/shared/messagequeue"
 	"github.com/1ocknight/mess/shared/messagequeue/kafka"
@@ -79,6 +81,7 @@ func ProfileDelete[T ProfileDeleteMessage](ctx context.Context, cons messagequeu
 	if err != nil {
 		return fmt.Errorf("with transaction: %w", err)
 	}
+	defer tx.Rollback()
 
 	prof, err := tx.Profile().DeleteProfile(ctx, msg.GetSubjectID())
 	if err != nil && errors.Is(err, storage.ErrNoRows) {
@@ -92,12 +95,25 @@ func ProfileDelete[T ProfileDeleteMessage](ctx context.Context, cons messagequeu
 		return fmt.Errorf("delete profile: %w", err)
 	}
 
-	out, err := tx.AvatarOutbox().AddKey(ctx, prof.SubjectID)
+	outboxes, err := domain.DeleteAvatars(ctx, tx, prof)
 	if err != nil {
-		return fmt.Errorf("add key: %w", err)
+		return fmt.Errorf("delete avatars: %w", err)
 	}
+	lg = lg.With(loglables.AvatarOutbox, outboxes)
 
-	lg = lg.With(loglables.AvatarOutbox, *out)
+	if err := domain.HoldUsername(ctx, tx, prof); err != nil {
+		return fmt.Errorf("hold username: %w", err)
+	}
+
+	contacts, err := tx.Contact().DeleteSubjectContacts(ctx, prof.SubjectID)
+	if err != nil {
+		return fmt.Errorf("delete subject contacts: %w", err)
+	}
+	lg = lg.With(loglables.DeletedContacts, len(contacts))
+
+	if _, err := tx.ProfileOutbox().AddProfileOutbox(ctx, prof.SubjectID, model.ProfileDeletedOperation); err != nil {
+		return fmt.Errorf("add profile outbox: %w", err)
+	}
 
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("commit: %w", err)
@@ -0,0 +1,50 @@
+package workers
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/1ocknight/mess/profile/internal/ctxkey"
+	"github.com/1ocknight/mess/profile/internal/model"
+	storagemocks "github.com/1ocknight/mess/profile/internal/storage/mocks"
+	"github.com/1ocknight/mess/shared/logger"
+	mqmocks "github.com/1ocknight/mess/shared/messagequeue/mocks"
+)
+
+func TestProfileDelete(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	ctx := ctxkey.WithLogger(t.Context(), logger.New(slog.NewJSONHandler(io.Discard, nil)))
+
+	msg := newMessage(t, []byte(`{"userId":"subj"}`))
+	consumer := mqmocks.NewMockConsumer(ctrl)
+	consumer.EXPECT().ReadMessage(ctx).Return(msg, nil)
+
+	storage := storagemocks.NewMockService(ctrl)
+	tx := storagemocks.NewMockServiceTransaction(ctrl)
+	profile := storagemocks.NewMockProfile(ctrl)
+	contact := storagemocks.NewMockContact(ctrl)
+	profileOutbox := storagemocks.NewMockProfileOutbox(ctrl)
+	storage.EXPECT().WithTransaction(gomock.Any()).Return(tx, nil)
+	tx.EXPECT().Profile().Return(profile).AnyTimes()
+	tx.EXPECT().Contact().Return(contact).AnyTimes()
+	tx.EXPECT().ProfileOutbox().Return(profileOutbox).AnyTimes()
+	tx.EXPECT().Rollback().Return(nil)
+
+	deleted := &model.Profile{SubjectID: "subj", LegacyAvatarChecked: true}
+	profile.EXPECT().DeleteProfile(ctx, "subj").Return(deleted, nil)
+	// contacts go in both directions before the transaction is committed
+	deleteContacts := contact.EXPECT().DeleteSubjectContacts(ctx, "subj").Return([]*model.Contact{
+		{SubjectID: "subj", ContactSubjectID: "friend"},
+		{SubjectID: "other", ContactSubjectID: "subj"},
+	}, nil)
+	profileOutbox.EXPECT().AddProfileOutbox(ctx, "subj", model.ProfileDeletedOperation).Return(&model.ProfileOutbox{}, nil)
+	commit := tx.EXPECT().Commit().Return(nil).After(deleteContacts)
+	consumer.EXPECT().Commit(ctx, msg).Return(nil).After(commit)
+
+	if err := ProfileDelete[*ClientProfileDeleteMessage](ctx, consumer, storage); err != nil {
+		t.Fatalf("profile delete: %v", err)
+	}
+}
@@ -0,0 +1,166 @@
+package workers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/1ocknight/mess/profile/internal/ctxkey"
+	"github.com/1ocknight/mess/profile/internal/loglables"
+	"github.com/1ocknight/mess/profile/internal/model"
+	"github.com/1ocknight/mess/profile/internal/storage"
+	mqdto "github.com/1ocknight/mess/shared/dto/mq"
+	"github.com/1ocknight/mess/shared/messagequeue"
+	"github.com/1ocknight/mess/shared/messagequeue/kafka"
+)
+
+type ProfileEventSenderConfig struct {
+	EventKafka  kafka.ProducerConfig `yaml:"event_kafka"`
+	OutboxLimit int                  `yaml:"outbox_limit"`
+	Delay       time.Duration        `yaml:"delay"`
+}
+
+// ProfileEventSender publishes the profile outbox as profile events, so other services can keep
+// copies of aliases without asking profile.
+type ProfileEventSender struct {
+	CFG           ProfileEventSenderConfig
+	EventProducer messagequeue.Producer
+	Storage       storage.Service
+}
+
+func NewProfileEventSender(cfg ProfileEventSenderConfig, s storage.Service) *ProfileEventSender {
+	return &ProfileEventSender{
+		CFG:           cfg,
+		EventProducer: kafka.NewProducer(cfg.EventKafka),
+		Storage:       s,
+	}
+}
+
+var (
+	NoProfileOutboxError = fmt.Errorf("no profile outbox")
+)
+
+// Publish sends a batch of the outbox. The batch has at most one row of a subject and the next row
+// is taken only after this one is committed, so replicas can not reorder the events of a subject.
+func (pes *ProfileEventSender) Publish(ctx context.Context) ([]int, error) {
+	lg, err := ctxkey.ExtractLogger(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("extract logger: %w", err)
+	}
+
+	tx, err := pes.Storage.WithTransaction(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("with transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	outboxes, err := tx.ProfileOutbox().GetProfileOutbox(ctx, pes.CFG.OutboxLimit)
+	if err != nil {
+		return nil, fmt.Errorf("get profile outbox: %w", err)
+	}
+	if len(outboxes) == 0 {
+		return nil, NoProfileOutboxError
+	}
+
+	profiles, err := tx.Profile().GetProfilesFromSubjectIDs(ctx, model.GetSubjectIDsFromProfileOutboxes(outboxes))
+	if err != nil {
+		return nil, fmt.Errorf("get profiles from subject ids: %w", err)
+	}
+
+	profilesMap := make(map[string]*model.Profile, len(profiles))
+	for _, profile := range profiles {
+		profilesMap[profile.SubjectID] = profile
+	}
+
+	events := make([]*messagequeue.KeyValPair, 0, len(outboxes))
+	for _, out := range outboxes {
+		var event mqdto.ProfileEvent
+		switch out.Operation {
+		case model.ProfileCreatedOperation, model.ProfileUpdatedOperation:
+			profile, ok := profilesMap[out.SubjectID]
+			if !ok {
+				// the profile is deleted since, its deleted event is later in the outbox
+				lg.With(loglables.ProfileOutbox, *out).Info("profile not found, skip event")
+				continue
+			}
+			event = mqdto.ProfileEvent{
+				Type:        mqdto.ProfileUpdatedEvent,
+				SubjectID:   profile.SubjectID,
+				Alias:       profile.Alias,
+				Username:    profile.Username,
+				DisplayName: profile.DisplayName,
+				Version:     profile.Version,
+				UpdatedAt:   profile.UpdatedAt,
+			}
+			if out.Operation == model.ProfileCreatedOperation {
+				event.Type = mqdto.ProfileCreatedEvent
+			}
+		case model.ProfileDeletedOperation:
+			event = mqdto.ProfileEvent{
+				Type:      mqdto.ProfileDeletedEvent,
+				SubjectID: out.SubjectID,
+				UpdatedAt: out.CreatedAt,
+			}
+		default:
+			lg.With(loglables.ProfileOutbox, *out).Error(fmt.Errorf("unknown operation"))
+			continue
+		}
+
+		val, err := json.Marshal(event)
+		if err != nil {
+			return nil, fmt.Errorf("marshal: %w", err)
+		}
+		events = append(events, &messagequeue.KeyValPair{Key: []byte(out.SubjectID), Val: val})
+	}
+
+	if len(events) != 0 {
+		if err := pes.EventProducer.BatchPublish(ctx, events); err != nil {
+			return nil, fmt.Errorf("publish events: %w", err)
+		}
+	}
+
+	ids := model.GetProfileOutboxIDs(outboxes)
+	if _, err := tx.ProfileOutbox().DeleteProfileOutbox(ctx, ids); err != nil {
+		return nil, fmt.Errorf("delete profile outbox: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+
+	return ids, nil
+}
+
+func (pes *ProfileEventSender) Start(ctx context.Context) error {
+	lg, err := ctxkey.ExtractLogger(ctx)
+	if err != nil {
+		return fmt.Errorf("extract logger: %w", err)
+	}
+
+	go func() {
+		ticker := time.NewTicker(pes.CFG.Delay)
+		defer ticker.Stop()
+		defer pes.EventProducer.Close()
+
+		for {
+			ids, err := pes.Publish(ctx)
+			if err == nil {
+				lg.With(loglables.ProfileOutbox, ids).Info("publish profile outbox")
+				continue
+			}
+			if !errors.Is(err, NoProfileOutboxError) {
+				lg.Error(fmt.Errorf("publish: %w", err))
+			}
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package workers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/1ocknight/mess/profile/internal/ctxkey"
+	"github.com/1ocknight/mess/profile/internal/model"
+	storagemocks "github.com/1ocknight/mess/profile/internal/storage/mocks"
+	mqdto "github.com/1ocknight/mess/shared/dto/mq"
+	"github.com/1ocknight/mess/shared/logger"
+)
+
+func TestProfileEventSender_Publish(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	ctx := ctxkey.WithLogger(t.Context(), logger.New(slog.NewJSONHandler(io.Discard, nil)))
+
+	storage := storagemocks.NewMockService(ctrl)
+	tx := storagemocks.NewMockServiceTransaction(ctrl)
+	profile := storagemocks.NewMockProfile(ctrl)
+	outbox := storagemocks.NewMockProfileOutbox(ctrl)
+	storage.EXPECT().WithTransaction(gomock.Any()).Return(tx, nil)
+	tx.EXPECT().Profile().Return(profile).AnyTimes()
+	tx.EXPECT().ProfileOutbox().Return(outbox).AnyTimes()
+	tx.EXPECT().Commit().Return(nil)
+	tx.EXPECT().Rollback().Return(nil)
+
+	deletedAt := time.Now().UTC()
+	outboxes := []*model.ProfileOutbox{
+		{ID: 1, SubjectID: "created", Operation: model.ProfileCreatedOperation},
+		// the profile is deleted since, its deleted event follows later
+		{ID: 2, SubjectID: "gone", Operation: model.ProfileUpdatedOperation},
+		{ID: 3, SubjectID: "deleted", Operation: model.ProfileDeletedOperation, CreatedAt: deletedAt},
+		{ID: 4, SubjectID: "unknown", Operation: model.UnknownProfileOperation},
+	}
+	outbox.EXPECT().GetProfileOutbox(ctx, 10).Return(outboxes, nil)
+	profile.EXPECT().GetProfilesFromSubjectIDs(ctx, []string{"created", "gone", "deleted", "unknown"}).
+		Return([]*model.Profile{{SubjectID: "created", Alias: "alias", Version: 1}}, nil)
+	// the skipped rows are deleted too, they would block the later rows of their subjects
+	outbox.EXPECT().DeleteProfileOutbox(ctx, []int{1, 2, 3, 4}).Return(outboxes, nil)
+
+	producer := &fakeProducer{}
+	pes := &ProfileEventSender{
+		CFG:           ProfileEventSenderConfig{OutboxLimit: 10},
+		EventProducer: producer,
+		Storage:       storage,
+	}
+
+	ids, err := pes.Publish(ctx)
+	if err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	if len(ids) != 4 {
+		t.Fatalf("wait 4 ids, have %v", ids)
+	}
+
+	if len(producer.pairs) != 2 {
+		t.Fatalf("wait 2 events, have %v", len(producer.pairs))
+	}
+	want := []mqdto.ProfileEvent{
+		{Type: mqdto.ProfileCreatedEvent, SubjectID: "created", Alias: "alias", Version: 1},
+		{Type: mqdto.ProfileDeletedEvent, SubjectID: "deleted", UpdatedAt: deletedAt},
+	}
+	for i, pair := range producer.pairs {
+		var event mqdto.ProfileEvent
+		if err := json.Unmarshal(pair.Val, &event); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		if string(pair.Key) != want[i].SubjectID || event.Type != want[i].Type || event.SubjectID != want[i].SubjectID ||
+			event.Alias != want[i].Alias || event.Version != want[i].Version || !event.UpdatedAt.Equal(want[i].UpdatedAt) {
+			t.Fatalf("event %v: wait %+v, have %+v with key %s", i, want[i], event, pair.Key)
+		}
+	}
+}
+
+func TestProfileEventSender_Publish_Empty(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	ctx := ctxkey.WithLogger(t.Context(), logger.New(slog.NewJSONHandler(io.Discard, nil)))
+
+	storage := storagemocks.NewMockService(ctrl)
+	tx := storagemocks.NewMockServiceTransaction(ctrl)
+	outbox := storagemocks.NewMockProfileOutbox(ctrl)
+	storage.EXPECT().WithTransaction(gomock.Any()).Return(tx, nil)
+	tx.EXPECT().ProfileOutbox().Return(outbox).AnyTimes()
+	tx.EXPECT().Rollback().Return(nil)
+	outbox.EXPECT().GetProfileOutbox(ctx, 10).Return(nil, nil)
+
+	pes := &ProfileEventSender{
+		CFG:           ProfileEventSenderConfig{OutboxLimit: 10},
+		EventProducer: &fakeProducer{},
+		Storage:       storage,
+	}
+
+	if _, err := pes.Publish(ctx); !errors.Is(err, NoProfileOutboxError) {
+		t.Fatalf("wait %v, have %v", NoProfileOutboxError, err)
+	}
+}
@@ -0,0 +1,6 @@
+CREATE INDEX IF NOT EXISTS idx_profile_alias ON profile(alias);
+
+DROP INDEX IF EXISTS idx_contact_nickname_trgm;
+DROP INDEX IF EXISTS idx_profile_alias_trgm;
+
+DROP EXTENSION IF EXISTS pg_trgm;
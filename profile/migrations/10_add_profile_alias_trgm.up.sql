@@ -0,0 +1,11 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- fuzzy case-insensitive search by alias and by contact nickname, prefix LIKE is served by the same indexes
+CREATE INDEX idx_profile_alias_trgm
+ON profile USING GIN (lower(alias) gin_trgm_ops)
+WHERE deleted_at IS NULL;
+
+CREATE INDEX idx_contact_nickname_trgm
+ON contact USING GIN (lower(nickname) gin_trgm_ops);
+
+DROP INDEX IF EXISTS idx_profile_alias;
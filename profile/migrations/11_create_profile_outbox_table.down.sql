@@ -0,0 +1 @@
+DROP TABLE IF EXISTS profile_outbox;
\ No newline at end of file
@@ -0,0 +1,11 @@
+CREATE TABLE profile_outbox (
+    id SERIAL PRIMARY KEY,
+    subject_id TEXT NOT NULL,
+    operation INT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    deleted_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_profile_outbox_pending
+ON profile_outbox (id)
+WHERE deleted_at IS NULL;
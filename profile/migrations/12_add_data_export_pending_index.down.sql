@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_data_export_collected;
+
+CREATE INDEX idx_data_export_collected
+ON data_export (id)
+WHERE status = 1 AND deleted_at IS NULL;
+
+DROP INDEX IF EXISTS idx_data_export_outbox_pending;
@@ -0,0 +1,9 @@
+CREATE INDEX idx_data_export_outbox_pending
+ON data_export_outbox (id)
+WHERE deleted_at IS NULL;
+
+DROP INDEX IF EXISTS idx_data_export_collected;
+
+CREATE INDEX idx_data_export_collected
+ON data_export (id)
+WHERE status IN (1, 4) AND deleted_at IS NULL;
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_profile_outbox_subject_pending;
@@ -0,0 +1,3 @@
+CREATE INDEX idx_profile_outbox_subject_pending
+ON profile_outbox (subject_id, id)
+WHERE deleted_at IS NULL;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_profile_legacy_avatar_unchecked;
+
+ALTER TABLE profile DROP COLUMN legacy_avatar_checked;
@@ -0,0 +1,8 @@
+-- profiles from before avatar_key may have an object under their subject id, the legacy avatar backfill
+-- checks every old profile once, new profiles never had such an object
+ALTER TABLE profile ADD COLUMN legacy_avatar_checked BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE profile ALTER COLUMN legacy_avatar_checked SET DEFAULT TRUE;
+
+CREATE INDEX idx_profile_legacy_avatar_unchecked
+ON profile (subject_id)
+WHERE NOT legacy_avatar_checked;
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_contact_contact_subject;
@@ -0,0 +1,8 @@
+-- contacts are deleted with the profile in both directions, the reverse side needs its own index
+CREATE INDEX idx_contact_contact_subject
+ON contact (contact_subject_id);
+
+-- profiles deleted before this migration left their contacts behind
+DELETE FROM contact
+WHERE subject_id IN (SELECT subject_id FROM profile WHERE deleted_at IS NOT NULL)
+   OR contact_subject_id IN (SELECT subject_id FROM profile WHERE deleted_at IS NOT NULL);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS data_export_outbox;
+DROP TABLE IF EXISTS data_export;
\ No newline at end of file
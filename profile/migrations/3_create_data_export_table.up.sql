@@ -0,0 +1,23 @@
+CREATE TABLE data_export (
+    id SERIAL PRIMARY KEY,
+    subject_id TEXT NOT NULL,
+    status INT NOT NULL DEFAULT 0,
+    chat_part_key TEXT,
+    object_key TEXT,
+    error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    deleted_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_data_export_collected
+ON data_export (id)
+WHERE status = 1 AND deleted_at IS NULL;
+
+CREATE TABLE data_export_outbox (
+    id SERIAL PRIMARY KEY,
+    export_id INT NOT NULL,
+    operation INT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    deleted_at TIMESTAMPTZ
+);
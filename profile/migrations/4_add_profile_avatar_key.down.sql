@@ -0,0 +1,4 @@
+ALTER TABLE avatar_outbox RENAME COLUMN key TO subject_id;
+
+ALTER TABLE profile DROP COLUMN IF EXISTS avatar_version;
+ALTER TABLE profile DROP COLUMN IF EXISTS avatar_key;
@@ -0,0 +1,8 @@
+ALTER TABLE profile ADD COLUMN avatar_key TEXT;
+ALTER TABLE profile ADD COLUMN avatar_version INT NOT NULL DEFAULT 0;
+
+-- avatar_key stays NULL: sql can not tell which profiles have an object under their subject id,
+-- a url to a missing object is worse than no avatar, the legacy avatar backfill worker sets the key
+-- after it finds the object, see migration 14
+
+ALTER TABLE avatar_outbox RENAME COLUMN subject_id TO key;
@@ -0,0 +1 @@
+ALTER TABLE profile ADD COLUMN pending_avatar_key TEXT;
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS username_hold;
+
+DROP INDEX IF EXISTS uniq_profile_username_alive;
+
+ALTER TABLE profile DROP COLUMN IF EXISTS username_changed_at;
+ALTER TABLE profile DROP COLUMN IF EXISTS username;
@@ -0,0 +1,15 @@
+ALTER TABLE profile ADD COLUMN username TEXT;
+ALTER TABLE profile ADD COLUMN username_changed_at TIMESTAMPTZ;
+
+-- usernames keep their case for display, but are unique and searched case-insensitive
+CREATE UNIQUE INDEX uniq_profile_username_alive
+ON profile(lower(username))
+WHERE deleted_at IS NULL;
+
+-- released usernames stay with their last owner until held_until, username is stored lowercased
+CREATE TABLE username_hold (
+    username TEXT NOT NULL PRIMARY KEY,
+    subject_id TEXT NOT NULL,
+    held_until TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
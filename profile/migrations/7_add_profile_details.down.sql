@@ -0,0 +1,6 @@
+ALTER TABLE profile DROP COLUMN IF EXISTS links;
+ALTER TABLE profile DROP COLUMN IF EXISTS status_expires_at;
+ALTER TABLE profile DROP COLUMN IF EXISTS status_emoji;
+ALTER TABLE profile DROP COLUMN IF EXISTS status_text;
+ALTER TABLE profile DROP COLUMN IF EXISTS bio;
+ALTER TABLE profile DROP COLUMN IF EXISTS display_name;
@@ -0,0 +1,7 @@
+ALTER TABLE profile ADD COLUMN display_name TEXT;
+ALTER TABLE profile ADD COLUMN bio TEXT;
+ALTER TABLE profile ADD COLUMN status_text TEXT;
+ALTER TABLE profile ADD COLUMN status_emoji TEXT;
+ALTER TABLE profile ADD COLUMN status_expires_at TIMESTAMPTZ;
+-- links are a short list of {"title", "url"} objects, validated by the service
+ALTER TABLE profile ADD COLUMN links JSONB NOT NULL DEFAULT '[]';
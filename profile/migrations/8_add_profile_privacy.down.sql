@@ -0,0 +1,6 @@
+ALTER TABLE profile DROP COLUMN IF EXISTS new_chat_permission;
+ALTER TABLE profile DROP COLUMN IF EXISTS last_seen_visibility;
+ALTER TABLE profile DROP COLUMN IF EXISTS avatar_visibility;
+ALTER TABLE profile DROP COLUMN IF EXISTS search_visibility;
+
+ALTER TABLE profile DROP COLUMN IF EXISTS last_seen_at;
@@ -0,0 +1,7 @@
+ALTER TABLE profile ADD COLUMN last_seen_at TIMESTAMPTZ;
+
+-- audiences are everyone, contacts or nobody, see model.Audience
+ALTER TABLE profile ADD COLUMN search_visibility TEXT NOT NULL DEFAULT 'everyone';
+ALTER TABLE profile ADD COLUMN avatar_visibility TEXT NOT NULL DEFAULT 'everyone';
+ALTER TABLE profile ADD COLUMN last_seen_visibility TEXT NOT NULL DEFAULT 'everyone';
+ALTER TABLE profile ADD COLUMN new_chat_permission TEXT NOT NULL DEFAULT 'everyone';
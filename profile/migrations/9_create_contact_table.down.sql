@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_contact_subject_created;
+DROP TABLE IF EXISTS contact;
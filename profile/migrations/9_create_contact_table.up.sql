@@ -0,0 +1,12 @@
+-- contacts of subject_id, the same subject is added once and adding again changes the nickname
+CREATE TABLE contact (
+    subject_id TEXT NOT NULL,
+    contact_subject_id TEXT NOT NULL,
+    nickname TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (subject_id, contact_subject_id)
+);
+
+CREATE INDEX idx_contact_subject_created
+ON contact (subject_id, created_at, contact_subject_id);
@@ -0,0 +1,100 @@
+package cursor
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+type Direction string
+
+const (
+	DirectionAfter  Direction = "after"
+	DirectionBefore Direction = "before"
+)
+
+type Cursor struct {
+	Direction Direction `json:"d"`
+	SortKey   string    `json:"k"`
+	ID        string    `json:"i"`
+}
+
+type Config struct {
+	Secret string `yaml:"secret"`
+}
+
+type Service interface {
+	Encode(c *Cursor) (string, error)
+	Decode(token string) (*Cursor, error)
+}
+
+var (
+	ErrInvalidCursor = fmt.Errorf("invalid cursor")
+)
+
+const tokenSeparator = "."
+
+var encoding = base64.RawURLEncoding
+
+type Signer struct {
+	secret []byte
+}
+
+func New(cfg Config) (*Signer, error) {
+	if cfg.Secret == "" {
+		return nil, fmt.Errorf("empty cursor secret")
+	}
+
+	return &Signer{
+		secret: []byte(cfg.Secret),
+	}, nil
+}
+
+func (s *Signer) Encode(c *Cursor) (string, error) {
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return "", fmt.Errorf("marshal cursor: %w", err)
+	}
+
+	return encoding.EncodeToString(payload) + tokenSeparator + encoding.EncodeToString(s.sign(payload)), nil
+}
+
+func (s *Signer) Decode(token string) (*Cursor, error) {
+	sPayload, sSign, ok := strings.Cut(token, tokenSeparator)
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+
+	payload, err := encoding.DecodeString(sPayload)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	sign, err := encoding.DecodeString(sSign)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	if !hmac.Equal(sign, s.sign(payload)) {
+		return nil, ErrInvalidCursor
+	}
+
+	var c Cursor
+	if err := json.Unmarshal(payload, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	if c.Direction != DirectionAfter && c.Direction != DirectionBefore {
+		return nil, ErrInvalidCursor
+	}
+
+	return &c, nil
+}
+
+func (s *Signer) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
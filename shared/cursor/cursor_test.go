@@ -0,0 +1,93 @@
+package cursor
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func newTestSigner(t *testing.T, secret string) *Signer {
+	t.Helper()
+
+	s, err := New(Config{Secret: secret})
+	if err != nil {
+		t.Fatalf("new signer: %v", err)
+	}
+	return s
+}
+
+func TestNew_EmptySecret(t *testing.T) {
+	if _, err := New(Config{}); err == nil {
+		t.Fatalf("wait error, have nil")
+	}
+}
+
+func TestSigner_EncodeDecode(t *testing.T) {
+	s := newTestSigner(t, "secret")
+
+	want := &Cursor{Direction: DirectionBefore, SortKey: "2024-01-02T03:04:05Z", ID: "42"}
+	token, err := s.Encode(want)
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+
+	have, err := s.Decode(token)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if *have != *want {
+		t.Fatalf("wait %+v, have %+v", want, have)
+	}
+}
+
+func TestSigner_Decode_Invalid(t *testing.T) {
+	s := newTestSigner(t, "secret")
+
+	token, err := s.Encode(&Cursor{Direction: DirectionAfter, SortKey: "1", ID: "1"})
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	payload, sign, _ := strings.Cut(token, tokenSeparator)
+
+	forged, err := s.Encode(&Cursor{Direction: DirectionAfter, SortKey: "1", ID: "2"})
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	forgedPayload, _, _ := strings.Cut(forged, tokenSeparator)
+
+	foreign, err := newTestSigner(t, "other").Encode(&Cursor{Direction: DirectionAfter, SortKey: "1", ID: "1"})
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+
+	badDirection := encoding.EncodeToString([]byte(`{"d":"up","k":"1","i":"1"}`))
+	badJSON := encoding.EncodeToString([]byte(`{"d":`))
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "empty", token: ""},
+		{name: "no separator", token: payload + sign},
+		{name: "bad payload encoding", token: "!!!" + tokenSeparator + sign},
+		{name: "bad signature encoding", token: payload + tokenSeparator + "!!!"},
+		{name: "tampered payload", token: forgedPayload + tokenSeparator + sign},
+		{name: "tampered signature", token: payload + tokenSeparator + encoding.EncodeToString([]byte("signature"))},
+		{name: "empty signature", token: payload + tokenSeparator},
+		{name: "wrong secret", token: foreign},
+		{name: "bad json", token: badJSON + tokenSeparator + encoding.EncodeToString(s.sign([]byte(`{"d":`)))},
+		{name: "bad direction", token: badDirection + tokenSeparator + encoding.EncodeToString(s.sign([]byte(`{"d":"up","k":"1","i":"1"}`)))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := s.Decode(tt.token)
+			if !errors.Is(err, ErrInvalidCursor) {
+				t.Fatalf("wait %v, have %v", ErrInvalidCursor, err)
+			}
+			if c != nil {
+				t.Fatalf("wait nil cursor, have %+v", c)
+			}
+		})
+	}
+}
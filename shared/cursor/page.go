@@ -0,0 +1,70 @@
+package cursor
+
+import (
+	"fmt"
+
+	"github.com/1ocknight/mess/shared/utils"
+)
+
+type Page struct {
+	Next *Cursor
+	Prev *Cursor
+}
+
+// GetDirection returns the direction of the requested page, def is used for the first page.
+func (c *Cursor) GetDirection(def Direction) Direction {
+	if c == nil {
+		return def
+	}
+	return c.Direction
+}
+
+// NewPage expects items fetched in the requested direction with limit+1 rows,
+// trims the extra row, restores display order and builds cursors to the neighbour pages.
+func NewPage[T any](items []T, limit int, req *Cursor, def Direction, key func(T) *Cursor) ([]T, *Page) {
+	dir := req.GetDirection(def)
+
+	hasMore := len(items) > limit
+	if hasMore {
+		items = items[:limit]
+	}
+
+	if dir == DirectionBefore {
+		utils.ReverseSlice(items)
+	}
+
+	page := &Page{}
+	if len(items) == 0 {
+		return items, page
+	}
+
+	if dir == DirectionAfter && hasMore || dir == DirectionBefore && req != nil {
+		page.Next = key(items[len(items)-1])
+		page.Next.Direction = DirectionAfter
+	}
+
+	if dir == DirectionBefore && hasMore || dir == DirectionAfter && req != nil {
+		page.Prev = key(items[0])
+		page.Prev.Direction = DirectionBefore
+	}
+
+	return items, page
+}
+
+// EncodePage signs the cursors of the neighbour pages, a missing neighbour gives an empty string.
+func EncodePage(s Service, page *Page) (next string, prev string, err error) {
+	if page.Next != nil {
+		next, err = s.Encode(page.Next)
+		if err != nil {
+			return "", "", fmt.Errorf("encode next cursor: %w", err)
+		}
+	}
+	if page.Prev != nil {
+		prev, err = s.Encode(page.Prev)
+		if err != nil {
+			return "", "", fmt.Errorf("encode prev cursor: %w", err)
+		}
+	}
+
+	return next, prev, nil
+}
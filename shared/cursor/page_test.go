@@ -0,0 +1,151 @@
+package cursor
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func intKey(v int) *Cursor {
+	return &Cursor{SortKey: strconv.Itoa(v), ID: strconv.Itoa(v)}
+}
+
+func pageIDs(page *Page) (next string, prev string) {
+	if page.Next != nil {
+		next = page.Next.ID + ":" + string(page.Next.Direction)
+	}
+	if page.Prev != nil {
+		prev = page.Prev.ID + ":" + string(page.Prev.Direction)
+	}
+	return next, prev
+}
+
+func TestNewPage(t *testing.T) {
+	after := &Cursor{Direction: DirectionAfter}
+	before := &Cursor{Direction: DirectionBefore}
+
+	tests := []struct {
+		name      string
+		items     []int
+		req       *Cursor
+		def       Direction
+		wantItems []int
+		wantNext  string
+		wantPrev  string
+	}{
+		{
+			name:      "first page after with more",
+			items:     []int{1, 2, 3, 4},
+			def:       DirectionAfter,
+			wantItems: []int{1, 2, 3},
+			wantNext:  "3:after",
+		},
+		{
+			name:      "first page after without more",
+			items:     []int{1, 2},
+			def:       DirectionAfter,
+			wantItems: []int{1, 2},
+		},
+		{
+			name:      "next page after with more",
+			items:     []int{4, 5, 6, 7},
+			req:       after,
+			def:       DirectionAfter,
+			wantItems: []int{4, 5, 6},
+			wantNext:  "6:after",
+			wantPrev:  "4:before",
+		},
+		{
+			name:      "last page after",
+			items:     []int{7, 8},
+			req:       after,
+			def:       DirectionAfter,
+			wantItems: []int{7, 8},
+			wantPrev:  "7:before",
+		},
+		{
+			name:      "first page before with more",
+			items:     []int{9, 8, 7, 6},
+			def:       DirectionBefore,
+			wantItems: []int{7, 8, 9},
+			wantPrev:  "7:before",
+		},
+		{
+			name:      "first page before without more",
+			items:     []int{2, 1},
+			def:       DirectionBefore,
+			wantItems: []int{1, 2},
+		},
+		{
+			name:      "prev page before with more",
+			items:     []int{6, 5, 4, 3},
+			req:       before,
+			def:       DirectionAfter,
+			wantItems: []int{4, 5, 6},
+			wantNext:  "6:after",
+			wantPrev:  "4:before",
+		},
+		{
+			name:      "oldest page before",
+			items:     []int{2, 1},
+			req:       before,
+			def:       DirectionAfter,
+			wantItems: []int{1, 2},
+			wantNext:  "2:after",
+		},
+		{
+			name:      "empty",
+			items:     []int{},
+			req:       after,
+			def:       DirectionAfter,
+			wantItems: []int{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, page := NewPage(tt.items, 3, tt.req, tt.def, intKey)
+			if !reflect.DeepEqual(items, tt.wantItems) {
+				t.Fatalf("wait %v, have %v", tt.wantItems, items)
+			}
+
+			next, prev := pageIDs(page)
+			if next != tt.wantNext {
+				t.Fatalf("wait next %q, have %q", tt.wantNext, next)
+			}
+			if prev != tt.wantPrev {
+				t.Fatalf("wait prev %q, have %q", tt.wantPrev, prev)
+			}
+		})
+	}
+}
+
+type failSigner struct{}
+
+func (failSigner) Encode(*Cursor) (string, error) { return "", errors.New("fail") }
+
+func (failSigner) Decode(string) (*Cursor, error) { return nil, errors.New("fail") }
+
+func TestEncodePage(t *testing.T) {
+	s := newTestSigner(t, "secret")
+
+	next, prev, err := EncodePage(s, &Page{Next: &Cursor{Direction: DirectionAfter, ID: "3"}})
+	if err != nil {
+		t.Fatalf("encode page: %v", err)
+	}
+	if prev != "" {
+		t.Fatalf("wait empty prev, have %q", prev)
+	}
+	c, err := s.Decode(next)
+	if err != nil {
+		t.Fatalf("decode next: %v", err)
+	}
+	if c.ID != "3" || c.Direction != DirectionAfter {
+		t.Fatalf("wait 3:after, have %s:%s", c.ID, c.Direction)
+	}
+
+	if _, _, err := EncodePage(failSigner{}, &Page{Prev: &Cursor{Direction: DirectionBefore}}); err == nil {
+		t.Fatalf("wait error, have nil")
+	}
+}
@@ -3,11 +3,13 @@ package httpdto
 import "time"
 
 type MessageResponse struct {
-	ID        int       `json:"id"`
-	Version   int       `json:"version"`
-	Content   string    `json:"content"`
-	SenderID  string    `json:"sender_id"`
-	CreatedAt time.Time `json:"created_at"`
+	ID              int       `json:"id"`
+	ChatID          int       `json:"chat_id"`
+	Version         int       `json:"version"`
+	Content         string    `json:"content"`
+	SenderID        string    `json:"sender_id"`
+	ClientMessageID string    `json:"client_message_id,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
 }
 
 type ChatResponse struct {
@@ -29,9 +31,24 @@ type ChatsMetadataResponse struct {
 	IsLastMessageRead bool `json:"is_last_message_read"`
 }
 
+type ChatsResponse struct {
+	Chats      []*ChatsMetadataResponse `json:"chats"`
+	NextCursor string                   `json:"next_cursor,omitempty"`
+	PrevCursor string                   `json:"prev_cursor,omitempty"`
+}
+
+type MessagesResponse struct {
+	Messages   []*MessageResponse `json:"messages"`
+	NextCursor string             `json:"next_cursor,omitempty"`
+	PrevCursor string             `json:"prev_cursor,omitempty"`
+}
+
 type AddMessageRequest struct {
 	ChatID  int    `json:"chat_id"`
 	Content string `json:"content"`
+
+	// ClientMessageID makes retries safe, a second request with the same id returns the first message.
+	ClientMessageID string `json:"client_message_id,omitempty"`
 }
 
 type UpdateMessageRequest struct {
@@ -48,3 +65,28 @@ type UpdateLastReadRequest struct {
 type UpdateLastReadResponse struct {
 	MessageID int `json:"message_id"`
 }
+
+type ExportResponse struct {
+	ID          int       `json:"id"`
+	ChatID      int       `json:"chat_id"`
+	Status      string    `json:"status"`
+	DownloadURL string    `json:"download_url,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type ErrorCode string
+
+const (
+	InvalidRequestCode ErrorCode = "invalid_request"
+	NotFoundCode       ErrorCode = "not_found"
+	ForbiddenCode      ErrorCode = "forbidden"
+	UnauthorizedCode   ErrorCode = "unauthorized"
+	ConflictCode       ErrorCode = "conflict"
+	InternalCode       ErrorCode = "internal"
+)
+
+type ErrorResponse struct {
+	Code    ErrorCode `json:"code"`
+	Message string    `json:"message"`
+}
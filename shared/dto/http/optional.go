@@ -0,0 +1,25 @@
+package httpdto
+
+import "encoding/json"
+
+// Optional is a request field that tells an omitted value from an explicit null.
+// Set is false when the field is omitted, Value is nil when the field is null.
+type Optional[T any] struct {
+	Set   bool
+	Value *T
+}
+
+func (o *Optional[T]) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	if string(data) == "null" {
+		o.Value = nil
+		return nil
+	}
+
+	o.Value = new(T)
+	return json.Unmarshal(data, o.Value)
+}
+
+func (o Optional[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(o.Value)
+}
@@ -1,25 +1,128 @@
 package httpdto
 
+import "time"
+
 type ProfileResponse struct {
-	SubjectID string `json:"subject_id"`
-	Alias     string `json:"alias"`
-	AvatarURL string `json:"avatar_url"`
-	Version   int    `json:"version"`
+	SubjectID   string          `json:"subject_id"`
+	Alias       string          `json:"alias"`
+	Username    string          `json:"username,omitempty"`
+	DisplayName string          `json:"display_name,omitempty"`
+	Bio         string          `json:"bio,omitempty"`
+	Status      *ProfileStatus  `json:"status,omitempty"`
+	Links       []ProfileLink   `json:"links,omitempty"`
+	AvatarURL   string          `json:"avatar_url"`
+	LastSeenAt  *time.Time      `json:"last_seen_at,omitempty"`
+	Privacy     *ProfilePrivacy `json:"privacy,omitempty"`
+	Version     int             `json:"version"`
+}
+
+// ProfilePrivacy is returned only to the owner. Values are everyone, contacts or nobody,
+// Search allows everyone or nobody, NewChat allows everyone or contacts.
+type ProfilePrivacy struct {
+	Search   string `json:"search"`
+	Avatar   string `json:"avatar"`
+	LastSeen string `json:"last_seen"`
+	NewChat  string `json:"new_chat"`
+}
+
+// ProfileStatus is omitted from responses after ExpiresAt.
+type ProfileStatus struct {
+	Text      string     `json:"text,omitempty"`
+	Emoji     string     `json:"emoji,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+type ProfileLink struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
 }
 
 type ProfilesResponse struct {
+	Profiles   []*ProfileResponse `json:"profiles"`
+	NextCursor string             `json:"next_cursor,omitempty"`
+	PrevCursor string             `json:"prev_cursor,omitempty"`
+}
+
+type ProfilesBatchRequest struct {
+	SubjectIDs []string `json:"subject_ids"`
+}
+
+// ProfilesBatchResponse lists every requested id without an alive profile in NotFound,
+// the ones whose profile was deleted are also in Deleted.
+type ProfilesBatchResponse struct {
 	Profiles []*ProfileResponse `json:"profiles"`
+	NotFound []string           `json:"not_found"`
+	Deleted  []string           `json:"deleted"`
 }
 
 type AddProfileRequest struct {
 	Alias string `json:"alias"`
 }
 
+// UpdateProfileMetadataRequest changes only the fields present in the body, omitted ones stay as they are.
+// An empty string, an empty links list or a null status clears the field, null for other fields is the same as omitted.
 type UpdateProfileMetadataRequest struct {
-	Alias   string `json:"alias"`
-	Version int    `json:"version"`
+	Alias       *string                 `json:"alias"`
+	DisplayName *string                 `json:"display_name"`
+	Bio         *string                 `json:"bio"`
+	Status      Optional[ProfileStatus] `json:"status"`
+	Links       *[]ProfileLink          `json:"links"`
+	Version     int                     `json:"version"`
+}
+
+type UpdatePrivacyRequest struct {
+	ProfilePrivacy
+	Version int `json:"version"`
+}
+
+// ChatPermissionResponse is the answer of the internal API to chat, whether From can start a chat with To.
+type ChatPermissionResponse struct {
+	Allowed bool `json:"allowed"`
+}
+
+// AddContactRequest needs SubjectID or Username, the same contact added again gets the new Nickname.
+type AddContactRequest struct {
+	SubjectID string `json:"subject_id,omitempty"`
+	Username  string `json:"username,omitempty"`
+	Nickname  string `json:"nickname"`
+}
+
+// ContactResponse has no Profile when the profile of the contact was deleted.
+type ContactResponse struct {
+	SubjectID string           `json:"subject_id"`
+	Nickname  string           `json:"nickname,omitempty"`
+	Profile   *ProfileResponse `json:"profile,omitempty"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+type ContactsResponse struct {
+	Contacts   []*ContactResponse `json:"contacts"`
+	NextCursor string             `json:"next_cursor,omitempty"`
+	PrevCursor string             `json:"prev_cursor,omitempty"`
 }
 
+type UpdateUsernameRequest struct {
+	Username string `json:"username"`
+	Version  int    `json:"version"`
+}
+
+// UploadAvatarRequest carries the hex sha256 of the file, it becomes part of the avatar key.
+// The upload form accepts only a file with this checksum and content type.
+type UploadAvatarRequest struct {
+	SHA256      string `json:"sha256"`
+	ContentType string `json:"content_type"`
+}
+
+// UploadAvatarResponse is a presigned POST: Fields go to the multipart form before the file.
 type UploadAvatarResponse struct {
-	UploadURL string `json:"upload_url"`
-}
\ No newline at end of file
+	UploadURL string            `json:"upload_url"`
+	Fields    map[string]string `json:"fields"`
+}
+
+type DataExportResponse struct {
+	ID          int       `json:"id"`
+	Status      string    `json:"status"`
+	DownloadURL string    `json:"download_url,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
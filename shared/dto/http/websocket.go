@@ -0,0 +1,21 @@
+package httpdto
+
+import "time"
+
+type TicketResponse struct {
+	Ticket    string    `json:"ticket"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type SessionResponse struct {
+	ID          string    `json:"id"`
+	Device      string    `json:"device,omitempty"`
+	RemoteAddr  string    `json:"remote_addr"`
+	UserAgent   string    `json:"user_agent"`
+	Transport   string    `json:"transport"`
+	ConnectedAt time.Time `json:"connected_at"`
+}
+
+type SessionsResponse struct {
+	Sessions []*SessionResponse `json:"sessions"`
+}
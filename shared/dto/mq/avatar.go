@@ -0,0 +1,19 @@
+package mqdto
+
+import "time"
+
+type AvatarStatus string
+
+const (
+	AvatarActive   AvatarStatus = "active"
+	AvatarRejected AvatarStatus = "rejected"
+)
+
+// AvatarProcessed tells the subject how the uploaded avatar was processed, Reason is set for rejected ones.
+type AvatarProcessed struct {
+	SubjectID string       `json:"subject_id"`
+	Status    AvatarStatus `json:"status"`
+	Reason    string       `json:"reason,omitempty"`
+	AvatarURL string       `json:"avatar_url,omitempty"`
+	CreatedAt time.Time    `json:"created_at"`
+}
@@ -0,0 +1,33 @@
+package mqdto
+
+import "time"
+
+type DataExportStatus string
+
+const (
+	DataExportDone   DataExportStatus = "done"
+	DataExportFailed DataExportStatus = "failed"
+)
+
+// DataExportRequest asks a service to collect everything it stores about the subject.
+type DataExportRequest struct {
+	ExportID  int    `json:"export_id"`
+	SubjectID string `json:"subject_id"`
+}
+
+// DataExportPart is the answer to DataExportRequest, Key points to the part in the data export bucket.
+// Error is a generic reason of a failed part, the details stay in the logs of the service.
+type DataExportPart struct {
+	ExportID  int    `json:"export_id"`
+	SubjectID string `json:"subject_id"`
+	Service   string `json:"service"`
+	Key       string `json:"key"`
+	Error     string `json:"error,omitempty"`
+}
+
+type DataExport struct {
+	ExportID  int              `json:"export_id"`
+	SubjectID string           `json:"subject_id"`
+	Status    DataExportStatus `json:"status"`
+	CreatedAt time.Time        `json:"created_at"`
+}
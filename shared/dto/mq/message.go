@@ -11,11 +11,12 @@ const (
 )
 
 type Message struct {
-	ID        int       `json:"id"`
-	SenderID  string    `json:"sender_id"`
-	Version   int       `json:"version"`
-	Content   string    `json:"content"`
-	CreatedAt time.Time `json:"created_at"`
+	ID              int       `json:"id"`
+	SenderID        string    `json:"sender_id"`
+	Version         int       `json:"version"`
+	Content         string    `json:"content"`
+	ClientMessageID string    `json:"client_message_id,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
 }
 
 type SendMessage struct {
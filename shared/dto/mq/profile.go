@@ -0,0 +1,32 @@
+package mqdto
+
+import "time"
+
+type ProfileEventType string
+
+const (
+	ProfileCreatedEvent ProfileEventType = "profile.created"
+	ProfileUpdatedEvent ProfileEventType = "profile.updated"
+	ProfileDeletedEvent ProfileEventType = "profile.deleted"
+)
+
+// ProfileEvent is published by profile from its outbox and keyed by the subject, so the events of one
+// profile keep their order. Services keep their copies of aliases by it, a deleted event has only
+// SubjectID and UpdatedAt set.
+type ProfileEvent struct {
+	Type        ProfileEventType `json:"type"`
+	SubjectID   string           `json:"subject_id"`
+	Alias       string           `json:"alias,omitempty"`
+	Username    *string          `json:"username,omitempty"`
+	DisplayName string           `json:"display_name,omitempty"`
+	Version     int              `json:"version"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+}
+
+// ProfileUpdated is sent by chat to every chat partner of the subject of a ProfileEvent.
+type ProfileUpdated struct {
+	RecipientID string    `json:"recipient_id,omitempty"`
+	SubjectID   string    `json:"subject_id"`
+	Version     int       `json:"version"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
@@ -0,0 +1,17 @@
+package wsdto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type Avatar struct {
+	Status    string    `json:"status"`
+	Reason    string    `json:"reason,omitempty"`
+	AvatarURL string    `json:"avatar_url,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (a *Avatar) GetData() ([]byte, error) {
+	return json.Marshal(a)
+}
@@ -0,0 +1,54 @@
+package wsdto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type SendMessageCommand struct {
+	ChatID          int    `json:"chat_id"`
+	Content         string `json:"content"`
+	ClientMessageID string `json:"client_message_id,omitempty"`
+}
+
+type UpdateMessageCommand struct {
+	MessageID int    `json:"message_id"`
+	Content   string `json:"content"`
+	Version   int    `json:"version"`
+}
+
+type MarkReadCommand struct {
+	ChatID    int `json:"chat_id"`
+	MessageID int `json:"message_id"`
+}
+
+// AuthCommand refreshes the token of an open connection before the current one expires.
+type AuthCommand struct {
+	Token string `json:"token"`
+}
+
+type AuthResult struct {
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (ar *AuthResult) GetData() ([]byte, error) {
+	return json.Marshal(ar)
+}
+
+// SubscribeCommand limits server events of the connection to Events, the ack carries the same shape.
+type SubscribeCommand struct {
+	Events []Operation `json:"events"`
+}
+
+func (sc *SubscribeCommand) GetData() ([]byte, error) {
+	return json.Marshal(sc)
+}
+
+type CommandError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *CommandError) GetData() ([]byte, error) {
+	return json.Marshal(e)
+}
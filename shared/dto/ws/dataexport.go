@@ -0,0 +1,16 @@
+package wsdto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type DataExport struct {
+	ExportID  int       `json:"export_id"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (de *DataExport) GetData() ([]byte, error) {
+	return json.Marshal(de)
+}
@@ -0,0 +1,28 @@
+package wsdto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ServerHello is the first frame of every connection.
+type ServerHello struct {
+	Version    int         `json:"version"`
+	ServerTime time.Time   `json:"server_time"`
+	SessionID  string      `json:"session_id"`
+	Events     []Operation `json:"events"`
+}
+
+func (h *ServerHello) GetData() ([]byte, error) {
+	return json.Marshal(h)
+}
+
+// ServerReconnect is sent before the server closes the connection on restart,
+// the client waits DelayMs before connecting again, so clients do not come back all at once.
+type ServerReconnect struct {
+	DelayMs int64 `json:"delay_ms"`
+}
+
+func (r *ServerReconnect) GetData() ([]byte, error) {
+	return json.Marshal(r)
+}
@@ -6,12 +6,13 @@ import (
 )
 
 type Message struct {
-	ID        int       `json:"id"`
-	ChatID    int       `json:"chat_id"`
-	SenderID  string    `json:"sender_id"`
-	Content   string    `json:"content"`
-	Version   int       `json:"version"`
-	CreatedAt time.Time `json:"created_at"`
+	ID              int       `json:"id"`
+	ChatID          int       `json:"chat_id"`
+	SenderID        string    `json:"sender_id"`
+	Content         string    `json:"content"`
+	Version         int       `json:"version"`
+	ClientMessageID string    `json:"client_message_id,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
 }
 
 func (m *Message) GetData() ([]byte, error) {
@@ -0,0 +1,17 @@
+package wsdto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Profile tells that a chat partner changed the profile, the client fetches it again.
+type Profile struct {
+	SubjectID string    `json:"subject_id"`
+	Version   int       `json:"version"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (p *Profile) GetData() ([]byte, error) {
+	return json.Marshal(p)
+}
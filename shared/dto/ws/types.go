@@ -7,4 +7,14 @@ const (
 	SendMessage      Operation = "send_message"
 	UpdateMessage    Operation = "update_message"
 	UpdateLastRead   Operation = "update_last_read"
+	DataExportReady  Operation = "data_export_ready"
+	AvatarProcessed  Operation = "avatar_processed"
+	ProfileUpdated   Operation = "profile_updated"
+	MarkRead         Operation = "mark_read"
+	Auth             Operation = "auth"
+	Subscribe        Operation = "subscribe"
+	Hello            Operation = "hello"
+	Ack              Operation = "ack"
+	Error            Operation = "error"
+	Reconnect        Operation = "reconnect"
 )
@@ -1,10 +1,20 @@
 package wsdto
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"time"
+)
+
+// ProtocolVersion is sent in hello and in V of every server frame, clients ignore fields they do not know.
+const ProtocolVersion = 1
 
 type WSMessage struct {
+	// ID is the event id on server events, on commands it is set by the client and echoed in the ack or error frame.
+	ID   string          `json:"id,omitempty"`
 	Type Operation       `json:"type"`
 	Data json.RawMessage `json:"data"`
+	Ts   time.Time       `json:"ts,omitzero"`
+	V    int             `json:"v,omitempty"`
 }
 
 func (wsm *WSMessage) GetBytes() ([]byte, error) {
@@ -8,11 +8,12 @@ import (
 )
 
 type PaginationFilter[T comparable] struct {
-	Limit     int
-	Asc       bool
-	SortLabel string
-	IDLabel   string
-	LastID    *T
+	Limit         int
+	Asc           bool
+	SortLabel     string
+	IDLabel       string
+	LastID        *T
+	LastSortValue any
 }
 
 const (
@@ -26,16 +27,33 @@ func MakeQueryWithPagination[T comparable](ctx context.Context, b sq.SelectBuild
 	}
 
 	order := AscSortLabel
+	cmp := ">"
 	if !filter.Asc {
 		order = DescSortLabel
+		cmp = "<"
+	}
+
+	keyset := filter.SortLabel != "" && filter.SortLabel != filter.IDLabel
+	if keyset {
+		b = b.OrderBy(
+			fmt.Sprintf("%s %s", filter.SortLabel, order),
+			fmt.Sprintf("%s %s", filter.IDLabel, order),
+		)
+	} else {
+		b = b.OrderBy(fmt.Sprintf("%s %s", filter.IDLabel, order))
 	}
-	b = b.OrderBy(fmt.Sprintf("%s %s", filter.SortLabel, order))
 
 	if filter.LastID != nil {
-		if !filter.Asc {
-			b = b.Where(sq.Lt{filter.IDLabel: filter.LastID})
-		} else {
-			b = b.Where(sq.Gt{filter.IDLabel: filter.LastID})
+		switch {
+		case keyset && filter.LastSortValue == nil:
+			return "", nil, fmt.Errorf("invalid pagination: last %s is required", filter.SortLabel)
+		case keyset:
+			b = b.Where(
+				sq.Expr(fmt.Sprintf("(%s, %s) %s (?, ?)", filter.SortLabel, filter.IDLabel, cmp),
+					filter.LastSortValue, *filter.LastID),
+			)
+		default:
+			b = b.Where(sq.Expr(fmt.Sprintf("%s %s ?", filter.IDLabel, cmp), *filter.LastID))
 		}
 	}
 
@@ -0,0 +1,69 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+func TestMakeQueryWithPagination(t *testing.T) {
+	lastID := 10
+	b := sq.Select("id").From("message")
+
+	tests := []struct {
+		name     string
+		filter   *PaginationFilter[int]
+		wantSQL  string
+		wantArgs int
+		wantErr  bool
+	}{
+		{
+			name:    "nil filter",
+			wantErr: true,
+		},
+		{
+			name:    "first page",
+			filter:  &PaginationFilter[int]{Limit: 5, Asc: true, SortLabel: "created_at", IDLabel: "id"},
+			wantSQL: "SELECT id FROM message ORDER BY created_at ASC, id ASC LIMIT 5",
+		},
+		{
+			name:     "keyset",
+			filter:   &PaginationFilter[int]{Limit: 5, SortLabel: "created_at", IDLabel: "id", LastID: &lastID, LastSortValue: "2024-01-01"},
+			wantSQL:  "SELECT id FROM message WHERE (created_at, id) < ($1, $2) ORDER BY created_at DESC, id DESC LIMIT 5",
+			wantArgs: 2,
+		},
+		{
+			name:    "keyset without last sort value",
+			filter:  &PaginationFilter[int]{Limit: 5, SortLabel: "created_at", IDLabel: "id", LastID: &lastID},
+			wantErr: true,
+		},
+		{
+			name:     "id only",
+			filter:   &PaginationFilter[int]{Limit: 5, Asc: true, IDLabel: "id", LastID: &lastID},
+			wantSQL:  "SELECT id FROM message WHERE id > $1 ORDER BY id ASC LIMIT 5",
+			wantArgs: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, args, err := MakeQueryWithPagination(context.Background(), b, tt.filter)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("wait error, have %q", query)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("make query: %v", err)
+			}
+			if query != tt.wantSQL {
+				t.Fatalf("wait %q, have %q", tt.wantSQL, query)
+			}
+			if len(args) != tt.wantArgs {
+				t.Fatalf("wait %d args, have %d", tt.wantArgs, len(args))
+			}
+		})
+	}
+}
@@ -1,15 +1,31 @@
 package s3client
 
+import "net/url"
+
 type UploadEventMinIO struct {
-	Records []struct {
-		S3 struct {
-			Object struct {
-				Key string `json:"key"`
-			} `json:"object"`
-		} `json:"s3"`
-	} `json:"Records"`
+	Records []UploadRecordMinIO `json:"Records"`
+}
+
+type UploadRecordMinIO struct {
+	EventName string `json:"eventName"`
+	S3        struct {
+		Object struct {
+			Key         string `json:"key"`
+			Size        int64  `json:"size"`
+			ContentType string `json:"contentType"`
+		} `json:"object"`
+	} `json:"s3"`
 }
 
 func (uemio *UploadEventMinIO) GetKey() string {
-	return uemio.Records[0].S3.Object.Key
+	return uemio.Records[0].GetKey()
+}
+
+// GetKey returns the object key, MinIO sends it url encoded.
+func (r *UploadRecordMinIO) GetKey() string {
+	key, err := url.QueryUnescape(r.S3.Object.Key)
+	if err != nil {
+		return r.S3.Object.Key
+	}
+	return key
 }
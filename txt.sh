@@ -4,4 +4,9 @@ export ACCESS_TOKEN=$( curl -s -X POST \
   -d "grant_type=password&client_id=main&client_secret=main&username=test&password=test" \
 | jq -r '.access_token')
 
-wscat -c ws://localhost:8082/ws?token=$ACCESS_TOKEN
\ No newline at end of file
+export WS_TICKET=$( curl -s -X POST \
+  http://localhost:8082/ws/ticket \
+  -H "Authorization: Bearer $ACCESS_TOKEN" \
+| jq -r '.ticket')
+
+wscat -c ws://localhost:8082/ws?ticket=$WS_TICKET
\ No newline at end of file
@@ -8,18 +8,27 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
+	"time"
 
 	"github.com/1ocknight/mess/shared/auth/keycloak"
 	"github.com/1ocknight/mess/shared/logger"
+	"github.com/1ocknight/mess/shared/redisclient"
 	"github.com/1ocknight/mess/websocket/config"
+	"github.com/1ocknight/mess/websocket/internal/adapter/chat"
 	"github.com/1ocknight/mess/websocket/internal/ctxkey"
 	"github.com/1ocknight/mess/websocket/internal/loglables"
 	"github.com/1ocknight/mess/websocket/internal/model"
+	"github.com/1ocknight/mess/websocket/internal/session"
+	"github.com/1ocknight/mess/websocket/internal/ticket"
 	"github.com/1ocknight/mess/websocket/internal/transport"
 	"github.com/1ocknight/mess/websocket/internal/worker"
 )
 
+// consumersStopTimeout bounds the wait for kafka consumers after the drain, by then they only close their kafka clients.
+const consumersStopTimeout = 5 * time.Second
+
 func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -37,6 +46,11 @@ func main() {
 		return
 	}
 
+	if err := cfg.Drain.Validate(); err != nil {
+		lg.Error(fmt.Errorf("drain config: %w", err))
+		return
+	}
+
 	msgs := make(chan *model.Message)
 
 	keycloak, err := keycloak.New(cfg.Keycloak, lg)
@@ -45,13 +59,19 @@ func main() {
 		return
 	}
 
+	// every replica reads all partitions of the topics, so events read after the consumers stop
+	// reach the clients that reconnected to other replicas
+	consumersCtx, stopConsumers := context.WithCancel(ctx)
+	defer stopConsumers()
+	var consumers sync.WaitGroup
+
 	messageWorkerLg := lg.With(loglables.Layer, "message worker")
 	messageWorker, err := worker.NewMessageWorker(cfg.MessageWorker, msgs, messageWorkerLg)
 	if err != nil {
 		lg.Error(fmt.Errorf("new message worker: %w", err))
 		return
 	}
-	go messageWorker.Run(ctx)
+	consumers.Go(func() { messageWorker.Run(consumersCtx) })
 
 	lastreadWorkerLg := lg.With(loglables.Layer, "lastread worker")
 	lastreadWorker, err := worker.NewLastReadWorker(cfg.LastReadWorker, msgs, lastreadWorkerLg)
@@ -59,16 +79,73 @@ func main() {
 		lg.Error(fmt.Errorf("new message worker: %w", err))
 		return
 	}
-	go lastreadWorker.Run(ctx)
+	consumers.Go(func() { lastreadWorker.Run(consumersCtx) })
+
+	dataExportWorkerLg := lg.With(loglables.Layer, "data export worker")
+	dataExportWorker, err := worker.NewDataExportWorker(cfg.DataExport, msgs, dataExportWorkerLg)
+	if err != nil {
+		lg.Error(fmt.Errorf("new data export worker: %w", err))
+		return
+	}
+	consumers.Go(func() { dataExportWorker.Run(consumersCtx) })
+
+	avatarWorkerLg := lg.With(loglables.Layer, "avatar worker")
+	avatarWorker, err := worker.NewAvatarWorker(cfg.AvatarWorker, msgs, avatarWorkerLg)
+	if err != nil {
+		lg.Error(fmt.Errorf("new avatar worker: %w", err))
+		return
+	}
+	consumers.Go(func() { avatarWorker.Run(consumersCtx) })
+
+	profileWorkerLg := lg.With(loglables.Layer, "profile worker")
+	profileWorker, err := worker.NewProfileWorker(cfg.ProfileWorker, msgs, profileWorkerLg)
+	if err != nil {
+		lg.Error(fmt.Errorf("new profile worker: %w", err))
+		return
+	}
+	consumers.Go(func() { profileWorker.Run(consumersCtx) })
 
 	hubLg := lg.With(loglables.Layer, "hub")
-	hub := transport.NewHub(msgs, hubLg)
-	go hub.Run()
+	if err := cfg.Hub.Validate(); err != nil {
+		lg.Error(fmt.Errorf("hub config: %w", err))
+		return
+	}
+	hub := transport.NewHub(msgs, cfg.Hub, hubLg)
+	// the hub outlives the http server, ctx is cancelled only after the drain
+	go hub.Run(ctx)
 
-	handler := transport.NewHandler(cfg.WSConfig, hub)
+	chatService := chat.New(cfg.Chat)
+
+	rdb := redisclient.NewClient(cfg.Redis)
+	defer rdb.Close()
+	if err := rdb.Ping(ctx).Err(); err != nil {
+		lg.Error(fmt.Errorf("redis ping: %w", err))
+		return
+	}
+
+	if err := cfg.Ticket.Validate(); err != nil {
+		lg.Error(fmt.Errorf("ticket config: %w", err))
+		return
+	}
+	tickets := ticket.NewRedis(cfg.Ticket, rdb)
+
+	if err := cfg.Session.Validate(); err != nil {
+		lg.Error(fmt.Errorf("session config: %w", err))
+		return
+	}
+	sessionsLg := lg.With(loglables.Layer, "sessions")
+	sessions := session.NewRedis(cfg.Session, rdb, sessionsLg)
+	go sessions.Run(ctx)
+
+	if err := cfg.WSConfig.Validate(); err != nil {
+		lg.Error(fmt.Errorf("ws config: %w", err))
+		return
+	}
+	handler := transport.NewHandler(cfg.WSConfig, hub, chatService, keycloak, tickets, sessions)
+	go handler.RunTerminations(ctx)
 
 	serverLg := lg.With(loglables.Layer, "server")
-	server := transport.NewServer(cfg.HTTP, keycloak, handler, serverLg)
+	server := transport.NewServer(cfg.HTTP, keycloak, tickets, sessions, handler, serverLg)
 	go func() {
 		if err := server.Run(); err != nil && !errors.Is(http.ErrServerClosed, err) {
 			lg.Error(fmt.Errorf("server run: %w", err))
@@ -76,18 +153,59 @@ func main() {
 		}
 	}()
 
+	debugServer := transport.NewDebugServer(cfg.Debug)
+	go func() {
+		if err := debugServer.Run(); err != nil {
+			lg.Error(fmt.Errorf("debug server run: %w", err))
+			return
+		}
+	}()
+
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
 	<-stop
 
 	lg.Info("start graceful shutdown")
 
-	err = server.Stop(ctx)
-	if err != nil {
+	drainCtx, drainCancel := context.WithTimeout(context.Background(), cfg.Drain.Timeout)
+	defer drainCancel()
+
+	// Shutdown closes the listener but does not wait for hijacked websocket connections,
+	// sse streams end when the hub closes them below
+	handler.Drain()
+	serverStopped := make(chan error, 1)
+	go func() {
+		serverStopped <- server.Stop(drainCtx)
+	}()
+
+	if err := hub.Drain(drainCtx, cfg.Drain.ReconnectMaxDelay); err != nil {
+		lg.Error(fmt.Errorf("hub drain: %w", err))
+	}
+	lg.Info("hub is drained")
+
+	if err := <-serverStopped; err != nil {
 		lg.Error(fmt.Errorf("server stop: %w", err))
 	}
 	lg.Info("server is stop")
 
+	// the clients are gone, events read from now on reach them on the replicas they reconnected to
+	stopConsumers()
+	consumersStopped := make(chan struct{})
+	go func() {
+		consumers.Wait()
+		close(consumersStopped)
+	}()
+	select {
+	case <-consumersStopped:
+		lg.Info("consumers are stopped")
+	case <-time.After(consumersStopTimeout):
+		lg.Error(fmt.Errorf("consumers did not stop in %v", consumersStopTimeout))
+	}
+
+	if err := debugServer.Stop(ctx); err != nil {
+		lg.Error(fmt.Errorf("debug server stop: %w", err))
+	}
+
 	cancel()
 	lg.Info("successful stop")
 }
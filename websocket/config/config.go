@@ -6,6 +6,10 @@ import (
 	"os"
 
 	"github.com/1ocknight/mess/shared/auth/keycloak"
+	"github.com/1ocknight/mess/shared/redisclient"
+	"github.com/1ocknight/mess/websocket/internal/adapter/chat"
+	"github.com/1ocknight/mess/websocket/internal/session"
+	"github.com/1ocknight/mess/websocket/internal/ticket"
 	"github.com/1ocknight/mess/websocket/internal/transport"
 	"github.com/1ocknight/mess/websocket/internal/worker"
 	"github.com/goccy/go-yaml"
@@ -15,8 +19,18 @@ type Config struct {
 	Keycloak       keycloak.Config            `yaml:"keycloak"`
 	MessageWorker  worker.MessageWorkerConfig `yaml:"message_worker"`
 	LastReadWorker worker.LastReadConfig      `yaml:"lastread_worker"`
+	DataExport     worker.DataExportConfig    `yaml:"data_export_worker"`
+	AvatarWorker   worker.AvatarConfig        `yaml:"avatar_worker"`
+	ProfileWorker  worker.ProfileConfig       `yaml:"profile_worker"`
+	Chat           chat.Config                `yaml:"chat"`
+	Redis          redisclient.Config         `yaml:"redis"`
+	Ticket         ticket.Config              `yaml:"ticket"`
+	Session        session.Config             `yaml:"session"`
 	HTTP           transport.HTTPConfig       `yaml:"http"`
+	Debug          transport.HTTPConfig       `yaml:"debug"`
+	Hub            transport.HubConfig        `yaml:"hub"`
 	WSConfig       transport.WSHandlerConfig  `yaml:"ws_config"`
+	Drain          transport.DrainConfig      `yaml:"drain"`
 }
 
 func LoadConfig() (*Config, error) {
@@ -0,0 +1,144 @@
+package chat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	httpdto "github.com/1ocknight/mess/shared/dto/http"
+	wsdto "github.com/1ocknight/mess/shared/dto/ws"
+)
+
+const (
+	sendMessagePath   = "/rpc/send_message"
+	updateMessagePath = "/rpc/update_message"
+	markReadPath      = "/rpc/mark_read"
+)
+
+type Config struct {
+	URL     string        `yaml:"url"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+type HTTP struct {
+	cfg    Config
+	client *http.Client
+}
+
+func New(cfg Config) Service {
+	return &HTTP{
+		cfg: cfg,
+		client: &http.Client{
+			Timeout: cfg.Timeout,
+		},
+	}
+}
+
+func (h *HTTP) SendMessage(ctx context.Context, token string, cmd *wsdto.SendMessageCommand) (*wsdto.Message, error) {
+	var res httpdto.MessageResponse
+	err := h.call(ctx, token, sendMessagePath, httpdto.AddMessageRequest{
+		ChatID:          cmd.ChatID,
+		Content:         cmd.Content,
+		ClientMessageID: cmd.ClientMessageID,
+	}, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	return messageResponseToWS(&res), nil
+}
+
+func (h *HTTP) UpdateMessage(ctx context.Context, token string, cmd *wsdto.UpdateMessageCommand) (*wsdto.Message, error) {
+	var res httpdto.MessageResponse
+	err := h.call(ctx, token, updateMessagePath, httpdto.UpdateMessageRequest{
+		MessageID: cmd.MessageID,
+		Content:   cmd.Content,
+		Version:   cmd.Version,
+	}, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	return messageResponseToWS(&res), nil
+}
+
+func (h *HTTP) MarkRead(ctx context.Context, token string, cmd *wsdto.MarkReadCommand) (*wsdto.LastRead, error) {
+	var res httpdto.UpdateLastReadResponse
+	err := h.call(ctx, token, markReadPath, httpdto.UpdateLastReadRequest{
+		ChatID:    cmd.ChatID,
+		MessageID: cmd.MessageID,
+	}, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	return &wsdto.LastRead{
+		ChatID:    cmd.ChatID,
+		MessageID: res.MessageID,
+	}, nil
+}
+
+func (h *HTTP) call(ctx context.Context, token string, path string, req any, res any) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, h.cfg.URL+path, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := h.client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("do: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return responseError(resp.StatusCode, data)
+	}
+
+	if err := json.Unmarshal(data, res); err != nil {
+		return fmt.Errorf("unmarshal: %w", err)
+	}
+
+	return nil
+}
+
+func responseError(status int, data []byte) error {
+	var errRes httpdto.ErrorResponse
+	if err := json.Unmarshal(data, &errRes); err == nil && errRes.Code != "" {
+		return &RPCError{Code: string(errRes.Code), Message: errRes.Message}
+	}
+
+	code := httpdto.InternalCode
+	if status == http.StatusUnauthorized {
+		code = httpdto.UnauthorizedCode
+	}
+
+	return &RPCError{Code: string(code), Message: http.StatusText(status)}
+}
+
+func messageResponseToWS(res *httpdto.MessageResponse) *wsdto.Message {
+	return &wsdto.Message{
+		ID:              res.ID,
+		ChatID:          res.ChatID,
+		SenderID:        res.SenderID,
+		Content:         res.Content,
+		Version:         res.Version,
+		ClientMessageID: res.ClientMessageID,
+		CreatedAt:       res.CreatedAt,
+	}
+}
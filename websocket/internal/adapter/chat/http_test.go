@@ -0,0 +1,93 @@
+package chat
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	httpdto "github.com/1ocknight/mess/shared/dto/http"
+	wsdto "github.com/1ocknight/mess/shared/dto/ws"
+)
+
+func TestHTTP_SendMessage(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != sendMessagePath || r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("unexpected request %v with %q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		var req httpdto.AddMessageRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+		json.NewEncoder(w).Encode(httpdto.MessageResponse{
+			ID:              7,
+			ChatID:          req.ChatID,
+			Content:         req.Content,
+			ClientMessageID: req.ClientMessageID,
+		})
+	}))
+	defer srv.Close()
+
+	h := New(Config{URL: srv.URL, Timeout: time.Second})
+	msg, err := h.SendMessage(t.Context(), "token", &wsdto.SendMessageCommand{ChatID: 1, Content: "hi", ClientMessageID: "c1"})
+	if err != nil {
+		t.Fatalf("send message: %v", err)
+	}
+	if msg.ID != 7 || msg.ChatID != 1 || msg.Content != "hi" || msg.ClientMessageID != "c1" {
+		t.Fatalf("unexpected message %+v", msg)
+	}
+}
+
+func TestHTTP_Error(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		code    httpdto.ErrorCode
+		message string
+	}{
+		{
+			name:    "error response",
+			status:  http.StatusForbidden,
+			body:    `{"code":"forbidden","message":"subject not have this resource"}`,
+			code:    httpdto.ForbiddenCode,
+			message: "subject not have this resource",
+		},
+		{
+			name:    "unauthorized without body",
+			status:  http.StatusUnauthorized,
+			code:    httpdto.UnauthorizedCode,
+			message: http.StatusText(http.StatusUnauthorized),
+		},
+		{
+			name:    "other status without body",
+			status:  http.StatusBadGateway,
+			body:    "<html>bad gateway</html>",
+			code:    httpdto.InternalCode,
+			message: http.StatusText(http.StatusBadGateway),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			h := New(Config{URL: srv.URL, Timeout: time.Second})
+			_, err := h.MarkRead(t.Context(), "token", &wsdto.MarkReadCommand{ChatID: 1, MessageID: 7})
+
+			var rpcErr *RPCError
+			if !errors.As(err, &rpcErr) {
+				t.Fatalf("wait rpc error, have %v", err)
+			}
+			if rpcErr.Code != string(tt.code) || rpcErr.Message != tt.message {
+				t.Fatalf("wait %v %q, have %v %q", tt.code, tt.message, rpcErr.Code, rpcErr.Message)
+			}
+		})
+	}
+}
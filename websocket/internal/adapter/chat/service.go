@@ -0,0 +1,24 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+
+	wsdto "github.com/1ocknight/mess/shared/dto/ws"
+)
+
+type Service interface {
+	SendMessage(ctx context.Context, token string, cmd *wsdto.SendMessageCommand) (*wsdto.Message, error)
+	UpdateMessage(ctx context.Context, token string, cmd *wsdto.UpdateMessageCommand) (*wsdto.Message, error)
+	MarkRead(ctx context.Context, token string, cmd *wsdto.MarkReadCommand) (*wsdto.LastRead, error)
+}
+
+// RPCError is an error answered by chat, Code is forwarded to the client as is.
+type RPCError struct {
+	Code    string
+	Message string
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("%v: %v", e.Code, e.Message)
+}
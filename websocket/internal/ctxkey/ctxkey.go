@@ -3,9 +3,12 @@ package ctxkey
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/1ocknight/mess/shared/logger"
 	"github.com/1ocknight/mess/shared/model"
+	wsmodel "github.com/1ocknight/mess/websocket/internal/model"
+	"github.com/1ocknight/mess/websocket/internal/ticket"
 )
 
 type loggerKeyStruct struct{}
@@ -51,3 +54,91 @@ func ExtractSubject(ctx context.Context) (model.Subject, error) {
 
 	return s, nil
 }
+
+type tokenKeyStruct struct{}
+
+var tokenKey = tokenKeyStruct{}
+
+func WithToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, tokenKey, token)
+}
+
+func ExtractToken(ctx context.Context) (string, error) {
+	v := ctx.Value(tokenKey)
+	if v == nil {
+		return "", fmt.Errorf("not have token in context")
+	}
+
+	t, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("value is not token: %T", v)
+	}
+
+	return t, nil
+}
+
+type tokenExpiresAtKeyStruct struct{}
+
+var tokenExpiresAtKey = tokenExpiresAtKeyStruct{}
+
+func WithTokenExpiresAt(ctx context.Context, expiresAt time.Time) context.Context {
+	return context.WithValue(ctx, tokenExpiresAtKey, expiresAt)
+}
+
+func ExtractTokenExpiresAt(ctx context.Context) (time.Time, error) {
+	v := ctx.Value(tokenExpiresAtKey)
+	if v == nil {
+		return time.Time{}, fmt.Errorf("not have token expires at in context")
+	}
+
+	t, ok := v.(time.Time)
+	if !ok {
+		return time.Time{}, fmt.Errorf("value is not time: %T", v)
+	}
+
+	return t, nil
+}
+
+type tokenIDsKeyStruct struct{}
+
+var tokenIDsKey = tokenIDsKeyStruct{}
+
+func WithTokenIDs(ctx context.Context, ids wsmodel.TokenIDs) context.Context {
+	return context.WithValue(ctx, tokenIDsKey, ids)
+}
+
+func ExtractTokenIDs(ctx context.Context) (wsmodel.TokenIDs, error) {
+	v := ctx.Value(tokenIDsKey)
+	if v == nil {
+		return wsmodel.TokenIDs{}, fmt.Errorf("not have token ids in context")
+	}
+
+	ids, ok := v.(wsmodel.TokenIDs)
+	if !ok {
+		return wsmodel.TokenIDs{}, fmt.Errorf("value is not token ids: %T", v)
+	}
+
+	return ids, nil
+}
+
+type ticketKeyStruct struct{}
+
+var ticketKey = ticketKeyStruct{}
+
+func WithTicket(ctx context.Context, t *ticket.Ticket) context.Context {
+	return context.WithValue(ctx, ticketKey, t)
+}
+
+func ExtractTicket(ctx context.Context) (*ticket.Ticket, error) {
+	v := ctx.Value(ticketKey)
+	if v == nil {
+		return nil, fmt.Errorf("not have ticket in context")
+	}
+
+	t, ok := v.(*ticket.Ticket)
+	if !ok {
+		return nil, fmt.Errorf("value is not ticket: %T", v)
+	}
+
+	return t, nil
+}
@@ -4,4 +4,7 @@ const (
 	Service = "service"
 	Layer   = "layer"
 	Subject = "subject"
+	Shard   = "shard"
+	Reason  = "reason"
+	Dropped = "dropped"
 )
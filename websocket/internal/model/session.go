@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+type Transport string
+
+const (
+	WebSocketTransport Transport = "websocket"
+	SSETransport       Transport = "sse"
+)
+
+// Session is one open connection of a subject, the registry of sessions is shared by all replicas.
+type Session struct {
+	ID          string
+	SubjectID   string
+	Device      string
+	RemoteAddr  string
+	UserAgent   string
+	Transport   Transport
+	ConnectedAt time.Time
+
+	TokenIDs
+}
+
+// TokenIDs name the token of the connection and the keycloak session it was issued in,
+// a terminated session revokes both.
+type TokenIDs struct {
+	// TokenID is the jti claim
+	TokenID string
+	// AuthSessionID is the sid claim, it stays the same when the token is refreshed
+	AuthSessionID string
+}
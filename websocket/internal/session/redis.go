@@ -0,0 +1,303 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/1ocknight/mess/shared/logger"
+	"github.com/1ocknight/mess/websocket/internal/model"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// one hash per subject, session id -> session
+	sessionsKeyPrefix = "ws:sessions:"
+	terminateChannel  = "ws:sessions:terminate"
+
+	revokedTokenKeyPrefix       = "ws:revoked:token:"
+	revokedAuthSessionKeyPrefix = "ws:revoked:sid:"
+)
+
+// Redis keeps sessions of all replicas in one place. Every replica refreshes the sessions it holds,
+// so sessions of a replica that died without removing them expire after TTL.
+type Redis struct {
+	cfg    Config
+	client *redis.Client
+	lg     logger.Logger
+
+	mu    sync.Mutex
+	local map[string]model.Session
+}
+
+func NewRedis(cfg Config, client *redis.Client, lg logger.Logger) *Redis {
+	return &Redis{
+		cfg:    cfg,
+		client: client,
+		lg:     lg,
+		local:  make(map[string]model.Session),
+	}
+}
+
+type redisSession struct {
+	ID            string          `json:"id"`
+	SubjectID     string          `json:"subject_id"`
+	Device        string          `json:"device"`
+	RemoteAddr    string          `json:"remote_addr"`
+	UserAgent     string          `json:"user_agent"`
+	Transport     model.Transport `json:"transport"`
+	ConnectedAt   time.Time       `json:"connected_at"`
+	TokenID       string          `json:"token_id"`
+	AuthSessionID string          `json:"auth_session_id"`
+	ExpiresAt     time.Time       `json:"expires_at"`
+}
+
+func (rs *redisSession) toModel() model.Session {
+	return model.Session{
+		ID:          rs.ID,
+		SubjectID:   rs.SubjectID,
+		Device:      rs.Device,
+		RemoteAddr:  rs.RemoteAddr,
+		UserAgent:   rs.UserAgent,
+		Transport:   rs.Transport,
+		ConnectedAt: rs.ConnectedAt,
+		TokenIDs: model.TokenIDs{
+			TokenID:       rs.TokenID,
+			AuthSessionID: rs.AuthSessionID,
+		},
+	}
+}
+
+// Run refreshes the sessions of this replica until ctx is done.
+func (r *Redis) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.TTL / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			r.lg.Info("context done - stop")
+			return
+		case <-ticker.C:
+			r.refresh(ctx)
+		}
+	}
+}
+
+// refresh holds the lock over the writes, so Remove can not be overwritten by a refresh of the same session.
+func (r *Redis) refresh(ctx context.Context) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, s := range r.local {
+		if err := r.store(ctx, s); err != nil {
+			r.lg.Error(fmt.Errorf("refresh session: %w", err))
+		}
+	}
+}
+
+func (r *Redis) Add(ctx context.Context, s model.Session) ([]model.Session, error) {
+	if err := r.store(ctx, s); err != nil {
+		return nil, fmt.Errorf("store: %w", err)
+	}
+
+	r.mu.Lock()
+	r.local[s.ID] = s
+	r.mu.Unlock()
+
+	// two replicas adding at once both see every session and pick the same oldest ones
+	sessions, err := r.List(ctx, s.SubjectID)
+	if err != nil {
+		return nil, fmt.Errorf("list: %w", err)
+	}
+
+	return overLimit(sessions, r.cfg.MaxSessions), nil
+}
+
+func (r *Redis) Remove(ctx context.Context, s model.Session) error {
+	r.mu.Lock()
+	delete(r.local, s.ID)
+	r.mu.Unlock()
+
+	if err := r.client.HDel(ctx, sessionsKeyPrefix+s.SubjectID, s.ID).Err(); err != nil {
+		return fmt.Errorf("hdel: %w", err)
+	}
+	return nil
+}
+
+func (r *Redis) Get(ctx context.Context, subjectID string, sessionID string) (model.Session, error) {
+	val, err := r.client.HGet(ctx, sessionsKeyPrefix+subjectID, sessionID).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return model.Session{}, ErrNotFound
+	}
+	if err != nil {
+		return model.Session{}, fmt.Errorf("hget: %w", err)
+	}
+
+	var rs redisSession
+	if err := json.Unmarshal(val, &rs); err != nil {
+		return model.Session{}, fmt.Errorf("unmarshal: %w", err)
+	}
+	if time.Now().After(rs.ExpiresAt) {
+		return model.Session{}, ErrNotFound
+	}
+
+	return rs.toModel(), nil
+}
+
+// List returns live sessions of the subject, the oldest first, and drops the expired ones.
+func (r *Redis) List(ctx context.Context, subjectID string) ([]model.Session, error) {
+	key := sessionsKeyPrefix + subjectID
+	vals, err := r.client.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("hgetall: %w", err)
+	}
+
+	now := time.Now()
+	sessions := make([]model.Session, 0, len(vals))
+	expired := make([]string, 0)
+	for id, val := range vals {
+		var rs redisSession
+		if err := json.Unmarshal([]byte(val), &rs); err != nil {
+			return nil, fmt.Errorf("unmarshal: %w", err)
+		}
+		if now.After(rs.ExpiresAt) {
+			expired = append(expired, id)
+			continue
+		}
+		sessions = append(sessions, rs.toModel())
+	}
+
+	if len(expired) > 0 {
+		if err := r.client.HDel(ctx, key, expired...).Err(); err != nil {
+			return nil, fmt.Errorf("hdel expired: %w", err)
+		}
+	}
+
+	sortSessions(sessions)
+	return sessions, nil
+}
+
+func (r *Redis) Publish(ctx context.Context, t Termination) error {
+	val, err := json.Marshal(t)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	if err := r.client.Publish(ctx, terminateChannel, val).Err(); err != nil {
+		return fmt.Errorf("publish: %w", err)
+	}
+	return nil
+}
+
+// Terminations is closed when ctx is done. Pub/sub does not keep messages, a replica that missed one
+// still refuses the reconnect of a terminated session because its token is revoked.
+func (r *Redis) Terminations(ctx context.Context) <-chan Termination {
+	ps := r.client.Subscribe(ctx, terminateChannel)
+	res := make(chan Termination)
+
+	go func() {
+		defer close(res)
+		defer ps.Close()
+
+		ch := ps.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-ch:
+				if !ok {
+					return
+				}
+
+				var t Termination
+				if err := json.Unmarshal([]byte(msg.Payload), &t); err != nil {
+					r.lg.Error(fmt.Errorf("unmarshal termination: %w", err))
+					continue
+				}
+
+				select {
+				case res <- t:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return res
+}
+
+func (r *Redis) Revoke(ctx context.Context, s model.Session) error {
+	keys := revokedKeys(s.TokenIDs)
+	if len(keys) == 0 {
+		return nil
+	}
+
+	_, err := r.client.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for _, key := range keys {
+			p.Set(ctx, key, s.ID, r.cfg.RevocationTTL)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("set: %w", err)
+	}
+	return nil
+}
+
+func (r *Redis) IsRevoked(ctx context.Context, ids model.TokenIDs) (bool, error) {
+	keys := revokedKeys(ids)
+	if len(keys) == 0 {
+		return false, nil
+	}
+
+	n, err := r.client.Exists(ctx, keys...).Result()
+	if err != nil {
+		return false, fmt.Errorf("exists: %w", err)
+	}
+	return n > 0, nil
+}
+
+func (r *Redis) store(ctx context.Context, s model.Session) error {
+	val, err := json.Marshal(redisSession{
+		ID:            s.ID,
+		SubjectID:     s.SubjectID,
+		Device:        s.Device,
+		RemoteAddr:    s.RemoteAddr,
+		UserAgent:     s.UserAgent,
+		Transport:     s.Transport,
+		ConnectedAt:   s.ConnectedAt,
+		TokenID:       s.TokenID,
+		AuthSessionID: s.AuthSessionID,
+		ExpiresAt:     time.Now().Add(r.cfg.TTL),
+	})
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	key := sessionsKeyPrefix + s.SubjectID
+	_, err = r.client.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.HSet(ctx, key, s.ID, val)
+		p.Expire(ctx, key, r.cfg.TTL)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("hset: %w", err)
+	}
+	return nil
+}
+
+func revokedKeys(ids model.TokenIDs) []string {
+	keys := make([]string, 0, 2)
+	if ids.TokenID != "" {
+		keys = append(keys, revokedTokenKeyPrefix+ids.TokenID)
+	}
+	if ids.AuthSessionID != "" {
+		keys = append(keys, revokedAuthSessionKeyPrefix+ids.AuthSessionID)
+	}
+	return keys
+}
@@ -0,0 +1,95 @@
+package session
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/1ocknight/mess/websocket/internal/model"
+)
+
+var (
+	ErrNotFound = fmt.Errorf("session not found")
+)
+
+type Reason string
+
+const (
+	// ReasonTerminated is a session closed by the user from another device
+	ReasonTerminated Reason = "terminated"
+	// ReasonLimit is the oldest session closed to make room over max_sessions
+	ReasonLimit Reason = "limit"
+)
+
+type Config struct {
+	// MaxSessions per subject over all replicas, the oldest sessions are closed to make room, 0 means no limit
+	MaxSessions int `yaml:"max_sessions"`
+	// TTL is how long a session outlives its replica, replicas refresh their sessions every TTL/3
+	TTL time.Duration `yaml:"ttl"`
+	// RevocationTTL keeps a terminated token and keycloak session out, it should cover the keycloak sso session
+	RevocationTTL time.Duration `yaml:"revocation_ttl"`
+}
+
+func (cfg Config) Validate() error {
+	if cfg.MaxSessions < 0 {
+		return fmt.Errorf("max sessions must not be negative")
+	}
+	if cfg.TTL <= 0 {
+		return fmt.Errorf("session ttl must be positive")
+	}
+	if cfg.RevocationTTL <= 0 {
+		return fmt.Errorf("revocation ttl must be positive")
+	}
+	return nil
+}
+
+// Termination asks the replica that holds the session to close it.
+type Termination struct {
+	SubjectID string `json:"subject_id"`
+	SessionID string `json:"session_id"`
+	Reason    Reason `json:"reason"`
+}
+
+// Service is the registry of sessions shared by all replicas.
+type Service interface {
+	// Add stores the session and returns the sessions of the subject over the limit, oldest first.
+	Add(ctx context.Context, s model.Session) ([]model.Session, error)
+	Remove(ctx context.Context, s model.Session) error
+	Get(ctx context.Context, subjectID string, sessionID string) (model.Session, error)
+	List(ctx context.Context, subjectID string) ([]model.Session, error)
+
+	// Publish sends the termination to every replica, Terminations receives them until ctx is done.
+	Publish(ctx context.Context, t Termination) error
+	Terminations(ctx context.Context) <-chan Termination
+
+	Revocations
+}
+
+// Revocations is the deny-list of tokens and keycloak sessions of terminated sessions.
+type Revocations interface {
+	Revoke(ctx context.Context, s model.Session) error
+	IsRevoked(ctx context.Context, ids model.TokenIDs) (bool, error)
+}
+
+// overLimit returns the oldest sessions that do not fit into maxSessions.
+func overLimit(sessions []model.Session, maxSessions int) []model.Session {
+	if maxSessions <= 0 || len(sessions) <= maxSessions {
+		return nil
+	}
+
+	sessions = slices.Clone(sessions)
+	sortSessions(sessions)
+	return sessions[:len(sessions)-maxSessions]
+}
+
+// sortSessions orders by connection time, the id breaks ties so every replica picks the same sessions.
+func sortSessions(sessions []model.Session) {
+	slices.SortFunc(sessions, func(a, b model.Session) int {
+		if c := a.ConnectedAt.Compare(b.ConnectedAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+}
@@ -0,0 +1,69 @@
+package session
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/1ocknight/mess/websocket/internal/model"
+)
+
+func TestOverLimit(t *testing.T) {
+	now := time.Now()
+	sessions := []model.Session{
+		{ID: "c", ConnectedAt: now.Add(2 * time.Second)},
+		{ID: "b", ConnectedAt: now},
+		{ID: "a", ConnectedAt: now},
+		{ID: "d", ConnectedAt: now.Add(3 * time.Second)},
+	}
+
+	tests := []struct {
+		name        string
+		maxSessions int
+		want        []string
+	}{
+		{name: "no limit", maxSessions: 0, want: nil},
+		{name: "under limit", maxSessions: 5, want: nil},
+		{name: "at limit", maxSessions: 4, want: nil},
+		{name: "over limit, same time is ordered by id", maxSessions: 2, want: []string{"a", "b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, s := range overLimit(sessions, tt.maxSessions) {
+				got = append(got, s.ID)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("wait %v, have %v", tt.want, got)
+			}
+		})
+	}
+
+	if sessions[0].ID != "c" {
+		t.Fatalf("overLimit must not reorder the input")
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	valid := Config{MaxSessions: 5, TTL: time.Minute, RevocationTTL: time.Hour}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		cfg  func(Config) Config
+	}{
+		{name: "negative max sessions", cfg: func(c Config) Config { c.MaxSessions = -1; return c }},
+		{name: "zero ttl", cfg: func(c Config) Config { c.TTL = 0; return c }},
+		{name: "zero revocation ttl", cfg: func(c Config) Config { c.RevocationTTL = 0; return c }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg(valid).Validate(); err == nil {
+				t.Fatalf("config must be rejected")
+			}
+		})
+	}
+}
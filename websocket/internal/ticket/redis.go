@@ -0,0 +1,149 @@
+package ticket
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	idBytes   = 32
+	keyPrefix = "ws:ticket:"
+)
+
+type Config struct {
+	TTL time.Duration `yaml:"ttl"`
+}
+
+func (cfg Config) Validate() error {
+	if cfg.TTL <= 0 {
+		return fmt.Errorf("ticket ttl must be positive")
+	}
+	return nil
+}
+
+// Redis keeps tickets in a store shared by all replicas, so a ticket issued by one replica
+// opens a connection on any other. Redis expires tickets that were never redeemed.
+type Redis struct {
+	cfg    Config
+	client *redis.Client
+}
+
+func NewRedis(cfg Config, client *redis.Client) *Redis {
+	return &Redis{
+		cfg:    cfg,
+		client: client,
+	}
+}
+
+type redisTicket struct {
+	SubjectID      string    `json:"subject_id"`
+	Token          string    `json:"token"`
+	TokenExpiresAt time.Time `json:"token_expires_at"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
+
+func (r *Redis) Issue(ctx context.Context, subjectID string, token string, tokenExpiresAt time.Time) (*Ticket, error) {
+	id, err := newID()
+	if err != nil {
+		return nil, fmt.Errorf("new id: %w", err)
+	}
+
+	t, err := r.put(ctx, &Ticket{
+		ID:             id,
+		SubjectID:      subjectID,
+		Token:          token,
+		TokenExpiresAt: tokenExpiresAt,
+	}, r.cfg.TTL)
+	if errors.Is(err, ErrTokenExpired) {
+		return nil, fmt.Errorf("token already expired")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// Rearm puts a redeemed ticket back for ttl. EventSource reconnects with the url it was opened with,
+// so the ticket of a broken stream has to open the next one, but only one stream at a time.
+func (r *Redis) Rearm(ctx context.Context, t *Ticket, ttl time.Duration) error {
+	_, err := r.put(ctx, t, ttl)
+	return err
+}
+
+// put stores the ticket for ttl, but never longer than its token lives.
+func (r *Redis) put(ctx context.Context, t *Ticket, ttl time.Duration) (*Ticket, error) {
+	expiresAt := time.Now().Add(ttl)
+	if t.TokenExpiresAt.Before(expiresAt) {
+		expiresAt = t.TokenExpiresAt
+	}
+	ttl = time.Until(expiresAt)
+	if ttl <= 0 {
+		return nil, ErrTokenExpired
+	}
+
+	val, err := json.Marshal(redisTicket{
+		SubjectID:      t.SubjectID,
+		Token:          t.Token,
+		TokenExpiresAt: t.TokenExpiresAt,
+		ExpiresAt:      expiresAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshal: %w", err)
+	}
+
+	if err := r.client.Set(ctx, keyPrefix+t.ID, val, ttl).Err(); err != nil {
+		return nil, fmt.Errorf("set: %w", err)
+	}
+
+	return &Ticket{
+		ID:             t.ID,
+		SubjectID:      t.SubjectID,
+		Token:          t.Token,
+		TokenExpiresAt: t.TokenExpiresAt,
+		ExpiresAt:      expiresAt,
+	}, nil
+}
+
+// Redeem reads and deletes the ticket with one GETDEL, so two replicas can not redeem it both.
+func (r *Redis) Redeem(ctx context.Context, id string) (*Ticket, error) {
+	val, err := r.client.GetDel(ctx, keyPrefix+id).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getdel: %w", err)
+	}
+
+	var t redisTicket
+	if err := json.Unmarshal(val, &t); err != nil {
+		return nil, fmt.Errorf("unmarshal: %w", err)
+	}
+	if time.Now().After(t.ExpiresAt) {
+		return nil, ErrNotFound
+	}
+
+	return &Ticket{
+		ID:             id,
+		SubjectID:      t.SubjectID,
+		Token:          t.Token,
+		TokenExpiresAt: t.TokenExpiresAt,
+		ExpiresAt:      t.ExpiresAt,
+	}, nil
+}
+
+func newID() (string, error) {
+	b := make([]byte, idBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
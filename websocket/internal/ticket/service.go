@@ -0,0 +1,27 @@
+package ticket
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrNotFound     = fmt.Errorf("ticket not found")
+	ErrTokenExpired = fmt.Errorf("token of ticket expired")
+)
+
+// Ticket is what the client traded its bearer token for, it opens one connection at a time.
+type Ticket struct {
+	ID             string
+	SubjectID      string
+	Token          string
+	TokenExpiresAt time.Time
+	ExpiresAt      time.Time
+}
+
+type Service interface {
+	Issue(ctx context.Context, subjectID string, token string, tokenExpiresAt time.Time) (*Ticket, error)
+	Redeem(ctx context.Context, id string) (*Ticket, error)
+	Rearm(ctx context.Context, t *Ticket, ttl time.Duration) error
+}
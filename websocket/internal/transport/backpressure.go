@@ -0,0 +1,225 @@
+package transport
+
+import (
+	"cmp"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+
+	wsdto "github.com/1ocknight/mess/shared/dto/ws"
+	"github.com/1ocknight/mess/websocket/internal/loglables"
+	"github.com/gorilla/websocket"
+)
+
+type BackpressurePolicy string
+
+const (
+	// DropOldestPolicy drops the oldest queued event to make room for the new one.
+	DropOldestPolicy BackpressurePolicy = "drop_oldest"
+	// CoalescePolicy keeps only the latest update_last_read per chat and falls back to drop_oldest.
+	CoalescePolicy BackpressurePolicy = "coalesce"
+	// ClosePolicy closes the connection of the slow client, it reconnects and refetches state.
+	ClosePolicy BackpressurePolicy = "close"
+)
+
+type BackpressureConfig struct {
+	Policy      BackpressurePolicy `yaml:"policy"`
+	CloseCode   int                `yaml:"close_code"`
+	CloseReason string             `yaml:"close_reason"`
+}
+
+type dropReason string
+
+const (
+	dropClosed    dropReason = "closed"
+	dropCoalesced dropReason = "coalesced"
+	dropOldest    dropReason = "oldest"
+)
+
+const (
+	// droppedSubjectsLimit bounds the subjects tracked by DroppedBySubject
+	droppedSubjectsLimit = 1000
+	// droppedSubjectsTop is how many of them are served on /debug/vars
+	droppedSubjectsTop = 20
+)
+
+var (
+	// DroppedEvents is the number of events dropped for slow clients by reason, served on /debug/vars.
+	DroppedEvents = expvar.NewMap("ws_dropped_events")
+
+	// DroppedBySubject finds the subjects that lose the most events, served on /debug/vars as the top.
+	DroppedBySubject = newSubjectDrops(droppedSubjectsLimit)
+)
+
+func init() {
+	expvar.Publish("ws_dropped_events_by_subject", expvar.Func(func() any {
+		return DroppedBySubject.top(droppedSubjectsTop)
+	}))
+}
+
+// subjectDrops counts drops per subject in bounded memory with the space saving algorithm: a new subject
+// replaces the one with the least drops and inherits its count. Heavy hitters stay, their counts
+// may be overestimated by at most the count of the replaced subject.
+type subjectDrops struct {
+	mu     sync.Mutex
+	limit  int
+	counts map[string]int64
+}
+
+func newSubjectDrops(limit int) *subjectDrops {
+	return &subjectDrops{
+		limit:  limit,
+		counts: make(map[string]int64, limit),
+	}
+}
+
+func (d *subjectDrops) add(subjectID string, n int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.counts[subjectID]; !ok && len(d.counts) >= d.limit {
+		minSubject, minCount := "", int64(math.MaxInt64)
+		for subj, count := range d.counts {
+			if count < minCount {
+				minSubject, minCount = subj, count
+			}
+		}
+		delete(d.counts, minSubject)
+		d.counts[subjectID] = minCount
+	}
+	d.counts[subjectID] += int64(n)
+}
+
+// top returns up to n subjects with the most drops.
+func (d *subjectDrops) top(n int) map[string]int64 {
+	d.mu.Lock()
+	subjects := make([]string, 0, len(d.counts))
+	for subj := range d.counts {
+		subjects = append(subjects, subj)
+	}
+	slices.SortFunc(subjects, func(a, b string) int {
+		return cmp.Compare(d.counts[b], d.counts[a])
+	})
+	subjects = subjects[:min(n, len(subjects))]
+
+	res := make(map[string]int64, len(subjects))
+	for _, subj := range subjects {
+		res[subj] = d.counts[subj]
+	}
+	d.mu.Unlock()
+
+	return res
+}
+
+// enqueue puts the message into the Send of a registered client applying the backpressure policy when it is full.
+func (s *shard) enqueue(c *Client, msg *wsdto.WSMessage) {
+	select {
+	case c.Send <- msg:
+		return
+	default:
+	}
+
+	lg := s.lg.With(loglables.Subject, c.SubjectID)
+
+	switch s.cfg.Policy {
+	case ClosePolicy:
+		s.dropped(c, dropClosed, len(c.Send)+1)
+		s.remove(c, websocket.FormatCloseMessage(s.cfg.CloseCode, s.cfg.CloseReason))
+		lg.Info("close slow client")
+		return
+	case CoalescePolicy:
+		if n := coalesce(c.Send, msg); n > 0 {
+			s.dropped(c, dropCoalesced, n)
+			lg.With(loglables.Dropped, n).Info("coalesce last read events")
+		}
+	}
+
+	for {
+		select {
+		case c.Send <- msg:
+			return
+		default:
+		}
+
+		select {
+		case <-c.Send:
+			s.dropped(c, dropOldest, 1)
+			lg.Info("drop oldest event")
+		default:
+		}
+	}
+}
+
+func (s *shard) dropped(c *Client, reason dropReason, n int) {
+	if n == 0 {
+		return
+	}
+	DroppedEvents.Add(string(reason), int64(n))
+	DroppedBySubject.add(c.SubjectID, n)
+}
+
+type lastReadKey struct {
+	ChatID    int
+	SubjectID string
+}
+
+// coalesce drops queued update_last_read events superseded by a later one for the same chat, including msg.
+// It returns the number of dropped events. Order of the rest is kept.
+func coalesce(send chan *wsdto.WSMessage, msg *wsdto.WSMessage) int {
+	queued := make([]*wsdto.WSMessage, 0, len(send))
+	for n := len(send); n > 0; n-- {
+		select {
+		case m := <-send:
+			queued = append(queued, m)
+		default:
+		}
+	}
+
+	latest := make(map[lastReadKey]int)
+	for i, m := range append(queued, msg) {
+		if key, ok := getLastReadKey(m); ok {
+			latest[key] = i
+		}
+	}
+
+	dropped := 0
+	for i, m := range queued {
+		if key, ok := getLastReadKey(m); ok && latest[key] != i {
+			dropped++
+			continue
+		}
+		send <- m
+	}
+
+	return dropped
+}
+
+func getLastReadKey(m *wsdto.WSMessage) (lastReadKey, bool) {
+	if m.Type != wsdto.UpdateLastRead {
+		return lastReadKey{}, false
+	}
+
+	var lr wsdto.LastRead
+	if err := json.Unmarshal(m.Data, &lr); err != nil {
+		return lastReadKey{}, false
+	}
+
+	return lastReadKey{ChatID: lr.ChatID, SubjectID: lr.SubjectID}, true
+}
+
+func (cfg BackpressureConfig) Validate() error {
+	switch cfg.Policy {
+	case DropOldestPolicy, CoalescePolicy:
+		return nil
+	case ClosePolicy:
+		if cfg.CloseCode == 0 {
+			return fmt.Errorf("close code is required for %v policy", cfg.Policy)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown backpressure policy: %v", cfg.Policy)
+	}
+}
@@ -0,0 +1,155 @@
+package transport
+
+import (
+	"encoding/json"
+	"expvar"
+	"io"
+	"log/slog"
+	"slices"
+	"strconv"
+	"testing"
+
+	wsdto "github.com/1ocknight/mess/shared/dto/ws"
+	"github.com/1ocknight/mess/shared/logger"
+	"github.com/1ocknight/mess/websocket/internal/model"
+	"github.com/gorilla/websocket"
+)
+
+// newBackpressureShard returns a shard that is not running, enqueue is called on it directly.
+func newBackpressureShard(t *testing.T, cfg BackpressureConfig, subjectID string, buffer int) (*shard, *Client) {
+	t.Helper()
+	lg := logger.New(slog.NewJSONHandler(io.Discard, nil))
+	hub := NewHub(make(chan *model.Message), HubConfig{Shards: 1, ShardBuffer: 16, Backpressure: cfg}, lg)
+
+	s := hub.shards[0]
+	c := &Client{SubjectID: subjectID, Send: make(chan *wsdto.WSMessage, buffer), hub: hub}
+	s.clients[subjectID] = map[*Client]struct{}{c: {}}
+	return s, c
+}
+
+func event(id int) *wsdto.WSMessage {
+	return &wsdto.WSMessage{ID: strconv.Itoa(id), Type: wsdto.SendMessage, Data: []byte(`{}`)}
+}
+
+func lastReadEvent(t *testing.T, id int, chatID int) *wsdto.WSMessage {
+	t.Helper()
+	data, err := json.Marshal(wsdto.LastRead{ChatID: chatID, SubjectID: "reader", MessageID: id})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	return &wsdto.WSMessage{ID: strconv.Itoa(id), Type: wsdto.UpdateLastRead, Data: data}
+}
+
+func queuedIDs(c *Client) []string {
+	ids := make([]string, 0, len(c.Send))
+	for len(c.Send) > 0 {
+		ids = append(ids, (<-c.Send).ID)
+	}
+	return ids
+}
+
+// droppedForSubject reads the global counter, tests compare it with the value before they run.
+func droppedForSubject(subjectID string) int64 {
+	return DroppedBySubject.top(droppedSubjectsLimit)[subjectID]
+}
+
+func droppedFor(reason dropReason) int64 {
+	v, ok := DroppedEvents.Get(string(reason)).(*expvar.Int)
+	if !ok {
+		return 0
+	}
+	return v.Value()
+}
+
+func TestEnqueue_DropOldest(t *testing.T) {
+	s, c := newBackpressureShard(t, BackpressureConfig{Policy: DropOldestPolicy}, "drop-oldest", 2)
+	before := droppedFor(dropOldest)
+	beforeSubject := droppedForSubject("drop-oldest")
+
+	for i := 1; i <= 3; i++ {
+		s.enqueue(c, event(i))
+	}
+
+	if ids := queuedIDs(c); !slices.Equal(ids, []string{"2", "3"}) {
+		t.Fatalf("wait the newest events, have %v", ids)
+	}
+	if n := droppedFor(dropOldest) - before; n != 1 {
+		t.Fatalf("wait 1 dropped, have %v", n)
+	}
+	if n := droppedForSubject("drop-oldest") - beforeSubject; n != 1 {
+		t.Fatalf("wait 1 dropped for the subject, have %v", n)
+	}
+}
+
+func TestEnqueue_Coalesce(t *testing.T) {
+	s, c := newBackpressureShard(t, BackpressureConfig{Policy: CoalescePolicy}, "coalesce", 3)
+	before := droppedFor(dropCoalesced)
+	beforeSubject := droppedForSubject("coalesce")
+
+	s.enqueue(c, lastReadEvent(t, 1, 10))
+	s.enqueue(c, event(2))
+	s.enqueue(c, lastReadEvent(t, 3, 20))
+	// the queued last read of chat 10 is superseded, the order of the rest is kept
+	s.enqueue(c, lastReadEvent(t, 4, 10))
+
+	if ids := queuedIDs(c); !slices.Equal(ids, []string{"2", "3", "4"}) {
+		t.Fatalf("wait coalesced queue, have %v", ids)
+	}
+	if n := droppedFor(dropCoalesced) - before; n != 1 {
+		t.Fatalf("wait 1 coalesced, have %v", n)
+	}
+
+	// nothing to coalesce falls back to drop_oldest
+	for i := 5; i <= 8; i++ {
+		s.enqueue(c, event(i))
+	}
+	if ids := queuedIDs(c); !slices.Equal(ids, []string{"6", "7", "8"}) {
+		t.Fatalf("wait the newest events, have %v", ids)
+	}
+	if n := droppedForSubject("coalesce") - beforeSubject; n != 2 {
+		t.Fatalf("wait 2 dropped for the subject, have %v", n)
+	}
+}
+
+func TestEnqueue_Close(t *testing.T) {
+	cfg := BackpressureConfig{Policy: ClosePolicy, CloseCode: websocket.ClosePolicyViolation, CloseReason: "slow client"}
+	s, c := newBackpressureShard(t, cfg, "close", 2)
+	before := droppedFor(dropClosed)
+
+	for i := 1; i <= 3; i++ {
+		s.enqueue(c, event(i))
+	}
+
+	// what was queued is still written before the close message
+	if ids := queuedIDs(c); !slices.Equal(ids, []string{"1", "2"}) {
+		t.Fatalf("wait the queued events, have %v", ids)
+	}
+	if _, ok := <-c.Send; ok {
+		t.Fatalf("send of slow client is not closed")
+	}
+	if want := websocket.FormatCloseMessage(cfg.CloseCode, cfg.CloseReason); !slices.Equal(c.closeMessage, want) {
+		t.Fatalf("wait close message %q, have %q", want, c.closeMessage)
+	}
+	if _, ok := s.clients["close"]; ok {
+		t.Fatalf("slow client is still registered")
+	}
+	if n := droppedFor(dropClosed) - before; n != 3 {
+		t.Fatalf("wait 3 dropped, have %v", n)
+	}
+}
+
+func TestSubjectDrops_Bounded(t *testing.T) {
+	d := newSubjectDrops(2)
+	d.add("heavy", 10)
+	d.add("light", 1)
+	// the subject with the least drops makes room and its count is inherited
+	d.add("new", 1)
+
+	top := d.top(10)
+	if len(top) != 2 || top["heavy"] != 10 || top["new"] != 2 {
+		t.Fatalf("wait heavy and new, have %v", top)
+	}
+	if top := d.top(1); len(top) != 1 || top["heavy"] != 10 {
+		t.Fatalf("wait only heavy, have %v", top)
+	}
+}
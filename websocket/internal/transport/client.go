@@ -1,38 +1,85 @@
 package transport
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
+	"sync/atomic"
 	"time"
 
+	"github.com/1ocknight/mess/shared/auth"
+	httpdto "github.com/1ocknight/mess/shared/dto/http"
 	wsdto "github.com/1ocknight/mess/shared/dto/ws"
+	"github.com/1ocknight/mess/websocket/internal/adapter/chat"
+	"github.com/1ocknight/mess/websocket/internal/model"
 	"github.com/gorilla/websocket"
 )
 
 var (
 	newline = []byte{'\n'}
+
+	// SupportedEvents are the event types the server pushes, announced in hello.
+	SupportedEvents = []wsdto.Operation{
+		wsdto.SendMessage,
+		wsdto.UpdateMessage,
+		wsdto.UpdateLastRead,
+		wsdto.DataExportReady,
+		wsdto.AvatarProcessed,
+		wsdto.ProfileUpdated,
+	}
+
+	tokenExpiredMessage = websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "token expired")
 )
 
 type Client struct {
 	SubjectID string
+	Session   model.Session
 	Send      chan *wsdto.WSMessage
 	cfg       ClientConfig
 	hub       *Hub
 	conn      *websocket.Conn
+	token     string
+	chat      chat.Service
+	auth      auth.Service
+
+	// unix nano, written by auth command in readPump and read by writePump
+	tokenExpiresAt atomic.Int64
+
+	// set by the hub before it closes Send
+	closeMessage []byte
+
+	codec codec
+
+	// nil means every event, written by subscribe command in readPump and read by the shard
+	events atomic.Pointer[map[wsdto.Operation]struct{}]
+
+	// set before register, events after it are replayed from the hub history
+	lastEventID int64
 }
 
-func NewClient(subjectID string, conn *websocket.Conn, cfg ClientConfig, hub *Hub) *Client {
-	return &Client{
-		SubjectID: subjectID,
-		Send:      make(chan *wsdto.WSMessage, cfg.MessageBuffer),
+func NewClient(session model.Session, token string, tokenExpiresAt time.Time, conn *websocket.Conn, cfg ClientConfig, hub *Hub, chat chat.Service, auth auth.Service) *Client {
+	c := &Client{
+		SubjectID: session.SubjectID,
+		Session:   session,
+		Send:      make(chan *wsdto.WSMessage, max(cfg.MessageBuffer, 1)),
 		cfg:       cfg,
 		hub:       hub,
 		conn:      conn,
+		token:     token,
+		chat:      chat,
+		auth:      auth,
+		codec:     newCodec(conn.Subprotocol()),
 	}
+	c.tokenExpiresAt.Store(tokenExpiresAt.UnixNano())
+
+	return c
 }
 
 func (c *Client) readPump() {
 	defer func() {
-		c.hub.unregister <- c
+		c.hub.Unregister(c)
 		c.conn.Close()
 	}()
 
@@ -55,53 +102,244 @@ func (c *Client) readPump() {
 			break
 		}
 
-		_ = message
+		c.handleCommand(message)
 	}
 }
 
+// handleCommand runs commands one by one, so acks come back in the order the commands were sent.
+// Errors of chat are forwarded as is, except the internal ones which are logged and answered generically.
+func (c *Client) handleCommand(message []byte) {
+	cmd, err := c.codec.decode(message)
+	if err != nil {
+		c.reply(c.errorFrame("", string(httpdto.InvalidRequestCode), "invalid message"))
+		return
+	}
+
+	res, err := c.execCommand(context.Background(), cmd)
+	if err != nil {
+		var rpcErr *chat.RPCError
+		if errors.As(err, &rpcErr) && rpcErr.Code != string(httpdto.InternalCode) {
+			c.reply(c.errorFrame(cmd.ID, rpcErr.Code, rpcErr.Message))
+			return
+		}
+		c.sendError(fmt.Errorf("exec %v: %w", cmd.Type, err))
+		c.reply(c.errorFrame(cmd.ID, string(httpdto.InternalCode), "internal error"))
+		return
+	}
+
+	data, err := res.GetData()
+	if err != nil {
+		c.sendError(fmt.Errorf("get data: %w", err))
+		return
+	}
+
+	c.reply(&wsdto.WSMessage{
+		ID:   cmd.ID,
+		Type: wsdto.Ack,
+		Data: data,
+	})
+}
+
+type commandResult interface {
+	GetData() ([]byte, error)
+}
+
+func (c *Client) execCommand(ctx context.Context, cmd *wsdto.WSMessage) (commandResult, error) {
+	switch cmd.Type {
+	case wsdto.SendMessage:
+		var req wsdto.SendMessageCommand
+		if err := json.Unmarshal(cmd.Data, &req); err != nil {
+			return nil, invalidCommandError(err)
+		}
+		return c.chat.SendMessage(ctx, c.token, &req)
+	case wsdto.UpdateMessage:
+		var req wsdto.UpdateMessageCommand
+		if err := json.Unmarshal(cmd.Data, &req); err != nil {
+			return nil, invalidCommandError(err)
+		}
+		return c.chat.UpdateMessage(ctx, c.token, &req)
+	case wsdto.MarkRead:
+		var req wsdto.MarkReadCommand
+		if err := json.Unmarshal(cmd.Data, &req); err != nil {
+			return nil, invalidCommandError(err)
+		}
+		return c.chat.MarkRead(ctx, c.token, &req)
+	case wsdto.Subscribe:
+		var req wsdto.SubscribeCommand
+		if err := json.Unmarshal(cmd.Data, &req); err != nil {
+			return nil, invalidCommandError(err)
+		}
+		return c.subscribe(&req)
+	case wsdto.Auth:
+		var req wsdto.AuthCommand
+		if err := json.Unmarshal(cmd.Data, &req); err != nil {
+			return nil, invalidCommandError(err)
+		}
+		return c.refreshToken(&req)
+	default:
+		return nil, &chat.RPCError{
+			Code:    string(httpdto.InvalidRequestCode),
+			Message: fmt.Sprintf("unknown command: %v", cmd.Type),
+		}
+	}
+}
+
+// refreshToken swaps the token of the connection, it has to belong to the same subject.
+func (c *Client) refreshToken(cmd *wsdto.AuthCommand) (*wsdto.AuthResult, error) {
+	sub, claims, err := verifyToken(c.auth, cmd.Token)
+	if err != nil {
+		return nil, &chat.RPCError{
+			Code:    string(httpdto.UnauthorizedCode),
+			Message: err.Error(),
+		}
+	}
+	if sub.GetSubjectId() != c.SubjectID {
+		return nil, &chat.RPCError{
+			Code:    string(httpdto.ForbiddenCode),
+			Message: "token of other subject",
+		}
+	}
+
+	c.token = cmd.Token
+	c.tokenExpiresAt.Store(claims.expiresAt.UnixNano())
+
+	return &wsdto.AuthResult{ExpiresAt: claims.expiresAt}, nil
+}
+
+// subscribe replaces the set of events of the connection, an empty list brings back every event.
+func (c *Client) subscribe(cmd *wsdto.SubscribeCommand) (*wsdto.SubscribeCommand, error) {
+	if len(cmd.Events) == 0 {
+		c.events.Store(nil)
+		return &wsdto.SubscribeCommand{Events: SupportedEvents}, nil
+	}
+
+	events := make(map[wsdto.Operation]struct{}, len(cmd.Events))
+	for _, e := range cmd.Events {
+		if !slices.Contains(SupportedEvents, e) {
+			return nil, &chat.RPCError{
+				Code:    string(httpdto.InvalidRequestCode),
+				Message: fmt.Sprintf("unknown event: %v", e),
+			}
+		}
+		events[e] = struct{}{}
+	}
+	c.events.Store(&events)
+
+	return cmd, nil
+}
+
+func (c *Client) wants(event wsdto.Operation) bool {
+	events := c.events.Load()
+	if events == nil {
+		return true
+	}
+	_, ok := (*events)[event]
+	return ok
+}
+
+func (c *Client) hello() *wsdto.WSMessage {
+	data, _ := (&wsdto.ServerHello{
+		Version:    wsdto.ProtocolVersion,
+		ServerTime: time.Now().UTC(),
+		SessionID:  c.Session.ID,
+		Events:     SupportedEvents,
+	}).GetData()
+
+	return &wsdto.WSMessage{
+		Type: wsdto.Hello,
+		Data: data,
+		Ts:   time.Now().UTC(),
+		V:    wsdto.ProtocolVersion,
+	}
+}
+
+func (c *Client) getTokenExpiresAt() time.Time {
+	return time.Unix(0, c.tokenExpiresAt.Load())
+}
+
+func invalidCommandError(err error) error {
+	return &chat.RPCError{
+		Code:    string(httpdto.InvalidRequestCode),
+		Message: err.Error(),
+	}
+}
+
+func (c *Client) errorFrame(id string, code string, message string) *wsdto.WSMessage {
+	data, _ := (&wsdto.CommandError{Code: code, Message: message}).GetData()
+	return &wsdto.WSMessage{
+		ID:   id,
+		Type: wsdto.Error,
+		Data: data,
+	}
+}
+
+// reply goes through the hub, which is the only one allowed to write to Send.
+func (c *Client) reply(msg *wsdto.WSMessage) {
+	msg.Ts = time.Now().UTC()
+	msg.V = wsdto.ProtocolVersion
+	c.hub.Reply(c, msg)
+}
+
 func (c *Client) sendError(err error) {
 	c.hub.lg.Error(fmt.Errorf("subj: %v, err: %w", c.SubjectID, err))
 }
 
 func (c *Client) writePump() {
 	ticker := time.NewTicker(c.cfg.PingPeriod)
+	expiry := time.NewTimer(time.Until(c.getTokenExpiresAt()))
 	defer func() {
 		ticker.Stop()
+		expiry.Stop()
 		c.conn.Close()
 	}()
 
 	for {
 		select {
+		case <-expiry.C:
+			// the token may have been refreshed since the timer was set
+			if left := time.Until(c.getTokenExpiresAt()); left > 0 {
+				expiry.Reset(left)
+				continue
+			}
+			c.conn.SetWriteDeadline(time.Now().Add(c.cfg.WriteTimeout))
+			c.conn.WriteMessage(websocket.CloseMessage, tokenExpiredMessage)
+			return
 		case message, ok := <-c.Send:
 			c.conn.SetWriteDeadline(time.Now().Add(c.cfg.WriteTimeout))
 			if !ok {
-				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				c.conn.WriteMessage(websocket.CloseMessage, c.closeMessage)
 				return
 			}
 
-			w, err := c.conn.NextWriter(websocket.TextMessage)
+			w, err := c.conn.NextWriter(c.codec.frameType())
 			if err != nil {
 				c.sendError(err)
 				return
 			}
-			msg, err := message.GetBytes()
-			if err != nil {
+			if err := c.codec.encode(w, message, true); err != nil {
 				c.sendError(err)
 				return
 			}
 
-			w.Write(msg)
-
 			// Add queued chat messages to the current websocket message.
+			// The hub may take queued messages away under backpressure, so never block here.
 			n := len(c.Send)
+		batch:
 			for i := 0; i < n; i++ {
-				w.Write(newline)
-				msg, err := (<-c.Send).GetBytes()
-				if err != nil {
+				var queued *wsdto.WSMessage
+				select {
+				case queued, ok = <-c.Send:
+					if !ok {
+						break batch
+					}
+				default:
+					break batch
+				}
+
+				if err := c.codec.encode(w, queued, false); err != nil {
 					c.sendError(err)
 					return
 				}
-				w.Write(msg)
 			}
 
 			if err := w.Close(); err != nil {
@@ -0,0 +1,334 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+
+	httpdto "github.com/1ocknight/mess/shared/dto/http"
+	wsdto "github.com/1ocknight/mess/shared/dto/ws"
+	"github.com/1ocknight/mess/shared/logger"
+	"github.com/1ocknight/mess/websocket/internal/adapter/chat"
+	"github.com/1ocknight/mess/websocket/internal/model"
+)
+
+// fakeChat answers every command with the same result and remembers the token it was called with.
+type fakeChat struct {
+	message *wsdto.Message
+	err     error
+	token   string
+}
+
+func (f *fakeChat) SendMessage(_ context.Context, token string, _ *wsdto.SendMessageCommand) (*wsdto.Message, error) {
+	f.token = token
+	return f.message, f.err
+}
+
+func (f *fakeChat) UpdateMessage(_ context.Context, token string, _ *wsdto.UpdateMessageCommand) (*wsdto.Message, error) {
+	f.token = token
+	return f.message, f.err
+}
+
+func (f *fakeChat) MarkRead(_ context.Context, token string, cmd *wsdto.MarkReadCommand) (*wsdto.LastRead, error) {
+	f.token = token
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &wsdto.LastRead{ChatID: cmd.ChatID, MessageID: cmd.MessageID}, nil
+}
+
+func newCommandTestClient(t *testing.T, subjectID string, chat chat.Service) *Client {
+	t.Helper()
+	lg := logger.New(slog.NewJSONHandler(io.Discard, nil))
+	hub := NewHub(make(chan *model.Message), HubConfig{
+		Shards:       1,
+		ShardBuffer:  16,
+		Backpressure: BackpressureConfig{Policy: DropOldestPolicy},
+	}, lg)
+	go hub.Run(t.Context())
+
+	c := &Client{
+		SubjectID: subjectID,
+		Send:      make(chan *wsdto.WSMessage, 16),
+		hub:       hub,
+		token:     "token",
+		chat:      chat,
+		auth:      fakeAuth{},
+		codec:     newCodec(JSONSubprotocol),
+	}
+	hub.Register(c)
+	return c
+}
+
+func receive(t *testing.T, c *Client) *wsdto.WSMessage {
+	t.Helper()
+	select {
+	case msg := <-c.Send:
+		return msg
+	case <-time.After(time.Second):
+		t.Fatalf("no frame for client")
+		return nil
+	}
+}
+
+func command(t *testing.T, id string, op wsdto.Operation, data any) []byte {
+	t.Helper()
+	raw, err := json.Marshal(data)
+	if err != nil {
+		t.Fatalf("marshal data: %v", err)
+	}
+	msg, err := json.Marshal(wsdto.WSMessage{ID: id, Type: op, Data: raw})
+	if err != nil {
+		t.Fatalf("marshal command: %v", err)
+	}
+	return msg
+}
+
+func TestClient_HandleCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		chat    *fakeChat
+		message []byte
+		// empty code waits for an ack
+		code        httpdto.ErrorCode
+		errMessage  string
+		wantMessage *wsdto.Message
+	}{
+		{
+			name:        "ack with result",
+			chat:        &fakeChat{message: &wsdto.Message{ID: 7, ChatID: 1, Content: "hi"}},
+			message:     command(t, "c1", wsdto.SendMessage, wsdto.SendMessageCommand{ChatID: 1, Content: "hi"}),
+			wantMessage: &wsdto.Message{ID: 7, ChatID: 1, Content: "hi"},
+		},
+		{
+			name:       "rpc error is forwarded",
+			chat:       &fakeChat{err: &chat.RPCError{Code: string(httpdto.ForbiddenCode), Message: "subject not have this resource"}},
+			message:    command(t, "c1", wsdto.UpdateMessage, wsdto.UpdateMessageCommand{MessageID: 7, Content: "hi"}),
+			code:       httpdto.ForbiddenCode,
+			errMessage: "subject not have this resource",
+		},
+		{
+			name:       "internal rpc error is not forwarded",
+			chat:       &fakeChat{err: &chat.RPCError{Code: string(httpdto.InternalCode), Message: "db exec: pq: connection refused"}},
+			message:    command(t, "c1", wsdto.SendMessage, wsdto.SendMessageCommand{ChatID: 1, Content: "hi"}),
+			code:       httpdto.InternalCode,
+			errMessage: "internal error",
+		},
+		{
+			name:       "transport error is not forwarded",
+			chat:       &fakeChat{err: errors.New("do: dial tcp: connection refused")},
+			message:    command(t, "c1", wsdto.MarkRead, wsdto.MarkReadCommand{ChatID: 1, MessageID: 7}),
+			code:       httpdto.InternalCode,
+			errMessage: "internal error",
+		},
+		{
+			name:    "not a message",
+			chat:    &fakeChat{},
+			message: []byte("not a message"),
+			code:    httpdto.InvalidRequestCode,
+		},
+		{
+			name:    "invalid command data",
+			chat:    &fakeChat{},
+			message: command(t, "c1", wsdto.SendMessage, "not a command"),
+			code:    httpdto.InvalidRequestCode,
+		},
+		{
+			name:       "unknown command",
+			chat:       &fakeChat{},
+			message:    command(t, "c1", wsdto.Hello, struct{}{}),
+			code:       httpdto.InvalidRequestCode,
+			errMessage: "unknown command: hello",
+		},
+		{
+			name:       "subscribe to unknown event",
+			chat:       &fakeChat{},
+			message:    command(t, "c1", wsdto.Subscribe, wsdto.SubscribeCommand{Events: []wsdto.Operation{wsdto.MarkRead}}),
+			code:       httpdto.InvalidRequestCode,
+			errMessage: "unknown event: mark_read",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newCommandTestClient(t, "subj", tt.chat)
+			c.handleCommand(tt.message)
+
+			msg := receive(t, c)
+			if msg.V != wsdto.ProtocolVersion || msg.Ts.IsZero() {
+				t.Fatalf("frame without envelope: %+v", msg)
+			}
+
+			if tt.code == "" {
+				if msg.Type != wsdto.Ack || msg.ID != "c1" {
+					t.Fatalf("wait ack of c1, have %v of %v", msg.Type, msg.ID)
+				}
+				var res wsdto.Message
+				if err := json.Unmarshal(msg.Data, &res); err != nil {
+					t.Fatalf("unmarshal: %v", err)
+				}
+				if res.ID != tt.wantMessage.ID || res.ChatID != tt.wantMessage.ChatID || res.Content != tt.wantMessage.Content {
+					t.Fatalf("wait %+v, have %+v", tt.wantMessage, res)
+				}
+				if tt.chat.token != "token" {
+					t.Fatalf("chat is called with token %q", tt.chat.token)
+				}
+				return
+			}
+
+			if msg.Type != wsdto.Error {
+				t.Fatalf("wait error frame, have %v", msg.Type)
+			}
+			var res wsdto.CommandError
+			if err := json.Unmarshal(msg.Data, &res); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			if res.Code != string(tt.code) || (tt.errMessage != "" && res.Message != tt.errMessage) {
+				t.Fatalf("wait %v %q, have %v %q", tt.code, tt.errMessage, res.Code, res.Message)
+			}
+		})
+	}
+}
+
+func TestClient_ExecCommand_Auth(t *testing.T) {
+	token := newTestToken(t, "jti", "sid")
+
+	c := newCommandTestClient(t, "subj", &fakeChat{})
+	res, err := c.execCommand(t.Context(), &wsdto.WSMessage{Type: wsdto.Auth, Data: []byte(`{"token":"` + token + `"}`)})
+	if err != nil {
+		t.Fatalf("exec auth: %v", err)
+	}
+	if c.token != token {
+		t.Fatalf("token of connection is not swapped")
+	}
+	if _, ok := res.(*wsdto.AuthResult); !ok || c.getTokenExpiresAt().Before(time.Now()) {
+		t.Fatalf("wait new expiry, have %+v", res)
+	}
+
+	// fakeAuth verifies every token as subj
+	other := newCommandTestClient(t, "other", &fakeChat{})
+	_, err = other.execCommand(t.Context(), &wsdto.WSMessage{Type: wsdto.Auth, Data: []byte(`{"token":"` + token + `"}`)})
+	var rpcErr *chat.RPCError
+	if !errors.As(err, &rpcErr) || rpcErr.Code != string(httpdto.ForbiddenCode) {
+		t.Fatalf("wait forbidden, have %v", err)
+	}
+	if other.token != "token" {
+		t.Fatalf("token of other subject is taken")
+	}
+}
+
+func TestClient_Subscribe(t *testing.T) {
+	c := newCommandTestClient(t, "subj", &fakeChat{})
+
+	// a client that never subscribed gets every event
+	for _, e := range SupportedEvents {
+		if !c.wants(e) {
+			t.Fatalf("client without subscription must want %v", e)
+		}
+	}
+
+	subscribe := func(id string, events []wsdto.Operation) *wsdto.WSMessage {
+		t.Helper()
+		c.handleCommand(command(t, id, wsdto.Subscribe, wsdto.SubscribeCommand{Events: events}))
+		return receive(t, c)
+	}
+
+	chosen := []wsdto.Operation{wsdto.UpdateLastRead, wsdto.ProfileUpdated}
+	ack := subscribe("s1", chosen)
+	if ack.Type != wsdto.Ack || ack.ID != "s1" {
+		t.Fatalf("wait ack s1, have %v %v", ack.Type, ack.ID)
+	}
+	var res wsdto.SubscribeCommand
+	if err := json.Unmarshal(ack.Data, &res); err != nil {
+		t.Fatalf("unmarshal ack: %v", err)
+	}
+	if !slices.Equal(res.Events, chosen) {
+		t.Fatalf("wait %v, have %v", chosen, res.Events)
+	}
+	for _, e := range SupportedEvents {
+		if want := slices.Contains(chosen, e); c.wants(e) != want {
+			t.Fatalf("wants %v: wait %v, have %v", e, want, c.wants(e))
+		}
+	}
+
+	// an unknown event is rejected and the subscription stays as it was
+	frame := subscribe("s2", []wsdto.Operation{wsdto.SendMessage, "unknown"})
+	if frame.Type != wsdto.Error || frame.ID != "s2" {
+		t.Fatalf("wait error s2, have %v %v", frame.Type, frame.ID)
+	}
+	var cmdErr wsdto.CommandError
+	if err := json.Unmarshal(frame.Data, &cmdErr); err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+	if cmdErr.Code != string(httpdto.InvalidRequestCode) {
+		t.Fatalf("wait %v, have %v", httpdto.InvalidRequestCode, cmdErr.Code)
+	}
+	if c.wants(wsdto.SendMessage) {
+		t.Fatalf("rejected subscribe must not change the events")
+	}
+
+	// an empty list brings back every event
+	ack = subscribe("s3", nil)
+	if err := json.Unmarshal(ack.Data, &res); err != nil {
+		t.Fatalf("unmarshal ack: %v", err)
+	}
+	if !slices.Equal(res.Events, SupportedEvents) {
+		t.Fatalf("wait %v, have %v", SupportedEvents, res.Events)
+	}
+	for _, e := range SupportedEvents {
+		if !c.wants(e) {
+			t.Fatalf("reset subscription must want %v", e)
+		}
+	}
+}
+
+func TestClient_Hello(t *testing.T) {
+	c := &Client{SubjectID: "subj", Session: model.Session{ID: "session", SubjectID: "subj"}}
+
+	before := time.Now().UTC()
+	msg := c.hello()
+
+	if msg.Type != wsdto.Hello || msg.ID != "" {
+		t.Fatalf("wait hello without id, have %v %q", msg.Type, msg.ID)
+	}
+	if msg.V != wsdto.ProtocolVersion {
+		t.Fatalf("wait v %v, have %v", wsdto.ProtocolVersion, msg.V)
+	}
+	if msg.Ts.Before(before) || msg.Ts.Location() != time.UTC {
+		t.Fatalf("wait utc ts after %v, have %v", before, msg.Ts)
+	}
+
+	var hello wsdto.ServerHello
+	if err := json.Unmarshal(msg.Data, &hello); err != nil {
+		t.Fatalf("unmarshal hello: %v", err)
+	}
+	if hello.Version != wsdto.ProtocolVersion || hello.SessionID != "session" {
+		t.Fatalf("wait version %v session %v, have %+v", wsdto.ProtocolVersion, "session", hello)
+	}
+	if !slices.Equal(hello.Events, SupportedEvents) {
+		t.Fatalf("wait events %v, have %v", SupportedEvents, hello.Events)
+	}
+
+	// the envelope goes to the client with the short field names
+	raw, err := msg.GetBytes()
+	if err != nil {
+		t.Fatalf("get bytes: %v", err)
+	}
+	var envelope map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &envelope); err != nil {
+		t.Fatalf("unmarshal envelope: %v", err)
+	}
+	for _, key := range []string{"type", "data", "ts", "v"} {
+		if _, ok := envelope[key]; !ok {
+			t.Fatalf("wait %q in %s", key, raw)
+		}
+	}
+	if _, ok := envelope["id"]; ok {
+		t.Fatalf("hello must have no id, have %s", raw)
+	}
+}
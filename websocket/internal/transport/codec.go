@@ -0,0 +1,143 @@
+package transport
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	wsdto "github.com/1ocknight/mess/shared/dto/ws"
+	"github.com/gorilla/websocket"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+const (
+	JSONSubprotocol    = "json"
+	MsgpackSubprotocol = "msgpack"
+)
+
+var (
+	// Subprotocols in order of server preference, a client without Sec-WebSocket-Protocol gets json.
+	Subprotocols = []string{MsgpackSubprotocol, JSONSubprotocol}
+)
+
+// codec frames WSMessage for the negotiated subprotocol. Several messages may be written into one
+// websocket message: json separates them with a newline, msgpack values are self-delimiting.
+type codec interface {
+	frameType() int
+	encode(w io.Writer, msg *wsdto.WSMessage, first bool) error
+	decode(data []byte) (*wsdto.WSMessage, error)
+}
+
+func newCodec(subprotocol string) codec {
+	if subprotocol == MsgpackSubprotocol {
+		return msgpackCodec{}
+	}
+	return jsonCodec{}
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) frameType() int {
+	return websocket.TextMessage
+}
+
+func (jsonCodec) encode(w io.Writer, msg *wsdto.WSMessage, first bool) error {
+	data, err := msg.GetBytes()
+	if err != nil {
+		return fmt.Errorf("get bytes: %w", err)
+	}
+
+	if !first {
+		if _, err := w.Write(newline); err != nil {
+			return err
+		}
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func (jsonCodec) decode(data []byte) (*wsdto.WSMessage, error) {
+	var msg wsdto.WSMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+type msgpackFrame struct {
+	ID   string          `msgpack:"id,omitempty"`
+	Type wsdto.Operation `msgpack:"type"`
+	Data any             `msgpack:"data"`
+	Ts   time.Time       `msgpack:"ts,omitempty"`
+	V    int             `msgpack:"v,omitempty"`
+}
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) frameType() int {
+	return websocket.BinaryMessage
+}
+
+// encode turns the json payload into native msgpack values, so the client does not parse json twice.
+func (msgpackCodec) encode(w io.Writer, msg *wsdto.WSMessage, first bool) error {
+	var data any
+	if len(msg.Data) != 0 {
+		dec := json.NewDecoder(bytes.NewReader(msg.Data))
+		dec.UseNumber()
+		if err := dec.Decode(&data); err != nil {
+			return fmt.Errorf("decode data: %w", err)
+		}
+	}
+
+	return msgpack.NewEncoder(w).Encode(&msgpackFrame{
+		ID:   msg.ID,
+		Type: msg.Type,
+		Data: fromJSONNumbers(data),
+		Ts:   msg.Ts,
+		V:    msg.V,
+	})
+}
+
+func (msgpackCodec) decode(data []byte) (*wsdto.WSMessage, error) {
+	var frame msgpackFrame
+	if err := msgpack.Unmarshal(data, &frame); err != nil {
+		return nil, err
+	}
+
+	raw, err := json.Marshal(frame.Data)
+	if err != nil {
+		return nil, fmt.Errorf("marshal data: %w", err)
+	}
+
+	return &wsdto.WSMessage{
+		ID:   frame.ID,
+		Type: frame.Type,
+		Data: raw,
+	}, nil
+}
+
+// fromJSONNumbers keeps integers as integers, plain json decoding would make every id a float.
+func fromJSONNumbers(v any) any {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]any:
+		for k, val := range v {
+			v[k] = fromJSONNumbers(val)
+		}
+		return v
+	case []any:
+		for i, val := range v {
+			v[i] = fromJSONNumbers(val)
+		}
+		return v
+	default:
+		return v
+	}
+}
@@ -0,0 +1,150 @@
+package transport
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	wsdto "github.com/1ocknight/mess/shared/dto/ws"
+	"github.com/gorilla/websocket"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func testMessage() *wsdto.WSMessage {
+	return &wsdto.WSMessage{
+		ID:   "42",
+		Type: wsdto.SendMessage,
+		Data: json.RawMessage(`{"chat_id":9007199254740993,"content":"hi","rate":1.5,"tags":[1,"a"]}`),
+		Ts:   time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		V:    wsdto.ProtocolVersion,
+	}
+}
+
+func assertSameMessage(t *testing.T, want *wsdto.WSMessage, have *wsdto.WSMessage) {
+	t.Helper()
+
+	if have.ID != want.ID || have.Type != want.Type {
+		t.Fatalf("wait %v %v, have %v %v", want.ID, want.Type, have.ID, have.Type)
+	}
+
+	var wantData, haveData any
+	if err := json.Unmarshal(want.Data, &wantData); err != nil {
+		t.Fatalf("unmarshal want data: %v", err)
+	}
+	if err := json.Unmarshal(have.Data, &haveData); err != nil {
+		t.Fatalf("unmarshal have data: %v", err)
+	}
+	if !reflect.DeepEqual(wantData, haveData) {
+		t.Fatalf("wait data %s, have %s", want.Data, have.Data)
+	}
+}
+
+func TestCodec_JSONRoundTrip(t *testing.T) {
+	c := newCodec(JSONSubprotocol)
+	if c.frameType() != websocket.TextMessage {
+		t.Fatalf("wait text frame, have %v", c.frameType())
+	}
+
+	msg := testMessage()
+	var buf bytes.Buffer
+	if err := c.encode(&buf, msg, true); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	if err := c.encode(&buf, msg, false); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+
+	frames := bytes.Split(buf.Bytes(), newline)
+	if len(frames) != 2 {
+		t.Fatalf("wait 2 newline separated messages, have %v", len(frames))
+	}
+	for _, frame := range frames {
+		have, err := c.decode(frame)
+		if err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		assertSameMessage(t, msg, have)
+	}
+}
+
+func TestCodec_MsgpackRoundTrip(t *testing.T) {
+	c := newCodec(MsgpackSubprotocol)
+	if c.frameType() != websocket.BinaryMessage {
+		t.Fatalf("wait binary frame, have %v", c.frameType())
+	}
+
+	msg := testMessage()
+	var buf bytes.Buffer
+	if err := c.encode(&buf, msg, true); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+
+	have, err := c.decode(buf.Bytes())
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	assertSameMessage(t, msg, have)
+
+	// ids above 2^53 survive only as native integers
+	var frame struct {
+		Data map[string]any `msgpack:"data"`
+	}
+	if err := msgpack.Unmarshal(buf.Bytes(), &frame); err != nil {
+		t.Fatalf("unmarshal frame: %v", err)
+	}
+	if id, ok := frame.Data["chat_id"].(int64); !ok || id != 9007199254740993 {
+		t.Fatalf("wait integer chat_id, have %T %v", frame.Data["chat_id"], frame.Data["chat_id"])
+	}
+}
+
+func TestCodec_MsgpackSeveralMessages(t *testing.T) {
+	c := newCodec(MsgpackSubprotocol)
+
+	msg := testMessage()
+	var buf bytes.Buffer
+	for i := 0; i < 2; i++ {
+		if err := c.encode(&buf, msg, i == 0); err != nil {
+			t.Fatalf("encode: %v", err)
+		}
+	}
+
+	dec := msgpack.NewDecoder(&buf)
+	for i := 0; i < 2; i++ {
+		var frame msgpackFrame
+		if err := dec.Decode(&frame); err != nil {
+			t.Fatalf("decode message %v: %v", i, err)
+		}
+		if frame.ID != msg.ID {
+			t.Fatalf("wait id %v, have %v", msg.ID, frame.ID)
+		}
+	}
+}
+
+func TestFromJSONNumbers(t *testing.T) {
+	tests := []struct {
+		name string
+		in   any
+		want any
+	}{
+		{"int", json.Number("42"), int64(42)},
+		{"negative int", json.Number("-7"), int64(-7)},
+		{"float", json.Number("1.5"), 1.5},
+		{"string", "42", "42"},
+		{"nil", nil, nil},
+		{
+			"nested",
+			map[string]any{"a": json.Number("1"), "b": []any{json.Number("2.5"), map[string]any{"c": json.Number("3")}}},
+			map[string]any{"a": int64(1), "b": []any{2.5, map[string]any{"c": int64(3)}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if have := fromJSONNumbers(tt.in); !reflect.DeepEqual(have, tt.want) {
+				t.Fatalf("wait %#v, have %#v", tt.want, have)
+			}
+		})
+	}
+}
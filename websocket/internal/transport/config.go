@@ -1,6 +1,11 @@
 package transport
 
-import "time"
+import (
+	"compress/flate"
+	"fmt"
+	"net/netip"
+	"time"
+)
 
 type ClientConfig struct {
 	MessageBuffer int           `yaml:"message_buffer"`
@@ -13,9 +18,58 @@ type WSHandlerConfig struct {
 	ReadBufferSizeBytes  int          `yaml:"read_buffer_size_bytes"`
 	WriteBufferSizeBytes int          `yaml:"write_buffer_size_bytes"`
 	ClientConfig         ClientConfig `yaml:"client"`
+	SSE                  SSEConfig    `yaml:"sse"`
+
+	// permessage-deflate is used only when the client offers it
+	EnableCompression bool `yaml:"enable_compression"`
+	CompressionLevel  int  `yaml:"compression_level"`
+
+	// X-Real-IP and X-Forwarded-For are taken only from these addresses or CIDRs, empty means never
+	TrustedProxies []string `yaml:"trusted_proxies"`
 }
 
 type HTTPConfig struct {
 	Host string `yaml:"host"`
 	Port string `yaml:"port"`
 }
+
+func (cfg WSHandlerConfig) Validate() error {
+	if cfg.CompressionLevel != 0 && (cfg.CompressionLevel < flate.HuffmanOnly || cfg.CompressionLevel > flate.BestCompression) {
+		return fmt.Errorf("invalid compression level: %v", cfg.CompressionLevel)
+	}
+	if cfg.ClientConfig.PingPeriod <= 0 {
+		return fmt.Errorf("client ping period must be positive")
+	}
+	if cfg.SSE.HeartbeatPeriod <= 0 {
+		return fmt.Errorf("sse heartbeat period must be positive")
+	}
+	if cfg.SSE.ResumeTTL < 0 {
+		return fmt.Errorf("sse resume ttl must not be negative")
+	}
+	if cfg.SSE.ResumeTTL > 0 && cfg.SSE.ResumeTTL <= cfg.SSE.Retry {
+		return fmt.Errorf("sse resume ttl must be longer than retry, EventSource reconnects after retry")
+	}
+	if _, err := parseTrustedProxies(cfg.TrustedProxies); err != nil {
+		return fmt.Errorf("trusted proxies: %w", err)
+	}
+	return nil
+}
+
+// parseTrustedProxies accepts both a single address and a CIDR.
+func parseTrustedProxies(proxies []string) ([]netip.Prefix, error) {
+	res := make([]netip.Prefix, 0, len(proxies))
+	for _, p := range proxies {
+		if addr, err := netip.ParseAddr(p); err == nil {
+			addr = addr.Unmap()
+			res = append(res, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(p)
+		if err != nil {
+			return nil, fmt.Errorf("parse %v: %w", p, err)
+		}
+		res = append(res, prefix.Masked())
+	}
+	return res, nil
+}
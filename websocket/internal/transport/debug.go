@@ -0,0 +1,36 @@
+package transport
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"net/http"
+)
+
+// DebugServer serves expvar counters on an internal port, it must not be public.
+type DebugServer struct {
+	httpServer *http.Server
+}
+
+func NewDebugServer(cfg HTTPConfig) *DebugServer {
+	mux := http.NewServeMux()
+	mux.Handle("/debug/vars", expvar.Handler())
+
+	return &DebugServer{
+		httpServer: &http.Server{
+			Addr:    fmt.Sprintf("%v:%v", cfg.Host, cfg.Port),
+			Handler: mux,
+		},
+	}
+}
+
+func (s *DebugServer) Run() error {
+	if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+func (s *DebugServer) Stop(ctx context.Context) error {
+	return s.httpServer.Shutdown(ctx)
+}
@@ -0,0 +1,123 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	wsdto "github.com/1ocknight/mess/shared/dto/ws"
+	"github.com/1ocknight/mess/websocket/internal/loglables"
+	"github.com/gorilla/websocket"
+)
+
+type DrainConfig struct {
+	// Timeout bounds the whole drain, connections that did not flush by then are dropped with the process
+	Timeout time.Duration `yaml:"timeout"`
+	// clients are told to reconnect after a random delay up to ReconnectMaxDelay
+	ReconnectMaxDelay time.Duration `yaml:"reconnect_max_delay"`
+}
+
+func (cfg DrainConfig) Validate() error {
+	if cfg.Timeout <= 0 {
+		return fmt.Errorf("drain timeout must be positive")
+	}
+	if cfg.ReconnectMaxDelay < 0 {
+		return fmt.Errorf("reconnect max delay must not be negative")
+	}
+	return nil
+}
+
+var (
+	serviceRestartMessage = websocket.FormatCloseMessage(websocket.CloseServiceRestart, "service restart")
+)
+
+type drainRequest struct {
+	maxDelay time.Duration
+	res      chan int
+}
+
+// Drain asks every client to reconnect later and closes it with 1012, then waits until write pumps
+// flush what is left in Send. Clients registered after Drain are closed right after hello.
+func (h *Hub) Drain(ctx context.Context, maxDelay time.Duration) error {
+	total := 0
+	for _, s := range h.shards {
+		req := &drainRequest{maxDelay: maxDelay, res: make(chan int, 1)}
+		select {
+		case s.drain <- req:
+		case <-ctx.Done():
+			return fmt.Errorf("drain shards: %w", ctx.Err())
+		}
+		total += <-req.res
+	}
+	h.lg.With("clients", total).Info("drain")
+
+	done := make(chan struct{})
+	go func() {
+		h.conns.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("wait connections: %w", ctx.Err())
+	}
+}
+
+func (s *shard) drainAll(maxDelay time.Duration) int {
+	s.draining = true
+	s.drainDelay = maxDelay
+
+	n := 0
+	for _, clients := range s.clients {
+		for c := range clients {
+			s.drainClient(c)
+			n++
+		}
+	}
+
+	return n
+}
+
+// drainClient puts the reconnect frame behind everything queued, a closed Send is read to the end
+// before writePump writes the close message.
+func (s *shard) drainClient(c *Client) {
+	var delay int64
+	if s.drainDelay > 0 {
+		delay = rand.Int64N(s.drainDelay.Milliseconds() + 1)
+	}
+
+	msg := &wsdto.WSMessage{
+		Type: wsdto.Reconnect,
+		Ts:   time.Now().UTC(),
+		V:    wsdto.ProtocolVersion,
+	}
+	data, err := (&wsdto.ServerReconnect{DelayMs: delay}).GetData()
+	if err != nil {
+		s.lg.Error(fmt.Errorf("get data: %w", err))
+	} else {
+		msg.Data = data
+		s.enqueue(c, msg)
+	}
+
+	if s.remove(c, serviceRestartMessage) {
+		s.lg.With(loglables.Subject, c.SubjectID).Debug("drain client")
+	}
+}
+
+// Drain makes the handler refuse new tickets and connections, the load balancer sends them to other replicas.
+func (h *Handler) Drain() {
+	h.draining.Store(true)
+}
+
+func (h *Handler) rejectDraining(w http.ResponseWriter) bool {
+	if !h.draining.Load() {
+		return false
+	}
+
+	http.Error(w, "server is draining", http.StatusServiceUnavailable)
+	return true
+}
@@ -0,0 +1,109 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+
+	wsdto "github.com/1ocknight/mess/shared/dto/ws"
+	"github.com/1ocknight/mess/shared/logger"
+	"github.com/1ocknight/mess/websocket/internal/model"
+	"github.com/gorilla/websocket"
+)
+
+func TestDrainConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name  string
+		cfg   DrainConfig
+		valid bool
+	}{
+		{name: "valid", cfg: DrainConfig{Timeout: 20 * time.Second, ReconnectMaxDelay: 5 * time.Second}, valid: true},
+		{name: "no reconnect delay", cfg: DrainConfig{Timeout: 20 * time.Second}, valid: true},
+		{name: "zero timeout", cfg: DrainConfig{ReconnectMaxDelay: 5 * time.Second}},
+		{name: "negative timeout", cfg: DrainConfig{Timeout: -time.Second}},
+		{name: "negative reconnect delay", cfg: DrainConfig{Timeout: time.Second, ReconnectMaxDelay: -time.Second}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if tt.valid && err != nil {
+				t.Fatalf("validate: %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Fatalf("config must be rejected")
+			}
+		})
+	}
+}
+
+func TestHub_Drain(t *testing.T) {
+	lg := logger.New(slog.NewJSONHandler(io.Discard, nil))
+	hub := NewHub(make(chan *model.Message), HubConfig{
+		Shards:       2,
+		ShardBuffer:  16,
+		Backpressure: BackpressureConfig{Policy: DropOldestPolicy},
+	}, lg)
+	go hub.Run(t.Context())
+
+	maxDelay := 100 * time.Millisecond
+	clients := []*Client{
+		{SubjectID: "subj-1", Send: make(chan *wsdto.WSMessage, 4), hub: hub},
+		{SubjectID: "subj-2", Send: make(chan *wsdto.WSMessage, 4), hub: hub},
+	}
+
+	// the pumps stand for writePump, Drain returns only after they read Send to the end
+	frames := make([][]*wsdto.WSMessage, len(clients))
+	for i, c := range clients {
+		hub.Register(c)
+
+		hub.conns.Add(1)
+		go func() {
+			defer hub.conns.Done()
+			for msg := range c.Send {
+				frames[i] = append(frames[i], msg)
+			}
+		}()
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), time.Second)
+	defer cancel()
+	if err := hub.Drain(ctx, maxDelay); err != nil {
+		t.Fatalf("drain: %v", err)
+	}
+
+	for i, c := range clients {
+		if len(frames[i]) != 1 || frames[i][0].Type != wsdto.Reconnect {
+			t.Fatalf("client %v: wait only reconnect, have %+v", i, frames[i])
+		}
+		reconnect := frames[i][0]
+		if reconnect.V != wsdto.ProtocolVersion || reconnect.Ts.IsZero() {
+			t.Fatalf("client %v: reconnect frame without envelope: %+v", i, reconnect)
+		}
+		var data wsdto.ServerReconnect
+		if err := json.Unmarshal(reconnect.Data, &data); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		if data.DelayMs < 0 || data.DelayMs > maxDelay.Milliseconds() {
+			t.Fatalf("client %v: delay %v is out of [0, %v]", i, data.DelayMs, maxDelay.Milliseconds())
+		}
+		if !slices.Equal(c.closeMessage, serviceRestartMessage) {
+			t.Fatalf("client %v: wait close message %q, have %q", i, serviceRestartMessage, c.closeMessage)
+		}
+	}
+	if code := int(serviceRestartMessage[0])<<8 | int(serviceRestartMessage[1]); code != websocket.CloseServiceRestart {
+		t.Fatalf("wait close code %v, have %v", websocket.CloseServiceRestart, code)
+	}
+
+	// a client that connects during the drain is sent away at once
+	late := &Client{SubjectID: "subj-1", Send: make(chan *wsdto.WSMessage, 4), hub: hub}
+	hub.Register(late)
+	waitClosed(t, late)
+	if !slices.Equal(late.closeMessage, serviceRestartMessage) {
+		t.Fatalf("late client: wait close message %q, have %q", serviceRestartMessage, late.closeMessage)
+	}
+}
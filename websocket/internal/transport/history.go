@@ -0,0 +1,82 @@
+package transport
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	wsdto "github.com/1ocknight/mess/shared/dto/ws"
+	"github.com/1ocknight/mess/websocket/internal/model"
+)
+
+const (
+	LastEventIDHeader = "Last-Event-ID"
+	LastEventIDQuery  = "last_event_id"
+)
+
+type historyEvent struct {
+	id  int64
+	at  time.Time
+	msg *wsdto.WSMessage
+}
+
+// remember keeps the last HistorySize events of the subject, also when it has no connections.
+func (s *shard) remember(message *model.Message) {
+	if s.historyCfg.HistorySize <= 0 {
+		return
+	}
+
+	id, err := strconv.ParseInt(message.WSMessage.ID, 10, 64)
+	if err != nil {
+		return
+	}
+
+	events := append(s.history[message.SubjectID], &historyEvent{
+		id:  id,
+		at:  time.Now(),
+		msg: message.WSMessage,
+	})
+	if len(events) > s.historyCfg.HistorySize {
+		events = events[len(events)-s.historyCfg.HistorySize:]
+	}
+	s.history[message.SubjectID] = events
+}
+
+// replay sends the events the client missed after its Last-Event-ID, it runs right after register,
+// so replayed events always come before new ones.
+func (s *shard) replay(c *Client) {
+	for _, e := range s.history[c.SubjectID] {
+		if e.id <= c.lastEventID || !c.wants(e.msg.Type) {
+			continue
+		}
+		s.enqueue(c, e.msg)
+	}
+}
+
+func (s *shard) forget(now time.Time) {
+	for subj, events := range s.history {
+		i := 0
+		for i < len(events) && now.Sub(events[i].at) > s.historyCfg.HistoryTTL {
+			i++
+		}
+		if i == len(events) {
+			delete(s.history, subj)
+			continue
+		}
+		s.history[subj] = events[i:]
+	}
+}
+
+// getLastEventID reads the header set by EventSource on reconnect, or the query for clients that reconnect by hand.
+func getLastEventID(r *http.Request) int64 {
+	v := r.Header.Get(LastEventIDHeader)
+	if v == "" {
+		v = r.URL.Query().Get(LastEventIDQuery)
+	}
+
+	id, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return id
+}
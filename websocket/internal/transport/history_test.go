@@ -0,0 +1,131 @@
+package transport
+
+import (
+	"io"
+	"log/slog"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	wsdto "github.com/1ocknight/mess/shared/dto/ws"
+	"github.com/1ocknight/mess/shared/logger"
+	"github.com/1ocknight/mess/websocket/internal/model"
+)
+
+func newTestShard(historySize int, historyTTL time.Duration) *shard {
+	lg := logger.New(slog.NewJSONHandler(io.Discard, nil))
+	hub := NewHub(make(chan *model.Message), HubConfig{
+		Shards:       1,
+		Backpressure: BackpressureConfig{Policy: DropOldestPolicy},
+		HistorySize:  historySize,
+		HistoryTTL:   historyTTL,
+	}, lg)
+	return hub.shards[0]
+}
+
+func historyMessage(subjectID string, id int) *model.Message {
+	return &model.Message{
+		SubjectID: subjectID,
+		WSMessage: &wsdto.WSMessage{ID: strconv.Itoa(id), Type: wsdto.SendMessage, Data: []byte(`{}`)},
+	}
+}
+
+func historyIDs(s *shard, subjectID string) []int64 {
+	ids := make([]int64, 0, len(s.history[subjectID]))
+	for _, e := range s.history[subjectID] {
+		ids = append(ids, e.id)
+	}
+	return ids
+}
+
+func TestShard_RememberKeepsLastEvents(t *testing.T) {
+	s := newTestShard(2, time.Minute)
+
+	for id := 1; id <= 3; id++ {
+		s.remember(historyMessage("subj", id))
+	}
+
+	if ids := historyIDs(s, "subj"); len(ids) != 2 || ids[0] != 2 || ids[1] != 3 {
+		t.Fatalf("wait [2 3], have %v", ids)
+	}
+}
+
+func TestShard_RememberDisabled(t *testing.T) {
+	s := newTestShard(0, 0)
+
+	s.remember(historyMessage("subj", 1))
+
+	if len(s.history) != 0 {
+		t.Fatalf("wait no history, have %v", len(s.history))
+	}
+}
+
+func TestShard_ForgetExpiredEvents(t *testing.T) {
+	s := newTestShard(10, time.Minute)
+
+	s.remember(historyMessage("old", 1))
+	s.remember(historyMessage("mixed", 2))
+	now := time.Now()
+	s.history["old"][0].at = now.Add(-2 * time.Minute)
+	s.history["mixed"][0].at = now.Add(-2 * time.Minute)
+	s.remember(historyMessage("mixed", 3))
+
+	s.forget(now)
+
+	if _, ok := s.history["old"]; ok {
+		t.Fatalf("subject without fresh events must be deleted")
+	}
+	if ids := historyIDs(s, "mixed"); len(ids) != 1 || ids[0] != 3 {
+		t.Fatalf("wait [3], have %v", ids)
+	}
+}
+
+func TestHubConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     HubConfig
+		wantErr bool
+	}{
+		{"history disabled", HubConfig{Backpressure: BackpressureConfig{Policy: DropOldestPolicy}}, false},
+		{"history with ttl", HubConfig{Backpressure: BackpressureConfig{Policy: DropOldestPolicy}, HistorySize: 10, HistoryTTL: time.Minute}, false},
+		{"history without ttl", HubConfig{Backpressure: BackpressureConfig{Policy: DropOldestPolicy}, HistorySize: 10}, true},
+		{"unknown policy", HubConfig{Backpressure: BackpressureConfig{Policy: "unknown"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Fatalf("wait error %v, have %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestShard_ReplayRespectsSubscription(t *testing.T) {
+	s := newTestShard(10, time.Minute)
+
+	ops := []wsdto.Operation{wsdto.SendMessage, wsdto.ProfileUpdated, wsdto.SendMessage, wsdto.ProfileUpdated}
+	for i, op := range ops {
+		msg := historyMessage("subj", i+1)
+		msg.WSMessage.Type = op
+		s.remember(msg)
+	}
+
+	old := &Client{SubjectID: "subj", Send: make(chan *wsdto.WSMessage, 16), lastEventID: 1}
+	subscribed := &Client{SubjectID: "subj", Send: make(chan *wsdto.WSMessage, 16), lastEventID: 1}
+	if _, err := subscribed.subscribe(&wsdto.SubscribeCommand{Events: []wsdto.Operation{wsdto.ProfileUpdated}}); err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	s.clients["subj"] = map[*Client]struct{}{old: {}, subscribed: {}}
+
+	s.replay(old)
+	s.replay(subscribed)
+
+	if ids := queuedIDs(old); !slices.Equal(ids, []string{"2", "3", "4"}) {
+		t.Fatalf("wait [2 3 4], have %v", ids)
+	}
+	if ids := queuedIDs(subscribed); !slices.Equal(ids, []string{"2", "4"}) {
+		t.Fatalf("wait [2 4], have %v", ids)
+	}
+}
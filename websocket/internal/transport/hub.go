@@ -1,73 +1,313 @@
 package transport
 
 import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"runtime"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	wsdto "github.com/1ocknight/mess/shared/dto/ws"
 	"github.com/1ocknight/mess/shared/logger"
 	"github.com/1ocknight/mess/websocket/internal/loglables"
 	"github.com/1ocknight/mess/websocket/internal/model"
+	"github.com/1ocknight/mess/websocket/internal/session"
 )
 
+type HubConfig struct {
+	// Shards is the number of independent loops, 0 means GOMAXPROCS.
+	Shards       int                `yaml:"shards"`
+	ShardBuffer  int                `yaml:"shard_buffer"`
+	Backpressure BackpressureConfig `yaml:"backpressure"`
+
+	// last events of every subject are kept for resume by Last-Event-ID, 0 disables it.
+	// History lives in the memory of the replica, a client that reconnects to another replica
+	// gets only new events and refetches the rest over http
+	HistorySize int           `yaml:"history_size"`
+	HistoryTTL  time.Duration `yaml:"history_ttl"`
+}
+
+func (cfg HubConfig) Validate() error {
+	if err := cfg.Backpressure.Validate(); err != nil {
+		return fmt.Errorf("backpressure: %w", err)
+	}
+	if cfg.HistorySize > 0 && cfg.HistoryTTL <= 0 {
+		return fmt.Errorf("history ttl must be positive when history is enabled")
+	}
+	return nil
+}
+
+type clientMessage struct {
+	client *Client
+	msg    *wsdto.WSMessage
+}
+
+type sessionsRequest struct {
+	subjectID string
+	res       chan []model.Session
+}
+
+type terminateRequest struct {
+	subjectID string
+	sessionID string
+	reason    session.Reason
+	res       chan bool
+}
+
+// Hub routes every subject to one shard by hash of SubjectID, so all devices of a subject live in the same shard.
 type Hub struct {
-	lg logger.Logger
+	lg     logger.Logger
+	shards []*shard
 
-	clients    map[string]map[*Client]struct{}
-	register   chan *Client
-	unregister chan *Client
+	// event ids grow monotonically, seeded with the start time so they keep growing after a restart
+	lastEventID int64
 
 	messageChan chan *model.Message
+
+	// conns counts pumps still writing to their connections, Drain waits for them
+	conns sync.WaitGroup
+
+	// closed when Run stops, the shards are gone and nothing reads their channels anymore
+	done chan struct{}
 }
 
-func NewHub(messageChan chan *model.Message, lg logger.Logger) *Hub {
-	return &Hub{
-		lg: lg,
+type shard struct {
+	lg         logger.Logger
+	cfg        BackpressureConfig
+	historyCfg HubConfig
+
+	history map[string][]*historyEvent
+
+	draining   bool
+	drainDelay time.Duration
+
+	clients map[string]map[*Client]struct{}
+	// register and unregister are unbuffered: Register returns only after the shard took the client,
+	// so the Unregister of the same client can not be picked by select before its Register
+	register   chan *Client
+	unregister chan *Client
+	reply      chan *clientMessage
+	messages   chan *model.Message
+	sessions   chan *sessionsRequest
+	terminate  chan *terminateRequest
+	drain      chan *drainRequest
+}
+
+func NewHub(messageChan chan *model.Message, cfg HubConfig, lg logger.Logger) *Hub {
+	n := cfg.Shards
+	if n <= 0 {
+		n = runtime.GOMAXPROCS(0)
+	}
+
+	shards := make([]*shard, 0, n)
+	for i := 0; i < n; i++ {
+		shards = append(shards, &shard{
+			lg:         lg.With(loglables.Shard, i),
+			cfg:        cfg.Backpressure,
+			historyCfg: cfg,
 
-		clients:    make(map[string]map[*Client]struct{}),
-		register:   make(chan *Client),
-		unregister: make(chan *Client),
+			history: make(map[string][]*historyEvent),
+
+			clients:    make(map[string]map[*Client]struct{}),
+			register:   make(chan *Client),
+			unregister: make(chan *Client),
+			reply:      make(chan *clientMessage, cfg.ShardBuffer),
+			messages:   make(chan *model.Message, cfg.ShardBuffer),
+			sessions:   make(chan *sessionsRequest),
+			terminate:  make(chan *terminateRequest),
+			drain:      make(chan *drainRequest),
+		})
+	}
+
+	return &Hub{
+		lg:          lg,
+		shards:      shards,
+		lastEventID: time.Now().UnixMicro(),
 
 		messageChan: messageChan,
+
+		done: make(chan struct{}),
 	}
 }
 
-func (h *Hub) Run() {
+// Run stops the hub and all shards with ctx, which is cancelled only after Drain.
+func (h *Hub) Run(ctx context.Context) {
+	defer close(h.done)
+
+	for _, s := range h.shards {
+		go s.run(ctx)
+	}
+
 	for {
 		select {
+		case <-ctx.Done():
+			h.lg.Info("context done - stop")
+			return
+		case message := <-h.messageChan:
+			h.lastEventID++
+			message.WSMessage.ID = strconv.FormatInt(h.lastEventID, 10)
+			message.WSMessage.Ts = time.Now().UTC()
+			message.WSMessage.V = wsdto.ProtocolVersion
 
-		case client := <-h.register:
-			if _, ok := h.clients[client.SubjectID]; !ok {
-				h.clients[client.SubjectID] = make(map[*Client]struct{})
+			select {
+			case h.shard(message.SubjectID).messages <- message:
+			case <-ctx.Done():
+				return
 			}
-			h.clients[client.SubjectID][client] = struct{}{}
-			h.lg.With(loglables.Subject, client.SubjectID).Info("register")
-
-		case client := <-h.unregister:
-			if clients, ok := h.clients[client.SubjectID]; ok {
-				if _, exists := clients[client]; exists {
-					delete(clients, client)
-					close(client.Send)
-					h.lg.With(loglables.Subject, client.SubjectID).Info("unregister")
-				}
-				if len(clients) == 0 {
-					delete(h.clients, client.SubjectID)
-				}
+		}
+	}
+}
+
+// Register, Unregister and the other calls give up once the hub is stopped, so the handlers
+// still running at shutdown do not block on the shards forever.
+func (h *Hub) Register(c *Client) {
+	select {
+	case h.shard(c.SubjectID).register <- c:
+	case <-h.done:
+	}
+}
+
+func (h *Hub) Unregister(c *Client) {
+	select {
+	case h.shard(c.SubjectID).unregister <- c:
+	case <-h.done:
+	}
+}
+
+func (h *Hub) Reply(c *Client, msg *wsdto.WSMessage) {
+	select {
+	case h.shard(c.SubjectID).reply <- &clientMessage{client: c, msg: msg}:
+	case <-h.done:
+	}
+}
+
+func (h *Hub) Sessions(subjectID string) []model.Session {
+	req := &sessionsRequest{subjectID: subjectID, res: make(chan []model.Session, 1)}
+	select {
+	case h.shard(subjectID).sessions <- req:
+		return <-req.res
+	case <-h.done:
+		return nil
+	}
+}
+
+// Terminate closes the session of the subject, false means there is no such session on this replica.
+func (h *Hub) Terminate(subjectID string, sessionID string, reason session.Reason) bool {
+	req := &terminateRequest{subjectID: subjectID, sessionID: sessionID, reason: reason, res: make(chan bool, 1)}
+	select {
+	case h.shard(subjectID).terminate <- req:
+		return <-req.res
+	case <-h.done:
+		return false
+	}
+}
+
+func (h *Hub) shard(subjectID string) *shard {
+	f := fnv.New32a()
+	f.Write([]byte(subjectID))
+	return h.shards[f.Sum32()%uint32(len(h.shards))]
+}
+
+func (s *shard) run(ctx context.Context) {
+	var cleanup <-chan time.Time
+	if s.historyCfg.HistorySize > 0 {
+		ticker := time.NewTicker(s.historyCfg.HistoryTTL)
+		defer ticker.Stop()
+		cleanup = ticker.C
+	}
+
+	for {
+		select {
+
+		case <-ctx.Done():
+			return
+
+		case client := <-s.register:
+			if _, ok := s.clients[client.SubjectID]; !ok {
+				s.clients[client.SubjectID] = make(map[*Client]struct{})
 			}
+			s.clients[client.SubjectID][client] = struct{}{}
+			s.lg.With(loglables.Subject, client.SubjectID).Info("register")
 
-		case message := <-h.messageChan:
-			clients, ok := h.clients[message.SubjectID]
-			if !ok {
+			if client.lastEventID != 0 {
+				s.replay(client)
+			}
+			if s.draining {
+				s.drainClient(client)
+			}
+
+		case client := <-s.unregister:
+			if s.remove(client, nil) {
+				s.lg.With(loglables.Subject, client.SubjectID).Info("unregister")
+			}
+
+		case reply := <-s.reply:
+			c := reply.client
+			if _, ok := s.clients[c.SubjectID][c]; !ok {
 				continue
 			}
-			for c := range clients {
-				select {
-				case c.Send <- message.WSMessage:
-					h.lg.With(loglables.Subject, c.SubjectID).Info("send message")
-				default:
-					delete(clients, c)
-					close(c.Send)
+			s.enqueue(c, reply.msg)
+
+		case message := <-s.messages:
+			s.remember(message)
+			for c := range s.clients[message.SubjectID] {
+				if !c.wants(message.WSMessage.Type) {
+					continue
 				}
+				s.enqueue(c, message.WSMessage)
+			}
+
+		case req := <-s.sessions:
+			sessions := make([]model.Session, 0, len(s.clients[req.subjectID]))
+			for c := range s.clients[req.subjectID] {
+				sessions = append(sessions, c.Session)
 			}
-			if len(clients) == 0 {
-				delete(h.clients, message.SubjectID)
+			slices.SortFunc(sessions, func(a, b model.Session) int {
+				return a.ConnectedAt.Compare(b.ConnectedAt)
+			})
+			req.res <- sessions
+
+		case req := <-s.terminate:
+			terminated := false
+			for c := range s.clients[req.subjectID] {
+				if c.Session.ID == req.sessionID {
+					terminated = s.remove(c, terminateMessage(req.reason))
+					s.lg.With(loglables.Subject, c.SubjectID).With(loglables.Reason, req.reason).Info("terminate session")
+					break
+				}
 			}
+			req.res <- terminated
+
+		case req := <-s.drain:
+			req.res <- s.drainAll(req.maxDelay)
+
+		case now := <-cleanup:
+			s.forget(now)
 		}
 	}
 }
+
+// remove is the only place where Send is closed. writePump answers a closed Send with closeMessage
+// and closes the connection, readPump then unregisters a client that is already removed, which is a no-op.
+func (s *shard) remove(c *Client, closeMessage []byte) bool {
+	clients, ok := s.clients[c.SubjectID]
+	if !ok {
+		return false
+	}
+	if _, exists := clients[c]; !exists {
+		return false
+	}
+
+	delete(clients, c)
+	if len(clients) == 0 {
+		delete(s.clients, c.SubjectID)
+	}
+
+	c.closeMessage = closeMessage
+	close(c.Send)
+
+	return true
+}
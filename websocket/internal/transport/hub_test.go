@@ -0,0 +1,244 @@
+package transport
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	wsdto "github.com/1ocknight/mess/shared/dto/ws"
+	"github.com/1ocknight/mess/shared/logger"
+	"github.com/1ocknight/mess/websocket/internal/model"
+	"github.com/1ocknight/mess/websocket/internal/session"
+)
+
+const (
+	benchClients = 50_000
+	benchDevices = 2
+)
+
+func BenchmarkHub(b *testing.B) {
+	for _, shards := range []int{1, 8, 32} {
+		b.Run(fmt.Sprintf("shards=%d", shards), func(b *testing.B) {
+			benchmarkHub(b, shards)
+		})
+	}
+}
+
+// benchmarkHub delivers b.N events spread over all subjects, every event goes to each device of the subject.
+func benchmarkHub(b *testing.B, shards int) {
+	lg := logger.New(slog.NewJSONHandler(io.Discard, nil))
+	msgs := make(chan *model.Message, 1024)
+	hub := NewHub(msgs, HubConfig{
+		Shards:       shards,
+		ShardBuffer:  1024,
+		Backpressure: BackpressureConfig{Policy: DropOldestPolicy},
+	}, lg)
+	go hub.Run(b.Context())
+
+	var delivered atomic.Int64
+	subjects := make([]string, 0, benchClients/benchDevices)
+	clients := make([]*Client, 0, benchClients)
+	for i := 0; i < benchClients/benchDevices; i++ {
+		subj := "subj-" + strconv.Itoa(i)
+		subjects = append(subjects, subj)
+		for d := 0; d < benchDevices; d++ {
+			c := &Client{SubjectID: subj, Send: make(chan *wsdto.WSMessage, 256), hub: hub}
+			clients = append(clients, c)
+			hub.Register(c)
+			go func() {
+				for range c.Send {
+					delivered.Add(1)
+				}
+			}()
+		}
+	}
+	waitRegistered(b, hub, clients, &delivered)
+
+	events := make([]*model.Message, 0, len(subjects))
+	for _, subj := range subjects {
+		events = append(events, &model.Message{
+			SubjectID: subj,
+			WSMessage: &wsdto.WSMessage{Type: wsdto.SendMessage, Data: []byte(`{}`)},
+		})
+	}
+
+	start := delivered.Load()
+	startDropped := droppedTotal()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		msgs <- events[i%len(events)]
+	}
+
+	want := int64(b.N * benchDevices)
+	for delivered.Load()-start+droppedTotal()-startDropped < want {
+		time.Sleep(time.Millisecond)
+	}
+	b.StopTimer()
+
+	b.ReportMetric(float64(want)/b.Elapsed().Seconds(), "deliveries/s")
+	b.ReportMetric(float64(droppedTotal()-startDropped), "dropped")
+}
+
+// waitRegistered returns when every shard went through a reply after the registrations, it is only delivered to registered clients.
+func waitRegistered(b *testing.B, hub *Hub, clients []*Client, delivered *atomic.Int64) {
+	b.Helper()
+
+	seen := make(map[*shard]struct{})
+	for _, c := range clients {
+		s := hub.shard(c.SubjectID)
+		if _, ok := seen[s]; ok {
+			continue
+		}
+		seen[s] = struct{}{}
+
+		before := delivered.Load()
+		hub.Reply(c, &wsdto.WSMessage{Type: wsdto.Ack})
+		for delivered.Load() == before {
+			time.Sleep(time.Millisecond)
+		}
+	}
+}
+
+func droppedTotal() int64 {
+	var total int64
+	DroppedEvents.Do(func(kv expvar.KeyValue) {
+		total += kv.Value.(*expvar.Int).Value()
+	})
+	return total
+}
+
+func TestHub_RegisterUnregisterOrder(t *testing.T) {
+	lg := logger.New(slog.NewJSONHandler(io.Discard, nil))
+	hub := NewHub(make(chan *model.Message), HubConfig{
+		Shards:       1,
+		ShardBuffer:  1024,
+		Backpressure: BackpressureConfig{Policy: DropOldestPolicy},
+	}, lg)
+	go hub.Run(t.Context())
+
+	for i := 0; i < 1000; i++ {
+		c := &Client{SubjectID: "subj", Send: make(chan *wsdto.WSMessage, 1), hub: hub}
+		hub.Register(c)
+		hub.Unregister(c)
+
+		select {
+		case _, ok := <-c.Send:
+			if ok {
+				t.Fatalf("unexpected event for client %v", i)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("client %v is still registered after unregister", i)
+		}
+	}
+
+	if sessions := hub.Sessions("subj"); len(sessions) != 0 {
+		t.Fatalf("wait no sessions, have %v", len(sessions))
+	}
+}
+
+func TestHub_StoppedDoesNotBlock(t *testing.T) {
+	lg := logger.New(slog.NewJSONHandler(io.Discard, nil))
+	hub := NewHub(make(chan *model.Message), HubConfig{
+		Shards:       1,
+		ShardBuffer:  1,
+		Backpressure: BackpressureConfig{Policy: DropOldestPolicy},
+	}, lg)
+	ctx, cancel := context.WithCancel(t.Context())
+	stopped := make(chan struct{})
+	go func() {
+		hub.Run(ctx)
+		close(stopped)
+	}()
+	cancel()
+	<-stopped
+
+	// handlers that outlive the hub at shutdown still run their deferred Unregister
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c := &Client{SubjectID: "subj", Send: make(chan *wsdto.WSMessage, 1), hub: hub}
+		hub.Register(c)
+		for i := 0; i < 3; i++ {
+			hub.Reply(c, &wsdto.WSMessage{Type: wsdto.Ack})
+		}
+		if sessions := hub.Sessions("subj"); sessions != nil {
+			t.Errorf("wait no sessions, have %v", sessions)
+		}
+		if hub.Terminate("subj", "session", session.ReasonTerminated) {
+			t.Errorf("nothing to terminate on a stopped hub")
+		}
+		hub.Unregister(c)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("calls to a stopped hub are blocked")
+	}
+}
+
+func TestHub_DeliverSubscribedEvents(t *testing.T) {
+	lg := logger.New(slog.NewJSONHandler(io.Discard, nil))
+	msgs := make(chan *model.Message)
+	hub := NewHub(msgs, HubConfig{
+		Shards:       1,
+		ShardBuffer:  16,
+		Backpressure: BackpressureConfig{Policy: DropOldestPolicy},
+	}, lg)
+	go hub.Run(t.Context())
+
+	old := &Client{SubjectID: "subj", Send: make(chan *wsdto.WSMessage, 16), hub: hub}
+	subscribed := &Client{SubjectID: "subj", Send: make(chan *wsdto.WSMessage, 16), hub: hub}
+	if _, err := subscribed.subscribe(&wsdto.SubscribeCommand{Events: []wsdto.Operation{wsdto.ProfileUpdated}}); err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	hub.Register(old)
+	hub.Register(subscribed)
+
+	before := time.Now().UTC()
+	for _, op := range []wsdto.Operation{wsdto.SendMessage, wsdto.ProfileUpdated} {
+		msgs <- &model.Message{SubjectID: "subj", WSMessage: &wsdto.WSMessage{Type: op, Data: []byte(`{}`)}}
+	}
+
+	got := make([]*wsdto.WSMessage, 0, 2)
+	for i := 0; i < 2; i++ {
+		select {
+		case msg := <-old.Send:
+			got = append(got, msg)
+		case <-time.After(time.Second):
+			t.Fatalf("old client got %v events, wait 2", len(got))
+		}
+	}
+	if got[0].Type != wsdto.SendMessage || got[1].Type != wsdto.ProfileUpdated {
+		t.Fatalf("wait every event in order, have %v %v", got[0].Type, got[1].Type)
+	}
+
+	prevID := int64(0)
+	for _, msg := range got {
+		id, err := strconv.ParseInt(msg.ID, 10, 64)
+		if err != nil || id <= prevID {
+			t.Fatalf("wait growing numeric id after %v, have %q", prevID, msg.ID)
+		}
+		prevID = id
+		if msg.V != wsdto.ProtocolVersion || msg.Ts.Before(before) {
+			t.Fatalf("wait v %v and ts after %v, have %v %v", wsdto.ProtocolVersion, before, msg.V, msg.Ts)
+		}
+	}
+
+	// the first event the subscribed client gets is the second one, the send message is filtered out
+	select {
+	case msg := <-subscribed.Send:
+		if msg.Type != wsdto.ProfileUpdated || msg.ID != got[1].ID {
+			t.Fatalf("wait %v %v, have %v %v", wsdto.ProfileUpdated, got[1].ID, msg.Type, msg.ID)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("no event for the subscribed client")
+	}
+}
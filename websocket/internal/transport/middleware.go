@@ -3,39 +3,166 @@ package transport
 import (
 	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/1ocknight/mess/shared/auth"
 	"github.com/1ocknight/mess/shared/logger"
+	"github.com/1ocknight/mess/shared/model"
 	"github.com/1ocknight/mess/websocket/internal/ctxkey"
+	wsmodel "github.com/1ocknight/mess/websocket/internal/model"
+	"github.com/1ocknight/mess/websocket/internal/session"
+	"github.com/1ocknight/mess/websocket/internal/ticket"
+	"github.com/golang-jwt/jwt/v4"
 )
 
 const (
 	Bearer = "Bearer"
+
+	AuthorizationHeader = "Authorization"
+	TicketQuery         = "ticket"
+
+	expClaim = "exp"
+	jtiClaim = "jti"
+	sidClaim = "sid"
+)
+
+var (
+	errTokenRevoked = fmt.Errorf("token revoked")
 )
 
-func SubjectMiddleware(auth auth.Service, lg logger.Logger) func(http.Handler) http.Handler {
+// BearerMiddleware authorizes plain http requests, the token is taken from the Authorization header.
+// A token of a terminated session does not get a ticket or sessions.
+func BearerMiddleware(auth auth.Service, revocations session.Revocations, lg logger.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			token := r.URL.Query().Get("token")
-			if token == "" {
+			token, ok := strings.CutPrefix(r.Header.Get(AuthorizationHeader), Bearer+" ")
+			if !ok || token == "" {
 				err := fmt.Errorf("not found token")
 				lg.Error(err)
 				http.Error(w, err.Error(), http.StatusUnauthorized)
 				return
 			}
 
-			token = fmt.Sprintf("%v %v", Bearer, token)
-
-			sub, err := auth.Verify(token)
+			sub, claims, err := verifyToken(auth, token)
 			if err != nil {
-				err = fmt.Errorf("verify token: %v", err)
 				lg.Error(err)
 				http.Error(w, err.Error(), http.StatusUnauthorized)
 				return
 			}
 
+			if !checkRevoked(w, r, revocations, claims.ids, lg) {
+				return
+			}
+
 			ctx := ctxkey.WithSubject(r.Context(), sub)
+			ctx = ctxkey.WithToken(ctx, token)
+			ctx = ctxkey.WithTokenExpiresAt(ctx, claims.expiresAt)
+			ctx = ctxkey.WithTokenIDs(ctx, claims.ids)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// TicketMiddleware authorizes the websocket upgrade by a ticket, so the token never gets into the url.
+// The token is checked again, the session could be terminated after the ticket was issued.
+func TicketMiddleware(tickets ticket.Service, revocations session.Revocations, lg logger.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.URL.Query().Get(TicketQuery)
+			if id == "" {
+				err := fmt.Errorf("not found ticket")
+				lg.Error(err)
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+
+			t, err := tickets.Redeem(r.Context(), id)
+			if err != nil {
+				err = fmt.Errorf("redeem ticket: %w", err)
+				lg.Error(err)
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+
+			claims, err := parseClaims(t.Token)
+			if err != nil {
+				err = fmt.Errorf("parse claims: %w", err)
+				lg.Error(err)
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+
+			if !checkRevoked(w, r, revocations, claims.ids, lg) {
+				return
+			}
+
+			ctx := ctxkey.WithSubject(r.Context(), &model.SubjectIMPL{SubjectID: t.SubjectID})
+			ctx = ctxkey.WithToken(ctx, t.Token)
+			ctx = ctxkey.WithTokenExpiresAt(ctx, t.TokenExpiresAt)
+			ctx = ctxkey.WithTokenIDs(ctx, claims.ids)
+			ctx = ctxkey.WithTicket(ctx, t)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
+
+// checkRevoked answers the request itself when the token can not be used.
+func checkRevoked(w http.ResponseWriter, r *http.Request, revocations session.Revocations, ids wsmodel.TokenIDs, lg logger.Logger) bool {
+	revoked, err := revocations.IsRevoked(r.Context(), ids)
+	if err != nil {
+		err = fmt.Errorf("is revoked: %w", err)
+		lg.Error(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	if revoked {
+		lg.Error(errTokenRevoked)
+		http.Error(w, errTokenRevoked.Error(), http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+type tokenClaims struct {
+	expiresAt time.Time
+	ids       wsmodel.TokenIDs
+}
+
+func verifyToken(auth auth.Service, token string) (model.Subject, tokenClaims, error) {
+	sub, err := auth.Verify(fmt.Sprintf("%v %v", Bearer, token))
+	if err != nil {
+		return nil, tokenClaims{}, fmt.Errorf("verify token: %w", err)
+	}
+
+	claims, err := parseClaims(token)
+	if err != nil {
+		return nil, tokenClaims{}, fmt.Errorf("parse claims: %w", err)
+	}
+
+	return sub, claims, nil
+}
+
+// parseClaims reads claims of a token that is already verified, jti and sid may be absent.
+func parseClaims(token string) (tokenClaims, error) {
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(token, claims); err != nil {
+		return tokenClaims{}, fmt.Errorf("parse unverified: %w", err)
+	}
+
+	exp, ok := claims[expClaim].(float64)
+	if !ok {
+		return tokenClaims{}, fmt.Errorf("not found %v claim", expClaim)
+	}
+
+	jti, _ := claims[jtiClaim].(string)
+	sid, _ := claims[sidClaim].(string)
+
+	return tokenClaims{
+		expiresAt: time.Unix(int64(exp), 0),
+		ids: wsmodel.TokenIDs{
+			TokenID:       jti,
+			AuthSessionID: sid,
+		},
+	}, nil
+}
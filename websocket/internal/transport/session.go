@@ -0,0 +1,214 @@
+package transport
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+
+	httpdto "github.com/1ocknight/mess/shared/dto/http"
+	"github.com/1ocknight/mess/websocket/internal/ctxkey"
+	"github.com/1ocknight/mess/websocket/internal/loglables"
+	"github.com/1ocknight/mess/websocket/internal/model"
+	"github.com/1ocknight/mess/websocket/internal/session"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	DeviceQuery = "device"
+
+	SessionIDVar = "session_id"
+)
+
+var (
+	sessionLimitMessage      = websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "too many sessions")
+	sessionTerminatedMessage = websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "session terminated")
+)
+
+func (h *Handler) newSession(r *http.Request, subjectID string, transport model.Transport, ids model.TokenIDs) model.Session {
+	return model.Session{
+		ID:          newSessionID(),
+		SubjectID:   subjectID,
+		Device:      r.URL.Query().Get(DeviceQuery),
+		RemoteAddr:  remoteAddr(r, h.trustedProxies),
+		UserAgent:   r.UserAgent(),
+		Transport:   transport,
+		ConnectedAt: time.Now().UTC(),
+		TokenIDs:    ids,
+	}
+}
+
+func terminateMessage(reason session.Reason) []byte {
+	if reason == session.ReasonLimit {
+		return sessionLimitMessage
+	}
+	return sessionTerminatedMessage
+}
+
+func newSessionID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// remoteAddr takes the address set by nginx only when the request came from a trusted proxy,
+// anybody else can put any address into these headers.
+func remoteAddr(r *http.Request, trustedProxies []netip.Prefix) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if !isTrusted(host, trustedProxies) {
+		return host
+	}
+
+	if ip := r.Header.Get("X-Real-IP"); ip != "" {
+		return strings.TrimSpace(ip)
+	}
+	// proxies append to the right, the first address from the right that is not a proxy is the client
+	if ips := r.Header.Values("X-Forwarded-For"); len(ips) > 0 {
+		addrs := strings.Split(strings.Join(ips, ","), ",")
+		for i := len(addrs) - 1; i >= 0; i-- {
+			ip := strings.TrimSpace(addrs[i])
+			if ip != "" && !isTrusted(ip, trustedProxies) {
+				return ip
+			}
+		}
+	}
+
+	return host
+}
+
+func isTrusted(ip string, trustedProxies []netip.Prefix) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	for _, p := range trustedProxies {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// addSession puts the session into the registry of all replicas and closes the oldest sessions
+// of the subject over the limit, wherever they are.
+func (h *Handler) addSession(ctx context.Context, s model.Session) error {
+	evicted, err := h.sessions.Add(ctx, s)
+	if err != nil {
+		return fmt.Errorf("add: %w", err)
+	}
+
+	for _, e := range evicted {
+		if err := h.sessions.Remove(ctx, e); err != nil {
+			return fmt.Errorf("remove: %w", err)
+		}
+		err := h.sessions.Publish(ctx, session.Termination{
+			SubjectID: e.SubjectID,
+			SessionID: e.ID,
+			Reason:    session.ReasonLimit,
+		})
+		if err != nil {
+			return fmt.Errorf("publish: %w", err)
+		}
+		h.hub.lg.With(loglables.Subject, e.SubjectID).Info("evict oldest session")
+	}
+
+	return nil
+}
+
+func (h *Handler) removeSession(s model.Session) {
+	if err := h.sessions.Remove(context.Background(), s); err != nil {
+		h.hub.lg.Error(fmt.Errorf("subj: %v, remove session: %w", s.SubjectID, err))
+	}
+}
+
+// RunTerminations closes sessions of this replica terminated by any replica, until ctx is done.
+func (h *Handler) RunTerminations(ctx context.Context) {
+	for t := range h.sessions.Terminations(ctx) {
+		h.hub.Terminate(t.SubjectID, t.SessionID, t.Reason)
+	}
+}
+
+// ListSessions returns sessions of the subject on all replicas.
+func (h *Handler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	subj, err := ctxkey.ExtractSubject(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	sessions, err := h.sessions.List(r.Context(), subj.GetSubjectId())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	res := httpdto.SessionsResponse{
+		Sessions: make([]*httpdto.SessionResponse, 0, len(sessions)),
+	}
+	for _, s := range sessions {
+		res.Sessions = append(res.Sessions, &httpdto.SessionResponse{
+			ID:          s.ID,
+			Device:      s.Device,
+			RemoteAddr:  s.RemoteAddr,
+			UserAgent:   s.UserAgent,
+			Transport:   string(s.Transport),
+			ConnectedAt: s.ConnectedAt,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
+
+// TerminateSession revokes the token of the session first, so the device can not reconnect
+// on any replica, then the replica that holds the session closes it.
+func (h *Handler) TerminateSession(w http.ResponseWriter, r *http.Request) {
+	subj, err := ctxkey.ExtractSubject(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	s, err := h.sessions.Get(r.Context(), subj.GetSubjectId(), mux.Vars(r)[SessionIDVar])
+	if errors.Is(err, session.ErrNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.sessions.Revoke(r.Context(), s); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := h.sessions.Remove(r.Context(), s); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	err = h.sessions.Publish(r.Context(), session.Termination{
+		SubjectID: s.SubjectID,
+		SessionID: s.ID,
+		Reason:    session.ReasonTerminated,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
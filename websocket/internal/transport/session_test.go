@@ -0,0 +1,385 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	wsdto "github.com/1ocknight/mess/shared/dto/ws"
+	"github.com/1ocknight/mess/shared/logger"
+	sharedmodel "github.com/1ocknight/mess/shared/model"
+	"github.com/1ocknight/mess/websocket/internal/ctxkey"
+	"github.com/1ocknight/mess/websocket/internal/model"
+	"github.com/1ocknight/mess/websocket/internal/session"
+	"github.com/1ocknight/mess/websocket/internal/ticket"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/gorilla/mux"
+)
+
+// fakeSessions is the shared registry of several replicas in memory, Publish delivers to Terminations.
+type fakeSessions struct {
+	mu       sync.Mutex
+	sessions map[string]model.Session
+	revoked  map[string]struct{}
+	evict    []model.Session
+
+	terminations chan session.Termination
+}
+
+func newFakeSessions() *fakeSessions {
+	return &fakeSessions{
+		sessions:     make(map[string]model.Session),
+		revoked:      make(map[string]struct{}),
+		terminations: make(chan session.Termination, 16),
+	}
+}
+
+func (f *fakeSessions) Add(_ context.Context, s model.Session) ([]model.Session, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sessions[s.ID] = s
+	evict := f.evict
+	f.evict = nil
+	return evict, nil
+}
+
+func (f *fakeSessions) Remove(_ context.Context, s model.Session) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.sessions, s.ID)
+	return nil
+}
+
+func (f *fakeSessions) Get(_ context.Context, subjectID string, sessionID string) (model.Session, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, ok := f.sessions[sessionID]
+	if !ok || s.SubjectID != subjectID {
+		return model.Session{}, session.ErrNotFound
+	}
+	return s, nil
+}
+
+func (f *fakeSessions) List(_ context.Context, subjectID string) ([]model.Session, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	res := make([]model.Session, 0)
+	for _, s := range f.sessions {
+		if s.SubjectID == subjectID {
+			res = append(res, s)
+		}
+	}
+	return res, nil
+}
+
+func (f *fakeSessions) Publish(_ context.Context, t session.Termination) error {
+	f.terminations <- t
+	return nil
+}
+
+func (f *fakeSessions) Terminations(ctx context.Context) <-chan session.Termination {
+	return f.terminations
+}
+
+func (f *fakeSessions) Revoke(_ context.Context, s model.Session) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.revoked[s.TokenID] = struct{}{}
+	f.revoked[s.AuthSessionID] = struct{}{}
+	return nil
+}
+
+func (f *fakeSessions) IsRevoked(_ context.Context, ids model.TokenIDs) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, token := f.revoked[ids.TokenID]
+	_, sid := f.revoked[ids.AuthSessionID]
+	return token || sid, nil
+}
+
+func TestRemoteAddr(t *testing.T) {
+	proxies := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		realIP     string
+		forwarded  string
+		proxies    []netip.Prefix
+		want       string
+	}{
+		{
+			name:       "no proxies",
+			remoteAddr: "203.0.113.7:5000",
+			realIP:     "1.1.1.1",
+			forwarded:  "1.1.1.1",
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "untrusted sender",
+			remoteAddr: "203.0.113.7:5000",
+			realIP:     "1.1.1.1",
+			proxies:    proxies,
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "real ip from trusted proxy",
+			remoteAddr: "10.0.0.2:5000",
+			realIP:     "198.51.100.1",
+			proxies:    proxies,
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "forwarded skips trusted proxies and ignores what the client put",
+			remoteAddr: "10.0.0.2:5000",
+			forwarded:  "1.1.1.1, 198.51.100.1, 10.0.0.3",
+			proxies:    proxies,
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "trusted proxy without headers",
+			remoteAddr: "10.0.0.2:5000",
+			proxies:    proxies,
+			want:       "10.0.0.2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/ws", nil)
+			r.RemoteAddr = tt.remoteAddr
+			if tt.realIP != "" {
+				r.Header.Set("X-Real-IP", tt.realIP)
+			}
+			if tt.forwarded != "" {
+				r.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+
+			if got := remoteAddr(r, tt.proxies); got != tt.want {
+				t.Fatalf("wait %v, have %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func newSessionsTestHandler(t *testing.T) (*Handler, *Hub, *fakeSessions) {
+	lg := logger.New(slog.NewJSONHandler(io.Discard, nil))
+	hub := NewHub(make(chan *model.Message), HubConfig{
+		Shards:       1,
+		ShardBuffer:  16,
+		Backpressure: BackpressureConfig{Policy: DropOldestPolicy},
+	}, lg)
+	go hub.Run(t.Context())
+
+	sessions := newFakeSessions()
+	handler := NewHandler(WSHandlerConfig{}, hub, nil, nil, nil, sessions)
+	go handler.RunTerminations(t.Context())
+
+	return handler, hub, sessions
+}
+
+func newTerminateRequest(subjectID string, sessionID string) *http.Request {
+	r := httptest.NewRequest(http.MethodDelete, "/sessions/"+sessionID, nil)
+	r = mux.SetURLVars(r, map[string]string{SessionIDVar: sessionID})
+	return r.WithContext(ctxkey.WithSubject(r.Context(), &sharedmodel.SubjectIMPL{SubjectID: subjectID}))
+}
+
+func waitClosed(t *testing.T, c *Client) {
+	t.Helper()
+	for {
+		select {
+		case _, ok := <-c.Send:
+			if !ok {
+				return
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("client %v is not closed", c.Session.ID)
+		}
+	}
+}
+
+func TestHandler_TerminateSession(t *testing.T) {
+	handler, hub, sessions := newSessionsTestHandler(t)
+
+	local := model.Session{ID: "local", SubjectID: "subj", TokenIDs: model.TokenIDs{TokenID: "jti-local", AuthSessionID: "sid-local"}}
+	remote := model.Session{ID: "remote", SubjectID: "subj", TokenIDs: model.TokenIDs{TokenID: "jti-remote"}}
+	for _, s := range []model.Session{local, remote} {
+		if _, err := sessions.Add(t.Context(), s); err != nil {
+			t.Fatalf("add: %v", err)
+		}
+	}
+
+	c := &Client{SubjectID: "subj", Session: local, Send: make(chan *wsdto.WSMessage, 1), hub: hub}
+	hub.Register(c)
+
+	// the session of another replica is found in the shared registry and revoked
+	w := httptest.NewRecorder()
+	handler.TerminateSession(w, newTerminateRequest("subj", remote.ID))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("wait %v, have %v", http.StatusNoContent, w.Code)
+	}
+	if revoked, _ := sessions.IsRevoked(t.Context(), model.TokenIDs{TokenID: "jti-remote"}); !revoked {
+		t.Fatalf("token of terminated session is not revoked")
+	}
+	if _, err := sessions.Get(t.Context(), "subj", remote.ID); err == nil {
+		t.Fatalf("terminated session is still listed")
+	}
+
+	// the session of this replica is closed by the termination that came back from the registry
+	w = httptest.NewRecorder()
+	handler.TerminateSession(w, newTerminateRequest("subj", local.ID))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("wait %v, have %v", http.StatusNoContent, w.Code)
+	}
+	waitClosed(t, c)
+	if !slices.Equal(c.closeMessage, sessionTerminatedMessage) {
+		t.Fatalf("wait terminated close message, have %q", c.closeMessage)
+	}
+	if revoked, _ := sessions.IsRevoked(t.Context(), model.TokenIDs{AuthSessionID: "sid-local"}); !revoked {
+		t.Fatalf("keycloak session of terminated session is not revoked")
+	}
+
+	tests := []struct {
+		name      string
+		subjectID string
+		sessionID string
+	}{
+		{name: "unknown session", subjectID: "subj", sessionID: "unknown"},
+		{name: "session of other subject", subjectID: "other", sessionID: local.ID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.TerminateSession(w, newTerminateRequest(tt.subjectID, tt.sessionID))
+			if w.Code != http.StatusNotFound {
+				t.Fatalf("wait %v, have %v", http.StatusNotFound, w.Code)
+			}
+		})
+	}
+}
+
+func TestHandler_AddSessionEvictsOverLimit(t *testing.T) {
+	handler, hub, sessions := newSessionsTestHandler(t)
+
+	oldest := model.Session{ID: "oldest", SubjectID: "subj"}
+	if _, err := sessions.Add(t.Context(), oldest); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	c := &Client{SubjectID: "subj", Session: oldest, Send: make(chan *wsdto.WSMessage, 1), hub: hub}
+	hub.Register(c)
+
+	sessions.evict = []model.Session{oldest}
+	if err := handler.addSession(t.Context(), model.Session{ID: "newest", SubjectID: "subj"}); err != nil {
+		t.Fatalf("add session: %v", err)
+	}
+
+	waitClosed(t, c)
+	if !slices.Equal(c.closeMessage, sessionLimitMessage) {
+		t.Fatalf("wait limit close message, have %q", c.closeMessage)
+	}
+	if _, err := sessions.Get(t.Context(), "subj", oldest.ID); err == nil {
+		t.Fatalf("evicted session is still listed")
+	}
+	if revoked, _ := sessions.IsRevoked(t.Context(), oldest.TokenIDs); revoked {
+		t.Fatalf("evicted session must not be revoked")
+	}
+}
+
+type fakeAuth struct{}
+
+func (fakeAuth) Verify(src string) (sharedmodel.Subject, error) {
+	return &sharedmodel.SubjectIMPL{SubjectID: "subj"}, nil
+}
+
+type fakeTickets struct {
+	token string
+}
+
+func (f fakeTickets) Issue(ctx context.Context, subjectID string, token string, tokenExpiresAt time.Time) (*ticket.Ticket, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (f fakeTickets) Redeem(ctx context.Context, id string) (*ticket.Ticket, error) {
+	return &ticket.Ticket{ID: id, SubjectID: "subj", Token: f.token, TokenExpiresAt: time.Now().Add(time.Minute)}, nil
+}
+
+func (f fakeTickets) Rearm(ctx context.Context, t *ticket.Ticket, ttl time.Duration) error {
+	return nil
+}
+
+func newTestToken(t *testing.T, jti string, sid string) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		expClaim: time.Now().Add(time.Minute).Unix(),
+		jtiClaim: jti,
+		sidClaim: sid,
+	}).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatalf("signed string: %v", err)
+	}
+	return token
+}
+
+func TestMiddleware_RevokedToken(t *testing.T) {
+	lg := logger.New(slog.NewJSONHandler(io.Discard, nil))
+	sessions := newFakeSessions()
+	sessions.Revoke(t.Context(), model.Session{TokenIDs: model.TokenIDs{TokenID: "revoked-jti", AuthSessionID: "revoked-sid"}})
+
+	var ids model.TokenIDs
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ids, _ = ctxkey.ExtractTokenIDs(r.Context())
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name   string
+		jti    string
+		sid    string
+		status int
+	}{
+		{name: "live token", jti: "jti", sid: "sid", status: http.StatusOK},
+		{name: "revoked token", jti: "revoked-jti", sid: "sid", status: http.StatusUnauthorized},
+		{name: "refreshed token of revoked keycloak session", jti: "jti", sid: "revoked-sid", status: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		token := newTestToken(t, tt.jti, tt.sid)
+
+		t.Run("bearer "+tt.name, func(t *testing.T) {
+			ids = model.TokenIDs{}
+			r := httptest.NewRequest(http.MethodPost, "/ws/ticket", nil)
+			r.Header.Set(AuthorizationHeader, Bearer+" "+token)
+			w := httptest.NewRecorder()
+			BearerMiddleware(fakeAuth{}, sessions, lg)(next).ServeHTTP(w, r)
+
+			if w.Code != tt.status {
+				t.Fatalf("wait %v, have %v", tt.status, w.Code)
+			}
+			if tt.status == http.StatusOK && (ids.TokenID != tt.jti || ids.AuthSessionID != tt.sid) {
+				t.Fatalf("wait token ids %v %v, have %+v", tt.jti, tt.sid, ids)
+			}
+		})
+
+		t.Run("ticket "+tt.name, func(t *testing.T) {
+			ids = model.TokenIDs{}
+			r := httptest.NewRequest(http.MethodGet, "/ws?"+TicketQuery+"=t", nil)
+			w := httptest.NewRecorder()
+			TicketMiddleware(fakeTickets{token: token}, sessions, lg)(next).ServeHTTP(w, r)
+
+			if w.Code != tt.status {
+				t.Fatalf("wait %v, have %v", tt.status, w.Code)
+			}
+			if tt.status == http.StatusOK && (ids.TokenID != tt.jti || ids.AuthSessionID != tt.sid) {
+				t.Fatalf("wait token ids %v %v, have %+v", tt.jti, tt.sid, ids)
+			}
+		})
+	}
+}
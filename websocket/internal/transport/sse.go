@@ -0,0 +1,178 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	wsdto "github.com/1ocknight/mess/shared/dto/ws"
+	"github.com/1ocknight/mess/websocket/internal/ctxkey"
+	"github.com/1ocknight/mess/websocket/internal/model"
+	"github.com/1ocknight/mess/websocket/internal/ticket"
+)
+
+type SSEConfig struct {
+	MessageBuffer   int           `yaml:"message_buffer"`
+	WriteTimeout    time.Duration `yaml:"write_timeout"`
+	HeartbeatPeriod time.Duration `yaml:"heartbeat_period"`
+	// Retry is sent to EventSource as the reconnect delay
+	Retry time.Duration `yaml:"retry"`
+	// ResumeTTL is how long the ticket of a broken stream opens the next one, EventSource reconnects
+	// with the same url. Zero turns it off, the client then takes a new ticket for every stream
+	ResumeTTL time.Duration `yaml:"resume_ttl"`
+}
+
+const rearmTimeout = 5 * time.Second
+
+var (
+	heartbeat = []byte(": ping\n\n")
+)
+
+// SSEHandler is the fallback for networks that break websocket upgrades. The stream is a Client without
+// a connection in the hub, it gets the same frames, commands go to chat over plain http.
+// Tickets are single use, so when the stream breaks or the replica drains its ticket is put back
+// for ResumeTTL and the automatic reconnect of EventSource with the same url gets in.
+func (h *Handler) SSEHandler(w http.ResponseWriter, r *http.Request) {
+	if h.rejectDraining(w) {
+		return
+	}
+
+	subj, err := ctxkey.ExtractSubject(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	expiresAt, err := ctxkey.ExtractTokenExpiresAt(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	ids, err := ctxkey.ExtractTokenIDs(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	sess := h.newSession(r, subj.GetSubjectId(), model.SSETransport, ids)
+	if err := h.addSession(r.Context(), sess); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer h.removeSession(sess)
+
+	cfg := h.cfg.SSE
+	rc := http.NewResponseController(w)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// nginx buffers responses by default
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	client := &Client{
+		SubjectID:   subj.GetSubjectId(),
+		Session:     sess,
+		Send:        make(chan *wsdto.WSMessage, max(cfg.MessageBuffer, 1)),
+		hub:         h.hub,
+		codec:       jsonCodec{},
+		lastEventID: getLastEventID(r),
+	}
+	client.Send <- client.hello()
+	h.hub.conns.Add(1)
+	defer h.hub.conns.Done()
+	h.hub.Register(client)
+	defer h.hub.Unregister(client)
+
+	resume := true
+	defer func() {
+		if resume {
+			h.rearmTicket(r.Context(), client)
+		}
+	}()
+
+	if _, err := fmt.Fprintf(w, "retry: %d\n\n", cfg.Retry.Milliseconds()); err != nil {
+		return
+	}
+
+	ticker := time.NewTicker(cfg.HeartbeatPeriod)
+	defer ticker.Stop()
+	expiry := time.NewTimer(time.Until(expiresAt))
+	defer expiry.Stop()
+
+	for {
+		var frame []byte
+		select {
+		case <-r.Context().Done():
+			return
+		case <-expiry.C:
+			// without in-band auth the stream ends with the token, the client reconnects with a new ticket
+			resume = false
+			return
+		case <-ticker.C:
+			frame = heartbeat
+		case msg, ok := <-client.Send:
+			if !ok {
+				// a terminated or evicted session must not come back, a drained one reconnects elsewhere
+				resume = bytes.Equal(client.closeMessage, serviceRestartMessage)
+				return
+			}
+			frame, err = sseFrame(msg)
+			if err != nil {
+				client.sendError(err)
+				return
+			}
+		}
+
+		rc.SetWriteDeadline(time.Now().Add(cfg.WriteTimeout))
+		if _, err := w.Write(frame); err != nil {
+			return
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// rearmTicket gives the ticket of the ended stream to the reconnect of EventSource.
+func (h *Handler) rearmTicket(ctx context.Context, client *Client) {
+	ttl := h.cfg.SSE.ResumeTTL
+	if ttl <= 0 {
+		return
+	}
+
+	t, err := ctxkey.ExtractTicket(ctx)
+	if err != nil {
+		client.sendError(fmt.Errorf("extract ticket: %w", err))
+		return
+	}
+
+	// the request context is already cancelled when the client went away
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), rearmTimeout)
+	defer cancel()
+	if err := h.tickets.Rearm(ctx, t, ttl); err != nil && !errors.Is(err, ticket.ErrTokenExpired) {
+		client.sendError(fmt.Errorf("rearm ticket: %w", err))
+	}
+}
+
+// sseFrame puts the whole WSMessage into data, so the front parses it the same way as websocket frames.
+// Frames without id, like hello, do not move Last-Event-ID of EventSource.
+func sseFrame(msg *wsdto.WSMessage) ([]byte, error) {
+	data, err := msg.GetBytes()
+	if err != nil {
+		return nil, fmt.Errorf("get bytes: %w", err)
+	}
+
+	var b bytes.Buffer
+	if msg.ID != "" {
+		fmt.Fprintf(&b, "id: %s\n", msg.ID)
+	}
+	fmt.Fprintf(&b, "data: %s\n\n", data)
+
+	return b.Bytes(), nil
+}
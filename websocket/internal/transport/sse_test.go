@@ -0,0 +1,261 @@
+package transport
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	wsdto "github.com/1ocknight/mess/shared/dto/ws"
+	"github.com/1ocknight/mess/shared/logger"
+	sharedmodel "github.com/1ocknight/mess/shared/model"
+	"github.com/1ocknight/mess/websocket/internal/ctxkey"
+	"github.com/1ocknight/mess/websocket/internal/model"
+	"github.com/1ocknight/mess/websocket/internal/session"
+	"github.com/1ocknight/mess/websocket/internal/ticket"
+)
+
+func TestSSEHandler_ReplayAfterLastEventID(t *testing.T) {
+	lg := logger.New(slog.NewJSONHandler(io.Discard, nil))
+	msgs := make(chan *model.Message)
+	hub := NewHub(msgs, HubConfig{
+		Shards:       1,
+		ShardBuffer:  16,
+		Backpressure: BackpressureConfig{Policy: DropOldestPolicy},
+		HistorySize:  10,
+		HistoryTTL:   time.Minute,
+	}, lg)
+	go hub.Run(t.Context())
+
+	// a live client of the same subject tells when the events are in the history
+	live := &Client{SubjectID: "subj", Send: make(chan *wsdto.WSMessage, 16), hub: hub}
+	hub.Register(live)
+
+	ids := make([]string, 0, 3)
+	for i := 0; i < 3; i++ {
+		msgs <- &model.Message{
+			SubjectID: "subj",
+			WSMessage: &wsdto.WSMessage{Type: wsdto.SendMessage, Data: []byte(`{}`)},
+		}
+		ids = append(ids, (<-live.Send).ID)
+	}
+
+	handler := NewHandler(WSHandlerConfig{
+		SSE: SSEConfig{
+			MessageBuffer:   16,
+			WriteTimeout:    time.Second,
+			HeartbeatPeriod: time.Minute,
+			Retry:           time.Second,
+		},
+	}, hub, nil, nil, nil, newFakeSessions())
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := ctxkey.WithSubject(r.Context(), &sharedmodel.SubjectIMPL{SubjectID: "subj"})
+		ctx = ctxkey.WithTokenExpiresAt(ctx, time.Now().Add(time.Minute))
+		ctx = ctxkey.WithTokenIDs(ctx, model.TokenIDs{})
+		handler.SSEHandler(w, r.WithContext(ctx))
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	req.Header.Set(LastEventIDHeader, ids[0])
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("do: %v", err)
+	}
+	defer res.Body.Close()
+
+	if ct := res.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("wait event stream, have %v", ct)
+	}
+
+	// hello has no id, then only the events after Last-Event-ID come in order
+	replayed := make([]string, 0, 2)
+	sc := bufio.NewScanner(res.Body)
+	for len(replayed) < 2 && sc.Scan() {
+		if id, ok := strings.CutPrefix(sc.Text(), "id: "); ok {
+			replayed = append(replayed, id)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatalf("read stream: %v", err)
+	}
+
+	if len(replayed) != 2 || replayed[0] != ids[1] || replayed[1] != ids[2] {
+		t.Fatalf("wait replay %v, have %v", ids[1:], replayed)
+	}
+}
+
+func TestWSHandlerConfig_Validate(t *testing.T) {
+	valid := WSHandlerConfig{
+		ClientConfig: ClientConfig{PingPeriod: time.Second},
+		SSE:          SSEConfig{HeartbeatPeriod: time.Second},
+	}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+
+	noHeartbeat := valid
+	noHeartbeat.SSE.HeartbeatPeriod = 0
+	if err := noHeartbeat.Validate(); err == nil {
+		t.Fatalf("zero heartbeat period must be rejected")
+	}
+
+	noPing := valid
+	noPing.ClientConfig.PingPeriod = 0
+	if err := noPing.Validate(); err == nil {
+		t.Fatalf("zero ping period must be rejected")
+	}
+
+	proxies := valid
+	proxies.TrustedProxies = []string{"10.0.0.1", "172.16.0.0/12", "::1"}
+	if err := proxies.Validate(); err != nil {
+		t.Fatalf("validate proxies: %v", err)
+	}
+
+	badProxy := valid
+	badProxy.TrustedProxies = []string{"nginx"}
+	if err := badProxy.Validate(); err == nil {
+		t.Fatalf("invalid trusted proxy must be rejected")
+	}
+
+	shortResume := valid
+	shortResume.SSE.Retry = 3 * time.Second
+	shortResume.SSE.ResumeTTL = time.Second
+	if err := shortResume.Validate(); err == nil {
+		t.Fatalf("resume ttl shorter than retry must be rejected")
+	}
+}
+
+// rearmTickets tells which tickets the handler put back.
+type rearmTickets struct {
+	fakeTickets
+	rearmed chan *ticket.Ticket
+}
+
+func (f *rearmTickets) Rearm(_ context.Context, t *ticket.Ticket, ttl time.Duration) error {
+	f.rearmed <- t
+	return nil
+}
+
+func TestSSEHandler_RearmTicket(t *testing.T) {
+	tests := []struct {
+		name     string
+		tokenTTL time.Duration
+		// end stops the stream after hello, nil leaves it to the token expiry
+		end   func(cancel context.CancelFunc, hub *Hub)
+		rearm bool
+	}{
+		{
+			name:     "client went away",
+			tokenTTL: time.Minute,
+			end:      func(cancel context.CancelFunc, _ *Hub) { cancel() },
+			rearm:    true,
+		},
+		{
+			name:     "replica drains",
+			tokenTTL: time.Minute,
+			end: func(_ context.CancelFunc, hub *Hub) {
+				hub.Drain(context.Background(), 0)
+			},
+			rearm: true,
+		},
+		{
+			name:     "session terminated",
+			tokenTTL: time.Minute,
+			end: func(_ context.CancelFunc, hub *Hub) {
+				hub.Terminate("subj", hub.Sessions("subj")[0].ID, session.ReasonTerminated)
+			},
+		},
+		{
+			name:     "token expired",
+			tokenTTL: 100 * time.Millisecond,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lg := logger.New(slog.NewJSONHandler(io.Discard, nil))
+			hub := NewHub(make(chan *model.Message), HubConfig{
+				Shards:       1,
+				ShardBuffer:  16,
+				Backpressure: BackpressureConfig{Policy: DropOldestPolicy},
+			}, lg)
+			go hub.Run(t.Context())
+
+			tickets := &rearmTickets{rearmed: make(chan *ticket.Ticket, 1)}
+			handler := NewHandler(WSHandlerConfig{
+				SSE: SSEConfig{
+					MessageBuffer:   16,
+					WriteTimeout:    time.Second,
+					HeartbeatPeriod: time.Minute,
+					Retry:           time.Second,
+					ResumeTTL:       time.Minute,
+				},
+			}, hub, nil, nil, tickets, newFakeSessions())
+
+			tk := &ticket.Ticket{ID: "ticket", SubjectID: "subj", TokenExpiresAt: time.Now().Add(tt.tokenTTL)}
+			done := make(chan struct{})
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer close(done)
+				ctx := ctxkey.WithSubject(r.Context(), &sharedmodel.SubjectIMPL{SubjectID: "subj"})
+				ctx = ctxkey.WithTokenExpiresAt(ctx, tk.TokenExpiresAt)
+				ctx = ctxkey.WithTokenIDs(ctx, model.TokenIDs{})
+				ctx = ctxkey.WithTicket(ctx, tk)
+				handler.SSEHandler(w, r.WithContext(ctx))
+			}))
+			defer srv.Close()
+
+			ctx, cancel := context.WithCancel(t.Context())
+			defer cancel()
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+			if err != nil {
+				t.Fatalf("new request: %v", err)
+			}
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("do: %v", err)
+			}
+			defer res.Body.Close()
+
+			// hello is written after the client is registered
+			sc := bufio.NewScanner(res.Body)
+			for sc.Scan() && !strings.HasPrefix(sc.Text(), "data: ") {
+			}
+
+			if tt.end != nil {
+				tt.end(cancel, hub)
+			}
+
+			select {
+			case <-done:
+			case <-time.After(2 * time.Second):
+				t.Fatalf("stream is not ended")
+			}
+
+			select {
+			case rearmed := <-tickets.rearmed:
+				if !tt.rearm {
+					t.Fatalf("ticket must not be rearmed")
+				}
+				if rearmed != tk {
+					t.Fatalf("wait ticket of the stream, have %+v", rearmed)
+				}
+			default:
+				if tt.rearm {
+					t.Fatalf("ticket is not rearmed")
+				}
+			}
+		})
+	}
+}
@@ -8,6 +8,8 @@ import (
 
 	"github.com/1ocknight/mess/shared/auth"
 	"github.com/1ocknight/mess/shared/logger"
+	"github.com/1ocknight/mess/websocket/internal/session"
+	"github.com/1ocknight/mess/websocket/internal/ticket"
 	"github.com/gorilla/mux"
 	"github.com/rs/cors"
 )
@@ -20,7 +22,7 @@ type Server struct {
 	httpServer  *http.Server
 }
 
-func NewServer(cfg HTTPConfig, authService auth.Service, handler *Handler, lg logger.Logger) *Server {
+func NewServer(cfg HTTPConfig, authService auth.Service, tickets ticket.Service, revocations session.Revocations, handler *Handler, lg logger.Logger) *Server {
 	r := mux.NewRouter()
 
 	s := &Server{
@@ -39,10 +41,21 @@ func NewServer(cfg HTTPConfig, authService auth.Service, handler *Handler, lg lo
 		MaxAge:           int((12 * time.Hour).Seconds()), // rs/cors требует int секунд
 	})
 	r.Use(c.Handler)
-	r.Use(SubjectMiddleware(authService, lg))
+
+	r.Handle("/ws/ticket", BearerMiddleware(authService, revocations, lg)(http.HandlerFunc(handler.TicketHandler))).
+		Methods(http.MethodPost, http.MethodOptions)
 
 	// WS endpoint
-	r.HandleFunc("/ws", handler.WSHandler)
+	r.Handle("/ws", TicketMiddleware(tickets, revocations, lg)(http.HandlerFunc(handler.WSHandler)))
+
+	r.Handle("/sessions", BearerMiddleware(authService, revocations, lg)(http.HandlerFunc(handler.ListSessions))).
+		Methods(http.MethodGet, http.MethodOptions)
+	r.Handle(fmt.Sprintf("/sessions/{%v}", SessionIDVar), BearerMiddleware(authService, revocations, lg)(http.HandlerFunc(handler.TerminateSession))).
+		Methods(http.MethodDelete, http.MethodOptions)
+
+	// SSE fallback endpoint
+	r.Handle("/events", TicketMiddleware(tickets, revocations, lg)(http.HandlerFunc(handler.SSEHandler))).
+		Methods(http.MethodGet)
 
 	s.httpServer = &http.Server{
 		Addr:    fmt.Sprintf("%v:%v", cfg.Host, cfg.Port),
@@ -1,48 +1,155 @@
 package transport
 
 import (
+	"encoding/json"
 	"net/http"
+	"net/netip"
+	"sync/atomic"
 
+	"github.com/1ocknight/mess/shared/auth"
+	httpdto "github.com/1ocknight/mess/shared/dto/http"
+	"github.com/1ocknight/mess/websocket/internal/adapter/chat"
 	"github.com/1ocknight/mess/websocket/internal/ctxkey"
+	"github.com/1ocknight/mess/websocket/internal/model"
+	"github.com/1ocknight/mess/websocket/internal/session"
+	"github.com/1ocknight/mess/websocket/internal/ticket"
 	"github.com/gorilla/websocket"
 )
 
 type Handler struct {
 	cfg      WSHandlerConfig
 	hub      *Hub
+	chat     chat.Service
+	auth     auth.Service
+	tickets  ticket.Service
+	sessions session.Service
 	upgrader *websocket.Upgrader
+
+	trustedProxies []netip.Prefix
+
+	draining atomic.Bool
 }
 
-func NewHandler(cfg WSHandlerConfig, hub *Hub) *Handler {
+func NewHandler(cfg WSHandlerConfig, hub *Hub, chat chat.Service, auth auth.Service, tickets ticket.Service, sessions session.Service) *Handler {
 	upgrader := websocket.Upgrader{
-		ReadBufferSize:  cfg.ReadBufferSizeBytes,
-		WriteBufferSize: cfg.WriteBufferSizeBytes,
-		CheckOrigin:     func(r *http.Request) bool { return true },
+		ReadBufferSize:    cfg.ReadBufferSizeBytes,
+		WriteBufferSize:   cfg.WriteBufferSizeBytes,
+		EnableCompression: cfg.EnableCompression,
+		Subprotocols:      Subprotocols,
+		CheckOrigin:       func(r *http.Request) bool { return true },
 	}
+	// the proxies are checked by WSHandlerConfig.Validate on start
+	trustedProxies, _ := parseTrustedProxies(cfg.TrustedProxies)
 
 	return &Handler{
 		cfg:      cfg,
 		hub:      hub,
+		chat:     chat,
+		auth:     auth,
+		tickets:  tickets,
+		sessions: sessions,
 		upgrader: &upgrader,
+
+		trustedProxies: trustedProxies,
+	}
+
+}
+
+func (h *Handler) TicketHandler(w http.ResponseWriter, r *http.Request) {
+	if h.rejectDraining(w) {
+		return
+	}
+
+	subj, err := ctxkey.ExtractSubject(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	token, err := ctxkey.ExtractToken(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	expiresAt, err := ctxkey.ExtractTokenExpiresAt(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	t, err := h.tickets.Issue(r.Context(), subj.GetSubjectId(), token, expiresAt)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(httpdto.TicketResponse{
+		Ticket:    t.ID,
+		ExpiresAt: t.ExpiresAt,
+	})
 }
 
 func (h *Handler) WSHandler(w http.ResponseWriter, r *http.Request) {
+	if h.rejectDraining(w) {
+		return
+	}
+
 	subj, err := ctxkey.ExtractSubject(r.Context())
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusUnauthorized)
 		return
 	}
 
+	token, err := ctxkey.ExtractToken(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	expiresAt, err := ctxkey.ExtractTokenExpiresAt(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	ids, err := ctxkey.ExtractTokenIDs(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	sess := h.newSession(r, subj.GetSubjectId(), model.WebSocketTransport, ids)
+	if err := h.addSession(r.Context(), sess); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	conn, err := h.upgrader.Upgrade(w, r, nil)
 	if err != nil {
+		h.removeSession(sess)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	client := NewClient(subj.GetSubjectId(), conn, h.cfg.ClientConfig, h.hub)
-	client.hub.register <- client
+	if h.cfg.EnableCompression && h.cfg.CompressionLevel != 0 {
+		// the level is checked by WSHandlerConfig.Validate on start
+		conn.SetCompressionLevel(h.cfg.CompressionLevel)
+	}
+	client := NewClient(sess, token, expiresAt, conn, h.cfg.ClientConfig, h.hub, h.chat, h.auth)
+	client.lastEventID = getLastEventID(r)
+	// nobody else writes to Send before the client is registered, so hello is always the first frame
+	client.Send <- client.hello()
+	h.hub.conns.Add(1)
+	h.hub.Register(client)
 
-	go client.writePump()
-	go client.readPump()
+	go func() {
+		defer h.hub.conns.Done()
+		client.writePump()
+	}()
+	go func() {
+		client.readPump()
+		h.removeSession(sess)
+	}()
 }
@@ -0,0 +1,96 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	mqdto "github.com/1ocknight/mess/shared/dto/mq"
+	wsdto "github.com/1ocknight/mess/shared/dto/ws"
+	"github.com/1ocknight/mess/shared/kafkav2"
+	"github.com/1ocknight/mess/shared/logger"
+	"github.com/1ocknight/mess/websocket/internal/model"
+)
+
+type AvatarConfig struct {
+	Kafka kafkav2.ConsumerConfig `yaml:"kafka_consumer"`
+}
+
+type AvatarWorker struct {
+	Consumer    *kafkav2.Consumer
+	hubMessages chan *model.Message
+	lg          logger.Logger
+}
+
+func NewAvatarWorker(cfg AvatarConfig, hubMessages chan *model.Message, lg logger.Logger) (*AvatarWorker, error) {
+	consumer, err := kafkav2.NewConsumer(cfg.Kafka)
+	if err != nil {
+		return nil, fmt.Errorf("new consumer: %w", err)
+	}
+
+	return &AvatarWorker{
+		Consumer:    consumer,
+		hubMessages: hubMessages,
+		lg:          lg,
+	}, nil
+}
+
+func (aw *AvatarWorker) Send(kafkamessages chan *kafkav2.ConsumerMessage) {
+	for kfMsg := range kafkamessages {
+		var mqdtoMsg mqdto.AvatarProcessed
+		err := json.Unmarshal(kfMsg.Value, &mqdtoMsg)
+		if err != nil {
+			aw.lg.Error(fmt.Errorf("unmarshal: %w", err))
+			continue
+		}
+
+		wsdtoMsg := wsdto.Avatar{
+			Status:    string(mqdtoMsg.Status),
+			Reason:    mqdtoMsg.Reason,
+			AvatarURL: mqdtoMsg.AvatarURL,
+			CreatedAt: mqdtoMsg.CreatedAt,
+		}
+
+		data, err := wsdtoMsg.GetData()
+		if err != nil {
+			aw.lg.Error(fmt.Errorf("get data: %w", err))
+			continue
+		}
+
+		wsdtoWSMsg := wsdto.WSMessage{
+			Data: data,
+			Type: wsdto.AvatarProcessed,
+		}
+
+		res := model.Message{
+			SubjectID: mqdtoMsg.SubjectID,
+			WSMessage: &wsdtoWSMsg,
+		}
+		aw.hubMessages <- &res
+
+		aw.lg.With("avatar", res).Info("ok")
+	}
+}
+
+func (aw *AvatarWorker) Run(ctx context.Context) {
+	err := aw.Consumer.Start(ctx)
+	if err != nil {
+		aw.lg.Error(fmt.Errorf("start: %w", err))
+		return
+	}
+
+	msgs := aw.Consumer.GetMessagesChan()
+	go aw.Send(msgs)
+
+	errorsCh := aw.Consumer.GetErrorsChan()
+	go func() {
+		for err := range errorsCh {
+			aw.lg.Error(err)
+		}
+	}()
+
+	aw.lg.Info("start avatar worker")
+
+	<-ctx.Done()
+	aw.Consumer.Close()
+}
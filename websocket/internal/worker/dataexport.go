@@ -0,0 +1,95 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	mqdto "github.com/1ocknight/mess/shared/dto/mq"
+	wsdto "github.com/1ocknight/mess/shared/dto/ws"
+	"github.com/1ocknight/mess/shared/kafkav2"
+	"github.com/1ocknight/mess/shared/logger"
+	"github.com/1ocknight/mess/websocket/internal/model"
+)
+
+type DataExportConfig struct {
+	Kafka kafkav2.ConsumerConfig `yaml:"kafka_consumer"`
+}
+
+type DataExportWorker struct {
+	Consumer    *kafkav2.Consumer
+	hubMessages chan *model.Message
+	lg          logger.Logger
+}
+
+func NewDataExportWorker(cfg DataExportConfig, hubMessages chan *model.Message, lg logger.Logger) (*DataExportWorker, error) {
+	consumer, err := kafkav2.NewConsumer(cfg.Kafka)
+	if err != nil {
+		return nil, fmt.Errorf("new consumer: %w", err)
+	}
+
+	return &DataExportWorker{
+		Consumer:    consumer,
+		hubMessages: hubMessages,
+		lg:          lg,
+	}, nil
+}
+
+func (dew *DataExportWorker) Send(kafkamessages chan *kafkav2.ConsumerMessage) {
+	for kfMsg := range kafkamessages {
+		var mqdtoMsg mqdto.DataExport
+		err := json.Unmarshal(kfMsg.Value, &mqdtoMsg)
+		if err != nil {
+			dew.lg.Error(fmt.Errorf("unmarshal: %w", err))
+			continue
+		}
+
+		wsdtoMsg := wsdto.DataExport{
+			ExportID:  mqdtoMsg.ExportID,
+			Status:    string(mqdtoMsg.Status),
+			CreatedAt: mqdtoMsg.CreatedAt,
+		}
+
+		data, err := wsdtoMsg.GetData()
+		if err != nil {
+			dew.lg.Error(fmt.Errorf("get data: %w", err))
+			continue
+		}
+
+		wsdtoWSMsg := wsdto.WSMessage{
+			Data: data,
+			Type: wsdto.DataExportReady,
+		}
+
+		res := model.Message{
+			SubjectID: mqdtoMsg.SubjectID,
+			WSMessage: &wsdtoWSMsg,
+		}
+		dew.hubMessages <- &res
+
+		dew.lg.With("data_export", res).Info("ok")
+	}
+}
+
+func (dew *DataExportWorker) Run(ctx context.Context) {
+	err := dew.Consumer.Start(ctx)
+	if err != nil {
+		dew.lg.Error(fmt.Errorf("start: %w", err))
+		return
+	}
+
+	msgs := dew.Consumer.GetMessagesChan()
+	go dew.Send(msgs)
+
+	errorsCh := dew.Consumer.GetErrorsChan()
+	go func() {
+		for err := range errorsCh {
+			dew.lg.Error(err)
+		}
+	}()
+
+	dew.lg.Info("start data export worker")
+
+	<-ctx.Done()
+	dew.Consumer.Close()
+}
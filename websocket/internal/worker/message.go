@@ -45,12 +45,13 @@ func (mw *MessageWorker) Send(kafkamessages chan *kafkav2.ConsumerMessage) {
 		}
 
 		wsdtoMsg := wsdto.Message{
-			ID:        mqdtoMsg.Message.ID,
-			ChatID:    mqdtoMsg.ChatID,
-			SenderID:  mqdtoMsg.Message.SenderID,
-			Content:   mqdtoMsg.Message.Content,
-			Version:   mqdtoMsg.Message.Version,
-			CreatedAt: mqdtoMsg.Message.CreatedAt,
+			ID:              mqdtoMsg.Message.ID,
+			ChatID:          mqdtoMsg.ChatID,
+			SenderID:        mqdtoMsg.Message.SenderID,
+			Content:         mqdtoMsg.Message.Content,
+			Version:         mqdtoMsg.Message.Version,
+			ClientMessageID: mqdtoMsg.Message.ClientMessageID,
+			CreatedAt:       mqdtoMsg.Message.CreatedAt,
 		}
 
 		data, err := wsdtoMsg.GetData()
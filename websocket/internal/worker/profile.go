@@ -0,0 +1,95 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	mqdto "github.com/1ocknight/mess/shared/dto/mq"
+	wsdto "github.com/1ocknight/mess/shared/dto/ws"
+	"github.com/1ocknight/mess/shared/kafkav2"
+	"github.com/1ocknight/mess/shared/logger"
+	"github.com/1ocknight/mess/websocket/internal/model"
+)
+
+type ProfileConfig struct {
+	Kafka kafkav2.ConsumerConfig `yaml:"kafka_consumer"`
+}
+
+type ProfileWorker struct {
+	Consumer    *kafkav2.Consumer
+	hubMessages chan *model.Message
+	lg          logger.Logger
+}
+
+func NewProfileWorker(cfg ProfileConfig, hubMessages chan *model.Message, lg logger.Logger) (*ProfileWorker, error) {
+	consumer, err := kafkav2.NewConsumer(cfg.Kafka)
+	if err != nil {
+		return nil, fmt.Errorf("new consumer: %w", err)
+	}
+
+	return &ProfileWorker{
+		Consumer:    consumer,
+		hubMessages: hubMessages,
+		lg:          lg,
+	}, nil
+}
+
+func (pw *ProfileWorker) Send(kafkamessages chan *kafkav2.ConsumerMessage) {
+	for kfMsg := range kafkamessages {
+		var mqdtoMsg mqdto.ProfileUpdated
+		err := json.Unmarshal(kfMsg.Value, &mqdtoMsg)
+		if err != nil {
+			pw.lg.Error(fmt.Errorf("unmarshal: %w", err))
+			continue
+		}
+
+		wsdtoMsg := wsdto.Profile{
+			SubjectID: mqdtoMsg.SubjectID,
+			Version:   mqdtoMsg.Version,
+			UpdatedAt: mqdtoMsg.UpdatedAt,
+		}
+
+		data, err := wsdtoMsg.GetData()
+		if err != nil {
+			pw.lg.Error(fmt.Errorf("get data: %w", err))
+			continue
+		}
+
+		wsdtoWSMsg := wsdto.WSMessage{
+			Data: data,
+			Type: wsdto.ProfileUpdated,
+		}
+
+		res := model.Message{
+			SubjectID: mqdtoMsg.RecipientID,
+			WSMessage: &wsdtoWSMsg,
+		}
+		pw.hubMessages <- &res
+
+		pw.lg.With("profile", res).Info("ok")
+	}
+}
+
+func (pw *ProfileWorker) Run(ctx context.Context) {
+	err := pw.Consumer.Start(ctx)
+	if err != nil {
+		pw.lg.Error(fmt.Errorf("start: %w", err))
+		return
+	}
+
+	msgs := pw.Consumer.GetMessagesChan()
+	go pw.Send(msgs)
+
+	errorsCh := pw.Consumer.GetErrorsChan()
+	go func() {
+		for err := range errorsCh {
+			pw.lg.Error(err)
+		}
+	}()
+
+	pw.lg.Info("start profile worker")
+
+	<-ctx.Done()
+	pw.Consumer.Close()
+}